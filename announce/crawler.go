@@ -0,0 +1,102 @@
+package announce
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the announce crawler
+type Config struct {
+	// Interval between crawls, the crawler is disabled when zero
+	Interval time.Duration `yaml:"interval"`
+	// Pages is the number of list pages crawled, newest first
+	Pages int `yaml:"pages"`
+}
+
+// Source is the part of the parser used by the crawler
+type Source interface {
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+const (
+	defaultPages = 10
+
+	timeout = time.Second * 15
+)
+
+// Crawler periodically reads the announce list pages into the index
+type Crawler struct {
+	cfg    Config
+	source Source
+	index  *Index
+	paused func() bool
+	log    *logrus.Logger
+}
+
+// NewCrawler creates a new crawler
+func NewCrawler(cfg Config, source Source, index *Index, logger *logrus.Logger) *Crawler {
+	if cfg.Pages <= 0 {
+		cfg.Pages = defaultPages
+	}
+
+	return &Crawler{cfg: cfg, source: source, index: index, log: logger}
+}
+
+// SetPaused skips the crawls while paused reports true, e.g. during the peak
+func (c *Crawler) SetPaused(paused func() bool) {
+	c.paused = paused
+}
+
+// Run crawls the announces until the context is canceled
+func (c *Crawler) Run(ctx context.Context) {
+	if c.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if c.paused == nil || !c.paused() {
+			c.crawl(ctx)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Crawler) crawl(ctx context.Context) {
+	var indexed int
+	for page := 1; page <= c.cfg.Pages; page++ {
+		pageCtx, cancel := context.WithTimeout(ctx, timeout)
+		announces, err := c.source.GetAnnounces(pageCtx, page)
+		cancel()
+		if errors.Is(err, maintenance.ErrMaintenance) {
+			c.log.Debugf("announces crawler: page %d: %s", page, err)
+			break
+		} else if err != nil {
+			c.log.Warnf("announces crawler: page %d: %s", page, err)
+			break
+		}
+
+		for _, announce := range announces.Announces {
+			c.index.Add(announce)
+			indexed++
+		}
+
+		if page >= announces.LastPage {
+			break
+		}
+	}
+
+	c.log.Infof("announces crawler: %d announces crawled, %d indexed", indexed, c.index.Len())
+}
@@ -0,0 +1,184 @@
+package announce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+)
+
+const (
+	site = "https://hmtpk.ru"
+	href = site + "/ru/press-center/announce/"
+)
+
+var (
+	ErrNotFound = errors.New("announce not found")
+
+	// ID matches the identifier of an announce in its path
+	ID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	spaces = regexp.MustCompile(`\s+`)
+)
+
+// attachmentExtensions are the files linked from announces offered as attachments
+var attachmentExtensions = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+	".ppt": true, ".pptx": true, ".odt": true, ".zip": true, ".rar": true, ".7z": true,
+}
+
+// Detail is the full page of an announce
+type Detail struct {
+	ID          string       `json:"id"`
+	Href        string       `json:"href"`
+	Title       string       `json:"title"`
+	Date        string       `json:"date"`
+	Body        string       `json:"body"`
+	Images      []string     `json:"images"`
+	Attachments []Attachment `json:"attachments"`
+	Translation *Translation `json:"translation,omitempty"`
+	// Thumbnails maps an image to the srcset of its thumbnails served by the API
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+	// Local is set for an announce published through the API instead of the site
+	Local      bool       `json:"local,omitempty"`
+	Importance Importance `json:"importance"`
+}
+
+// Translation is the machine translation of an announce
+type Translation struct {
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+}
+
+// Attachment is a file linked from an announce
+type Attachment struct {
+	Title string `json:"title"`
+	Href  string `json:"href"`
+}
+
+// PathID returns the identifier of the announce from its path on the site
+func PathID(p string) string {
+	return path.Base(strings.TrimSuffix(p, "/"))
+}
+
+// GetDetail fetches and parses the page of the announce
+func GetDetail(ctx context.Context, id string) (Detail, error) {
+	if !ID.MatchString(id) {
+		return Detail{}, hmtpkErrors.ErrorBadRequest
+	}
+
+	detail := Detail{ID: id, Href: href + id + "/"}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, detail.Href, nil)
+	if err != nil {
+		return Detail{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return Detail{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Detail{}, ErrNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Detail{}, fmt.Errorf("%w: %s", hmtpkErrors.ErrorBadResponse, resp.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return Detail{}, err
+	}
+
+	return parseDetail(doc, detail)
+}
+
+func parseDetail(doc *goquery.Document, detail Detail) (Detail, error) {
+	main := doc.Find("main").First()
+	if main.Length() == 0 {
+		main = doc.Selection
+	}
+
+	detail.Title = clean(main.Find("h1").First().Text())
+	if detail.Title == "" {
+		return Detail{}, ErrNotFound
+	}
+
+	if datetime, ok := main.Find("time[datetime]").First().Attr("datetime"); ok {
+		detail.Date = strings.TrimSpace(datetime)
+	} else {
+		detail.Date = clean(main.Find("p.c-text-secondary").First().Text())
+	}
+
+	body := main.Find("div.iblock-detail-text, div.detail-text, div.news-detail, article").First()
+	if body.Length() == 0 {
+		body = main
+	}
+
+	html, err := body.Html()
+	if err != nil {
+		return Detail{}, err
+	}
+	detail.Body = strings.TrimSpace(spaces.ReplaceAllString(html, " "))
+
+	detail.Images = []string{}
+	body.Find("img[src]").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		if link := absolute(src); link != "" {
+			detail.Images = append(detail.Images, link)
+		}
+	})
+
+	detail.Attachments = []Attachment{}
+	main.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		link, _ := s.Attr("href")
+		link = absolute(link)
+
+		u, err := url.Parse(link)
+		if err != nil || !attachmentExtensions[strings.ToLower(path.Ext(u.Path))] {
+			return
+		}
+
+		title := clean(s.Text())
+		if title == "" {
+			title = path.Base(u.Path)
+		}
+
+		detail.Attachments = append(detail.Attachments, Attachment{Title: title, Href: link})
+	})
+
+	return detail, nil
+}
+
+// absolute resolves a link of the page against the site
+func absolute(link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+
+	base, _ := url.Parse(site)
+	u, err := base.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return u.String()
+}
+
+func clean(text string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(text, " "))
+}
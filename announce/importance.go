@@ -0,0 +1,111 @@
+package announce
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	ImportanceLow    = "low"
+	ImportanceNormal = "normal"
+	ImportanceHigh   = "high"
+	ImportanceUrgent = "urgent"
+
+	// titleImportance is how much more a keyword in the title counts than in the body
+	titleImportance = 2
+
+	// urgentScore takes a strong keyword in the title or several in the body
+	urgentScore = 10
+	highScore   = 3
+	lowScore    = -2
+)
+
+// importanceRanks orders the importance levels
+var importanceRanks = map[string]int{
+	ImportanceLow:    0,
+	ImportanceNormal: 1,
+	ImportanceHigh:   2,
+	ImportanceUrgent: 3,
+}
+
+// signal is a pattern raising or lowering the importance of an announce
+type signal struct {
+	name    string
+	pattern *regexp.Regexp
+	weight  int
+}
+
+// signals are matched against the lowercase text, the stems match the inflected words
+var signals = []signal{
+	{"срочно", regexp.MustCompile(`срочн`), 5},
+	{"отмена занятий", regexp.MustCompile(`отмен\S*\s+(занят|пар|урок)`), 5},
+	{"эвакуация", regexp.MustCompile(`эвакуац`), 5},
+	{"карантин", regexp.MustCompile(`карантин`), 4},
+	{"изменение расписания", regexp.MustCompile(`(изменени|корректировк)\S*\s+(в\s+)?расписани`), 4},
+	{"перенос", regexp.MustCompile(`перен[оеё]с`), 3},
+	{"замена", regexp.MustCompile(`замен[аыу]`), 2},
+	{"крайний срок", regexp.MustCompile(`крайн\S*\s+срок|дедлайн|не позднее`), 3},
+	{"срок сдачи", regexp.MustCompile(`(до|по)\s+\d{1,2}[./]\d{2}`), 2},
+	{"экзамен", regexp.MustCompile(`экзамен|сесси[яию]`), 2},
+	{"задолженность", regexp.MustCompile(`задолженност`), 2},
+	{"обязательно", regexp.MustCompile(`обязательн`), 2},
+	{"собрание", regexp.MustCompile(`собрани`), 1},
+	{"поздравление", regexp.MustCompile(`поздравля`), -3},
+	{"конкурс", regexp.MustCompile(`конкурс|олимпиад|фестивал`), -2},
+	{"приглашение", regexp.MustCompile(`приглаша`), -1},
+	{"праздник", regexp.MustCompile(`праздни`), -1},
+}
+
+// Importance is the heuristic importance of an announce
+type Importance struct {
+	Level string `json:"level"`
+	Score int    `json:"score"`
+	// Reasons are the signals found in the announce
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Classify scores the announce by keywords like "срочно", "изменение
+// расписания" or deadlines, a keyword in the title counts double
+func Classify(title, body string) Importance {
+	title, body = strings.ToLower(title), strings.ToLower(plain(body))
+
+	var importance Importance
+	for _, s := range signals {
+		weight := 0
+		switch {
+		case s.pattern.MatchString(title):
+			weight = s.weight * titleImportance
+		case s.pattern.MatchString(body):
+			weight = s.weight
+		default:
+			continue
+		}
+
+		importance.Score += weight
+		importance.Reasons = append(importance.Reasons, s.name)
+	}
+
+	switch {
+	case importance.Score >= urgentScore:
+		importance.Level = ImportanceUrgent
+	case importance.Score >= highScore:
+		importance.Level = ImportanceHigh
+	case importance.Score <= lowScore:
+		importance.Level = ImportanceLow
+	default:
+		importance.Level = ImportanceNormal
+	}
+
+	return importance
+}
+
+// ValidImportance reports whether the level is known
+func ValidImportance(level string) bool {
+	_, ok := importanceRanks[level]
+	return ok
+}
+
+// AtLeast reports whether the level is the minimum level or above it
+func AtLeast(level, minimum string) bool {
+	return importanceRanks[level] >= importanceRanks[minimum]
+}
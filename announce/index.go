@@ -0,0 +1,285 @@
+package announce
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	// titleWeight is how much more a term in the title counts than in the body
+	titleWeight = 3
+	// snippetWords is the number of words of the body shown around the first match
+	snippetWords = 30
+
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// endings are the Russian inflection endings removed from terms, longest first
+var endings = []string{
+	"иями", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими", "ией",
+	"ий", "ый", "ой", "ая", "яя", "ое", "ее", "ые", "ие", "ов", "ев", "ей",
+	"ам", "ям", "ах", "ях", "ом", "ем", "ую", "юю", "ия", "ья",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "ь",
+}
+
+// SearchResult is an announce matching the query
+type SearchResult struct {
+	ID      string  `json:"id"`
+	Path    string  `json:"path"`
+	Date    string  `json:"date"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+type document struct {
+	announce model.Announce
+	text     string
+	terms    map[string]int
+}
+
+// Index is an in-memory inverted index of announces
+type Index struct {
+	mu        sync.RWMutex
+	documents map[string]*document
+	postings  map[string]map[string]int
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{documents: make(map[string]*document), postings: make(map[string]map[string]int)}
+}
+
+// Add indexes the announce, replacing the previous version with the same path
+func (i *Index) Add(a model.Announce) {
+	text := plain(a.Body)
+
+	terms := make(map[string]int)
+	for _, term := range tokenize(a.Title) {
+		terms[term] += titleWeight
+	}
+	for _, term := range tokenize(text) {
+		terms[term]++
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(a.Path)
+
+	i.documents[a.Path] = &document{announce: a, text: text, terms: terms}
+	for term, count := range terms {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[string]int)
+		}
+		i.postings[term][a.Path] = count
+	}
+}
+
+// remove drops the document from the postings, the lock must be held
+func (i *Index) remove(path string) {
+	old, ok := i.documents[path]
+	if !ok {
+		return
+	}
+
+	for term := range old.terms {
+		delete(i.postings[term], path)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+
+	delete(i.documents, path)
+}
+
+// Rebuild tokenizes every indexed announce again, after the archived ones so
+// the indexed version wins, e.g. after fixing the normalization of terms, and
+// returns the number of indexed announces. Searches wait for the rebuild
+func (i *Index) Rebuild(archived []model.Announce) int {
+	fresh := NewIndex()
+	for _, a := range archived {
+		fresh.Add(a)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, doc := range i.documents {
+		fresh.Add(doc.announce)
+	}
+
+	i.documents, i.postings = fresh.documents, fresh.postings
+
+	return len(i.documents)
+}
+
+// Len returns the number of indexed announces
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.documents)
+}
+
+// Search returns the announces containing every term of the query ranked by TF-IDF,
+// with the matches in the title and the snippet wrapped in <mark>
+func (i *Index) Search(query string, limit int) []SearchResult {
+	terms := unique(tokenize(query))
+	if len(terms) == 0 {
+		return []SearchResult{}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	scores := make(map[string]float64)
+	for n, term := range terms {
+		postings := i.postings[term]
+		idf := math.Log(1 + float64(len(i.documents))/float64(len(postings)+1))
+
+		next := make(map[string]float64, len(postings))
+		for path, count := range postings {
+			if _, ok := scores[path]; n == 0 || ok {
+				next[path] = scores[path] + float64(count)*idf
+			}
+		}
+		scores = next
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for path, score := range scores {
+		doc := i.documents[path]
+		results = append(results, SearchResult{
+			ID:      PathID(path),
+			Path:    path,
+			Date:    doc.announce.Date,
+			Title:   highlight(strings.Fields(doc.announce.Title), terms),
+			Snippet: snippet(doc.text, terms),
+			Score:   math.Round(score*1000) / 1000,
+		})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Path > results[b].Path
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// plain returns the text of the HTML body
+func plain(body string) string {
+	return clean(html.UnescapeString(tags.ReplaceAllString(body, " ")))
+}
+
+// tokenize splits the text into normalized terms
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if term := stem(word); term != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// stem lowercases the word and strips its inflection ending
+func stem(word string) string {
+	word = strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+	word = strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, ending := range endings {
+		if strings.HasSuffix(word, ending) && len([]rune(word))-len([]rune(ending)) >= 3 {
+			return strings.TrimSuffix(word, ending)
+		}
+	}
+
+	return word
+}
+
+func unique(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	result := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+
+	return result
+}
+
+func matches(word string, terms []string) bool {
+	s := stem(word)
+	for _, term := range terms {
+		if s == term {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlight escapes the words and marks the ones matching the terms
+func highlight(words []string, terms []string) string {
+	result := make([]string, len(words))
+	for n, word := range words {
+		result[n] = html.EscapeString(word)
+		if matches(word, terms) {
+			result[n] = markOpen + result[n] + markClose
+		}
+	}
+
+	return strings.Join(result, " ")
+}
+
+// snippet returns the part of the text around the first match
+func snippet(text string, terms []string) string {
+	words := strings.Fields(text)
+
+	first := 0
+	for n, word := range words {
+		if matches(word, terms) {
+			first = n
+			break
+		}
+	}
+
+	start := max(0, first-snippetWords/3)
+	end := min(len(words), start+snippetWords)
+
+	result := highlight(words[start:end], terms)
+	if start > 0 {
+		result = "… " + result
+	}
+	if end < len(words) {
+		result += " …"
+	}
+
+	return result
+}
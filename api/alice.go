@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	// aliceTimeout is below the three seconds Yandex Dialogs waits for the webhook
+	aliceTimeout = time.Millisecond * 2500
+	// aliceMaxText is the length limit of the response text
+	aliceMaxText = 1024
+
+	aliceVersion = "1.0"
+	aliceGroup   = "group"
+)
+
+// Alice is the configuration of the Yandex Dialogs skill
+type Alice struct {
+	// SkillID is the ID of the skill in Yandex Dialogs, enables the webhook
+	// when not empty. The requests of the other skills are refused
+	SkillID string `yaml:"skill_id"`
+}
+
+// SetAlice enables the webhook of the Yandex Dialogs skill
+func (a *API) SetAlice(cfg Alice) {
+	a.skill = cfg
+}
+
+// aliceRequest is the part of the Yandex Dialogs webhook request used by the skill
+type aliceRequest struct {
+	Request struct {
+		Command           string `json:"command"`
+		OriginalUtterance string `json:"original_utterance"`
+	} `json:"request"`
+	Session struct {
+		New     bool   `json:"new"`
+		SkillID string `json:"skill_id"`
+	} `json:"session"`
+	State struct {
+		User map[string]interface{} `json:"user"`
+	} `json:"state"`
+	Version string `json:"version"`
+}
+
+type aliceResponse struct {
+	Response struct {
+		Text       string `json:"text"`
+		EndSession bool   `json:"end_session"`
+	} `json:"response"`
+	UserStateUpdate map[string]interface{} `json:"user_state_update,omitempty"`
+	Version         string                 `json:"version"`
+}
+
+// aliceDays are the words naming a day, matched by prefix
+var aliceDays = []struct {
+	prefix string
+	day    func(now time.Time) time.Time
+}{
+	{"послезавтра", func(now time.Time) time.Time { return now.AddDate(0, 0, 2) }},
+	{"завтра", func(now time.Time) time.Time { return now.AddDate(0, 0, 1) }},
+	{"сегодня", func(now time.Time) time.Time { return now }},
+	{"понедельник", nextWeekday(time.Monday)},
+	{"вторник", nextWeekday(time.Tuesday)},
+	{"сред", nextWeekday(time.Wednesday)},
+	{"четверг", nextWeekday(time.Thursday)},
+	{"пятниц", nextWeekday(time.Friday)},
+	{"суббот", nextWeekday(time.Saturday)},
+}
+
+func nextWeekday(weekday time.Weekday) func(now time.Time) time.Time {
+	return func(now time.Time) time.Time {
+		return now.AddDate(0, 0, (int(weekday)-int(now.Weekday())+7)%7)
+	}
+}
+
+// alice implements the webhook of a Yandex Dialogs skill: the user names
+// the group once, it is kept in the user state of the skill, and asks for
+// the schedule of a day which is told by the summary generator
+func (a *API) alice(w http.ResponseWriter, r *http.Request) {
+	var request aliceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	if request.Session.SkillID != a.skill.SkillID {
+		write(w, r, http.StatusForbidden, Response{Error: ErrorToken})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), aliceTimeout)
+	defer cancel()
+
+	response := aliceResponse{Version: aliceVersion}
+	response.Response.Text = a.aliceReply(ctx, request, &response)
+
+	if runes := []rune(response.Response.Text); len(runes) > aliceMaxText {
+		response.Response.Text = string(runes[:aliceMaxText-1]) + "…"
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (a *API) aliceReply(ctx context.Context, request aliceRequest, response *aliceResponse) string {
+	command := strings.ToLower(request.Request.Command)
+	if request.Request.OriginalUtterance == "ping" {
+		return "pong"
+	}
+
+	group, _ := request.State.User[aliceGroup].(string)
+
+	switch {
+	case strings.Contains(command, "помощь") || strings.Contains(command, "что ты умеешь"):
+		return "Я рассказываю расписание ХМТПК. Назовите группу, например «группа ИС-21», " +
+			"а потом спрашивайте «что сегодня» или «расписание на четверг»."
+	case strings.Contains(command, "забудь"):
+		response.UserStateUpdate = map[string]interface{}{aliceGroup: nil}
+		return "Хорошо, я забыла вашу группу. Назовите новую, когда понадобится."
+	case request.Session.New && command == "":
+		if group == "" {
+			return "Привет! Я расскажу расписание ХМТПК. Назовите вашу группу."
+		}
+		return "Привет! Спросите, например, «что сегодня» или «расписание на завтра»."
+	}
+
+	groups, err := a.hmtpk.GetGroupOptions(ctx)
+	if err != nil {
+		return "Не удалось связаться с сайтом колледжа, попробуйте позже."
+	}
+
+	if option, ok := resolveGroup(command, groups); ok {
+		if option.Value != group {
+			group = option.Value
+			response.UserStateUpdate = map[string]interface{}{aliceGroup: group}
+		}
+	}
+
+	if group == "" {
+		return "Назовите вашу группу, например «группа ИС-21»."
+	}
+
+	now := a.now()
+	date := now
+	for _, day := range aliceDays {
+		if containsWordPrefix(command, day.prefix) {
+			date = day.day(now)
+			break
+		}
+	}
+
+	schedule, err := a.getSchedule(ctx, group, "", date.Format("02.01.2006"))
+	if err != nil {
+		return "Не удалось получить расписание, попробуйте позже."
+	}
+
+	day, _ := findDay(schedule, date.Format("02.01.2006"))
+
+	return summarize(day.Lessons, date, now, false)
+}
+
+// resolveGroup finds the group named in the phrase, spaces and dashes are
+// ignored so "ис 21" matches "ИС-21". The longest name wins
+func resolveGroup(phrase string, groups []model.Option) (model.Option, bool) {
+	squashed := squash(phrase)
+
+	var (
+		found  model.Option
+		length int
+	)
+	for _, group := range groups {
+		name := squash(group.Label)
+		if len(name) > length && strings.Contains(squashed, name) {
+			found, length = group, len(name)
+		}
+	}
+
+	return found, length > 0
+}
+
+// squash lowercases the text and keeps only letters and digits
+func squash(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, text)
+}
+
+func containsWordPrefix(text, prefix string) bool {
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,56 +2,278 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/archive"
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk-parser-api/cabinet"
+	"github.com/chazari-x/hmtpk-parser-api/checkin"
+	"github.com/chazari-x/hmtpk-parser-api/edge"
+	"github.com/chazari-x/hmtpk-parser-api/ids"
+	"github.com/chazari-x/hmtpk-parser-api/limits"
+	"github.com/chazari-x/hmtpk-parser-api/links"
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/metrics"
+	"github.com/chazari-x/hmtpk-parser-api/mirror"
+	"github.com/chazari-x/hmtpk-parser-api/news"
+	"github.com/chazari-x/hmtpk-parser-api/notify"
+	"github.com/chazari-x/hmtpk-parser-api/rediscache"
+	"github.com/chazari-x/hmtpk-parser-api/replay"
+	"github.com/chazari-x/hmtpk-parser-api/replica"
+	"github.com/chazari-x/hmtpk-parser-api/sentry"
+	"github.com/chazari-x/hmtpk-parser-api/stableid"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/chazari-x/hmtpk-parser-api/thumb"
+	"github.com/chazari-x/hmtpk-parser-api/translate"
+	"github.com/chazari-x/hmtpk-parser-api/vault"
+	"github.com/chazari-x/hmtpk-parser-api/warmup"
 	hmtpk "github.com/chazari-x/hmtpk_parser/v2"
 	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 )
 
+// ScheduleProvider provides the data served by the API
+type ScheduleProvider interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
 // API is the handler for the API
 type API struct {
-	log   *logrus.Logger
-	hmtpk *hmtpk.Controller
+	log     *logrus.Logger
+	hmtpk   ScheduleProvider
+	tenants *tenants
+	warmer  *warmup.Warmer
+	bells   bells.Bells
+
+	translator translate.Translator
+	index      *announce.Index
+	news       *news.News
+	store      storage.Store
+	telegram   telegram.Config
+	skill      Alice
+	checkin    *checkin.Signer
+	location   *time.Location
+	edge       edge.Config
+	replica    *edge.Replica
+	readOnly   bool
+	thumbnails thumb.Config
+	changes    *changes
+	archive    *archive.Archive
+	links      *links.Builder
+	replayer   *replay.Replayer
+	mirrors    *mirror.Transport
+	ids        ids.Codec
+	cache      *rediscache.Cache
+	presets    map[string]Preset
+	reporter   *sentry.Reporter
+	metrics    *metrics.Metrics
+	limiter    *limits.Limiter
+	timeouts   Timeouts
+	stableIDs  *stableid.Registry
+	auth       *auth.Auth
+	cabinet    Cabinet
+	homework   *sessionCache[cabinet.Homework]
+	vault      *vault.Vault
+	attempts   *auth.Attempts
+	notifier   *notify.Notifier
+
+	// bookings serializes changes so two bookings never take the same slot
+	bookings sync.Mutex
+	// favorites serializes the changes of the favorites
+	favorites sync.Mutex
 }
 
-// NewApi creates a new API
-func NewApi(redis *redis.Client, logger *logrus.Logger) *API {
+// NewApi creates a new API serving the data of the provider, redis caches
+// the news and may be nil
+func NewApi(provider ScheduleProvider, redis *redis.Client, logger *logrus.Logger) *API {
 	if logger == nil {
-		logger = logrus.New()
-		logger.SetLevel(logrus.TraceLevel)
-		logger.SetReportCaller(true)
-		logger.SetFormatter(&logrus.TextFormatter{
-			TimestampFormat: "2006-01-02 15:04:05",
-			FullTimestamp:   true,
-			PadLevelText:    true,
-			CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-				return "", fmt.Sprintf(" %s:%d", frame.File, frame.Line)
-			},
-		})
+		logger = defaultLogger()
+	}
+
+	a := &API{
+		log:     logger,
+		hmtpk:   provider,
+		tenants: newTenants(),
+		index:   announce.NewIndex(),
+		news:    news.NewNews(redis, logger),
+		store:   storage.NewMemory(),
+		changes: newChanges(),
+		links:   defaultLinks,
+		ids:     ids.Plain{},
+		presets: defaultPresets,
+		limiter: limits.New(limits.Config{}),
 	}
 
-	return &API{logger, hmtpk.NewController(redis, logger)}
+	a.location, _ = time.LoadLocation(defaultLocation)
+
+	return a
+}
+
+// NewControllerApi creates a new API serving the data parsed from hmtpk.ru,
+// cached in redis when it is not nil
+func NewControllerApi(redis *redis.Client, logger *logrus.Logger) *API {
+	if logger == nil {
+		logger = defaultLogger()
+	}
+
+	return NewApi(hmtpk.NewController(redis, logger), redis, logger)
+}
+
+func defaultLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.TraceLevel)
+	logger.SetReportCaller(true)
+	logger.SetFormatter(&logrus.TextFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+		FullTimestamp:   true,
+		PadLevelText:    true,
+		CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
+			return "", fmt.Sprintf(" %s:%d", frame.File, frame.Line)
+		},
+	})
+
+	return logger
+}
+
+// SetThumbnails sets the sizes and the cache of announce image thumbnails
+func (a *API) SetThumbnails(cfg thumb.Config) {
+	a.thumbnails = cfg
+}
+
+// SetReadOnly makes the API serve cached data only, without contacting hmtpk.ru
+func (a *API) SetReadOnly(readOnly bool) {
+	a.readOnly = readOnly
 }
 
+// SetProvider replaces the provider of the data, e.g. with a plugin
+func (a *API) SetProvider(provider ScheduleProvider) {
+	a.hmtpk = provider
+}
+
+// optionalRoutes are registered by features that can be excluded with build tags
+var optionalRoutes []func(a *API, r chi.Router)
+
+// detailLinks add the links to the routes of such features to an announce
+var detailLinks []func(a *API, r *http.Request, detail *announce.Detail)
+
 // Router returns the router for the API
 func (a *API) Router() func(r chi.Router) {
 	return func(r chi.Router) {
+		r.Use(a.recoverMiddleware)
+		r.Use(a.limitsMiddleware)
 		r.Use(a.headersMiddleware)
+		r.Use(a.presetMiddleware)
+		r.Use(a.fieldsMiddleware)
+
+		// routes reaching hmtpk.ru share the upstream budget of the tenant
+		r.Group(func(r chi.Router) {
+			r.Use(a.timeoutMiddleware)
+			r.Use(a.tenantsMiddleware)
+			r.Use(a.staleMiddleware)
+			r.Use(a.stableIDsMiddleware)
+
+			r.Post("/groups", a.groups)
+			r.Post("/groups/archive", a.archivedGroups)
+			r.Post("/groups/archive/{group}", a.archivedGroup)
+			r.Post("/groups/rollovers", a.rollovers)
+			r.Post("/teachers", a.teachers)
+			r.Post("/teachers/{id}/groups", a.teacherGroups)
+			r.Post("/search", a.suggest)
+			r.Post("/schedule", a.schedule)
+			r.Post("/schedule/summary", a.scheduleSummary)
+			r.Post("/schedule/now", a.scheduleNow)
+			r.Post("/schedule/batch", a.scheduleBatch)
+			r.Post("/schedule/merged", a.scheduleMerged)
+			r.Post("/schedule/preview", a.schedulePreview)
+
+			r.Post("/announces", a.announces)
+			r.Post("/announces/{id}", a.announce)
+			r.Post("/news", a.getNews)
+			r.Post("/sync", a.syncChanges)
+
+			if a.telegram.BotToken != "" {
+				r.Route("/telegram", a.telegramRoutes)
+			}
+
+			if a.checkin != nil {
+				r.Route("/checkin", a.checkinRoutes)
+			}
+
+			// Yandex Dialogs calls from its own servers and waits only three seconds
+			if a.skill.SkillID != "" {
+				r.Post("/alice", a.alice)
+			}
+
+			r.Route("/bookings", a.bookingRoutes)
+
+			// the logins are limited by the client like the other routes
+			// and by the login whatever client tries it
+			if a.auth != nil {
+				r.Route("/auth", a.authRoutes)
+			}
+
+			if a.cabinet != nil {
+				r.Route("/student", a.studentRoutes)
+			}
+
+			for _, routes := range optionalRoutes {
+				routes(a, r)
+			}
+		})
+
+		r.Post("/bells", a.bellSchedule)
+		r.Post("/subjects", a.subjects)
+		r.Post("/rooms", a.rooms)
+		r.Post("/rooms/free", a.freeRooms)
+
+		if a.archive != nil {
+			r.Post("/groups/{group}/pattern", a.groupPattern)
+			r.Post("/admin/payroll", a.payroll)
+		}
+
+		if a.edge.Token != "" {
+			r.Route("/edge", a.edgeRoutes)
+		}
+
+		if a.replayer != nil {
+			r.Route("/admin/replay", a.replayRoutes)
+		}
+
+		if a.mirrors != nil {
+			r.Post("/upstream", a.upstreamHealth)
+		}
+
+		if a.cache != nil {
+			r.Route("/admin/cache", a.cacheRoutes)
+		}
+
+		r.Route("/admin/debug", a.debugRoutes)
 
-		r.Post("/groups", a.groups)
-		r.Post("/teachers", a.teachers)
-		r.Post("/schedule", a.schedule)
+		r.Route("/me", a.meRoutes)
 
-		r.Post("/announces", a.announces)
+		r.Post("/admin/announce", a.postLocalAnnounce)
+		r.Post("/admin/rebuild", a.rebuild)
+		if !a.readOnly {
+			r.Post("/admin/refresh", a.refresh)
+		}
+		r.Post("/rooms/{room}/schedule", a.roomSchedule)
+		r.Post("/announces/search", a.searchAnnounces)
 	}
 }
 
@@ -67,12 +289,8 @@ type Response struct {
 	Error   string `json:",omitempty"`
 }
 
-// write writes the response
-func write(w http.ResponseWriter, statusCode int, data interface{}) {
-	if statusCode != http.StatusOK {
-		w.WriteHeader(statusCode)
-	}
-
+// write writes the response in the content type requested by the client
+func write(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
 	if data == nil {
 		if statusCode == http.StatusOK {
 			data = Response{Message: http.StatusText(statusCode)}
@@ -81,7 +299,39 @@ func write(w http.ResponseWriter, statusCode int, data interface{}) {
 		}
 	}
 
-	_ = json.NewEncoder(w).Encode(data)
+	// the errors are sent as problems, the protobuf clients keep the envelope
+	contentType := negotiate(r)
+	problem := false
+	if response, ok := data.(Response); ok {
+		if response.Error != "" && contentType != contentTypeProtobuf {
+			data, problem = newProblem(w, r, statusCode, response.Error), true
+		} else {
+			data = localizeResponse(w, r, response)
+		}
+	}
+
+	if contentType != contentTypeProtobuf {
+		data = omitFields(r, data)
+		if !problem {
+			data = selectFields(r, data)
+		}
+	}
+
+	body, err := encode(contentType, data)
+	if err != nil {
+		contentType = contentTypeJSON
+		body, _ = encode(contentType, data)
+	}
+
+	if problem && contentType == contentTypeJSON {
+		w.Header().Set("Content-Type", contentTypeProblem)
+	} else {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(statusCode)
+
+	_, _ = w.Write(body)
 }
 
 const (
@@ -90,158 +340,283 @@ const (
 
 	ErrorHmtpkNotWorking = "Превышено время ожидания ответа от https://hmtpk.ru"
 	ErrorBadRequest      = "Неверный запрос"
+	ErrorBadDate         = "Неверная дата"
 	ErrorToken           = "Ошибка токена пользователя"
 	ErrorRequestTimeout  = "Превышено количество запросов к ХМТПК API в секунду"
 	ErrorAny             = "Произошла ошибка в ХМТПК API"
+	ErrorNotFound        = "Не найдено"
+	ErrorNotReplicated   = "Данные ещё не получены основным экземпляром"
+	ErrorGroupArchived   = "Группа больше не найдена на сайте колледжа"
+	ErrorReplayRunning   = "Воспроизведение запросов уже запущено"
+	ErrorMaintenance     = "На сайте https://hmtpk.ru идут технические работы"
+	ErrorTooLarge        = "Слишком большой запрос"
+	ErrorOverloaded      = "ХМТПК API перегружен, повторите запрос позже"
+	ErrorDateNotWarmed   = "Расписание на эту дату ещё не загружено"
 )
 
+// error writes the response for an error returned by the parser
+func (a *API) error(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, message := a.errorResponse(r, err)
+	write(w, r, statusCode, Response{Error: message})
+}
+
+// errorResponse returns the status code and the message of an error returned
+// by the parser, the unexpected ones and the pages the parser failed to read,
+// e.g. after a change of the layout of the site, are reported
+func (a *API) errorResponse(r *http.Request, err error) (int, string) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return http.StatusInternalServerError, ErrorHmtpkNotWorking
+	} else if errors.Is(err, hmtpkErrors.ErrorBadRequest) {
+		return http.StatusBadRequest, err.Error()
+	} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
+		a.report(r, err)
+		return http.StatusInternalServerError, err.Error()
+	} else if errors.Is(err, announce.ErrNotFound) || errors.Is(err, thumb.ErrNotFound) {
+		return http.StatusNotFound, ErrorNotFound
+	} else if errors.Is(err, replica.ErrMiss) {
+		return http.StatusServiceUnavailable, ErrorNotReplicated
+	} else if errors.Is(err, maintenance.ErrMaintenance) {
+		return http.StatusServiceUnavailable, ErrorMaintenance
+	} else if errors.Is(err, ErrBudgetExhausted) {
+		a.log.WithContext(r.Context()).Warn(err)
+		return http.StatusTooManyRequests, ErrorRequestTimeout
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		a.panicked(r, panicErr.Value, panicErr.Stack)
+		a.report(r, err)
+		return http.StatusInternalServerError, ErrorAny
+	}
+
+	a.log.WithContext(r.Context()).Error(err)
+	a.report(r, err)
+
+	return http.StatusInternalServerError, ErrorAny
+}
+
 func (a *API) teachers(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	ctx, cancel := upstreamContext(r.Context())
 	defer cancel()
 
 	options, err := a.hmtpk.GetTeacherOptions(ctx)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			write(w, http.StatusInternalServerError, Response{Error: ErrorHmtpkNotWorking})
-			return
-		} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
-			write(w, http.StatusInternalServerError, Response{Error: err.Error()})
-			return
-		}
-
-		a.log.Error(err)
+		a.error(w, r, err)
+		return
+	}
 
-		write(w, http.StatusInternalServerError, Response{Error: ErrorAny})
+	options, ok := filterOptions(r, options, false)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
 		return
 	}
 
-	write(w, http.StatusOK, options)
+	write(w, r, http.StatusOK, a.withStableIDs(ctx, stableid.KindTeacher, options))
 }
 
 func (a *API) groups(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	ctx, cancel := upstreamContext(r.Context())
 	defer cancel()
 
 	options, err := a.hmtpk.GetGroupOptions(ctx)
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			write(w, http.StatusInternalServerError, Response{Error: ErrorHmtpkNotWorking})
-			return
-		} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
-			write(w, http.StatusInternalServerError, Response{Error: err.Error()})
-			return
-		}
-
-		a.log.Error(err)
+		a.error(w, r, err)
+		return
+	}
 
-		write(w, http.StatusInternalServerError, Response{Error: ErrorAny})
+	options, ok := filterOptions(r, options, true)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
 		return
 	}
 
-	write(w, http.StatusOK, options)
+	write(w, r, http.StatusOK, a.withStableIDs(ctx, stableid.KindGroup, options))
+}
+
+// getSchedule returns the weekly schedule of the group or the teacher
+func (a *API) getSchedule(ctx context.Context, group, teacher, date string) ([]model.Schedule, error) {
+	ctx, cancel := upstreamContext(ctx)
+	defer cancel()
+
+	if group != "" {
+		return a.hmtpk.GetScheduleByGroup(ctx, group, date)
+	}
+
+	if teacher != "" {
+		return a.hmtpk.GetScheduleByTeacher(ctx, teacher, date)
+	}
+
+	return nil, hmtpkErrors.ErrorBadRequest
 }
 
 func (a *API) schedule(w http.ResponseWriter, r *http.Request) {
 	key := r.URL.Query().Get("key")
 	if key == "" {
-		write(w, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
 		return
 	}
 
-	date := r.URL.Query().Get("date")
-	if date != "" {
-		if _, err := time.Parse("02.01.2006", date); err != nil {
-			write(w, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	group, teacher := r.URL.Query().Get("group"), r.URL.Query().Get("teacher")
+	if group == "" && teacher == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != formatText && format != formatMarkdown {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	schedule, fetchedAt, archived := a.archivedSchedule(r.Context(), group, teacher, date)
+	if !archived {
+		var err error
+		if schedule, err = a.getSchedule(r.Context(), group, teacher, date); err != nil {
+			a.error(w, r, err)
 			return
 		}
 	} else {
-		date = time.Now().Format("02.01.2006")
+		w.Header().Set("X-Source", sourceArchive)
 	}
 
-	group := r.URL.Query().Get("group")
-	if group != "" {
-		ctx, cancel := context.WithTimeout(r.Context(), timeout)
-		defer cancel()
-
-		scheduleByGroup, err := a.hmtpk.GetScheduleByGroup(ctx, group, date)
-		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				write(w, http.StatusInternalServerError, Response{Error: ErrorHmtpkNotWorking})
-				return
-			} else if errors.Is(err, hmtpkErrors.ErrorBadRequest) {
-				write(w, http.StatusBadRequest, Response{Error: err.Error()})
-				return
-			} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
-				write(w, http.StatusInternalServerError, Response{Error: err.Error()})
-				return
-			}
+	if format != "" {
+		writeFormatted(w, format, schedule, group == "")
+		return
+	}
+
+	days := a.isoSchedule(schedule)
+	if archived {
+		for i := range days {
+			days[i].Source, days[i].FetchedAt = sourceArchive, &fetchedAt
+		}
+	} else {
+		markStale(r, days)
+	}
+	localize(w, r, days)
+
+	write(w, r, http.StatusOK, days)
+}
 
-			a.log.Error(err)
+// announces returns the page of announces with their importance, only the
+// ones at the importance given or above it when it is set
+func (a *API) announces(w http.ResponseWriter, r *http.Request) {
+	page, ok := sitePage(r, "announces")
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	importance := r.URL.Query().Get("importance")
+	if importance != "" && !announce.ValidImportance(importance) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
 
-			write(w, http.StatusInternalServerError, Response{Error: ErrorAny})
+	announces, err := a.hmtpk.GetAnnounces(ctx, page)
+	announces.Announces = a.withLocalAnnounces(ctx, page, announces.Announces)
+	if err != nil {
+		// the announces of the staff are served while the site is down
+		if len(announces.Announces) == 0 {
+			a.error(w, r, err)
 			return
 		}
+		announces.LastPage = 1
+	}
+
+	result := classify(announces, importance)
+	result.Cursors = siteCursors(w, r, "announces", page, result.LastPage)
 
-		write(w, http.StatusOK, scheduleByGroup)
+	write(w, r, http.StatusOK, result)
+}
+
+func (a *API) announce(w http.ResponseWriter, r *http.Request) {
+	language := r.URL.Query().Get("translate")
+	if language != "" && (a.translator == nil || !translate.Language.MatchString(language)) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
 		return
 	}
 
-	teacher := r.URL.Query().Get("teacher")
-	if teacher != "" {
-		ctx, cancel := context.WithTimeout(r.Context(), timeout)
-		defer cancel()
-
-		scheduleByTeacher, err := a.hmtpk.GetScheduleByTeacher(ctx, teacher, date)
-		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-				write(w, http.StatusInternalServerError, Response{Error: ErrorHmtpkNotWorking})
-				return
-			} else if errors.Is(err, hmtpkErrors.ErrorBadRequest) {
-				write(w, http.StatusBadRequest, Response{Error: err.Error()})
-				return
-			} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
-				write(w, http.StatusInternalServerError, Response{Error: err.Error()})
-				return
-			}
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	id, err := a.ids.Decode(ids.KindAnnounce, chi.URLParam(r, "id"))
+	if err != nil {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	}
 
-			a.log.Error(err)
+	var detail announce.Detail
+	switch {
+	case strings.HasPrefix(id, localIDPrefix):
+		detail, err = a.localDetail(r, id)
+	case a.readOnly:
+		err = replica.ErrMiss
+	default:
+		detail, err = announce.GetDetail(ctx, id)
+	}
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+	detail.Importance = announce.Classify(detail.Title, detail.Body)
 
-			write(w, http.StatusInternalServerError, Response{Error: ErrorAny})
+	if language != "" && language != translate.Source {
+		if detail.Translation, err = a.translate(ctx, detail, language); err != nil {
+			a.error(w, r, err)
 			return
 		}
+	}
 
-		write(w, http.StatusOK, scheduleByTeacher)
-		return
+	for _, links := range detailLinks {
+		links(a, r, &detail)
 	}
 
-	write(w, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+	detail.ID = a.ids.Encode(ids.KindAnnounce, detail.ID)
+
+	write(w, r, http.StatusOK, detail)
 }
 
-func (a *API) announces(w http.ResponseWriter, r *http.Request) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil {
-		write(w, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+// NewsPage is a page of the news of the college with the cursors around it
+type NewsPage struct {
+	model.Announces
+	Cursors
+}
+
+func (a *API) getNews(w http.ResponseWriter, r *http.Request) {
+	page, ok := sitePage(r, "news")
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	ctx, cancel := upstreamContext(r.Context())
 	defer cancel()
 
-	announces, err := a.hmtpk.GetAnnounces(ctx, page)
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			write(w, http.StatusInternalServerError, Response{Error: ErrorHmtpkNotWorking})
-			return
-		} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
-			write(w, http.StatusInternalServerError, Response{Error: err.Error()})
+	if a.readOnly {
+		items, ok := a.news.Cached(ctx, page)
+		if !ok {
+			a.error(w, r, replica.ErrMiss)
 			return
 		}
 
-		a.log.Error(err)
+		write(w, r, http.StatusOK, NewsPage{Announces: items, Cursors: siteCursors(w, r, "news", page, items.LastPage)})
+		return
+	}
 
-		write(w, http.StatusInternalServerError, Response{Error: ErrorAny})
+	items, err := a.news.GetNews(ctx, page)
+	if err != nil {
+		a.error(w, r, err)
 		return
 	}
 
-	write(w, http.StatusOK, announces)
+	write(w, r, http.StatusOK, NewsPage{Announces: items, Cursors: siteCursors(w, r, "news", page, items.LastPage)})
 }
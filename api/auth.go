@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	ErrorCredentials   = "Неверный логин или пароль"
+	ErrorLoginAttempts = "Слишком много попыток входа, повторите позже"
+)
+
+type userKey struct{}
+
+// SetAuth enables the authentication of the students for the personal endpoints
+func (a *API) SetAuth(authentication *auth.Auth) {
+	a.auth = authentication
+}
+
+// authRoutes issue, refresh and revoke the tokens of the students. The
+// credentials and the refresh tokens are read from the form in the body,
+// never from the query logged with the requests
+func (a *API) authRoutes(r chi.Router) {
+	if a.auth.CanLogin() {
+		r.Post("/login", a.login)
+	}
+	r.Post("/refresh", a.refreshTokens)
+	r.Post("/logout", a.logout)
+
+	r.With(a.userMiddleware).Post("/me", a.me)
+}
+
+func (a *API) login(w http.ResponseWriter, r *http.Request) {
+	login, password := strings.TrimSpace(r.PostFormValue("login")), r.PostFormValue("password")
+	if login == "" || password == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	tokens, err := a.auth.Login(r.Context(), login, password)
+	if errors.Is(err, auth.ErrCredentials) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorCredentials})
+		return
+	} else if errors.Is(err, auth.ErrTooManyAttempts) {
+		write(w, r, http.StatusTooManyRequests, Response{Error: ErrorLoginAttempts})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, tokens)
+}
+
+func (a *API) refreshTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := a.auth.Refresh(r.Context(), r.PostFormValue("refresh_token"))
+	if errors.Is(err, auth.ErrInvalid) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, tokens)
+}
+
+// logout revokes the refresh token, with all=true every session of the student
+func (a *API) logout(w http.ResponseWriter, r *http.Request) {
+	all, _ := strconv.ParseBool(r.PostFormValue("all"))
+
+	err := a.auth.Revoke(r.Context(), r.PostFormValue("refresh_token"), all)
+	if errors.Is(err, auth.ErrInvalid) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, nil)
+}
+
+// me returns the claims of the access token
+func (a *API) me(w http.ResponseWriter, r *http.Request) {
+	claims, _ := r.Context().Value(userKey{}).(auth.Claims)
+	write(w, r, http.StatusOK, claims)
+}
+
+// userMiddleware passes the claims of the access token in the Authorization
+// header to the personal endpoints, answering 401 without a valid one
+func (a *API) userMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := a.verifyBearer(w, r)
+		if !ok {
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, claims)))
+	})
+}
+
+// verifyBearer returns the claims of the access token in the Authorization
+// header, the error is written when it is missing or not valid
+func (a *API) verifyBearer(w http.ResponseWriter, r *http.Request) (auth.Claims, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || a.auth == nil {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return auth.Claims{}, false
+	}
+
+	claims, err := a.auth.Verify(r.Context(), strings.TrimSpace(token))
+	if errors.Is(err, auth.ErrInvalid) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return auth.Claims{}, false
+	} else if err != nil {
+		a.error(w, r, err)
+		return auth.Claims{}, false
+	}
+
+	return claims, true
+}
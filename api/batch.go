@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/chazari-x/hmtpk-parser-api/stableid"
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+)
+
+const (
+	// maxBatch is the number of schedules requested at once
+	maxBatch = 50
+	// batchConcurrency is the number of schedules of a batch fetched at once
+	batchConcurrency = 4
+
+	maxBatchBody = 64 << 10
+)
+
+// BatchItem is a schedule requested in a batch
+type BatchItem struct {
+	Group   string `json:"group,omitempty"`
+	Teacher string `json:"teacher,omitempty"`
+	// GroupID and TeacherID are the stable IDs passed instead of the group or the teacher
+	GroupID   int    `json:"group_id,omitempty"`
+	TeacherID int    `json:"teacher_id,omitempty"`
+	Date      string `json:"date,omitempty"`
+}
+
+// targets returns the number of the groups and the teachers the item names
+func (item BatchItem) targets() int {
+	n := 0
+	for _, set := range []bool{item.Group != "", item.Teacher != "", item.GroupID != 0, item.TeacherID != 0} {
+		if set {
+			n++
+		}
+	}
+
+	return n
+}
+
+// BatchResult is the schedule of a batch item or the error returned for it
+type BatchResult struct {
+	BatchItem
+	Schedule []Day  `json:"schedule"`
+	Error    string `json:"error,omitempty"`
+	// Code is the code of the problem of the error
+	Code string `json:"code,omitempty"`
+}
+
+// scheduleBatch returns the weekly schedules of a JSON list of groups and
+// teachers in one response, in the order of the request
+func (a *API) scheduleBatch(w http.ResponseWriter, r *http.Request) {
+	var items []BatchItem
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBatchBody)).Decode(&items); err != nil || len(items) == 0 || len(items) > maxBatch {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		if item.targets() != 1 || item.GroupID < 0 || item.TeacherID < 0 {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+
+		date, ok := ParseDate(item.Date, a.now())
+		if !ok {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+			return
+		}
+
+		item.Date = date
+		results[i].BatchItem = item
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchConcurrency)
+	for i := range results {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(result *BatchResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			fail := func(statusCode int, message string) {
+				result.Error, result.Code = localizeMessage(r, message), errorCode(message, statusCode)
+			}
+
+			group, teacher := result.Group, result.Teacher
+			for _, id := range []struct {
+				kind  string
+				id    int
+				value *string
+			}{{stableid.KindGroup, result.GroupID, &group}, {stableid.KindTeacher, result.TeacherID, &teacher}} {
+				if id.id == 0 {
+					continue
+				}
+
+				value, ok, err := a.resolveStableID(r.Context(), id.kind, id.id)
+				if err != nil {
+					fail(a.errorResponse(r, err))
+					return
+				} else if !ok {
+					fail(http.StatusNotFound, ErrorNotFound)
+					return
+				}
+				*id.value = value
+			}
+
+			// every item tells the fallback it was served from itself
+			ctx, report := stale.WithReport(r.Context())
+			schedule, err := a.getSchedule(ctx, group, teacher, result.Date)
+			if err != nil {
+				fail(a.errorResponse(r, err))
+				return
+			}
+
+			result.Schedule = a.isoSchedule(schedule)
+			if source, fetchedAt, ok := report.Get(); ok {
+				stale.Mark(r.Context(), source, fetchedAt)
+				for i := range result.Schedule {
+					result.Schedule[i].Source, result.Schedule[i].FetchedAt = source, &fetchedAt
+				}
+			}
+		}(&results[i])
+	}
+
+	wg.Wait()
+
+	schedules := make([][]Day, 0, len(results))
+	for _, result := range results {
+		schedules = append(schedules, result.Schedule)
+	}
+	localize(w, r, schedules...)
+
+	write(w, r, http.StatusOK, results)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk-parser-api/warmup"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	bellsSourceSchedule = "schedule"
+	bellsSourceConfig   = "config"
+)
+
+// BellsResponse is the bell schedule with the origin of the data
+type BellsResponse struct {
+	bells.Bells
+	Source string `json:"source"`
+}
+
+// SetWarmer sets the warmer whose snapshot is used by aggregated endpoints
+func (a *API) SetWarmer(warmer *warmup.Warmer) {
+	a.warmer = warmer
+}
+
+// SetBells sets the bell schedule used when no schedules are warmed yet
+func (a *API) SetBells(bells bells.Bells) {
+	a.bells = bells
+}
+
+// snapshot returns the warmed weekly schedules of all groups
+func (a *API) snapshot() map[string][]model.Schedule {
+	if a.warmer == nil {
+		return nil
+	}
+
+	return a.warmer.Schedules()
+}
+
+// currentBells returns the bell schedule built from warmed schedules, the configured one otherwise
+func (a *API) currentBells() BellsResponse {
+	snapshot := a.snapshot()
+
+	schedules := make([][]model.Schedule, 0, len(snapshot))
+	for _, schedule := range snapshot {
+		schedules = append(schedules, schedule)
+	}
+
+	if b := bells.FromSchedules(schedules, DayDate); !b.Empty() {
+		return BellsResponse{Bells: b, Source: bellsSourceSchedule}
+	}
+
+	return BellsResponse{Bells: a.bells, Source: bellsSourceConfig}
+}
+
+func (a *API) bellSchedule(w http.ResponseWriter, r *http.Request) {
+	write(w, r, http.StatusOK, a.currentBells())
+}
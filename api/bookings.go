@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	bookingPrefix     = "booking:"
+	bookingSlotPrefix = "booking-slot:"
+
+	maxBookingTitle = 200
+
+	ErrorSlotTaken = "Кабинет занят в это время"
+)
+
+// Booking is a provisional reservation of a free room for a lesson slot
+type Booking struct {
+	ID      string    `json:"id"`
+	Room    string    `json:"room"`
+	Date    string    `json:"date"`
+	Lesson  string    `json:"lesson"`
+	Title   string    `json:"title"`
+	Owner   string    `json:"owner"`
+	Created time.Time `json:"created"`
+}
+
+func (b Booking) slotKey() string {
+	return bookingSlotPrefix + b.Date + ":" + b.Lesson + ":" + strings.ToLower(b.Room)
+}
+
+func (a *API) bookingRoutes(r chi.Router) {
+	r.Post("/", a.listBookings)
+	r.Post("/{id}", a.getBooking)
+
+	// a replica only reads the bookings made on the primary
+	if !a.readOnly {
+		r.Post("/new", a.createBooking)
+		r.Post("/{id}/update", a.updateBooking)
+		r.Post("/{id}/delete", a.deleteBooking)
+	}
+}
+
+// tenantName returns the name of the tenant with an API key, empty for anonymous clients
+func (a *API) tenantName(r *http.Request) string {
+	if r.Header.Get(apiKeyHeader) == "" {
+		return ""
+	}
+
+	state, ok := a.tenants.get(r)
+	if !ok {
+		return ""
+	}
+
+	return state.tenant.Name
+}
+
+func (a *API) loadBooking(ctx context.Context, id string) (Booking, error) {
+	value, err := a.store.Get(ctx, bookingPrefix+id)
+	if err != nil {
+		return Booking{}, err
+	}
+
+	var booking Booking
+	err = json.Unmarshal([]byte(value), &booking)
+
+	return booking, err
+}
+
+// saveBooking validates the slot against the schedule and other bookings,
+// normalizes the date and the room and stores the booking
+func (a *API) saveBooking(ctx context.Context, booking *Booking, previous *Booking) (int, string, error) {
+	now := a.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date, ok := ParseDate(booking.Date, now)
+	if d, err := time.Parse("02.01.2006", date); booking.Date == "" || !ok || err != nil || d.Before(today) {
+		return http.StatusBadRequest, ErrorBadDate, nil
+	}
+	booking.Date = date
+
+	// the rooms are known to be free in the warmed week only
+	if !a.warmed(booking.Date) {
+		return http.StatusBadRequest, ErrorDateNotWarmed, nil
+	}
+
+	if _, err := strconv.Atoi(booking.Lesson); err != nil || booking.Room == "" || len([]rune(booking.Title)) > maxBookingTitle {
+		return http.StatusBadRequest, ErrorBadRequest, nil
+	}
+
+	known := false
+	for room := range a.roomLessons() {
+		if strings.EqualFold(room, booking.Room) {
+			booking.Room, known = room, true
+			break
+		}
+	}
+
+	if !known {
+		return http.StatusNotFound, ErrorNotFound, nil
+	}
+
+	if a.occupied(booking.Date, booking.Lesson)[strings.ToLower(booking.Room)] {
+		return http.StatusConflict, ErrorSlotTaken, nil
+	}
+
+	data, err := json.Marshal(booking)
+	if err != nil {
+		return 0, "", err
+	}
+
+	a.bookings.Lock()
+	defer a.bookings.Unlock()
+
+	// the slot is claimed in the store, shared by the instances with Redis
+	moved := previous == nil || previous.slotKey() != booking.slotKey()
+	if moved {
+		claimed, err := a.store.SetIfAbsent(ctx, booking.slotKey(), booking.ID)
+		if err != nil {
+			return 0, "", err
+		} else if !claimed {
+			return http.StatusConflict, ErrorSlotTaken, nil
+		}
+	}
+
+	if err = a.store.Set(ctx, bookingPrefix+booking.ID, string(data)); err != nil {
+		if moved {
+			_ = a.store.Delete(ctx, booking.slotKey())
+		}
+		return 0, "", err
+	}
+
+	if previous != nil && moved {
+		return 0, "", a.store.Delete(ctx, previous.slotKey())
+	}
+
+	return 0, "", nil
+}
+
+// listBookings returns the bookings filtered by date and room, a page of
+// them with the links to the other pages in the Link header
+func (a *API) listBookings(w http.ResponseWriter, r *http.Request) {
+	date, room := r.URL.Query().Get("date"), r.URL.Query().Get("room")
+	if date != "" {
+		var ok bool
+		if date, ok = ParseDate(date, a.now()); !ok {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+			return
+		}
+	}
+
+	offset, limit, ok := offsetPage(r, "bookings", maxPageSize, maxPageSize)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	keys, err := a.store.Keys(r.Context(), bookingPrefix)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	list := make([]Booking, 0, len(keys))
+	for _, key := range keys {
+		booking, err := a.loadBooking(r.Context(), strings.TrimPrefix(key, bookingPrefix))
+		if err != nil {
+			continue
+		}
+
+		if (date == "" || booking.Date == date) && (room == "" || strings.EqualFold(booking.Room, room)) {
+			list = append(list, booking)
+		}
+	}
+
+	sortBookings(list)
+
+	list, more := paginate(list, offset, limit)
+	offsetCursors(w, r, "bookings", offset, limit, more)
+
+	write(w, r, http.StatusOK, list)
+}
+
+// sortBookings orders the bookings by date and lesson
+func sortBookings(list []Booking) {
+	sort.SliceStable(list, func(i, j int) bool {
+		x, _ := time.Parse("02.01.2006", list[i].Date)
+		y, _ := time.Parse("02.01.2006", list[j].Date)
+		if !x.Equal(y) {
+			return x.Before(y)
+		}
+		return lessNumeric(list[i].Lesson, list[j].Lesson)
+	})
+}
+
+// createBooking books a free room, only tenants with an API key may book
+func (a *API) createBooking(w http.ResponseWriter, r *http.Request) {
+	owner := a.tenantName(r)
+	if owner == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	booking := Booking{
+		ID:      hex.EncodeToString(id),
+		Room:    strings.TrimSpace(query.Get("room")),
+		Date:    query.Get("date"),
+		Lesson:  query.Get("lesson"),
+		Title:   strings.TrimSpace(query.Get("title")),
+		Owner:   owner,
+		Created: time.Now(),
+	}
+
+	if status, message, err := a.saveBooking(r.Context(), &booking, nil); err != nil {
+		a.error(w, r, err)
+		return
+	} else if status != 0 {
+		write(w, r, status, Response{Error: message})
+		return
+	}
+
+	write(w, r, http.StatusCreated, booking)
+}
+
+func (a *API) getBooking(w http.ResponseWriter, r *http.Request) {
+	booking, err := a.loadBooking(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, booking)
+}
+
+// ownBooking loads the booking of the id if it belongs to the tenant making the request
+func (a *API) ownBooking(w http.ResponseWriter, r *http.Request) (Booking, bool) {
+	owner := a.tenantName(r)
+	if owner == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return Booking{}, false
+	}
+
+	booking, err := a.loadBooking(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && booking.Owner != owner) {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return Booking{}, false
+	} else if err != nil {
+		a.error(w, r, err)
+		return Booking{}, false
+	}
+
+	return booking, true
+}
+
+// updateBooking moves the booking or changes its title, omitted fields are kept
+func (a *API) updateBooking(w http.ResponseWriter, r *http.Request) {
+	previous, ok := a.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	booking := previous
+	query := r.URL.Query()
+	if v := strings.TrimSpace(query.Get("room")); v != "" {
+		booking.Room = v
+	}
+	if v := query.Get("date"); v != "" {
+		booking.Date = v
+	}
+	if v := query.Get("lesson"); v != "" {
+		booking.Lesson = v
+	}
+	if query.Has("title") {
+		booking.Title = strings.TrimSpace(query.Get("title"))
+	}
+
+	if status, message, err := a.saveBooking(r.Context(), &booking, &previous); err != nil {
+		a.error(w, r, err)
+		return
+	} else if status != 0 {
+		write(w, r, status, Response{Error: message})
+		return
+	}
+
+	write(w, r, http.StatusOK, booking)
+}
+
+func (a *API) deleteBooking(w http.ResponseWriter, r *http.Request) {
+	booking, ok := a.ownBooking(w, r)
+	if !ok {
+		return
+	}
+
+	a.bookings.Lock()
+	defer a.bookings.Unlock()
+
+	if err := a.store.Delete(r.Context(), booking.slotKey()); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if err := a.store.Delete(r.Context(), bookingPrefix+booking.ID); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, nil)
+}
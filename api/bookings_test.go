@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/warmup"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSortBookings(t *testing.T) {
+	tests := []struct {
+		name  string
+		list  []Booking
+		order []string
+	}{
+		{
+			name: "dates across months and years",
+			list: []Booking{
+				{ID: "a", Date: "01.02.2026", Lesson: "1"},
+				{ID: "b", Date: "31.01.2026", Lesson: "1"},
+				{ID: "c", Date: "15.12.2025", Lesson: "1"},
+			},
+			order: []string{"c", "b", "a"},
+		},
+		{
+			name: "lessons of a day numerically",
+			list: []Booking{
+				{ID: "a", Date: "02.03.2026", Lesson: "10"},
+				{ID: "b", Date: "02.03.2026", Lesson: "2"},
+				{ID: "c", Date: "01.03.2026", Lesson: "12"},
+			},
+			order: []string{"c", "b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortBookings(tt.list)
+
+			for i, id := range tt.order {
+				if tt.list[i].ID != id {
+					t.Fatalf("position %d: got %s, want %s", i, tt.list[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+// bookingAPI returns the API with the week of today warmed for one group
+// having a lesson in room 101 at the first slot of today
+func bookingAPI(t *testing.T) (*API, time.Time) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	a := NewApi(nil, nil, logger)
+
+	now := a.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+
+	week := make([]model.Schedule, 0, 7)
+	for i := 0; i < 7; i++ {
+		day := monday.AddDate(0, 0, i)
+		schedule := model.Schedule{Href: "/?date_edu1c=" + day.Format("02.01.2006")}
+		if day.Equal(today) {
+			schedule.Lessons = []model.Lesson{{Num: "1", Room: "101"}, {Num: "2", Room: "202"}}
+		}
+		week = append(week, schedule)
+	}
+
+	warmer := warmup.NewWarmer(warmup.Config{}, weekSource(week), logger)
+	warmer.Warm(context.Background())
+	a.SetWarmer(warmer)
+
+	return a, today
+}
+
+// weekSource serves the week for its only group
+type weekSource []model.Schedule
+
+func (s weekSource) GetGroupOptions(context.Context) ([]model.Option, error) {
+	return []model.Option{{Label: "group", Value: "group"}}, nil
+}
+
+func (s weekSource) GetScheduleByGroup(context.Context, string, string) ([]model.Schedule, error) {
+	return s, nil
+}
+
+func TestSaveBooking(t *testing.T) {
+	a, today := bookingAPI(t)
+	date := today.Format("02.01.2006")
+
+	taken := Booking{ID: "taken", Room: "202", Date: date, Lesson: "3"}
+	if status, message, err := a.saveBooking(context.Background(), &taken, nil); err != nil || status != 0 {
+		t.Fatalf("booking the free slot: %d %s %v", status, message, err)
+	}
+
+	tests := []struct {
+		name    string
+		booking Booking
+		status  int
+		message string
+	}{
+		{"free slot", Booking{Room: "101", Date: date, Lesson: "2"}, 0, ""},
+		{"lesson of the schedule", Booking{Room: "101", Date: date, Lesson: "1"}, http.StatusConflict, ErrorSlotTaken},
+		{"other booking", Booking{Room: "202", Date: date, Lesson: "3"}, http.StatusConflict, ErrorSlotTaken},
+		{"past date", Booking{Room: "101", Date: today.AddDate(0, 0, -1).Format("02.01.2006"), Lesson: "2"}, http.StatusBadRequest, ErrorBadDate},
+		{"date outside of the warmed week", Booking{Room: "101", Date: today.AddDate(0, 0, 7).Format("02.01.2006"), Lesson: "2"}, http.StatusBadRequest, ErrorDateNotWarmed},
+		{"malformed date", Booking{Room: "101", Date: "31.02", Lesson: "2"}, http.StatusBadRequest, ErrorBadDate},
+		{"malformed lesson", Booking{Room: "101", Date: date, Lesson: "first"}, http.StatusBadRequest, ErrorBadRequest},
+		{"unknown room", Booking{Room: "999", Date: date, Lesson: "2"}, http.StatusNotFound, ErrorNotFound},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := tt.booking
+			booking.ID = string(rune('a' + i))
+
+			status, message, err := a.saveBooking(context.Background(), &booking, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if status != tt.status || message != tt.message {
+				t.Fatalf("got %d %q, want %d %q", status, message, tt.status, tt.message)
+			}
+
+			// the booked slots are freed for the next cases
+			if status == 0 {
+				_ = a.store.Delete(context.Background(), booking.slotKey())
+			}
+		})
+	}
+}
+
+func TestMoveBooking(t *testing.T) {
+	a, today := bookingAPI(t)
+	date := today.Format("02.01.2006")
+
+	booking := Booking{ID: "moved", Room: "101", Date: date, Lesson: "3"}
+	if status, message, err := a.saveBooking(context.Background(), &booking, nil); err != nil || status != 0 {
+		t.Fatalf("booking: %d %s %v", status, message, err)
+	}
+
+	tests := []struct {
+		name   string
+		lesson string
+		status int
+	}{
+		{"same slot", "3", 0},
+		{"another slot", "4", 0},
+		{"back to the freed slot", "3", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := booking
+			booking.Lesson = tt.lesson
+
+			status, message, err := a.saveBooking(context.Background(), &booking, &previous)
+			if err != nil || status != tt.status {
+				t.Fatalf("got %d %s %v, want %d", status, message, err, tt.status)
+			}
+		})
+	}
+
+	if keys, _ := a.store.Keys(context.Background(), bookingSlotPrefix); len(keys) != 1 {
+		t.Fatalf("slots left: %v", keys)
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/rediscache"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCacheKeys bounds the keys listed by the cache inspection
+const maxCacheKeys = 1000
+
+// CacheKeys are the keys of the parser cache with their time to live
+type CacheKeys struct {
+	Keys []rediscache.Key `json:"keys"`
+	// More is set when there are more keys than listed
+	More bool `json:"more"`
+	Cursors
+}
+
+// SetCache enables the administration of the Redis cache of the parser
+func (a *API) SetCache(cache *rediscache.Cache) {
+	a.cache = cache
+}
+
+func (a *API) cacheRoutes(r chi.Router) {
+	r.Post("/", a.cacheKeys)
+	r.Post("/flush", a.flushCache)
+}
+
+// cachePatterns returns the patterns of the keys selected by the query: the
+// weeks of the group or the teacher, of the week of the date when set, the
+// pages of announces or the lists of groups and teachers. Nothing is selected
+// without parameters
+func (a *API) cachePatterns(r *http.Request) ([]string, bool) {
+	query := r.URL.Query()
+
+	var patterns []string
+	for _, value := range []string{query.Get("group"), query.Get("teacher")} {
+		if value == "" {
+			continue
+		}
+
+		date := ""
+		if query.Get("date") != "" {
+			var ok bool
+			if date, ok = a.parseDate(r); !ok {
+				return nil, false
+			}
+		}
+
+		patterns = append(patterns, rediscache.SchedulePattern(value, date))
+	}
+
+	if query.Get("announces") == "true" {
+		patterns = append(patterns, rediscache.AnnouncesPattern())
+	}
+
+	if query.Get("options") == "true" {
+		patterns = append(patterns, rediscache.OptionsPatterns()...)
+	}
+
+	return patterns, true
+}
+
+// cacheKeys lists the cached keys selected like the flush, every key of the
+// parser without parameters, with their time to live. Only administrator
+// tenants may inspect the cache
+func (a *API) cacheKeys(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	patterns, ok := a.cachePatterns(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	offset, limit, ok := offsetPage(r, "cache", maxCacheKeys, maxCacheKeys)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	if len(patterns) == 0 {
+		patterns = rediscache.Namespace()
+	}
+
+	keys, more, err := a.cache.Keys(r.Context(), patterns, offset, limit)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, CacheKeys{Keys: keys, More: more, Cursors: offsetCursors(w, r, "cache", offset, limit, more)})
+}
+
+// flushCache deletes the cached schedules of the group or the teacher, of the
+// week of the date when set, the announces with announces=true, the lists of
+// groups and teachers with options=true or the whole parser cache with
+// all=true, so the next request fetches hmtpk.ru. The data of the service is
+// never flushed. Only administrator tenants may flush the cache
+func (a *API) flushCache(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	patterns, ok := a.cachePatterns(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	if r.URL.Query().Get("all") == "true" {
+		patterns = rediscache.Namespace()
+	}
+
+	if len(patterns) == 0 {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	flushed, err := a.cache.Flush(r.Context(), patterns)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	a.log.WithContext(r.Context()).Infof("cache: %d keys flushed by %v", flushed, patterns)
+
+	write(w, r, http.StatusOK, struct {
+		Flushed int `json:"flushed"`
+	}{flushed})
+}
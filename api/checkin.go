@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/checkin"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxStudentName = 100
+
+// CheckinToken is the lesson token shown to the students by the group leader
+type CheckinToken struct {
+	checkin.Token
+	Value string `json:"token"`
+}
+
+// Checkin is a student marked present at the lesson
+type Checkin struct {
+	Student string    `json:"student"`
+	Time    time.Time `json:"time"`
+}
+
+// CheckinList is the attendance of the lesson
+type CheckinList struct {
+	checkin.Token
+	Checkins []Checkin `json:"checkins"`
+}
+
+// SetCheckin enables attendance check-ins
+func (a *API) SetCheckin(cfg checkin.Config) {
+	if cfg.Secret != "" {
+		a.checkin = checkin.NewSigner(cfg)
+	}
+}
+
+func (a *API) checkinRoutes(r chi.Router) {
+	r.Post("/", a.checkIn)
+	r.Post("/token", a.checkinToken)
+	r.Post("/list", a.checkinList)
+}
+
+func checkinKey(token checkin.Token) string {
+	return "checkin:" + token.Group + ":" + token.Date + ":" + token.Lesson + ":"
+}
+
+// checkinToken issues the token of a lesson found in the schedule of the group.
+// Only tenants with an API key, e.g. the bot of group leaders, may issue tokens
+func (a *API) checkinToken(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	group, lesson := r.URL.Query().Get("group"), r.URL.Query().Get("lesson")
+	if group == "" || lesson == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	schedule, err := a.getSchedule(r.Context(), group, "", date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	day, _ := findDay(schedule, date)
+
+	found := false
+	for _, l := range day.Lessons {
+		if l.Num == lesson {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	}
+
+	token, value := a.checkin.Issue(group, date, lesson, time.Now())
+
+	write(w, r, http.StatusOK, CheckinToken{Token: token, Value: value})
+}
+
+// checkIn records the student at the lesson of the token, repeated check-ins keep the first time
+func (a *API) checkIn(w http.ResponseWriter, r *http.Request) {
+	token, err := a.checkin.Verify(r.URL.Query().Get("token"), time.Now())
+	if err != nil {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	student := strings.TrimSpace(r.URL.Query().Get("student"))
+	if student == "" || len([]rune(student)) > maxStudentName {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	key := checkinKey(token) + student
+	if _, err = a.store.Get(r.Context(), key); err == nil {
+		write(w, r, http.StatusOK, nil)
+		return
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		a.error(w, r, err)
+		return
+	}
+
+	if err = a.store.Set(r.Context(), key, time.Now().Format(time.RFC3339)); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, nil)
+}
+
+// checkinList returns the students checked in to the lesson of the token, also after it expired
+func (a *API) checkinList(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	token, err := a.checkin.Verify(r.URL.Query().Get("token"), time.Time{})
+	if err != nil {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	prefix := checkinKey(token)
+	keys, err := a.store.Keys(r.Context(), prefix)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	list := CheckinList{Token: token, Checkins: make([]Checkin, 0, len(keys))}
+	for _, key := range keys {
+		value, err := a.store.Get(r.Context(), key)
+		if err != nil {
+			continue
+		}
+
+		at, _ := time.Parse(time.RFC3339, value)
+		list.Checkins = append(list.Checkins, Checkin{Student: strings.TrimPrefix(key, prefix), Time: at})
+	}
+
+	write(w, r, http.StatusOK, list)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"golang.org/x/sync/singleflight"
+)
+
+// coalesced shares one fetch between concurrent identical requests, e.g. the
+// whole group opening its schedule at the start of the first lesson
+type coalesced struct {
+	source ScheduleProvider
+	group  singleflight.Group
+}
+
+// Coalesce wraps the provider so concurrent identical requests make a single
+// upstream fetch and share its result
+func Coalesce(source ScheduleProvider) ScheduleProvider {
+	return &coalesced{source: source}
+}
+
+// shared is the result of a shared fetch with the report of where it came from
+type shared struct {
+	value  interface{}
+	report *stale.Report
+}
+
+// do runs the fetch once per key. The shared fetch outlives a caller giving up,
+// every caller still stops waiting when its own context is done
+func do[T any](ctx context.Context, c *coalesced, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	// a refresh never joins a fetch started before the cache was flushed
+	if refreshing(ctx) {
+		key = "refresh\x00" + key
+	}
+
+	// leader is set when this caller runs the fetch, the others wait in memory
+	var leader bool
+	ch := c.group.DoChan(key, func() (result interface{}, err error) {
+		leader = true
+
+		// a panic of the shared fetch would crash the process, it fails the callers instead
+		defer func() {
+			if v := recover(); v != nil {
+				result, err = nil, &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+
+		// the fetch ends with the deadline of the request starting it, the
+		// timeout of its route or the one requested by the client
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(timeout)
+		}
+
+		fetchCtx, cancel := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+		defer cancel()
+
+		// the fallback is reported to every caller, not only the first one
+		fetchCtx, report := stale.WithReport(fetchCtx)
+		value, err := fetch(fetchCtx)
+
+		return shared{value: value, report: report}, err
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+
+		value := result.Val.(shared)
+		if _, _, ok := value.report.Get(); ok || leader {
+			value.report.Propagate(ctx)
+		} else {
+			stale.Hit(ctx, stale.LayerMemory, 0)
+		}
+
+		return value.value.(T), nil
+	}
+}
+
+func (c *coalesced) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	options, err := do(ctx, c, "groups", c.source.GetGroupOptions)
+	return append([]model.Option(nil), options...), err
+}
+
+func (c *coalesced) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	options, err := do(ctx, c, "teachers", c.source.GetTeacherOptions)
+	return append([]model.Option(nil), options...), err
+}
+
+func (c *coalesced) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	schedule, err := do(ctx, c, "group\x00"+group+"\x00"+date, func(ctx context.Context) ([]model.Schedule, error) {
+		return c.source.GetScheduleByGroup(ctx, group, date)
+	})
+	return cloneSchedule(schedule), err
+}
+
+func (c *coalesced) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	schedule, err := do(ctx, c, "teacher\x00"+teacher+"\x00"+date, func(ctx context.Context) ([]model.Schedule, error) {
+		return c.source.GetScheduleByTeacher(ctx, teacher, date)
+	})
+	return cloneSchedule(schedule), err
+}
+
+func (c *coalesced) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	announces, err := do(ctx, c, "announces\x00"+strconv.Itoa(page), func(ctx context.Context) (model.Announces, error) {
+		return c.source.GetAnnounces(ctx, page)
+	})
+	announces.Announces = append([]model.Announce(nil), announces.Announces...)
+	return announces, err
+}
+
+// cloneSchedule copies the shared result, handlers filter the lessons in place
+func cloneSchedule(schedule []model.Schedule) []model.Schedule {
+	if schedule == nil {
+		return nil
+	}
+
+	clone := make([]model.Schedule, len(schedule))
+	for i, day := range schedule {
+		day.Lessons = append([]model.Lesson(nil), day.Lessons...)
+		clone[i] = day
+	}
+
+	return clone
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxSubjects = 50
+
+// TeacherGroups is the list of groups a teacher has lessons with this week
+type TeacherGroups struct {
+	Teacher string         `json:"teacher"`
+	Groups  []TeacherGroup `json:"groups"`
+	// Coverage is the share of groups whose schedules were warmed
+	Coverage float64 `json:"coverage"`
+}
+
+// TeacherGroup is a group taught by the teacher
+type TeacherGroup struct {
+	Group    string   `json:"group"`
+	Label    string   `json:"label"`
+	Lessons  int      `json:"lessons"`
+	Subjects []string `json:"subjects"`
+}
+
+// teacherGroups aggregates the warmed schedules of all groups by the teacher
+func (a *API) teacherGroups(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	teacher, ok, err := a.resolveTeacher(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if !ok {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	}
+
+	labels := make(map[string]string)
+	if groups, err := a.hmtpk.GetGroupOptions(ctx); err == nil {
+		for _, group := range groups {
+			labels[group.Value] = group.Label
+		}
+	}
+
+	name := squash(teacher)
+	result := TeacherGroups{Teacher: teacher, Groups: []TeacherGroup{}}
+	for group, schedule := range a.snapshot() {
+		entry := TeacherGroup{Group: group, Label: labels[group]}
+		subjects := make(map[string]bool)
+
+		for _, day := range schedule {
+			for _, lesson := range day.Lessons {
+				if lesson.Teacher == "" || !strings.Contains(squash(lesson.Teacher), name) {
+					continue
+				}
+
+				entry.Lessons++
+				if !subjects[lesson.Name] {
+					subjects[lesson.Name] = true
+					entry.Subjects = append(entry.Subjects, lesson.Name)
+				}
+			}
+		}
+
+		if entry.Lessons > 0 {
+			sort.Strings(entry.Subjects)
+			result.Groups = append(result.Groups, entry)
+		}
+	}
+
+	sort.Slice(result.Groups, func(i, j int) bool {
+		return result.Groups[i].Label < result.Groups[j].Label
+	})
+
+	if a.warmer != nil {
+		result.Coverage = a.warmer.Progress().Coverage
+	}
+
+	write(w, r, http.StatusOK, result)
+}
+
+// resolveTeacher returns the name of the teacher by the value of the option or the name itself
+func (a *API) resolveTeacher(ctx context.Context, id string) (string, bool, error) {
+	if id == "" {
+		return "", false, nil
+	}
+
+	teachers, err := a.hmtpk.GetTeacherOptions(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, teacher := range teachers {
+		if teacher.Value == id || squash(teacher.Label) == squash(id) {
+			return teacher.Label, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// Subject is a subject observed in the warmed schedules
+type Subject struct {
+	Name     string   `json:"name"`
+	Groups   []string `json:"groups"`
+	Teachers []string `json:"teachers"`
+}
+
+// subjects lists the distinct subjects of the warmed schedules with their
+// groups and teachers. q filters by a part of the name for autocomplete
+func (a *API) subjects(w http.ResponseWriter, r *http.Request) {
+	query := squash(r.URL.Query().Get("q"))
+
+	labels := a.groupLabels()
+
+	type sets struct {
+		groups   map[string]bool
+		teachers map[string]bool
+	}
+
+	found := make(map[string]*sets)
+	for group, schedule := range a.snapshot() {
+		label := labels(group)
+
+		for _, day := range schedule {
+			for _, lesson := range day.Lessons {
+				if lesson.Name == "" || !strings.Contains(squash(lesson.Name), query) {
+					continue
+				}
+
+				s, ok := found[lesson.Name]
+				if !ok {
+					s = &sets{groups: make(map[string]bool), teachers: make(map[string]bool)}
+					found[lesson.Name] = s
+				}
+
+				s.groups[label] = true
+				if lesson.Teacher != "" {
+					s.teachers[lesson.Teacher] = true
+				}
+			}
+		}
+	}
+
+	subjects := make([]Subject, 0, len(found))
+	for name, s := range found {
+		subjects = append(subjects, Subject{Name: name, Groups: sortedKeys(s.groups), Teachers: sortedKeys(s.teachers)})
+	}
+
+	sort.Slice(subjects, func(i, j int) bool {
+		return subjects[i].Name < subjects[j].Name
+	})
+
+	if query != "" && len(subjects) > maxSubjects {
+		subjects = subjects[:maxSubjects]
+	}
+
+	write(w, r, http.StatusOK, subjects)
+}
+
+// groupLabels returns the function naming the warmed groups by their value
+func (a *API) groupLabels() func(group string) string {
+	labels := make(map[string]string)
+	if a.warmer != nil {
+		for _, group := range a.warmer.Groups() {
+			labels[group.Value] = group.Label
+		}
+	}
+
+	return func(group string) string {
+		if label, ok := labels[group]; ok && label != "" {
+			return label
+		}
+
+		return group
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
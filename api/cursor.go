@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxPageSize caps the limit parameter of the lists paged by the service
+// unless a list sets its own cap
+const maxPageSize = 100
+
+// cursor is the position of a page in a list, opaque to the clients. A list
+// paged by hmtpk.ru, like the announces, keeps the page of the site, the
+// lists paged by the service keep the offset and the size of the page
+type cursor struct {
+	// List binds the cursor to the list it was issued for
+	List   string `json:"l"`
+	Page   int    `json:"p,omitempty"`
+	Offset int    `json:"o,omitempty"`
+	Limit  int    `json:"n,omitempty"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value, list string) (cursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, false
+	}
+
+	var c cursor
+	if json.Unmarshal(data, &c) != nil || c.List != list || c.Page < 0 || c.Offset < 0 || c.Limit < 0 {
+		return cursor{}, false
+	}
+
+	return c, true
+}
+
+// Cursors are the cursors of the neighbouring pages of a list, passed back
+// as the cursor parameter, and the links to them. The links are also sent
+// in the Link header, the only place for them in the lists returned as arrays
+type Cursors struct {
+	Next     string `json:"next,omitempty"`
+	Prev     string `json:"prev,omitempty"`
+	NextLink string `json:"next_link,omitempty"`
+	PrevLink string `json:"prev_link,omitempty"`
+}
+
+// sitePage returns the page of a list paged by hmtpk.ru from the cursor
+// parameter or the page parameter of the older clients, the first one without both
+func sitePage(r *http.Request, list string) (int, bool) {
+	query := r.URL.Query()
+	if v := query.Get("cursor"); v != "" {
+		c, ok := decodeCursor(v, list)
+		return max(c.Page, 1), ok
+	}
+
+	if v := query.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		return page, err == nil
+	}
+
+	return 1, true
+}
+
+// offsetPage returns the offset and the size of the page of a list paged by
+// the service from the cursor and the limit parameters, the limit overrides
+// the size kept in the cursor
+func offsetPage(r *http.Request, list string, defaultLimit, maxLimit int) (int, int, bool) {
+	query := r.URL.Query()
+
+	var c cursor
+	if v := query.Get("cursor"); v != "" {
+		var ok bool
+		if c, ok = decodeCursor(v, list); !ok {
+			return 0, 0, false
+		}
+	}
+
+	limit := defaultLimit
+	if c.Limit > 0 {
+		limit = c.Limit
+	}
+
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, false
+		}
+	}
+
+	return c.Offset, limit, limit <= maxLimit
+}
+
+// siteCursors returns the cursors around the page of a list paged by hmtpk.ru
+func siteCursors(w http.ResponseWriter, r *http.Request, list string, page, lastPage int) Cursors {
+	var prev, next *cursor
+	if page > 1 {
+		prev = &cursor{List: list, Page: page - 1}
+	}
+
+	if page < lastPage {
+		next = &cursor{List: list, Page: page + 1}
+	}
+
+	return pageCursors(w, r, prev, next)
+}
+
+// offsetCursors returns the cursors around the page of a list paged by the
+// service, more reports whether items follow the page
+func offsetCursors(w http.ResponseWriter, r *http.Request, list string, offset, limit int, more bool) Cursors {
+	var prev, next *cursor
+	if offset > 0 {
+		prev = &cursor{List: list, Offset: max(offset-limit, 0), Limit: limit}
+	}
+
+	if more {
+		next = &cursor{List: list, Offset: offset + limit, Limit: limit}
+	}
+
+	return pageCursors(w, r, prev, next)
+}
+
+// pageCursors encodes the cursors, builds the links to them from the URL of
+// the request and sets the Link header
+func pageCursors(w http.ResponseWriter, r *http.Request, prev, next *cursor) Cursors {
+	var (
+		cursors Cursors
+		links   []string
+	)
+
+	link := func(c cursor) string {
+		query := r.URL.Query()
+		query.Del("page")
+		query.Del("limit")
+		query.Set("cursor", c.encode())
+
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	if prev != nil {
+		cursors.Prev, cursors.PrevLink = prev.encode(), link(*prev)
+		links = append(links, "<"+cursors.PrevLink+`>; rel="prev"`)
+	}
+
+	if next != nil {
+		cursors.Next, cursors.NextLink = next.encode(), link(*next)
+		links = append(links, "<"+cursors.NextLink+`>; rel="next"`)
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	return cursors
+}
+
+// paginate returns the page of the items at the offset and whether items follow it
+func paginate[T any](items []T, offset, limit int) ([]T, bool) {
+	if offset >= len(items) {
+		return items[:0], false
+	}
+
+	items = items[offset:]
+	if len(items) > limit {
+		return items[:limit], true
+	}
+
+	return items, false
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/warmup"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// hrefDate extracts the date of the day from the link to the site
+var hrefDate = regexp.MustCompile(`date_edu1c=(\d{2}\.\d{2}\.\d{4})`)
+
+// DayDate returns the date of a day of the weekly schedule from its link to the site
+func DayDate(day model.Schedule) (time.Time, bool) {
+	match := hrefDate.FindStringSubmatch(day.Href)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse("02.01.2006", match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// findDay returns the day of the weekly schedule with the date
+func findDay(schedule []model.Schedule, date string) (model.Schedule, bool) {
+	for _, day := range schedule {
+		if d, ok := DayDate(day); ok && d.Format("02.01.2006") == date {
+			return day, true
+		}
+	}
+
+	return model.Schedule{}, false
+}
+
+// Day is a day of the schedule with the date and the lesson times in ISO 8601
+type Day struct {
+	Date    string   `json:"date"`
+	ISODate string   `json:"iso_date,omitempty"`
+	Lessons []Lesson `json:"lesson"`
+	Href    string   `json:"href"`
+	// Source is "archive" or "cache" for a day served from a fallback instead of the site
+	Source    string     `json:"source,omitempty"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	// Display is set with the localize parameter
+	Display *Display `json:"display,omitempty"`
+	// Status is published, empty for a published day without lessons or
+	// unpublished for a day of the week the site has not published yet
+	Status string `json:"status"`
+}
+
+const (
+	DayPublished   = "published"
+	DayEmpty       = "empty"
+	DayUnpublished = "unpublished"
+)
+
+// Lesson is a lesson with its start and end in the time zone of the college
+type Lesson struct {
+	model.Lesson
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// schedule returns the day as the parser returned it
+func (d Day) schedule() model.Schedule {
+	day := model.Schedule{Date: d.Date, Href: d.Href, Lessons: make([]model.Lesson, 0, len(d.Lessons))}
+	for _, lesson := range d.Lessons {
+		day.Lessons = append(day.Lessons, lesson.Lesson)
+	}
+
+	return day
+}
+
+// isoSchedule adds ISO 8601 dates and lesson times to the weekly schedule
+func (a *API) isoSchedule(schedule []model.Schedule) []Day {
+	location := a.Location()
+
+	// the site lists the whole week, the dates of the days it has not
+	// published yet follow from the days around them
+	var (
+		monday time.Time
+		week   = len(schedule) == 7
+	)
+	for i, day := range schedule {
+		if date, ok := DayDate(day); ok && week {
+			monday = date.AddDate(0, 0, -i)
+			break
+		}
+	}
+
+	days := make([]Day, 0, len(schedule))
+	for i, day := range schedule {
+		result := Day{Date: day.Date, Href: day.Href, Lessons: make([]Lesson, 0, len(day.Lessons))}
+
+		switch {
+		case !warmup.Published(day):
+			result.Status = DayUnpublished
+		case len(day.Lessons) == 0:
+			result.Status = DayEmpty
+		default:
+			result.Status = DayPublished
+		}
+
+		date, ok := DayDate(day)
+		if !ok && result.Status == DayUnpublished && !monday.IsZero() {
+			date, ok = monday.AddDate(0, 0, i), true
+		}
+		if ok {
+			date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+			result.ISODate = date.Format(time.DateOnly)
+		}
+
+		for _, lesson := range day.Lessons {
+			item := Lesson{Lesson: lesson}
+			if start, end, found := a.lessonTime(lesson, date.Weekday()); ok && found {
+				start, end := clockAt(date, start), clockAt(date, end)
+				item.Start, item.End = &start, &end
+			}
+			result.Lessons = append(result.Lessons, item)
+		}
+
+		days = append(days, result)
+	}
+
+	return days
+}
+
+// relativeDates resolves the date keywords accepted instead of a date
+var relativeDates = map[string]func(now time.Time) time.Time{
+	"today":       func(now time.Time) time.Time { return now },
+	"tomorrow":    func(now time.Time) time.Time { return now.AddDate(0, 0, 1) },
+	"yesterday":   func(now time.Time) time.Time { return now.AddDate(0, 0, -1) },
+	"сегодня":     func(now time.Time) time.Time { return now },
+	"завтра":      func(now time.Time) time.Time { return now.AddDate(0, 0, 1) },
+	"вчера":       func(now time.Time) time.Time { return now.AddDate(0, 0, -1) },
+	"monday":      nextWeekday(time.Monday),
+	"tuesday":     nextWeekday(time.Tuesday),
+	"wednesday":   nextWeekday(time.Wednesday),
+	"thursday":    nextWeekday(time.Thursday),
+	"friday":      nextWeekday(time.Friday),
+	"saturday":    nextWeekday(time.Saturday),
+	"sunday":      nextWeekday(time.Sunday),
+	"понедельник": nextWeekday(time.Monday),
+	"вторник":     nextWeekday(time.Tuesday),
+	"среда":       nextWeekday(time.Wednesday),
+	"четверг":     nextWeekday(time.Thursday),
+	"пятница":     nextWeekday(time.Friday),
+	"суббота":     nextWeekday(time.Saturday),
+	"воскресенье": nextWeekday(time.Sunday),
+}
+
+// parseDate returns the date query parameter resolved by ParseDate
+func (a *API) parseDate(r *http.Request) (string, bool) {
+	return ParseDate(r.URL.Query().Get("date"), a.now())
+}
+
+// ParseDate returns the date as 02.01.2006, today if it is empty. Both
+// 02.01.2006 and ISO 8601 2006-01-02 are accepted. Keywords like "tomorrow"
+// or "friday" are resolved relative to now, a day of the week is the nearest
+// one starting from today
+func ParseDate(date string, now time.Time) (string, bool) {
+	if date == "" {
+		return now.Format("02.01.2006"), true
+	}
+
+	if relative, ok := relativeDates[strings.ToLower(date)]; ok {
+		return relative(now).Format("02.01.2006"), true
+	}
+
+	for _, layout := range []string{"02.01.2006", time.DateOnly} {
+		if d, err := time.Parse(layout, date); err == nil {
+			return d.Format("02.01.2006"), true
+		}
+	}
+
+	return "", false
+}
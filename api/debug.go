@@ -0,0 +1,67 @@
+package api
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+}
+
+// maxStackDump bounds the dump of the stacks of every goroutine
+const maxStackDump = 64 << 20
+
+// debugRoutes serve the runtime profiles, the exported variables and the
+// stacks of the goroutines to administrators, to diagnose the memory growth
+// and the goroutine leaks in production. The profiles are read like
+// go tool pprof -http=: profile.pb.gz after
+// curl -H "X-API-Key: ..." .../admin/debug/pprof/heap -o profile.pb.gz
+func (a *API) debugRoutes(r chi.Router) {
+	r.Use(a.adminMiddleware)
+
+	r.HandleFunc("/pprof/", pprof.Index)
+	r.HandleFunc("/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/pprof/profile", pprof.Profile)
+	r.HandleFunc("/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/pprof/trace", pprof.Trace)
+	r.HandleFunc("/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(chi.URLParam(r, "profile")).ServeHTTP(w, r)
+	})
+	r.Handle("/vars", expvar.Handler())
+	r.HandleFunc("/goroutines", goroutines)
+}
+
+// adminMiddleware lets only administrator tenants through
+func (a *API) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.isAdmin(r) {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// goroutines writes the stacks of every goroutine as text
+func goroutines(w http.ResponseWriter, _ *http.Request) {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDump {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(buf)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/edge"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxEdgePush limits the size of a decompressed snapshot
+const maxEdgePush = 64 << 20
+
+// SetEdge enables replication, the replica is set on edge instances only
+func (a *API) SetEdge(cfg edge.Config, replica *edge.Replica) {
+	a.edge = cfg
+	a.replica = replica
+}
+
+// EdgeSnapshot returns the warmed schedules to be replicated to edge instances
+func (a *API) EdgeSnapshot() edge.Snapshot {
+	snapshot := edge.Snapshot{Entries: make(map[string]edge.Entry)}
+	if a.warmer == nil {
+		return snapshot
+	}
+
+	snapshot.Groups = a.warmer.Groups()
+	if started := a.warmer.Progress().StartedAt; started != nil {
+		snapshot.GroupsUpdated = *started
+	}
+
+	warmedAt := a.warmer.WarmedAt()
+	for group, schedule := range a.warmer.Schedules() {
+		snapshot.Entries[group] = edge.Entry{Schedule: schedule, Updated: warmedAt[group]}
+	}
+
+	return snapshot
+}
+
+func (a *API) edgeRoutes(r chi.Router) {
+	r.Use(a.edgeMiddleware)
+
+	r.Post("/snapshot", a.edgeSnapshot)
+	if a.replica != nil {
+		r.Post("/push", a.edgePush)
+	}
+}
+
+func (a *API) edgeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(edge.TokenHeader)), []byte(a.edge.Token)) != 1 {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// edgeSnapshot lets edge instances pull the snapshot, e.g. when the origin can't reach them
+func (a *API) edgeSnapshot(w http.ResponseWriter, r *http.Request) {
+	write(w, r, http.StatusOK, a.EdgeSnapshot())
+}
+
+// edgePush merges the snapshot pushed by the origin into the replica
+func (a *API) edgePush(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+
+		body = gz
+	}
+
+	var snapshot edge.Snapshot
+	if err := json.NewDecoder(io.LimitReader(body, maxEdgePush)).Decode(&snapshot); err != nil {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	a.replica.Merge(snapshot)
+	a.log.WithContext(r.Context()).Debugf("edge: snapshot merged, %d schedules replicated", a.replica.Len())
+
+	write(w, r, http.StatusOK, nil)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	contentTypeJSON     = "application/json"
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeMsgpack  = "application/msgpack"
+)
+
+// contentTypes maps accepted media types to the encoding used for them
+var contentTypes = map[string]string{
+	"application/json":       contentTypeJSON,
+	"application/*":          contentTypeJSON,
+	"*/*":                    contentTypeJSON,
+	"application/x-protobuf": contentTypeProtobuf,
+	"application/protobuf":   contentTypeProtobuf,
+	"application/msgpack":    contentTypeMsgpack,
+	"application/x-msgpack":  contentTypeMsgpack,
+}
+
+var errUnsupported = errors.New("unsupported value")
+
+// negotiate returns the content type of the response from the Accept header
+func negotiate(r *http.Request) string {
+	type accepted struct {
+		contentType string
+		q           float64
+	}
+
+	var list []accepted
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		contentType, ok := contentTypes[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+
+		list = append(list, accepted{contentType, q})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+
+	if len(list) == 0 {
+		return contentTypeJSON
+	}
+
+	return list[0].contentType
+}
+
+// encode marshals data in the content type, JSON is always supported
+func encode(contentType string, data interface{}) ([]byte, error) {
+	switch contentType {
+	case contentTypeProtobuf:
+		return marshalProtobuf(data)
+	case contentTypeMsgpack:
+		var buf bytes.Buffer
+		encoder := msgpack.NewEncoder(&buf)
+		encoder.SetCustomStructTag("json")
+		if err := encoder.Encode(data); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,8 @@
+//go:build no_grpc
+
+package api
+
+// marshalProtobuf is unavailable without the protobuf definitions, JSON is used instead
+func marshalProtobuf(interface{}) ([]byte, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,53 @@
+//go:build !no_grpc
+
+package api
+
+import (
+	hmtpkv1 "github.com/chazari-x/hmtpk-parser-api/proto/hmtpk/v1"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalProtobuf encodes a response value as its protobuf message
+func marshalProtobuf(data interface{}) ([]byte, error) {
+	message, err := toMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(message)
+}
+
+// toMessage converts a response value to its protobuf message
+func toMessage(data interface{}) (proto.Message, error) {
+	switch v := data.(type) {
+	case Response:
+		return &hmtpkv1.Response{Message: v.Message, Error: v.Error}, nil
+	case []model.Option:
+		return &hmtpkv1.GetGroupsResponse{Options: hmtpkv1.FromOptions(v)}, nil
+	case []Option:
+		options := make([]model.Option, 0, len(v))
+		for _, option := range v {
+			options = append(options, option.Option)
+		}
+
+		return &hmtpkv1.GetGroupsResponse{Options: hmtpkv1.FromOptions(options)}, nil
+	case []model.Schedule:
+		return &hmtpkv1.GetScheduleResponse{Days: hmtpkv1.FromSchedule(v)}, nil
+	case []Day:
+		schedule := make([]model.Schedule, 0, len(v))
+		for _, day := range v {
+			schedule = append(schedule, day.schedule())
+		}
+
+		return &hmtpkv1.GetScheduleResponse{Days: hmtpkv1.FromSchedule(schedule)}, nil
+	case model.Announces:
+		return hmtpkv1.FromAnnounces(v), nil
+	case Announces:
+		return hmtpkv1.FromAnnounces(v.model()), nil
+	case NewsPage:
+		return hmtpkv1.FromAnnounces(v.Announces), nil
+	}
+
+	return nil, errUnsupported
+}
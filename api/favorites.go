@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	favoritesPrefix = "favorites:"
+
+	// maxFavorites bounds the number of the favorite groups and of the favorite teachers
+	maxFavorites = 20
+	maxFavorite  = 100
+)
+
+// Favorites are the favorite groups and teachers of a user and the
+// preferences of the display, shared by the devices of the user
+type Favorites struct {
+	Groups      []string    `json:"groups"`
+	Teachers    []string    `json:"teachers"`
+	Preferences Preferences `json:"preferences"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Preferences are the defaults of the display. At most one of DefaultGroup and
+// DefaultTeacher is set, it is the schedule shown on start
+type Preferences struct {
+	DefaultGroup   string `json:"default_group,omitempty"`
+	DefaultTeacher string `json:"default_teacher,omitempty"`
+	// View is day or week
+	View     string `json:"view,omitempty"`
+	Language string `json:"lang,omitempty"`
+	// Preset is the name of the fields preset of the responses
+	Preset string `json:"preset,omitempty"`
+}
+
+// views are the values of Preferences.View
+var views = []string{"day", "week"}
+
+func (a *API) meRoutes(r chi.Router) {
+	r.Post("/favorites", a.getFavorites)
+	r.Post("/favorites/add", a.addFavorites)
+	r.Post("/favorites/remove", a.removeFavorites)
+	r.Post("/favorites/preferences", a.setPreferences)
+
+	if a.notifier != nil {
+		r.Route("/notifications", a.notificationRoutes)
+	}
+}
+
+// owner returns the user of the request: the subject of the access token or
+// the tenant of the API key. The error is written when there is none
+func (a *API) owner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if r.Header.Get("Authorization") != "" {
+		claims, ok := a.verifyBearer(w, r)
+		if !ok {
+			return "", false
+		}
+
+		return "user:" + claims.Subject, true
+	}
+
+	if name := a.tenantName(r); name != "" {
+		return "tenant:" + name, true
+	}
+
+	write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+	return "", false
+}
+
+func (a *API) loadFavorites(ctx context.Context, owner string) (Favorites, error) {
+	favorites := Favorites{Groups: []string{}, Teachers: []string{}}
+
+	value, err := a.store.Get(ctx, favoritesPrefix+owner)
+	if errors.Is(err, storage.ErrNotFound) {
+		return favorites, nil
+	} else if err != nil {
+		return Favorites{}, err
+	}
+
+	if err = json.Unmarshal([]byte(value), &favorites); err != nil {
+		return Favorites{}, err
+	}
+
+	return favorites, nil
+}
+
+func (a *API) saveFavorites(ctx context.Context, owner string, favorites *Favorites) error {
+	favorites.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(favorites)
+	if err != nil {
+		return err
+	}
+
+	return a.store.Set(ctx, favoritesPrefix+owner, string(data))
+}
+
+// updateFavorites applies the change to the favorites of the user of the
+// request and writes them, the change returns false for a bad request
+func (a *API) updateFavorites(w http.ResponseWriter, r *http.Request, change func(favorites *Favorites) bool) {
+	owner, ok := a.owner(w, r)
+	if !ok {
+		return
+	}
+
+	// the changes of a user made at once from several devices are not lost
+	a.favorites.Lock()
+	defer a.favorites.Unlock()
+
+	favorites, err := a.loadFavorites(r.Context(), owner)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if !change(&favorites) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	if err = a.saveFavorites(r.Context(), owner, &favorites); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, favorites)
+}
+
+// getFavorites returns the favorites of the user
+func (a *API) getFavorites(w http.ResponseWriter, r *http.Request) {
+	owner, ok := a.owner(w, r)
+	if !ok {
+		return
+	}
+
+	favorites, err := a.loadFavorites(r.Context(), owner)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, favorites)
+}
+
+// favoriteValues returns the trimmed values of the repeated parameter, false
+// when one is empty or too long
+func favoriteValues(r *http.Request, name string) ([]string, bool) {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		value = strings.TrimSpace(value)
+		if value == "" || len(value) > maxFavorite {
+			return nil, false
+		}
+
+		values = append(values, value)
+	}
+
+	return values, true
+}
+
+// addFavorites adds the group and teacher parameters, repeated for several,
+// to the favorites
+func (a *API) addFavorites(w http.ResponseWriter, r *http.Request) {
+	groups, ok := favoriteValues(r, "group")
+	teachers, ok2 := favoriteValues(r, "teacher")
+
+	a.updateFavorites(w, r, func(favorites *Favorites) bool {
+		if !ok || !ok2 || len(groups)+len(teachers) == 0 {
+			return false
+		}
+
+		for _, group := range groups {
+			if !slices.Contains(favorites.Groups, group) {
+				favorites.Groups = append(favorites.Groups, group)
+			}
+		}
+		for _, teacher := range teachers {
+			if !slices.Contains(favorites.Teachers, teacher) {
+				favorites.Teachers = append(favorites.Teachers, teacher)
+			}
+		}
+
+		return len(favorites.Groups) <= maxFavorites && len(favorites.Teachers) <= maxFavorites
+	})
+}
+
+// removeFavorites removes the group and teacher parameters from the favorites
+func (a *API) removeFavorites(w http.ResponseWriter, r *http.Request) {
+	groups, ok := favoriteValues(r, "group")
+	teachers, ok2 := favoriteValues(r, "teacher")
+
+	a.updateFavorites(w, r, func(favorites *Favorites) bool {
+		if !ok || !ok2 || len(groups)+len(teachers) == 0 {
+			return false
+		}
+
+		favorites.Groups = slices.DeleteFunc(favorites.Groups, func(group string) bool {
+			return slices.Contains(groups, group)
+		})
+		favorites.Teachers = slices.DeleteFunc(favorites.Teachers, func(teacher string) bool {
+			return slices.Contains(teachers, teacher)
+		})
+
+		return true
+	})
+}
+
+// setPreferences sets the preferences of the parameters, the ones missing are
+// kept and the empty ones are cleared
+func (a *API) setPreferences(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	a.updateFavorites(w, r, func(favorites *Favorites) bool {
+		preferences := &favorites.Preferences
+
+		if query.Has("default_group") && query.Has("default_teacher") {
+			return false
+		}
+		if query.Has("default_group") {
+			preferences.DefaultGroup, preferences.DefaultTeacher = strings.TrimSpace(query.Get("default_group")), ""
+		}
+		if query.Has("default_teacher") {
+			preferences.DefaultTeacher, preferences.DefaultGroup = strings.TrimSpace(query.Get("default_teacher")), ""
+		}
+		if len(preferences.DefaultGroup) > maxFavorite || len(preferences.DefaultTeacher) > maxFavorite {
+			return false
+		}
+
+		if query.Has("view") {
+			view := query.Get("view")
+			if view != "" && !slices.Contains(views, view) {
+				return false
+			}
+			preferences.View = view
+		}
+
+		if query.Has("lang") {
+			language := strings.ToLower(query.Get("lang"))
+			if language != "" && !hasMessages(language) {
+				return false
+			}
+			preferences.Language = language
+		}
+
+		if query.Has("preset") {
+			preset := query.Get("preset")
+			if _, ok := a.presets[preset]; preset != "" && !ok {
+				return false
+			}
+			preferences.Preset = preset
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// maxFieldsDepth bounds the nesting of the fields parameter
+const maxFieldsDepth = 8
+
+// selection is the fields picked with ?fields=, a nil selection of a field
+// keeps all of its value
+type selection map[string]selection
+
+type fieldsKey struct{}
+
+// parseFields parses the fields parameter like name,time,lessons(name,room)
+// or the same with dots, lessons.name. The parentheses select the fields of
+// a field, the fields without them are looked up at any depth
+func parseFields(value string) (selection, bool) {
+	sel := make(selection)
+	rest, ok := parseSelection(value, sel, 0)
+	return sel, ok && rest == "" && len(sel) > 0
+}
+
+// parseSelection parses the fields up to the closing parenthesis of the level
+// and returns the rest of the value after them
+func parseSelection(value string, sel selection, depth int) (string, bool) {
+	if depth > maxFieldsDepth {
+		return "", false
+	}
+
+	for {
+		end := strings.IndexAny(value, ",()")
+		if end < 0 {
+			end = len(value)
+		}
+
+		name := strings.TrimSpace(value[:end])
+		if name == "" {
+			return "", false
+		}
+		value = value[end:]
+
+		// lessons.name is lessons(name)
+		target := sel
+		parts := strings.Split(name, ".")
+		for i, part := range parts {
+			if part == "" {
+				return "", false
+			}
+
+			// a field selected whole overrides the selection of its fields
+			if i == len(parts)-1 && !strings.HasPrefix(value, "(") {
+				target[part] = nil
+				break
+			}
+
+			child, ok := target[part]
+			if ok && child == nil {
+				// the whole value is already selected, the fields are parsed and dropped
+				target = make(selection)
+				continue
+			}
+
+			if !ok {
+				child = make(selection)
+				target[part] = child
+			}
+			target = child
+		}
+
+		if strings.HasPrefix(value, "(") {
+			var ok bool
+			if value, ok = parseSelection(value[1:], target, depth+1); !ok || !strings.HasPrefix(value, ")") {
+				return "", false
+			}
+			value = value[1:]
+		}
+
+		switch {
+		case value == "":
+			return "", depth == 0
+		case value[0] == ')':
+			return value, depth > 0
+		case value[0] == ',':
+			value = value[1:]
+		default:
+			return "", false
+		}
+	}
+}
+
+// fieldsMiddleware parses the fields parameter, after the preset so a
+// preset can set it
+func (a *API) fieldsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.URL.Query().Get("fields")
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sel, ok := parseFields(value)
+		if !ok {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), fieldsKey{}, sel)))
+	})
+}
+
+// selectFields returns the data with only the fields picked by the fields parameter
+func selectFields(r *http.Request, data interface{}) interface{} {
+	sel, _ := r.Context().Value(fieldsKey{}).(selection)
+	if len(sel) == 0 {
+		return data
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+
+	var generic interface{}
+	if err = json.Unmarshal(body, &generic); err != nil {
+		return data
+	}
+
+	return pick(generic, sel)
+}
+
+// pick keeps the selected fields of the objects of the decoded JSON, the
+// objects holding selected fields deeper are kept with only them
+func pick(value interface{}, sel selection) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if child, ok := sel[key]; ok {
+				if child != nil {
+					v[key] = pick(item, child)
+				}
+				continue
+			}
+
+			if item = pick(item, sel); picked(item) {
+				v[key] = item
+			} else {
+				delete(v, key)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = pick(item, sel)
+		}
+	}
+
+	return value
+}
+
+// picked reports whether a value pruned by pick still holds a selected field
+func picked(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		for _, item := range v {
+			if picked(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	formatText     = "text"
+	formatMarkdown = "markdown"
+
+	contentTypeText     = "text/plain; charset=utf-8"
+	contentTypeMarkdown = "text/markdown; charset=utf-8"
+)
+
+// writeFormatted writes the schedule as plain text or Markdown ready to be sent to messengers
+func writeFormatted(w http.ResponseWriter, format string, schedule []model.Schedule, byTeacher bool) {
+	contentType := contentTypeText
+	if format == formatMarkdown {
+		contentType = contentTypeMarkdown
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(formatSchedule(schedule, format, byTeacher)))
+}
+
+// formatSchedule renders the schedule, lesson numbers and times are aligned in columns
+func formatSchedule(schedule []model.Schedule, format string, byTeacher bool) string {
+	var numWidth, timeWidth int
+	for _, day := range schedule {
+		for _, lesson := range day.Lessons {
+			numWidth = max(numWidth, utf8.RuneCountInString(lesson.Num))
+			timeWidth = max(timeWidth, utf8.RuneCountInString(lesson.Time))
+		}
+	}
+
+	var b strings.Builder
+	for i, day := range schedule {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		if format == formatMarkdown {
+			fmt.Fprintf(&b, "**📅 %s**\n", markdownEscape(day.Date))
+		} else {
+			fmt.Fprintf(&b, "📅 %s\n", day.Date)
+		}
+
+		if len(day.Lessons) == 0 {
+			b.WriteString("Нет занятий\n")
+			continue
+		}
+
+		for _, lesson := range day.Lessons {
+			num := pad(lesson.Num, numWidth)
+			lessonTime := pad(lesson.Time, timeWidth)
+
+			var details []string
+			if lesson.Room != "" {
+				details = append(details, "🚪 "+lesson.Room)
+			}
+			if byTeacher && lesson.Group != "" {
+				details = append(details, "👥 "+lesson.Group)
+			} else if !byTeacher && lesson.Teacher != "" {
+				details = append(details, "👤 "+lesson.Teacher)
+			}
+
+			name := lesson.Name
+			if lesson.Subgroup != "" {
+				name += fmt.Sprintf(" (%s подгр.)", lesson.Subgroup)
+			}
+
+			if format == formatMarkdown {
+				fmt.Fprintf(&b, "`%s │ %s` **%s**\n", num, lessonTime, markdownEscape(name))
+				if len(details) > 0 {
+					fmt.Fprintf(&b, "`%s   %s ` %s\n", pad("", numWidth), pad("", timeWidth), markdownEscape(strings.Join(details, " · ")))
+				}
+				continue
+			}
+
+			fmt.Fprintf(&b, "%s │ %s │ %s\n", num, lessonTime, name)
+			if len(details) > 0 {
+				fmt.Fprintf(&b, "%s │ %s │ %s\n", pad("", numWidth), pad("", timeWidth), strings.Join(details, " · "))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func pad(value string, width int) string {
+	if n := utf8.RuneCountInString(value); n < width {
+		return value + strings.Repeat(" ", width-n)
+	}
+
+	return value
+}
+
+var markdownReplacer = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+func markdownEscape(value string) string {
+	return markdownReplacer.Replace(value)
+}
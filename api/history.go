@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/archive"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// sourceArchive marks the data served from the archive of parsed data
+const sourceArchive = archive.SourceArchive
+
+// SetArchive enables answering the schedules of past weeks from the archive of parsed data
+func (a *API) SetArchive(archive *archive.Archive) {
+	a.archive = archive
+}
+
+// archivedSchedule returns the archived weekly schedule when the date is in
+// a past week, which the site no longer answers, with the time it was fetched
+func (a *API) archivedSchedule(ctx context.Context, group, teacher, date string) ([]model.Schedule, time.Time, bool) {
+	if a.archive == nil {
+		return nil, time.Time{}, false
+	}
+
+	d, err := time.Parse("02.01.2006", date)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	now := a.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if d.AddDate(0, 0, 7-(int(d.Weekday())+6)%7).After(today) {
+		return nil, time.Time{}, false
+	}
+
+	kind, value := archive.KindGroup, group
+	if group == "" {
+		kind, value = archive.KindTeacher, teacher
+	}
+
+	ctx, cancel := upstreamContext(ctx)
+	defer cancel()
+
+	schedule, fetchedAt, err := a.archive.Week(ctx, kind, value, d)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	return schedule, fetchedAt, true
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/ids"
+)
+
+// SetIDs sets the codec of the public identifiers
+func (a *API) SetIDs(codec ids.Codec) {
+	a.ids = codec
+}
+
+// publicResults replaces the identifiers of the found announces with the public ones
+func (a *API) publicResults(results []announce.SearchResult) []announce.SearchResult {
+	for i := range results {
+		results[i].ID = a.ids.Encode(ids.KindAnnounce, results[i].ID)
+	}
+
+	return results
+}
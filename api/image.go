@@ -0,0 +1,312 @@
+//go:build !no_render
+
+package api
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+func init() {
+	optionalRoutes = append(optionalRoutes, func(a *API, r chi.Router) {
+		r.Post("/schedule/image", a.scheduleImage)
+	})
+}
+
+const (
+	contentTypePNG = "image/png"
+
+	defaultImageWidth = 800
+	minImageWidth     = 400
+	maxImageWidth     = 2000
+
+	imagePadding = 20
+	imageLine    = 22
+)
+
+// imageTheme is the color scheme of the rendered schedule
+type imageTheme struct {
+	background color.Color
+	header     color.Color
+	text       color.Color
+	secondary  color.Color
+	separator  color.Color
+}
+
+var imageThemes = map[string]imageTheme{
+	"light": {
+		background: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		header:     color.RGBA{R: 217, G: 225, B: 242, A: 255},
+		text:       color.RGBA{R: 33, G: 33, B: 33, A: 255},
+		secondary:  color.RGBA{R: 110, G: 110, B: 110, A: 255},
+		separator:  color.RGBA{R: 225, G: 225, B: 225, A: 255},
+	},
+	"dark": {
+		background: color.RGBA{R: 24, G: 25, B: 28, A: 255},
+		header:     color.RGBA{R: 45, G: 55, B: 80, A: 255},
+		text:       color.RGBA{R: 235, G: 235, B: 235, A: 255},
+		secondary:  color.RGBA{R: 160, G: 160, B: 160, A: 255},
+		separator:  color.RGBA{R: 55, G: 57, B: 62, A: 255},
+	},
+}
+
+var imageFonts struct {
+	once    sync.Once
+	err     error
+	title   font.Face
+	regular font.Face
+	small   font.Face
+}
+
+func loadImageFonts() error {
+	imageFonts.once.Do(func() {
+		regular, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			imageFonts.err = err
+			return
+		}
+
+		bold, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			imageFonts.err = err
+			return
+		}
+
+		face := func(f *opentype.Font, size float64) font.Face {
+			if imageFonts.err != nil {
+				return nil
+			}
+
+			var result font.Face
+			result, imageFonts.err = opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+			return result
+		}
+
+		imageFonts.title = face(bold, 22)
+		imageFonts.regular = face(regular, 16)
+		imageFonts.small = face(regular, 14)
+	})
+
+	return imageFonts.err
+}
+
+// scheduleImage renders the schedule of a group or a teacher for the day
+// (period=day, default) or the week (period=week) as a PNG picture
+func (a *API) scheduleImage(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	group, teacher := r.URL.Query().Get("group"), r.URL.Query().Get("teacher")
+	if group == "" && teacher == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period != "" && period != "day" && period != "week" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	themeName := r.URL.Query().Get("theme")
+	if themeName == "" {
+		themeName = "light"
+	}
+
+	theme, ok := imageThemes[themeName]
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	width := defaultImageWidth
+	if v := r.URL.Query().Get("width"); v != "" {
+		var err error
+		if width, err = strconv.Atoi(v); err != nil || width < minImageWidth || width > maxImageWidth {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+	}
+
+	schedule, err := a.getSchedule(r.Context(), group, teacher, date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if period != "week" {
+		day, ok := findDay(schedule, date)
+		if !ok {
+			day = model.Schedule{Date: date}
+		}
+		schedule = []model.Schedule{day}
+	}
+
+	if err = loadImageFonts(); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	title := group
+	if title == "" {
+		title = teacher
+	}
+
+	img := renderImage(title, group == "", schedule, theme, width)
+
+	w.Header().Set("Content-Type", contentTypePNG)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="schedule-%s.png"`, date))
+
+	if err = png.Encode(w, img); err != nil {
+		a.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// imageRow is a line of the rendered schedule
+type imageRow struct {
+	face   font.Face
+	color  color.Color
+	text   string
+	x      int
+	prefix string
+	band   bool
+	line   bool
+}
+
+func renderImage(title string, byTeacher bool, schedule []model.Schedule, theme imageTheme, width int) image.Image {
+	textWidth := width - 2*imagePadding
+
+	rows := []imageRow{{face: imageFonts.title, color: theme.text, text: title}}
+	for _, day := range schedule {
+		rows = append(rows, imageRow{face: imageFonts.regular, color: theme.text, text: day.Date, band: true})
+
+		if len(day.Lessons) == 0 {
+			rows = append(rows, imageRow{face: imageFonts.regular, color: theme.secondary, text: "Нет занятий", line: true})
+			continue
+		}
+
+		for _, lesson := range day.Lessons {
+			head := strings.TrimSpace(fmt.Sprintf("%s. %s", lesson.Num, lesson.Time))
+			indent := font.MeasureString(imageFonts.regular, head+"  ").Ceil()
+
+			for i, text := range wrapText(imageFonts.regular, lesson.Name, textWidth-indent) {
+				row := imageRow{face: imageFonts.regular, color: theme.text, text: text, x: indent}
+				if i == 0 {
+					row.prefix = head
+				}
+				rows = append(rows, row)
+			}
+
+			details := []string{lesson.Room}
+			if byTeacher {
+				details = append(details, lesson.Group)
+			} else {
+				details = append(details, lesson.Teacher)
+			}
+			if lesson.Subgroup != "" {
+				details = append(details, "подгруппа "+lesson.Subgroup)
+			}
+
+			rows = append(rows, imageRow{face: imageFonts.small, color: theme.secondary, text: joinNonEmpty(details, " · "), x: indent, line: true})
+		}
+	}
+
+	height := imagePadding * 2
+	for _, row := range rows {
+		height += rowHeight(row)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(theme.background), image.Point{}, draw.Src)
+
+	y := imagePadding
+	for _, row := range rows {
+		if row.band {
+			draw.Draw(img, image.Rect(0, y, width, y+rowHeight(row)), image.NewUniform(theme.header), image.Point{}, draw.Src)
+		}
+
+		if row.prefix != "" {
+			drawText(img, row.face, theme.secondary, imagePadding, y, row.prefix)
+		}
+
+		drawText(img, row.face, row.color, imagePadding+row.x, y, row.text)
+		y += rowHeight(row)
+
+		if row.line {
+			draw.Draw(img, image.Rect(imagePadding, y-2, width-imagePadding, y-1), image.NewUniform(theme.separator), image.Point{}, draw.Src)
+		}
+	}
+
+	return img
+}
+
+func rowHeight(row imageRow) int {
+	if row.band || row.line || row.face == imageFonts.title {
+		return imageLine + 10
+	}
+
+	return imageLine
+}
+
+func drawText(img draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()+(imageLine-face.Metrics().Height.Ceil())/2),
+	}
+	d.DrawString(text)
+}
+
+// wrapText splits the text into lines not wider than width
+func wrapText(face font.Face, text string, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && font.MeasureString(face, candidate).Ceil() > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = candidate
+	}
+
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func joinNonEmpty(values []string, sep string) string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return strings.Join(result, sep)
+}
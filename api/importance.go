@@ -0,0 +1,45 @@
+package api
+
+import (
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Announces is a page of announces with their importance
+type Announces struct {
+	Announces []Announce `json:"announces"`
+	LastPage  int        `json:"last_page"`
+	Cursors
+}
+
+// Announce is an announce of the feed with its importance
+type Announce struct {
+	model.Announce
+	Importance announce.Importance `json:"importance"`
+}
+
+// classify adds the importance to the page of announces, keeping the ones at
+// the minimum importance or above it when it is set
+func classify(announces model.Announces, minimum string) Announces {
+	result := Announces{Announces: make([]Announce, 0, len(announces.Announces)), LastPage: announces.LastPage}
+	for _, item := range announces.Announces {
+		importance := announce.Classify(item.Title, item.Body)
+		if minimum != "" && !announce.AtLeast(importance.Level, minimum) {
+			continue
+		}
+
+		result.Announces = append(result.Announces, Announce{Announce: item, Importance: importance})
+	}
+
+	return result
+}
+
+// model returns the page as the parser returned it
+func (a Announces) model() model.Announces {
+	announces := model.Announces{Announces: make([]model.Announce, 0, len(a.Announces)), LastPage: a.LastPage}
+	for _, item := range a.Announces {
+		announces.Announces = append(announces.Announces, item.Announce)
+	}
+
+	return announces
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/limits"
+)
+
+// SetLimits replaces the default limits of the request bodies and the requests in flight
+func (a *API) SetLimits(cfg limits.Config) {
+	a.limiter = limits.New(cfg)
+}
+
+// limitsMiddleware rejects the bodies over the limit and, when too many
+// requests are in flight, sheds the new ones with 503 so the ones already
+// served finish in time
+func (a *API) limitsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.limiter.TooLarge(w, r) {
+			write(w, r, http.StatusRequestEntityTooLarge, Response{Error: ErrorTooLarge})
+			return
+		}
+
+		if !a.limiter.Acquire() {
+			w.Header().Set("Retry-After", "1")
+			write(w, r, http.StatusServiceUnavailable, Response{Error: ErrorOverloaded})
+			return
+		}
+		defer a.limiter.Release()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/links"
+)
+
+// defaultLinks guesses the links from the requests to the default prefix
+var defaultLinks, _ = links.New("", "/api/hmtpk")
+
+// SetLinks sets the builder of the absolute links given out by the API
+func (a *API) SetLinks(builder *links.Builder) {
+	a.links = builder
+}
+
+// link returns the absolute URL of the path of the API
+func (a *API) link(r *http.Request, path string) string {
+	return a.links.URL(r, path)
+}
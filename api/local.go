@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/notify"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// LocalAnnounce is an announce written by the college staff through the API
+// instead of the site, e.g. an urgent message while the site is down
+type LocalAnnounce struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// Groups limits the notification to the users of the groups, everyone when empty
+	Groups    []string  `json:"groups,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the announce leaves the feed, it stays readable by its id
+	ExpiresAt time.Time `json:"expires_at"`
+	// Notified is the number of Telegram users notified
+	Notified int `json:"notified"`
+}
+
+const (
+	localAnnouncePrefix = "announce:local:"
+	// localIDPrefix starts the identifiers of local announces, in the feed
+	// their path is the identifier while the announces of the site have paths on the site
+	localIDPrefix = "local-"
+
+	// defaultLocalTTL is how long an announce without an expiry stays in the feed
+	defaultLocalTTL  = time.Hour * 24 * 7
+	maxLocalTitle    = 200
+	maxLocalBody     = 10000
+	maxLocalAnnounce = 64 << 10
+)
+
+// model returns the local announce as an announce of the feed
+func (l LocalAnnounce) model() model.Announce {
+	return model.Announce{
+		Path:  "/" + l.ID + "/",
+		Date:  l.CreatedAt.Format("02.01.2006"),
+		Title: l.Title,
+		Body:  l.Body,
+	}
+}
+
+// localAnnounces returns the local announces still in the feed, newest first
+func (a *API) localAnnounces(ctx context.Context) ([]LocalAnnounce, error) {
+	keys, err := a.store.Keys(ctx, localAnnouncePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.now()
+	var announces []LocalAnnounce
+	for i := len(keys) - 1; i >= 0; i-- {
+		local, err := a.loadLocalAnnounce(ctx, strings.TrimPrefix(keys[i], localAnnouncePrefix))
+		if err != nil {
+			return nil, err
+		}
+
+		if now.Before(local.ExpiresAt) {
+			announces = append(announces, local)
+		}
+	}
+
+	return announces, nil
+}
+
+func (a *API) loadLocalAnnounce(ctx context.Context, id string) (LocalAnnounce, error) {
+	data, err := a.store.Get(ctx, localAnnouncePrefix+id)
+	if err != nil {
+		return LocalAnnounce{}, err
+	}
+
+	var local LocalAnnounce
+	err = json.Unmarshal([]byte(data), &local)
+
+	return local, err
+}
+
+// withLocalAnnounces puts the local announces in the feed before the first page of the site
+func (a *API) withLocalAnnounces(ctx context.Context, page int, announces []model.Announce) []model.Announce {
+	if page != 1 {
+		return announces
+	}
+
+	locals, err := a.localAnnounces(ctx)
+	if err != nil {
+		a.log.WithContext(ctx).Errorf("local announces: %s", err)
+		return announces
+	}
+
+	feed := make([]model.Announce, 0, len(locals)+len(announces))
+	for _, local := range locals {
+		feed = append(feed, local.model())
+	}
+
+	return append(feed, announces...)
+}
+
+// localDetail returns the page of the local announce
+func (a *API) localDetail(r *http.Request, id string) (announce.Detail, error) {
+	local, err := a.loadLocalAnnounce(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return announce.Detail{}, announce.ErrNotFound
+	} else if err != nil {
+		return announce.Detail{}, err
+	}
+
+	return announce.Detail{
+		ID:          local.ID,
+		Href:        a.link(r, "/announces/"+local.ID),
+		Title:       local.Title,
+		Date:        local.CreatedAt.Format("02.01.2006"),
+		Body:        local.Body,
+		Images:      []string{},
+		Attachments: []announce.Attachment{},
+		Local:       true,
+	}, nil
+}
+
+// postLocalAnnounce publishes an announce written by the staff: it enters
+// the feed, the search and the sync flagged as local and is sent to the
+// Telegram users, of the groups when set. Only administrator tenants may post
+func (a *API) postLocalAnnounce(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	var request struct {
+		Title     string    `json:"title"`
+		Body      string    `json:"body"`
+		Groups    []string  `json:"groups"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxLocalAnnounce)).Decode(&request); err != nil {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	now := a.now()
+	if request.ExpiresAt.IsZero() {
+		request.ExpiresAt = now.Add(defaultLocalTTL)
+	}
+
+	request.Title, request.Body = strings.TrimSpace(request.Title), strings.TrimSpace(request.Body)
+	if request.Title == "" || len([]rune(request.Title)) > maxLocalTitle || len([]rune(request.Body)) > maxLocalBody || !request.ExpiresAt.After(now) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+
+	local := LocalAnnounce{
+		// the time first keeps the keys in the order of publication
+		ID:        localIDPrefix + strconv.FormatInt(now.Unix(), 36) + hex.EncodeToString(id),
+		Title:     request.Title,
+		Body:      request.Body,
+		Groups:    request.Groups,
+		CreatedAt: now,
+		ExpiresAt: request.ExpiresAt,
+	}
+
+	if err := a.saveLocalAnnounce(r.Context(), local); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	a.index.Add(local.model())
+
+	if a.telegram.BotToken != "" {
+		go a.notifyLocalAnnounce(context.WithoutCancel(r.Context()), local)
+	}
+
+	if a.notifier != nil {
+		go a.publishLocalAnnounce(context.WithoutCancel(r.Context()), local)
+	}
+
+	write(w, r, http.StatusCreated, local)
+}
+
+func (a *API) saveLocalAnnounce(ctx context.Context, local LocalAnnounce) error {
+	data, err := json.Marshal(local)
+	if err != nil {
+		return err
+	}
+
+	return a.store.Set(ctx, localAnnouncePrefix+local.ID, string(data))
+}
+
+// notifyLocalAnnounce sends the announce to the Telegram users bound to a
+// group, except the users filtering out announces of its importance
+func (a *API) notifyLocalAnnounce(ctx context.Context, local LocalAnnounce) {
+	keys, err := a.store.Keys(ctx, telegramPrefix)
+	if err != nil {
+		a.log.WithContext(ctx).Errorf("local announces: %s", err)
+		return
+	}
+
+	groups := make(map[string]bool, len(local.Groups))
+	for _, group := range local.Groups {
+		groups[group] = true
+	}
+
+	text := local.Title
+	if local.Body != "" {
+		text += "\n\n" + local.Body
+	}
+
+	importance := announce.Classify(local.Title, local.Body)
+
+	for _, key := range keys {
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, telegramPrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if len(groups) > 0 {
+			group, err := a.telegramGroup(ctx, telegram.User{ID: id})
+			if err != nil || group == nil || !groups[group.Value] {
+				continue
+			}
+		}
+
+		// the users filtering the announces get the important ones only
+		if minimum, err := a.telegramImportance(ctx, id); err != nil || (minimum != "" && !announce.AtLeast(importance.Level, minimum)) {
+			continue
+		}
+
+		if err = telegram.SendMessage(ctx, a.telegram, id, text); err != nil {
+			a.log.WithContext(ctx).Warnf("telegram: user %d: %s", id, err)
+			continue
+		}
+		local.Notified++
+	}
+
+	if err = a.saveLocalAnnounce(ctx, local); err != nil {
+		a.log.WithContext(ctx).Errorf("local announces: %s", err)
+	}
+
+	a.log.WithContext(ctx).Infof("local announce %s sent to %d Telegram users", local.ID, local.Notified)
+}
+
+// publishLocalAnnounce sends the announce to the subscribers of the announces
+func (a *API) publishLocalAnnounce(ctx context.Context, local LocalAnnounce) {
+	count, err := a.notifier.Publish(ctx, notify.Notification{
+		Event:  notify.EventAnnounce,
+		Title:  local.Title,
+		Text:   local.Body,
+		Groups: local.Groups,
+		Time:   local.CreatedAt,
+	})
+	if err != nil {
+		a.log.WithContext(ctx).Errorf("local announces: %s", err)
+		return
+	}
+
+	a.log.WithContext(ctx).Infof("local announce %s sent to %d subscribers", local.ID, count)
+}
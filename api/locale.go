@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Display holds the date of a day spelled in the language of the client
+type Display struct {
+	Language string `json:"language"`
+	Weekday  string `json:"weekday"`
+	Month    string `json:"month"`
+	Date     string `json:"date"`
+}
+
+// locale spells the dates in a language
+type locale struct {
+	weekdays [7]string
+	// months are in the form used after the day number
+	months [12]string
+	date   func(l locale, date time.Time) string
+}
+
+const defaultLanguage = "ru"
+
+var locales = map[string]locale{
+	"ru": {
+		weekdays: [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		months:   [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		date: func(l locale, date time.Time) string {
+			return strconv.Itoa(date.Day()) + " " + l.months[date.Month()-1] + " " + strconv.Itoa(date.Year())
+		},
+	},
+	"en": {
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		date: func(l locale, date time.Time) string {
+			return l.months[date.Month()-1] + " " + strconv.Itoa(date.Day()) + ", " + strconv.Itoa(date.Year())
+		},
+	},
+}
+
+// acceptLanguage returns the language of the dates preferred in the
+// Accept-Language header, Russian when none is supported
+func acceptLanguage(header string) string {
+	return preferredLanguage(header, func(language string) bool {
+		_, ok := locales[language]
+		return ok
+	})
+}
+
+// preferredLanguage returns the language accepted by supported preferred in
+// the Accept-Language header, Russian when none is supported
+func preferredLanguage(header string, supported func(language string) bool) string {
+	type preference struct {
+		language string
+		q        float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !supported(language) {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			preferences = append(preferences, preference{language, q})
+		}
+	}
+
+	if len(preferences) == 0 {
+		return defaultLanguage
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].q > preferences[j].q
+	})
+
+	return preferences[0].language
+}
+
+// localize spells the dates of the days of the schedules in the language of
+// the Accept-Language header when the localize parameter is set
+func localize(w http.ResponseWriter, r *http.Request, schedules ...[]Day) {
+	if enabled, _ := strconv.ParseBool(r.URL.Query().Get("localize")); !enabled {
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	language := acceptLanguage(r.Header.Get("Accept-Language"))
+	l := locales[language]
+	for _, days := range schedules {
+		for i, day := range days {
+			date, err := time.Parse(time.DateOnly, day.ISODate)
+			if err != nil {
+				continue
+			}
+
+			days[i].Display = &Display{
+				Language: language,
+				Weekday:  l.weekdays[date.Weekday()],
+				Month:    l.months[date.Month()-1],
+				Date:     l.date(l, date),
+			}
+		}
+	}
+}
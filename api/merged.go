@@ -0,0 +1,161 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// maxElectives is the number of other groups merged into a personal timetable
+const maxElectives = 5
+
+// elective is a subject attended with another group, all of its lessons
+// without the subject
+type elective struct {
+	group   string
+	subject string
+}
+
+// parseElective parses "group" or "group:subject"
+func parseElective(value string) (elective, bool) {
+	group, subject, _ := strings.Cut(value, ":")
+	e := elective{group: strings.TrimSpace(group), subject: strings.TrimSpace(subject)}
+
+	return e, e.group != ""
+}
+
+// matches reports whether the lesson of the group is attended
+func (e elective) matches(lesson model.Lesson) bool {
+	return e.subject == "" || strings.EqualFold(strings.TrimSpace(lesson.Name), e.subject)
+}
+
+// scheduleMerged returns the personal weekly timetable of a student: the
+// lessons of the group, only of the subgroup when set, and of the electives
+// attended with other groups. A lesson shared by several of the groups is
+// listed once, every lesson has the group it comes from
+func (a *API) scheduleMerged(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	group, subgroup := query.Get("group"), query.Get("subgroup")
+	if group == "" || len(query["elective"]) > maxElectives {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	sources := []elective{{group: group}}
+	for _, value := range query["elective"] {
+		e, ok := parseElective(value)
+		if !ok {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+		sources = append(sources, e)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		schedules = make([][]model.Schedule, len(sources))
+		errs      = make([]error, len(sources))
+	)
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			schedules[i], errs[i] = a.getSchedule(r.Context(), source.group, "", date)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+	}
+
+	days := a.isoSchedule(mergeSchedules(sources, schedules, subgroup))
+	markStale(r, days)
+	localize(w, r, days)
+
+	write(w, r, http.StatusOK, days)
+}
+
+// mergeSchedules merges the weekly schedules of the sources day by day, the
+// days of the first one come first
+func mergeSchedules(sources []elective, schedules [][]model.Schedule, subgroup string) []model.Schedule {
+	type lessonKey struct {
+		num, name, room, teacher, subgroup string
+	}
+
+	var (
+		merged []model.Schedule
+		index  = make(map[string]int)
+		seen   = make(map[string]map[lessonKey]bool)
+	)
+	for i, schedule := range schedules {
+		for _, day := range schedule {
+			key := day.Date
+			if d, ok := DayDate(day); ok {
+				key = d.Format("02.01.2006")
+			}
+
+			n, ok := index[key]
+			if !ok {
+				n = len(merged)
+				index[key] = n
+				seen[key] = make(map[lessonKey]bool)
+				merged = append(merged, model.Schedule{Date: day.Date, Href: day.Href})
+			}
+
+			for _, lesson := range day.Lessons {
+				// the subgroup applies to the own group, electives are attended as a whole
+				if i == 0 && subgroup != "" && lesson.Subgroup != "" && lesson.Subgroup != subgroup {
+					continue
+				}
+
+				if i > 0 && !sources[i].matches(lesson) {
+					continue
+				}
+
+				k := lessonKey{
+					num:      lesson.Num,
+					name:     strings.ToLower(strings.TrimSpace(lesson.Name)),
+					room:     strings.ToLower(strings.TrimSpace(lesson.Room)),
+					teacher:  strings.ToLower(strings.TrimSpace(lesson.Teacher)),
+					subgroup: lesson.Subgroup,
+				}
+				if seen[key][k] {
+					continue
+				}
+				seen[key][k] = true
+
+				if lesson.Group == "" {
+					lesson.Group = sources[i].group
+				}
+				merged[n].Lessons = append(merged[n].Lessons, lesson)
+			}
+		}
+	}
+
+	for i := range merged {
+		sort.SliceStable(merged[i].Lessons, func(x, y int) bool {
+			return lessNumeric(merged[i].Lessons[x].Num, merged[i].Lessons[y].Num)
+		})
+	}
+
+	sort.SliceStable(merged, func(x, y int) bool {
+		dx, okX := DayDate(merged[x])
+		dy, okY := DayDate(merged[y])
+		return okX && okY && dx.Before(dy)
+	})
+
+	return merged
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// messages are the translations of the messages of the responses, keyed by
+// the language and the Russian message written by the handlers. Russian is
+// the language of the messages, a message missing from a catalog is sent as is
+var messages = struct {
+	sync.RWMutex
+	catalogs map[string]map[string]string
+}{catalogs: map[string]map[string]string{
+	"en": {
+		ErrorHmtpkNotWorking: "Timed out waiting for a response from https://hmtpk.ru",
+		ErrorBadRequest:      "Bad request",
+		ErrorBadDate:         "Bad date",
+		ErrorToken:           "Invalid user token",
+		ErrorRequestTimeout:  "Too many requests to the HMTPK API per second",
+		ErrorAny:             "An error occurred in the HMTPK API",
+		ErrorNotFound:        "Not found",
+		ErrorNotReplicated:   "The data has not been fetched by the primary instance yet",
+		ErrorGroupArchived:   "The group is no longer listed on the college website",
+		ErrorReplayRunning:   "A request replay is already running",
+		ErrorMaintenance:     "https://hmtpk.ru is under maintenance",
+		ErrorTooLarge:        "Request too large",
+		ErrorOverloaded:      "The HMTPK API is overloaded, retry later",
+		ErrorSlotTaken:       "The room is taken at this time",
+		ErrorRefreshFailed:   "Failed to fetch fresh data from https://hmtpk.ru",
+		ErrorCredentials:     "Invalid login or password",
+		ErrorSessionExpired:  "The personal cabinet session has expired, log in again",
+		// the errors of the parser
+		"Неверный ответ от https://hmtpk.ru": "Bad response from https://hmtpk.ru",
+	},
+}}
+
+// RegisterMessages adds the translations of the messages, keyed by the
+// Russian message, to the catalog of the language, e.g. to translate the
+// messages of the handlers added by an embedder or to support a new language
+func RegisterMessages(language string, translations map[string]string) {
+	language = strings.ToLower(language)
+
+	messages.Lock()
+	defer messages.Unlock()
+
+	catalog, ok := messages.catalogs[language]
+	if !ok {
+		catalog = make(map[string]string, len(translations))
+		messages.catalogs[language] = catalog
+	}
+
+	for message, translation := range translations {
+		catalog[message] = translation
+	}
+}
+
+// hasMessages reports whether the messages are translated to the language
+func hasMessages(language string) bool {
+	if language == defaultLanguage {
+		return true
+	}
+
+	messages.RLock()
+	defer messages.RUnlock()
+
+	_, ok := messages.catalogs[language]
+	return ok
+}
+
+// messageLanguage returns the language of the messages from the lang
+// parameter or the Accept-Language header, Russian when none is supported
+func messageLanguage(r *http.Request) string {
+	if language := strings.ToLower(r.URL.Query().Get("lang")); hasMessages(language) {
+		return language
+	}
+
+	return preferredLanguage(r.Header.Get("Accept-Language"), hasMessages)
+}
+
+// localizeMessage returns the message in the language requested by the client
+func localizeMessage(r *http.Request, message string) string {
+	language := messageLanguage(r)
+	if message == "" || language == defaultLanguage {
+		return message
+	}
+
+	messages.RLock()
+	defer messages.RUnlock()
+
+	if translation, ok := messages.catalogs[language][message]; ok {
+		return translation
+	}
+
+	return message
+}
+
+// localizeResponse localizes the messages of the response envelope
+func localizeResponse(w http.ResponseWriter, r *http.Request, response Response) Response {
+	w.Header().Add("Vary", "Accept-Language")
+
+	response.Message = localizeMessage(r, response.Message)
+	response.Error = localizeMessage(r, response.Error)
+
+	return response
+}
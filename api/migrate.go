@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Migrate moves the Telegram users bound to the groups that disappeared to
+// the groups replacing them or, unless migration is enabled, suggests the new
+// group, and notifies the users about the change
+func (a *API) Migrate(ctx context.Context, removed, added []model.Option, renamed map[string]string) error {
+	if a.telegram.BotToken == "" {
+		return nil
+	}
+
+	gone := make(map[string]bool, len(removed))
+	for _, option := range removed {
+		gone[option.Value] = true
+	}
+
+	labels := make(map[string]string, len(added))
+	for _, option := range added {
+		labels[option.Value] = option.Label
+	}
+
+	keys, err := a.store.Keys(ctx, telegramPrefix)
+	if err != nil {
+		return err
+	}
+
+	var moved, suggested, lost int
+	for _, key := range keys {
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, telegramPrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		user := telegram.User{ID: id}
+		group, err := a.telegramGroup(ctx, user)
+		if err != nil || group == nil || group.Archived || !gone[group.Value] {
+			continue
+		}
+
+		var (
+			text    string
+			binding = &TelegramGroup{Value: group.Value, Label: group.Label, Archived: true}
+		)
+
+		switch successor, ok := renamed[group.Value]; {
+		case ok && a.telegram.Migrate:
+			binding = &TelegramGroup{Value: successor, Label: labels[successor]}
+			text = fmt.Sprintf("Группа %s больше не найдена на сайте колледжа, расписание теперь показывается для группы %s.", group.Label, binding.Label)
+			moved++
+		case ok:
+			binding.Successor = &TelegramGroup{Value: successor, Label: labels[successor]}
+			text = fmt.Sprintf("Группа %s больше не найдена на сайте колледжа. Возможно, теперь она называется %s, откройте приложение, чтобы подтвердить.", group.Label, binding.Successor.Label)
+			suggested++
+		default:
+			text = fmt.Sprintf("Группа %s больше не найдена на сайте колледжа. Откройте приложение, чтобы выбрать новую группу.", group.Label)
+			lost++
+		}
+
+		data, err := json.Marshal(binding)
+		if err != nil {
+			return err
+		}
+
+		if err = a.store.Set(ctx, key, string(data)); err != nil {
+			return err
+		}
+
+		if err = telegram.SendMessage(ctx, a.telegram, id, text); err != nil {
+			a.log.WithContext(ctx).Warnf("telegram: user %d: %s", id, err)
+		}
+	}
+
+	if moved+suggested+lost > 0 {
+		a.log.WithContext(ctx).Infof("telegram: %d users moved to new groups, %d offered a new group, %d left without a group", moved, suggested, lost)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/mirror"
+)
+
+// SetMirrors sets the transport trying the alternative origins of the site
+func (a *API) SetMirrors(mirrors *mirror.Transport) {
+	a.mirrors = mirrors
+}
+
+// upstreamHealth returns the health of hmtpk.ru and its mirrors, for administrators
+func (a *API) upstreamHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	write(w, r, http.StatusOK, a.mirrors.Health())
+}
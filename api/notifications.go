@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/notify"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/chazari-x/hmtpk-parser-api/traceparent"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// telegramInitDataHeader carries the init data of the Mini App proving the
+	// Telegram user whose chat is chosen as a channel
+	telegramInitDataHeader = "X-Telegram-Init-Data"
+
+	ErrorTelegramChat = "Уведомления в Telegram можно получать только в свой чат"
+)
+
+// NotificationSettings are the notification preferences of a user with the
+// channels that can be chosen. The secret of a webhook channel verifies the
+// notify.SignatureHeader of its requests
+type NotificationSettings struct {
+	notify.Preferences
+	// Available are the kinds of the enabled channels
+	Available []string `json:"available_channels"`
+}
+
+// SetNotifier enables the notification preferences of the users
+func (a *API) SetNotifier(notifier *notify.Notifier) {
+	a.notifier = notifier
+}
+
+func (a *API) notificationRoutes(r chi.Router) {
+	r.Post("/", a.notificationPreferences)
+	r.Post("/update", a.updateNotificationPreferences)
+	r.Post("/delete", a.deleteNotificationPreferences)
+}
+
+// loadNotificationPreferences returns the preferences of the user, the default ones when not set
+func (a *API) loadNotificationPreferences(ctx context.Context, owner string) (notify.Preferences, error) {
+	preferences, err := a.notifier.Preferences(ctx, owner)
+	if errors.Is(err, storage.ErrNotFound) {
+		return notify.DefaultPreferences(), nil
+	}
+
+	return preferences, err
+}
+
+// notificationPreferences returns the notification preferences of the user
+func (a *API) notificationPreferences(w http.ResponseWriter, r *http.Request) {
+	owner, ok := a.owner(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := a.loadNotificationPreferences(r.Context(), owner)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, NotificationSettings{Preferences: preferences, Available: a.notifier.Channels()})
+}
+
+// updateNotificationPreferences sets the preferences of the parameters, the
+// missing ones are kept. The repeated event, channel like telegram:12345 or
+// webhook:https://..., group and teacher parameters replace the lists, an
+// empty one clears it, the channels listed are enabled again. quiet is like
+// 22:00-07:00, empty to disable them. A new Telegram chat must be the one of
+// the user of the init data in the X-Telegram-Init-Data header
+func (a *API) updateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	owner, ok := a.owner(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := a.loadNotificationPreferences(r.Context(), owner)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	previous := preferences.Channels
+	if !applyNotificationParams(r, &preferences) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	if !a.ownTelegramChats(r, previous, preferences.Channels) {
+		write(w, r, http.StatusForbidden, Response{Error: ErrorTelegramChat})
+		return
+	}
+
+	err = a.notifier.SetPreferences(r.Context(), owner, &preferences)
+	if errors.Is(err, notify.ErrInvalid) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, NotificationSettings{Preferences: preferences, Available: a.notifier.Channels()})
+}
+
+// ownTelegramChats reports whether every Telegram chat added to the channels
+// is the one of the user of the init data of the request, so nobody can be
+// subscribed to the notifications of somebody else
+func (a *API) ownTelegramChats(r *http.Request, previous, channels []notify.Channel) bool {
+	for _, channel := range channels {
+		if channel.Kind != notify.ChannelTelegram || slices.ContainsFunc(previous, func(c notify.Channel) bool {
+			return c.Kind == channel.Kind && c.Target == channel.Target
+		}) {
+			continue
+		}
+
+		if a.telegram.BotToken == "" {
+			return false
+		}
+
+		data, err := telegram.Validate(r.Header.Get(telegramInitDataHeader), a.telegram, time.Now())
+		if err != nil || strconv.FormatInt(data.User.ID, 10) != channel.Target {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyNotificationParams changes the preferences, false for malformed parameters
+func applyNotificationParams(r *http.Request, preferences *notify.Preferences) bool {
+	query := r.URL.Query()
+
+	list := func(name string) []string {
+		values := []string{}
+		for _, value := range query[name] {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	if query.Has("event") {
+		preferences.Events = list("event")
+	}
+
+	if query.Has("channel") {
+		preferences.Channels = []notify.Channel{}
+		for _, value := range list("channel") {
+			kind, target, ok := strings.Cut(value, ":")
+			if !ok {
+				return false
+			}
+			preferences.Channels = append(preferences.Channels, notify.Channel{Kind: kind, Target: target})
+		}
+	}
+
+	if query.Has("group") {
+		preferences.Groups = list("group")
+	}
+
+	if query.Has("teacher") {
+		preferences.Teachers = list("teacher")
+	}
+
+	if query.Has("quiet") {
+		preferences.Quiet = nil
+		if value := query.Get("quiet"); value != "" {
+			from, to, ok := strings.Cut(value, "-")
+			if !ok {
+				return false
+			}
+			preferences.Quiet = &notify.QuietHours{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+		}
+	}
+
+	if query.Has("digest_hour") {
+		hour, err := strconv.Atoi(query.Get("digest_hour"))
+		if err != nil {
+			return false
+		}
+		preferences.DigestHour = hour
+	}
+
+	return true
+}
+
+// deleteNotificationPreferences unsubscribes the user from every notification
+func (a *API) deleteNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	owner, ok := a.owner(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.notifier.Unsubscribe(r.Context(), owner); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, nil)
+}
+
+// NotifyScheduleChange notifies the subscribers that the week of the group
+// changed, e.g. when the warmer fetched a different schedule
+func (a *API) NotifyScheduleChange(group string, _ []model.Schedule) {
+	if a.notifier == nil {
+		return
+	}
+
+	// the change starts the trace its notifications are delivered in
+	parent := traceparent.New()
+	ctx := traceparent.NewContext(context.Background(), parent)
+	count, err := a.notifier.Publish(ctx, notify.Notification{
+		Event: notify.EventScheduleChange,
+		Title: "Изменилось расписание группы " + group,
+		Group: group,
+	})
+	if err != nil {
+		a.log.Errorf("notify: schedule of %s: %s", group, err)
+		return
+	}
+
+	a.log.Debugf("notify: schedule change of %s sent to %d subscribers, trace %s", group, count, parent)
+}
+
+// NotificationDigest returns the digest of the day of the groups and the
+// teachers of the preferences
+func (a *API) NotificationDigest(ctx context.Context, preferences notify.Preferences, now time.Time) []notify.Notification {
+	now = now.In(a.location)
+	date := now.Format("02.01.2006")
+
+	var notifications []notify.Notification
+	digest := func(group, teacher string) {
+		schedule, err := a.getSchedule(ctx, group, teacher, date)
+		if err != nil {
+			a.log.Warnf("notify: digest of %s%s: %s", group, teacher, err)
+			return
+		}
+
+		day, _ := findDay(schedule, date)
+		notifications = append(notifications, notify.Notification{
+			Event:   notify.EventDigest,
+			Title:   "Расписание " + group + teacher + " на " + date,
+			Text:    summarize(day.Lessons, now, now, teacher != ""),
+			Group:   group,
+			Teacher: teacher,
+			Time:    now,
+		})
+	}
+
+	for _, group := range preferences.Groups {
+		digest(group, "")
+	}
+	for _, teacher := range preferences.Teachers {
+		digest("", teacher)
+	}
+
+	return notifications
+}
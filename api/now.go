@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// defaultLocation is the time zone of the college
+const defaultLocation = "Asia/Yekaterinburg"
+
+// NowResponse is the lesson going on at the moment and the next one
+type NowResponse struct {
+	Time    time.Time   `json:"time"`
+	Current *LessonSlot `json:"current"`
+	Next    *LessonSlot `json:"next"`
+}
+
+// LessonSlot is a lesson number of the day with the lessons of all subgroups
+type LessonSlot struct {
+	Num   string `json:"num"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Minutes is the time left until the end of the current lesson or the start of the next one
+	Minutes int            `json:"minutes"`
+	Lessons []model.Lesson `json:"lessons"`
+}
+
+// SetLocation sets the time zone of the college
+func (a *API) SetLocation(location *time.Location) {
+	a.location = location
+}
+
+// Location returns the time zone of the college
+func (a *API) Location() *time.Location {
+	return a.now().Location()
+}
+
+// now returns the current time in the time zone of the college
+func (a *API) now() time.Time {
+	if a.location == nil {
+		return time.Now()
+	}
+
+	return time.Now().In(a.location)
+}
+
+// scheduleNow returns the current and the next lesson of the group or the teacher
+func (a *API) scheduleNow(w http.ResponseWriter, r *http.Request) {
+	group, teacher := r.URL.Query().Get("group"), r.URL.Query().Get("teacher")
+	if group == "" && teacher == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	now := a.now()
+	date := now.Format("02.01.2006")
+
+	schedule, err := a.getSchedule(r.Context(), group, teacher, date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	day, _ := findDay(schedule, date)
+	response := NowResponse{Time: now.Truncate(time.Minute)}
+	for _, slot := range a.lessonSlots(day.Lessons, now.Weekday()) {
+		start, end := clockAt(now, slot.Start), clockAt(now, slot.End)
+
+		switch {
+		case response.Current == nil && !now.Before(start) && now.Before(end):
+			slot.Minutes = int(end.Sub(now).Minutes())
+			response.Current = &slot
+		case response.Next == nil && now.Before(start):
+			slot.Minutes = int(start.Sub(now).Minutes())
+			response.Next = &slot
+		}
+	}
+
+	write(w, r, http.StatusOK, response)
+}
+
+// lessonSlots groups the lessons by number with the start and end times taken
+// from the lessons or, when they have none, from the bell schedule
+func (a *API) lessonSlots(lessons []model.Lesson, weekday time.Weekday) []LessonSlot {
+	var slots []LessonSlot
+	for _, lesson := range lessons {
+		if n := len(slots); n > 0 && slots[n-1].Num == lesson.Num {
+			slots[n-1].Lessons = append(slots[n-1].Lessons, lesson)
+			continue
+		}
+
+		start, end, ok := a.lessonTime(lesson, weekday)
+		if !ok {
+			continue
+		}
+
+		slots = append(slots, LessonSlot{Num: lesson.Num, Start: start, End: end, Lessons: []model.Lesson{lesson}})
+	}
+
+	return slots
+}
+
+// lessonTime returns the start and end of the lesson taken from the lesson or,
+// when it has none, from the bell schedule
+func (a *API) lessonTime(lesson model.Lesson, weekday time.Weekday) (string, string, bool) {
+	if start, end, ok := bells.ParseTime(lesson.Time); ok {
+		return start, end, true
+	}
+
+	for _, bell := range a.currentBells().For(weekday) {
+		if bell.Num == lesson.Num {
+			return bell.Start, bell.End, true
+		}
+	}
+
+	return "", "", false
+}
+
+// clockAt returns the time of the clock "15:04" on the day of now
+func clockAt(now time.Time, clock string) time.Time {
+	t, _ := time.Parse("15:04", clock)
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+}
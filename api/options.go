@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// maxOptionsQuery bounds the q parameter of the groups and the teachers
+const maxOptionsQuery = 100
+
+// groupCourse returns the course of the group, the first digit of its name
+// like 2 of "ИСП-21" or 1 of "1ИС-21", 0 when the name has none
+func groupCourse(label string) int {
+	for _, r := range label {
+		if r >= '0' && r <= '9' {
+			return int(r - '0')
+		}
+	}
+
+	return 0
+}
+
+// groupSpecialty returns the specialty of the group, the letters of its name like "ИСП"
+func groupSpecialty(label string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, label))
+}
+
+// filterOptions returns the options matching the q parameter, a case
+// insensitive substring of the label, sorted by the sort parameter: name,
+// -name or, for the groups, course. The groups are also filtered by the
+// course and the specialty parameters. Without the parameters the options
+// are returned as the site lists them
+func filterOptions(r *http.Request, options []model.Option, groups bool) ([]model.Option, bool) {
+	query := r.URL.Query()
+
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+	if len([]rune(q)) > maxOptionsQuery {
+		return nil, false
+	}
+
+	course := 0
+	if v := query.Get("course"); v != "" {
+		var err error
+		if course, err = strconv.Atoi(v); err != nil || course <= 0 || !groups {
+			return nil, false
+		}
+	}
+
+	specialty := strings.ToUpper(strings.TrimSpace(query.Get("specialty")))
+	if specialty != "" && !groups {
+		return nil, false
+	}
+
+	var less func(a, b model.Option) bool
+	switch query.Get("sort") {
+	case "":
+	case "name":
+		less = func(a, b model.Option) bool {
+			return strings.ToLower(a.Label) < strings.ToLower(b.Label)
+		}
+	case "-name":
+		less = func(a, b model.Option) bool {
+			return strings.ToLower(a.Label) > strings.ToLower(b.Label)
+		}
+	case "course":
+		if !groups {
+			return nil, false
+		}
+		less = func(a, b model.Option) bool {
+			if x, y := groupCourse(a.Label), groupCourse(b.Label); x != y {
+				return x < y
+			}
+			return strings.ToLower(a.Label) < strings.ToLower(b.Label)
+		}
+	default:
+		return nil, false
+	}
+
+	if q == "" && course == 0 && specialty == "" && less == nil {
+		return options, true
+	}
+
+	result := make([]model.Option, 0, len(options))
+	for _, option := range options {
+		if q != "" && !strings.Contains(strings.ToLower(option.Label), q) {
+			continue
+		}
+		if course != 0 && groupCourse(option.Label) != course {
+			continue
+		}
+		if specialty != "" && groupSpecialty(option.Label) != specialty {
+			continue
+		}
+		result = append(result, option)
+	}
+
+	if less != nil {
+		sort.SliceStable(result, func(i, j int) bool {
+			return less(result[i], result[j])
+		})
+	}
+
+	return result, true
+}
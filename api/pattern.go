@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/archive"
+	"github.com/chazari-x/hmtpk-parser-api/pattern"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPatternWeeks = 8
+	maxPatternWeeks     = 26
+)
+
+// GroupPattern is the typical week of the group
+type GroupPattern struct {
+	Group string `json:"group"`
+	pattern.Pattern
+}
+
+// groupPattern infers the typical week of the group from the archived weeks,
+// the current one and the weeks before it, with the one-off deviations of
+// every week from it. A lesson held every other week has the interval 2 and
+// the parity of its ISO weeks
+func (a *API) groupPattern(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	weeks := defaultPatternWeeks
+	if v := query.Get("weeks"); v != "" {
+		var err error
+		if weeks, err = strconv.Atoi(v); err != nil || weeks < 2 || weeks > maxPatternWeeks {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+	}
+
+	threshold := pattern.DefaultThreshold
+	if v := query.Get("threshold"); v != "" {
+		var err error
+		if threshold, err = strconv.ParseFloat(v, 64); err != nil || threshold <= 0 || threshold > 1 {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	group := chi.URLParam(r, "group")
+
+	now := a.now()
+	monday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday = monday.AddDate(0, 0, -(int(monday.Weekday())+6)%7)
+
+	schedule, _, err := a.archive.Schedule(ctx, archive.KindGroup, group, monday.AddDate(0, 0, -7*(weeks-1)), monday.AddDate(0, 0, 6))
+	if errors.Is(err, archive.ErrNotFound) {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, GroupPattern{Group: group, Pattern: pattern.Infer(schedule, DayDate, threshold)})
+}
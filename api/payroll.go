@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/archive"
+	"github.com/chazari-x/hmtpk-parser-api/payroll"
+)
+
+// payrollTimeout bounds reading a month of archived schedules
+const payrollTimeout = time.Minute
+
+// payrollReport aggregates the academic hours per teacher in the month of
+// the month parameter like 2025-10, the current one by default, from the
+// archived schedules of the groups. The teacher parameter, repeated, limits
+// the report to those teachers
+func (a *API) payrollReport(r *http.Request) (payroll.Report, bool, error) {
+	query := r.URL.Query()
+
+	now := a.now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := query.Get("month"); v != "" {
+		var err error
+		if month, err = time.Parse("2006-01", v); err != nil {
+			return payroll.Report{}, false, nil
+		}
+	}
+
+	var filter func(string) bool
+	if teachers := query["teacher"]; len(teachers) > 0 {
+		filter = func(teacher string) bool {
+			for _, t := range teachers {
+				if strings.EqualFold(strings.TrimSpace(t), teacher) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), payrollTimeout)
+	defer cancel()
+
+	schedules, err := a.archive.Days(ctx, archive.KindGroup, month, month.AddDate(0, 1, -1))
+	if err != nil {
+		return payroll.Report{}, true, err
+	}
+
+	return payroll.Aggregate(schedules, month, DayDate, filter), true, nil
+}
+
+// payroll returns the academic hours actually held by every teacher in the
+// month, after the replacements, for the planning department. Only
+// administrator tenants may read it
+func (a *API) payroll(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	report, ok, err := a.payrollReport(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,77 @@
+//go:build !no_xlsx
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/xuri/excelize/v2"
+)
+
+func init() {
+	optionalRoutes = append(optionalRoutes, func(a *API, r chi.Router) {
+		if a.archive != nil {
+			r.Post("/admin/payroll/xlsx", a.payrollXLSX)
+		}
+	})
+}
+
+// payrollXLSX exports the academic hours of the teachers in the month as a spreadsheet
+func (a *API) payrollXLSX(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	report, ok, err := a.payrollReport(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	book, err := newScheduleBook()
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+	defer func() {
+		_ = book.file.Close()
+	}()
+
+	rows := make([][]interface{}, 0, len(report.Teachers))
+	for _, teacher := range report.Teachers {
+		rows = append(rows, []interface{}{teacher.Teacher, teacher.Lessons, teacher.Hours, strings.Join(teacher.Groups, ", "), strings.Join(teacher.Subjects, ", ")})
+	}
+
+	sheet := "Часы " + report.Month
+	if err = book.addSheet(sheet, []string{"Преподаватель", "Занятий", "Академических часов", "Группы", "Предметы"}, rows); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	for column, width := range []float64{30, 10, 14, 40, 60} {
+		name, _ := excelize.ColumnNumberToName(column + 1)
+		if err = book.file.SetColWidth(sheet, name, name, width); err != nil {
+			a.error(w, r, err)
+			return
+		}
+	}
+
+	if err = book.finish(); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeXLSX)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="payroll-%s.xlsx"`, report.Month))
+
+	if err = book.file.Write(w); err != nil {
+		a.log.WithContext(r.Context()).Error(err)
+	}
+}
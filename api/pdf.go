@@ -0,0 +1,161 @@
+//go:build !no_render
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func init() {
+	optionalRoutes = append(optionalRoutes, func(a *API, r chi.Router) {
+		r.Post("/schedule/pdf", a.schedulePDF)
+	})
+}
+
+const (
+	contentTypePDF = "application/pdf"
+
+	pdfFont       = "go"
+	pdfLineHeight = 5.0
+	pdfMargin     = 10.0
+)
+
+// pdfColumn is a column of the timetable
+type pdfColumn struct {
+	title string
+	width float64
+	value func(lesson model.Lesson) string
+}
+
+// schedulePDF renders the weekly schedule of a group or a teacher as a printable A4 timetable
+func (a *API) schedulePDF(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	group, teacher := r.URL.Query().Get("group"), r.URL.Query().Get("teacher")
+	if group == "" && teacher == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	schedule, err := a.getSchedule(r.Context(), group, teacher, date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	title := "Расписание группы " + group
+	other := pdfColumn{"Преподаватель", 85, func(lesson model.Lesson) string { return lesson.Teacher }}
+	if group == "" {
+		title = "Расписание преподавателя " + teacher
+		other = pdfColumn{"Группа", 85, func(lesson model.Lesson) string { return lesson.Group }}
+	}
+
+	columns := []pdfColumn{
+		{"№", 10, func(lesson model.Lesson) string { return lesson.Num }},
+		{"Время", 28, func(lesson model.Lesson) string { return lesson.Time }},
+		{"Предмет", 110, func(lesson model.Lesson) string { return lesson.Name }},
+		{"Кабинет", 24, func(lesson model.Lesson) string { return lesson.Room }},
+		other,
+		{"Подгруппа", 20, func(lesson model.Lesson) string { return lesson.Subgroup }},
+	}
+
+	pdf := renderPDF(title+", неделя с датой "+date, columns, schedule)
+	if err = pdf.Error(); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypePDF)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="schedule-%s.pdf"`, date))
+
+	if err = pdf.Output(w); err != nil {
+		a.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+func renderPDF(title string, columns []pdfColumn, schedule []model.Schedule) *fpdf.Fpdf {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.SetTitle(title, true)
+
+	var width float64
+	for _, column := range columns {
+		width += column.width
+	}
+
+	header := func() {
+		pdf.SetFont(pdfFont, "B", 9)
+		pdf.SetFillColor(217, 225, 242)
+		for _, column := range columns {
+			pdf.CellFormat(column.width, pdfLineHeight+2, column.title, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	_, pageHeight := pdf.GetPageSize()
+	fits := func(height float64) bool {
+		return pdf.GetY()+height <= pageHeight-pdfMargin
+	}
+
+	pdf.AddPage()
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.CellFormat(width, 10, title, "", 1, "L", false, 0, "")
+	header()
+
+	for _, day := range schedule {
+		if !fits(2 * (pdfLineHeight + 2)) {
+			pdf.AddPage()
+			header()
+		}
+
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.SetFillColor(242, 242, 242)
+		pdf.CellFormat(width, pdfLineHeight+2, day.Date, "1", 1, "L", true, 0, "")
+
+		pdf.SetFont(pdfFont, "", 9)
+		if len(day.Lessons) == 0 {
+			pdf.CellFormat(width, pdfLineHeight+2, "Нет занятий", "1", 1, "C", false, 0, "")
+			continue
+		}
+
+		for _, lesson := range day.Lessons {
+			lines := 1
+			for _, column := range columns {
+				lines = max(lines, len(pdf.SplitText(column.value(lesson), column.width-2)))
+			}
+
+			height := float64(lines)*pdfLineHeight + 2
+			if !fits(height) {
+				pdf.AddPage()
+				header()
+				pdf.SetFont(pdfFont, "", 9)
+			}
+
+			x, y := pdf.GetXY()
+			for _, column := range columns {
+				pdf.Rect(x, y, column.width, height, "D")
+				pdf.SetXY(x+1, y+1)
+				pdf.MultiCell(column.width-2, pdfLineHeight, column.value(lesson), "", "L", false)
+				x += column.width
+			}
+
+			pdf.SetXY(pdfMargin, y+height)
+		}
+	}
+
+	return pdf
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Preset is a named set of response options picked with ?preset=, so thin
+// clients pass one parameter instead of many
+type Preset struct {
+	// Query are the query parameters the preset sets, the ones in the request win
+	Query map[string]string `yaml:"query"`
+	// Omit are the fields removed from the JSON and MessagePack responses at any depth
+	Omit []string `yaml:"omit"`
+}
+
+// defaultPresets are served unless the configuration replaces them
+var defaultPresets = map[string]Preset{
+	"compact": {Omit: []string{"href", "display", "source", "fetched_at", "start", "end", "location", "group"}},
+	"full":    {Query: map[string]string{"localize": "true"}},
+	"bot":     {Query: map[string]string{"localize": "true", "format": formatText}, Omit: []string{"href", "source", "fetched_at", "start", "end", "iso_date"}},
+}
+
+type omitKey struct{}
+
+// SetPresets adds the configured presets, replacing the default ones with the same name
+func (a *API) SetPresets(presets map[string]Preset) {
+	merged := make(map[string]Preset, len(defaultPresets)+len(presets))
+	for name, preset := range defaultPresets {
+		merged[name] = preset
+	}
+	for name, preset := range presets {
+		merged[name] = preset
+	}
+
+	a.presets = merged
+}
+
+// presetMiddleware applies the preset named by the preset parameter
+func (a *API) presetMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("preset")
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		preset, ok := a.presets[name]
+		if !ok {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+
+		query := r.URL.Query()
+		for key, value := range preset.Query {
+			if !query.Has(key) {
+				query.Set(key, value)
+			}
+		}
+
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = query.Encode()
+
+		if len(preset.Omit) > 0 {
+			omit := make(map[string]bool, len(preset.Omit))
+			for _, field := range preset.Omit {
+				omit[field] = true
+			}
+			r = r.WithContext(context.WithValue(r.Context(), omitKey{}, omit))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// omitFields returns the data without the fields the preset of the request omits
+func omitFields(r *http.Request, data interface{}) interface{} {
+	omit, _ := r.Context().Value(omitKey{}).(map[string]bool)
+	if len(omit) == 0 {
+		return data
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+
+	var generic interface{}
+	if err = json.Unmarshal(body, &generic); err != nil {
+		return data
+	}
+
+	return prune(generic, omit)
+}
+
+// prune removes the fields from the objects of the decoded JSON
+func prune(value interface{}, omit map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if omit[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = prune(item, omit)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = prune(item, omit)
+		}
+	}
+
+	return value
+}
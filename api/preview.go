@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/draft"
+)
+
+// maxDraft is the size of an uploaded draft schedule page
+const maxDraft = 2 << 20
+
+// PreviewResponse is the draft schedule as it would be served with the problems found in it
+type PreviewResponse struct {
+	Group     string           `json:"group"`
+	Schedule  []Day            `json:"schedule"`
+	Conflicts []draft.Conflict `json:"conflicts"`
+}
+
+// schedulePreview parses a draft weekly schedule of the group uploaded as the
+// body, a page in the format of the site, and checks it against itself and
+// the warmed schedules of other groups before it is published
+func (a *API) schedulePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	group := r.URL.Query().Get("group")
+	if group == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	schedule, err := draft.Parse(io.LimitReader(r.Body, maxDraft), group)
+	if errors.Is(err, draft.ErrEmpty) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	conflicts := draft.Conflicts(schedule, group, a.snapshot(), DayDate)
+	if conflicts == nil {
+		conflicts = []draft.Conflict{}
+	}
+
+	write(w, r, http.StatusOK, PreviewResponse{Group: group, Schedule: a.isoSchedule(schedule), Conflicts: conflicts})
+}
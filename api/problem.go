@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/chazari-x/hmtpk-parser-api/requestid"
+)
+
+const (
+	contentTypeProblem = "application/problem+json"
+
+	// ProblemTypeBase is the prefix of the type URI of the problems, followed by the code
+	ProblemTypeBase = "urn:hmtpk-parser-api:problem:"
+)
+
+// Problem is the RFC 7807 body of the error responses. Code is stable and
+// meant for the clients to branch on, Title is the message in the language
+// requested by the client
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorCodes are the codes of the error messages, the messages missing from
+// it get the code of their status like method_not_allowed
+var errorCodes = struct {
+	sync.RWMutex
+	codes map[string]string
+}{codes: map[string]string{
+	ErrorHmtpkNotWorking: "upstream_timeout",
+	ErrorBadRequest:      "bad_request",
+	ErrorBadDate:         "bad_date",
+	ErrorToken:           "invalid_token",
+	ErrorRequestTimeout:  "rate_limited",
+	ErrorAny:             "internal_error",
+	ErrorNotFound:        "not_found",
+	ErrorNotReplicated:   "not_replicated",
+	ErrorGroupArchived:   "group_archived",
+	ErrorReplayRunning:   "replay_running",
+	ErrorMaintenance:     "upstream_maintenance",
+	ErrorTooLarge:        "request_too_large",
+	ErrorOverloaded:      "overloaded",
+	ErrorSlotTaken:       "slot_taken",
+	ErrorRefreshFailed:   "refresh_failed",
+	ErrorCredentials:     "invalid_credentials",
+	ErrorSessionExpired:  "session_expired",
+	// the errors of the parser
+	"Неверный ответ от https://hmtpk.ru": "upstream_bad_response",
+}}
+
+// RegisterErrorCode sets the code of the problems of an error message, e.g.
+// of the errors of the handlers added by an embedder
+func RegisterErrorCode(message, code string) {
+	errorCodes.Lock()
+	defer errorCodes.Unlock()
+
+	errorCodes.codes[message] = code
+}
+
+// errorCode returns the code of the error message
+func errorCode(message string, statusCode int) string {
+	errorCodes.RLock()
+	code, ok := errorCodes.codes[message]
+	errorCodes.RUnlock()
+
+	if ok {
+		return code
+	}
+
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(http.StatusText(statusCode)))
+}
+
+// newProblem returns the problem of the error message of the response
+func newProblem(w http.ResponseWriter, r *http.Request, statusCode int, message string) Problem {
+	w.Header().Add("Vary", "Accept-Language")
+
+	code := errorCode(message, statusCode)
+
+	return Problem{
+		Type:      ProblemTypeBase + code,
+		Title:     localizeMessage(r, message),
+		Status:    statusCode,
+		Code:      code,
+		Instance:  r.URL.Path,
+		RequestID: requestid.FromContext(r.Context()),
+	}
+}
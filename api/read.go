@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/ids"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+)
+
+const (
+	// telegramReadPrefix keeps the identifiers of the announces a user has seen
+	telegramReadPrefix = "telegram:read:"
+
+	// maxRead bounds the announces kept as read per user, the oldest are dropped first
+	maxRead = 500
+)
+
+// AnnounceReadState is the read state of the first page of announces for a
+// user, the same on every device of the user
+type AnnounceReadState struct {
+	Unread int `json:"unread"`
+	// UnreadIDs are the identifiers of the unread announces, newest first
+	UnreadIDs []string `json:"unread_ids"`
+}
+
+// telegramRead returns the identifiers of the announces the user has seen, oldest first
+func (a *API) telegramRead(ctx context.Context, id int64) ([]string, error) {
+	value, err := a.store.Get(ctx, telegramReadPrefix+strconv.FormatInt(id, 10))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var read []string
+	err = json.Unmarshal([]byte(value), &read)
+
+	return read, err
+}
+
+// feedIDs returns the identifiers of the announces on the first page, newest first
+func (a *API) feedIDs(ctx context.Context) ([]string, error) {
+	ctx, cancel := upstreamContext(ctx)
+	defer cancel()
+
+	announces, err := a.hmtpk.GetAnnounces(ctx, 1)
+	announces.Announces = a.withLocalAnnounces(ctx, 1, announces.Announces)
+	if err != nil && len(announces.Announces) == 0 {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(announces.Announces))
+	for _, item := range announces.Announces {
+		list = append(list, announce.PathID(item.Path))
+	}
+
+	return list, nil
+}
+
+// readState counts the announces of the feed the user has not seen
+func (a *API) readState(feed, read []string) AnnounceReadState {
+	seen := make(map[string]bool, len(read))
+	for _, id := range read {
+		seen[id] = true
+	}
+
+	state := AnnounceReadState{UnreadIDs: make([]string, 0)}
+	for _, id := range feed {
+		if !seen[id] {
+			state.UnreadIDs = append(state.UnreadIDs, a.ids.Encode(ids.KindAnnounce, id))
+		}
+	}
+	state.Unread = len(state.UnreadIDs)
+
+	return state
+}
+
+// telegramAnnounces returns the number of the announces on the first page
+// the user has not seen, to badge the news tab
+func (a *API) telegramAnnounces(w http.ResponseWriter, r *http.Request) {
+	user := telegramUser(r)
+
+	read, err := a.telegramRead(r.Context(), user.ID)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	feed, err := a.feedIDs(r.Context())
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, a.readState(feed, read))
+}
+
+// telegramAnnouncesRead marks the announces given by the id parameter,
+// repeated, as seen by the user, or every announce of the first page with
+// all=true, and returns the new read state
+func (a *API) telegramAnnouncesRead(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") == "true"
+	if !all && len(r.URL.Query()["id"]) == 0 {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	var marked []string
+	for _, public := range r.URL.Query()["id"] {
+		id, err := a.ids.Decode(ids.KindAnnounce, public)
+		if err != nil {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+		marked = append(marked, id)
+	}
+
+	user := telegramUser(r)
+
+	read, err := a.telegramRead(r.Context(), user.ID)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	feed, err := a.feedIDs(r.Context())
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if all {
+		// the oldest of the feed is appended first
+		for i := len(feed) - 1; i >= 0; i-- {
+			marked = append(marked, feed[i])
+		}
+	}
+
+	seen := make(map[string]bool, len(read))
+	for _, id := range read {
+		seen[id] = true
+	}
+	for _, id := range marked {
+		if !seen[id] {
+			seen[id] = true
+			read = append(read, id)
+		}
+	}
+
+	if len(read) > maxRead {
+		read = read[len(read)-maxRead:]
+	}
+
+	data, err := json.Marshal(read)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if err = a.store.Set(r.Context(), telegramReadPrefix+strconv.FormatInt(user.ID, 10), string(data)); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, a.readState(feed, read))
+}
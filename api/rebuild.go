@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// rebuildTimeout bounds reading the stored data for a rebuild
+const rebuildTimeout = time.Minute
+
+// RebuildReport is the result of rebuilding the derived data
+type RebuildReport struct {
+	// Archived is the number of announces read from the archive
+	Archived int `json:"archived"`
+	// Local is the number of local announces still in the feed
+	Local int `json:"local"`
+	// Indexed is the number of announces in the search index after the rebuild
+	Indexed  int     `json:"indexed"`
+	Duration float64 `json:"duration"`
+}
+
+// rebuild builds the search index of announces again from the announces it
+// holds, the archived ones and the local ones, without fetching hmtpk.ru,
+// e.g. after fixing the normalization of terms. The cross-references, the
+// rooms, the subjects and the bells are derived from the warmed schedules on
+// every request and need no rebuild. Only administrator tenants may rebuild
+func (a *API) rebuild(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), rebuildTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var (
+		report    RebuildReport
+		announces []model.Announce
+	)
+	if a.archive != nil {
+		archived, err := a.archive.AllAnnounces(ctx)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+
+		report.Archived = len(archived)
+		announces = append(announces, archived...)
+	}
+
+	locals, err := a.localAnnounces(ctx)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	for _, local := range locals {
+		announces = append(announces, local.model())
+	}
+	report.Local = len(locals)
+
+	report.Indexed = a.index.Rebuild(announces)
+	report.Duration = time.Since(start).Seconds()
+
+	a.log.WithContext(r.Context()).Infof("rebuild: search index rebuilt with %d announces, %d archived and %d local", report.Indexed, report.Archived, report.Local)
+
+	write(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/chazari-x/hmtpk-parser-api/metrics"
+)
+
+// PanicError is the error of a fetch that panicked, e.g. the parser on a page
+// of the site it did not expect
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// SetMetrics enables counting the recovered panics
+func (a *API) SetMetrics(metrics *metrics.Metrics) {
+	a.metrics = metrics
+}
+
+// recoverMiddleware answers a panicking handler with the standard error
+// instead of dropping the connection. An aborted handler is let through
+func (a *API) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			a.panicked(r, v, debug.Stack())
+			if a.reporter != nil {
+				a.reporter.CapturePanic(v, r)
+			}
+
+			write(w, r, http.StatusInternalServerError, Response{Error: ErrorAny})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// panicked logs the stack of a recovered panic and counts it
+func (a *API) panicked(r *http.Request, v interface{}, stack []byte) {
+	a.log.WithContext(r.Context()).Errorf("panic: %v\n%s", v, stack)
+
+	if a.metrics != nil {
+		a.metrics.Panic()
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/cache"
+	"github.com/chazari-x/hmtpk-parser-api/rediscache"
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+)
+
+// ErrorRefreshFailed is returned when hmtpk.ru failed and a refresh would only
+// serve the data kept before
+const ErrorRefreshFailed = "Не удалось получить свежие данные с https://hmtpk.ru"
+
+type refreshKey struct{}
+
+// withRefresh marks the fetches of the context as refreshes, they never share
+// a fetch started before the cache was flushed
+func withRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+func refreshing(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+// refresh fetches the schedule of the group or the teacher for the date, or
+// the page of announces with announces=true, from hmtpk.ru right away: the
+// cached data is flushed first and the fresh data updates the caches, the
+// archive, the last good data and the warmed snapshot. Useful right after the
+// college publishes corrections. Only administrator tenants may refresh
+func (a *API) refresh(w http.ResponseWriter, r *http.Request) {
+	if !a.isAdmin(r) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	query := r.URL.Query()
+	group, teacher := query.Get("group"), query.Get("teacher")
+	announces := query.Get("announces") == "true"
+
+	var targets int
+	for _, set := range []bool{group != "", teacher != "", announces} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	// a fallback served instead of hmtpk.ru is a failed refresh
+	ctx, report := stale.WithReport(cache.Bypass(withRefresh(ctx)))
+	failed := func() bool {
+		_, _, ok := report.Get()
+		return ok
+	}
+
+	if announces {
+		page := 1
+		if v := query.Get("page"); v != "" {
+			var err error
+			if page, err = strconv.Atoi(v); err != nil || page < 1 {
+				write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+				return
+			}
+		}
+
+		if !a.flushCached(ctx, rediscache.AnnouncesPagePattern(page)) {
+			write(w, r, http.StatusInternalServerError, Response{Error: ErrorAny})
+			return
+		}
+
+		result, err := a.hmtpk.GetAnnounces(ctx, page)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+
+		if failed() {
+			write(w, r, http.StatusBadGateway, Response{Error: ErrorRefreshFailed})
+			return
+		}
+
+		for _, announce := range result.Announces {
+			a.index.Add(announce)
+		}
+		result.Announces = a.withLocalAnnounces(ctx, page, result.Announces)
+
+		a.log.WithContext(r.Context()).Infof("refresh: announces page %d", page)
+		write(w, r, http.StatusOK, result)
+		return
+	}
+
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	value := group
+	if value == "" {
+		value = teacher
+	}
+
+	if !a.flushCached(ctx, rediscache.SchedulePattern(value, date)) {
+		write(w, r, http.StatusInternalServerError, Response{Error: ErrorAny})
+		return
+	}
+
+	schedule, err := a.getSchedule(ctx, group, teacher, date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if failed() {
+		write(w, r, http.StatusBadGateway, Response{Error: ErrorRefreshFailed})
+		return
+	}
+
+	// the warmed snapshot holds the current week only
+	if group != "" && a.warmer != nil && a.currentWeek(date) {
+		a.warmer.Put(group, schedule)
+	}
+
+	a.log.WithContext(r.Context()).Infof("refresh: schedule of %s for %s", value, date)
+	write(w, r, http.StatusOK, a.isoSchedule(schedule))
+}
+
+// flushCached deletes the cached keys of the pattern, reports false on failure
+func (a *API) flushCached(ctx context.Context, pattern string) bool {
+	if a.cache == nil {
+		return true
+	}
+
+	if _, err := a.cache.Flush(ctx, []string{pattern}); err != nil {
+		a.log.WithContext(ctx).Errorf("refresh: %s", err)
+		return false
+	}
+
+	return true
+}
+
+// currentWeek reports whether the date, as 02.01.2006, is in the current week
+func (a *API) currentWeek(date string) bool {
+	d, err := time.ParseInLocation("02.01.2006", date, a.Location())
+	if err != nil {
+		return false
+	}
+
+	year, week := d.ISOWeek()
+	nowYear, nowWeek := a.now().ISOWeek()
+
+	return year == nowYear && week == nowWeek
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/chazari-x/hmtpk-parser-api/replay"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxReplaySpeed bounds the speed multiplier of a replay
+const maxReplaySpeed = 1000
+
+// SetReplayer enables the capacity replays of the recorded requests
+func (a *API) SetReplayer(replayer *replay.Replayer) {
+	a.replayer = replayer
+}
+
+func (a *API) replayRoutes(r chi.Router) {
+	r.Post("/", a.replayDays)
+	r.Post("/start", a.startReplay)
+	r.Post("/{id}", a.replayReport)
+}
+
+// replayDays returns the days of recorded requests, for administrators
+func (a *API) replayDays(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	days, err := a.replayer.Days()
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, days)
+}
+
+// startReplay replays the recorded day against this instance at the speed
+// multiplier, the report is polled by its id
+func (a *API) startReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	speed := 1.0
+	if v := r.URL.Query().Get("speed"); v != "" {
+		var err error
+		if speed, err = strconv.ParseFloat(v, 64); err != nil || speed <= 0 || speed > maxReplaySpeed {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+	}
+
+	report, err := a.replayer.Start(r.URL.Query().Get("day"), speed)
+	switch {
+	case errors.Is(err, replay.ErrNotFound):
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	case errors.Is(err, replay.ErrRunning):
+		write(w, r, http.StatusConflict, Response{Error: ErrorReplayRunning})
+		return
+	case err != nil:
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusAccepted, report)
+}
+
+// replayReport returns the latency and error curves of the replay
+func (a *API) replayReport(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	report, ok := a.replayer.Report(chi.URLParam(r, "id"))
+	if !ok {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	}
+
+	write(w, r, http.StatusOK, report)
+}
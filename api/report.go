@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/sentry"
+)
+
+// SetReporter enables reporting the unexpected errors to Sentry
+func (a *API) SetReporter(reporter *sentry.Reporter) {
+	a.reporter = reporter
+}
+
+// report sends the error of the request to Sentry when it is enabled
+func (a *API) report(r *http.Request, err error) {
+	if a.reporter != nil {
+		a.reporter.Capture(err, r)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chazari-x/hmtpk-parser-api/rollover"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// archivedGroups returns the groups that disappeared from the site
+func (a *API) archivedGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := rollover.ArchivedGroups(r.Context(), a.store)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, groups)
+}
+
+// archivedGroup returns the archived group with its last known weekly schedule
+func (a *API) archivedGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := rollover.ArchivedGroup(r.Context(), a.store, chi.URLParam(r, "group"))
+	if errors.Is(err, storage.ErrNotFound) {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	} else if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, group)
+}
+
+// rollovers returns the semester rollovers detected in the group list, for administrators
+func (a *API) rollovers(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(apiKeyHeader) == "" {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+		return
+	}
+
+	events, err := rollover.Events(r.Context(), a.store)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, events)
+}
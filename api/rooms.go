@@ -0,0 +1,199 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// Room is a cabinet seen in the warmed schedules
+type Room struct {
+	Room    string `json:"room"`
+	Lessons int    `json:"lessons"`
+}
+
+// RoomSchedule is what is taught in the room during the day
+type RoomSchedule struct {
+	Room    string         `json:"room"`
+	Date    string         `json:"date"`
+	Lessons []model.Lesson `json:"lessons"`
+}
+
+// FreeRooms are the rooms without a lesson at the slot
+type FreeRooms struct {
+	Date   string   `json:"date"`
+	Lesson string   `json:"lesson"`
+	Rooms  []string `json:"rooms"`
+	// Coverage is the share of groups whose schedules were warmed,
+	// rooms of groups not warmed yet may be shown as free
+	Coverage float64 `json:"coverage"`
+}
+
+// roomLessons counts the lessons of every room in the warmed schedules
+func (a *API) roomLessons() map[string]int {
+	counts := make(map[string]int)
+	for _, schedule := range a.snapshot() {
+		for _, day := range schedule {
+			for _, lesson := range day.Lessons {
+				if room := strings.TrimSpace(lesson.Room); room != "" {
+					counts[room]++
+				}
+			}
+		}
+	}
+
+	return counts
+}
+
+// warmed reports whether the date is in the warmed schedules, the rooms are
+// known to be free on such dates only
+func (a *API) warmed(date string) bool {
+	for _, schedule := range a.snapshot() {
+		if _, ok := findDay(schedule, date); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// occupied returns the rooms having a lesson at the slot
+func (a *API) occupied(date, num string) map[string]bool {
+	rooms := make(map[string]bool)
+	for _, schedule := range a.snapshot() {
+		day, ok := findDay(schedule, date)
+		if !ok {
+			continue
+		}
+
+		for _, lesson := range day.Lessons {
+			if lesson.Num == num {
+				rooms[strings.ToLower(strings.TrimSpace(lesson.Room))] = true
+			}
+		}
+	}
+
+	return rooms
+}
+
+// rooms lists the cabinets of the warmed schedules with the number of lessons this week
+func (a *API) rooms(w http.ResponseWriter, r *http.Request) {
+	counts := a.roomLessons()
+
+	rooms := make([]Room, 0, len(counts))
+	for room, lessons := range counts {
+		rooms = append(rooms, Room{Room: room, Lessons: lessons})
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return lessNumeric(rooms[i].Room, rooms[j].Room)
+	})
+
+	write(w, r, http.StatusOK, rooms)
+}
+
+// roomSchedule collects the lessons of all warmed groups held in the room on
+// the date. Only the warmed week is known, other dates have no lessons
+func (a *API) roomSchedule(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	room := strings.TrimSpace(chi.URLParam(r, "room"))
+	if room == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	labels := a.groupLabels()
+	result := RoomSchedule{Room: room, Date: date, Lessons: []model.Lesson{}}
+	for group, schedule := range a.snapshot() {
+		day, ok := findDay(schedule, date)
+		if !ok {
+			continue
+		}
+
+		for _, lesson := range day.Lessons {
+			if !strings.EqualFold(strings.TrimSpace(lesson.Room), room) {
+				continue
+			}
+
+			if lesson.Group == "" {
+				lesson.Group = labels(group)
+			}
+			result.Lessons = append(result.Lessons, lesson)
+		}
+	}
+
+	sort.Slice(result.Lessons, func(i, j int) bool {
+		if result.Lessons[i].Num != result.Lessons[j].Num {
+			return lessNumeric(result.Lessons[i].Num, result.Lessons[j].Num)
+		}
+		return result.Lessons[i].Group < result.Lessons[j].Group
+	})
+
+	write(w, r, http.StatusOK, result)
+}
+
+// freeRooms lists the known rooms without a lesson at the slot of the date,
+// the dates outside of the warmed week are rejected
+func (a *API) freeRooms(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	if !a.warmed(date) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorDateNotWarmed})
+		return
+	}
+
+	lesson := r.URL.Query().Get("lesson")
+	if _, err := strconv.Atoi(lesson); err != nil {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	occupied := a.occupied(date, lesson)
+
+	result := FreeRooms{Date: date, Lesson: lesson, Rooms: []string{}}
+	for room := range a.roomLessons() {
+		if !occupied[strings.ToLower(room)] {
+			result.Rooms = append(result.Rooms, room)
+		}
+	}
+
+	sort.Slice(result.Rooms, func(i, j int) bool {
+		return lessNumeric(result.Rooms[i], result.Rooms[j])
+	})
+
+	if a.warmer != nil {
+		result.Coverage = a.warmer.Progress().Coverage
+	}
+
+	write(w, r, http.StatusOK, result)
+}
+
+// lessNumeric orders numbers numerically and names alphabetically
+func lessNumeric(a, b string) bool {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+
+	switch {
+	case errX == nil && errY == nil:
+		return x < y
+	case errX == nil:
+		return true
+	case errY == nil:
+		return false
+	}
+
+	return a < b
+}
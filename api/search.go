@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQuery     = 200
+)
+
+// SearchResponse is the result of a search over announces
+type SearchResponse struct {
+	Query   string                  `json:"query"`
+	Indexed int                     `json:"indexed"`
+	Results []announce.SearchResult `json:"results"`
+	Cursors
+}
+
+// Index returns the index of announces filled by the crawler
+func (a *API) Index() *announce.Index {
+	return a.index
+}
+
+// searchAnnounces searches the crawled announces by the words of q
+func (a *API) searchAnnounces(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len([]rune(query)) > maxSearchQuery {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	offset, limit, ok := offsetPage(r, "search", defaultSearchLimit, maxSearchLimit)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	// one result past the page tells whether another page follows
+	results, more := paginate(a.index.Search(query, offset+limit+1), offset, limit)
+
+	write(w, r, http.StatusOK, SearchResponse{
+		Query:   query,
+		Indexed: a.index.Len(),
+		Results: a.publicResults(results),
+		Cursors: offsetCursors(w, r, "search", offset, limit, more),
+	})
+}
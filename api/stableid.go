@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/chazari-x/hmtpk-parser-api/stableid"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Option is a group or a teacher with its stable ID, passed as group_id or
+// teacher_id instead of the value that can change between semesters
+type Option struct {
+	model.Option
+	ID int `json:"id,omitempty"`
+}
+
+// SetStableIDs enables the stable IDs of the groups and the teachers
+func (a *API) SetStableIDs(registry *stableid.Registry) {
+	a.stableIDs = registry
+}
+
+// withStableIDs returns the options with their stable IDs, without them when
+// the IDs are disabled or failed to load
+func (a *API) withStableIDs(ctx context.Context, kind string, options []model.Option) []Option {
+	result := make([]Option, len(options))
+	for i, option := range options {
+		result[i].Option = option
+	}
+
+	if a.stableIDs == nil {
+		return result
+	}
+
+	labels := make([]string, len(options))
+	for i, option := range options {
+		labels[i] = option.Label
+	}
+
+	ids, err := a.stableIDs.Assign(ctx, kind, labels)
+	if err != nil {
+		a.log.WithContext(ctx).Warnf("stable ids: %s", err)
+		return result
+	}
+
+	for i := range result {
+		result[i].ID = ids[result[i].Label]
+	}
+
+	return result
+}
+
+// resolveStableID returns the current value of the option of the kind with the ID
+func (a *API) resolveStableID(ctx context.Context, kind string, id int) (string, bool, error) {
+	if a.stableIDs == nil {
+		return "", false, nil
+	}
+
+	name, ok, err := a.stableIDs.Name(ctx, kind, id)
+	if err != nil || !ok {
+		return "", false, err
+	}
+
+	var options []model.Option
+	if kind == stableid.KindGroup {
+		options, err = a.hmtpk.GetGroupOptions(ctx)
+	} else {
+		options, err = a.hmtpk.GetTeacherOptions(ctx)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	key := stableid.Key(name)
+	for _, option := range options {
+		if stableid.Key(option.Label) == key {
+			return option.Value, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// stableIDsMiddleware replaces the group_id and the teacher_id parameters
+// with the group and the teacher parameters holding the current values, so
+// every handler accepting a group or a teacher accepts its stable ID
+func (a *API) stableIDsMiddleware(next http.Handler) http.Handler {
+	params := map[string]string{"group_id": "group", "teacher_id": "teacher"}
+	kinds := map[string]string{"group_id": stableid.KindGroup, "teacher_id": stableid.KindTeacher}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has("group_id") && !query.Has("teacher_id") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := upstreamContext(r.Context())
+		defer cancel()
+
+		for param, name := range params {
+			if !query.Has(param) {
+				continue
+			}
+
+			id, err := strconv.Atoi(query.Get(param))
+			if err != nil || id <= 0 || query.Has(name) {
+				write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+				return
+			}
+
+			value, ok, err := a.resolveStableID(ctx, kinds[param], id)
+			if err != nil {
+				a.error(w, r, err)
+				return
+			} else if !ok {
+				write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+				return
+			}
+
+			query.Del(param)
+			query.Set(name, value)
+		}
+
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = query.Encode()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+)
+
+// staleMiddleware tells the clients that the response was served from a
+// fallback while hmtpk.ru is unreachable: X-Source names the fallback and
+// X-Data-Age is the age of the data in seconds when known. X-Cache is HIT,
+// MISS or STALE with X-Cache-Layer naming the cache of a hit and X-Cache-Age
+// the age of the cached data in seconds
+func (a *API) staleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, report := stale.WithReport(r.Context())
+		next.ServeHTTP(&staleWriter{ResponseWriter: w, report: report}, r.WithContext(ctx))
+	})
+}
+
+type staleWriter struct {
+	http.ResponseWriter
+	report      *stale.Report
+	wroteHeader bool
+}
+
+func (w *staleWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.cacheHeaders()
+		if source, fetchedAt, ok := w.report.Get(); ok {
+			w.Header().Set("X-Source", source)
+			if !fetchedAt.IsZero() {
+				w.Header().Set("X-Data-Age", strconv.Itoa(int(time.Since(fetchedAt).Seconds())))
+			}
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// cacheHeaders sets the X-Cache headers of the report
+func (w *staleWriter) cacheHeaders() {
+	status, layer, age := w.report.Status()
+	w.Header().Set("X-Cache", status)
+	if status == stale.StatusHit {
+		w.Header().Set("X-Cache-Layer", layer)
+	}
+	if status == stale.StatusHit || age > 0 {
+		w.Header().Set("X-Cache-Age", strconv.Itoa(int(age.Seconds())))
+	}
+}
+
+func (w *staleWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes of streamed responses through
+func (w *staleWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// markStale sets the fallback the schedule was served from on its days
+func markStale(r *http.Request, days []Day) {
+	source, fetchedAt, ok := stale.Get(r.Context())
+	if !ok {
+		return
+	}
+
+	for i := range days {
+		days[i].Source, days[i].FetchedAt = source, &fetchedAt
+	}
+}
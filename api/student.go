@@ -0,0 +1,363 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	"github.com/chazari-x/hmtpk-parser-api/cabinet"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/vault"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	ErrorSessionExpired = "Сессия личного кабинета истекла, войдите заново"
+
+	// cabinetSessionPrefix is the prefix of the ids in the vault of the
+	// sessions of the students logged in with /auth/login
+	cabinetSessionPrefix = "cabinet:session:"
+	// studentSubject prefixes the login of a student in the subject of the tokens
+	studentSubject = "student:"
+
+	maxPeriod = 32
+
+	// homeworkTTL is how long the homework of a session is served from the cache
+	homeworkTTL = time.Minute * 10
+)
+
+// Cabinet is the personal cabinet of the students on the site of the college
+type Cabinet interface {
+	Login(ctx context.Context, login, password string) (cabinet.Session, error)
+	Marks(ctx context.Context, session cabinet.Session, period string) (cabinet.Marks, error)
+	Homework(ctx context.Context, session cabinet.Session) (cabinet.Homework, error)
+	Attendance(ctx context.Context, session cabinet.Session) (cabinet.Attendance, error)
+}
+
+// SetCabinet enables the student endpoints reading the personal cabinet, the
+// sessions of the cabinet are kept encrypted in the vault
+func (a *API) SetCabinet(c Cabinet, sessions *vault.Vault) {
+	a.cabinet = c
+	a.vault = sessions
+	a.homework = newSessionCache[cabinet.Homework](homeworkTTL)
+	a.attempts = auth.NewAttempts()
+}
+
+// loginAttempts returns the limit of the attempts to log in with the form,
+// the one of /auth/login when the authentication is enabled
+func (a *API) loginAttempts() *auth.Attempts {
+	if a.auth != nil {
+		return a.auth.Attempts()
+	}
+
+	return a.attempts
+}
+
+// CabinetAuthenticator logs the students into the cabinet on /auth/login and
+// keeps their sessions for the requests with the access token
+func (a *API) CabinetAuthenticator() auth.Authenticator {
+	return cabinetAuthenticator{a}
+}
+
+type cabinetAuthenticator struct {
+	a *API
+}
+
+func (c cabinetAuthenticator) Authenticate(ctx context.Context, login, password string) (string, error) {
+	session, err := c.a.cabinet.Login(ctx, login, password)
+	if err != nil {
+		return "", err
+	}
+
+	subject := studentSubject + auth.NormalizeLogin(login)
+	if err = c.a.saveCabinetSession(ctx, subject, session); err != nil {
+		return "", err
+	}
+
+	return subject, nil
+}
+
+func (a *API) saveCabinetSession(ctx context.Context, subject string, session cabinet.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return a.vault.Put(ctx, cabinetSessionPrefix+subject, data)
+}
+
+func (a *API) studentRoutes(r chi.Router) {
+	r.Post("/marks", a.studentMarks)
+	r.Post("/homework", a.studentHomework)
+	r.Post("/attendance", a.studentAttendance)
+	r.Post("/revoke", a.revokeStudent)
+}
+
+// studentSession returns the stored session of the student of the access
+// token or, without the Authorization header, logs in with the login and the
+// password of the form without keeping the session, counted as an attempt to
+// log in. The error is written when there is no session
+func (a *API) studentSession(w http.ResponseWriter, r *http.Request) (cabinet.Session, bool) {
+	if r.Header.Get("Authorization") == "" {
+		login, password := strings.TrimSpace(r.PostFormValue("login")), r.PostFormValue("password")
+		if login == "" || password == "" {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return cabinet.Session{}, false
+		}
+
+		if !a.loginAttempts().Allow(login) {
+			write(w, r, http.StatusTooManyRequests, Response{Error: ErrorLoginAttempts})
+			return cabinet.Session{}, false
+		}
+
+		session, err := a.cabinet.Login(r.Context(), login, password)
+		if err != nil {
+			a.studentError(w, r, err)
+			return cabinet.Session{}, false
+		}
+
+		return session, true
+	}
+
+	claims, ok := a.verifyBearer(w, r)
+	if !ok {
+		return cabinet.Session{}, false
+	}
+
+	value, err := a.vault.Get(r.Context(), cabinetSessionPrefix+claims.Subject)
+	if errors.Is(err, storage.ErrNotFound) {
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorSessionExpired})
+		return cabinet.Session{}, false
+	} else if err != nil {
+		a.error(w, r, err)
+		return cabinet.Session{}, false
+	}
+
+	var session cabinet.Session
+	if err = json.Unmarshal(value, &session); err != nil {
+		a.error(w, r, err)
+		return cabinet.Session{}, false
+	}
+
+	return session, true
+}
+
+// revokeStudent deletes the stored session of the student of the access token
+// and revokes the tokens. The admins revoke the session of the login parameter
+func (a *API) revokeStudent(w http.ResponseWriter, r *http.Request) {
+	var subject string
+	if login := strings.TrimSpace(r.URL.Query().Get("login")); login != "" {
+		if !a.isAdmin(r) {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return
+		}
+
+		subject = studentSubject + auth.NormalizeLogin(login)
+	} else {
+		claims, ok := a.verifyBearer(w, r)
+		if !ok {
+			return
+		}
+
+		subject = claims.Subject
+	}
+
+	if err := a.vault.Revoke(r.Context(), cabinetSessionPrefix+subject); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if a.auth != nil {
+		if err := a.auth.RevokeSubject(r.Context(), subject); err != nil {
+			a.error(w, r, err)
+			return
+		}
+	}
+
+	write(w, r, http.StatusOK, nil)
+}
+
+// studentError writes the error returned by the cabinet
+func (a *API) studentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, auth.ErrCredentials):
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorCredentials})
+	case errors.Is(err, cabinet.ErrSessionExpired):
+		write(w, r, http.StatusUnauthorized, Response{Error: ErrorSessionExpired})
+	default:
+		a.error(w, r, err)
+	}
+}
+
+// studentMarks returns the grade journal of the student per subject in the
+// period parameter, the current period without it
+func (a *API) studentMarks(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if len(period) > maxPeriod {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	session, ok := a.studentSession(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	marks, err := a.cabinet.Marks(ctx, session, period)
+	if err != nil {
+		a.studentError(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, marks)
+}
+
+// studentHomework returns the tasks assigned to the student per subject. The
+// homework is kept for a while per session, fresh=true reads it again
+func (a *API) studentHomework(w http.ResponseWriter, r *http.Request) {
+	session, ok := a.studentSession(w, r)
+	if !ok {
+		return
+	}
+
+	key := sessionKey(session)
+	if r.URL.Query().Get("fresh") != "true" {
+		if homework, ok := a.homework.get(key); ok {
+			write(w, r, http.StatusOK, homework)
+			return
+		}
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	homework, err := a.cabinet.Homework(ctx, session)
+	if err != nil {
+		a.studentError(w, r, err)
+		return
+	}
+
+	a.homework.set(key, homework)
+
+	write(w, r, http.StatusOK, homework)
+}
+
+// studentAttendance returns the hours missed by the student per subject and
+// month, of the month parameter only when it is set
+func (a *API) studentAttendance(w http.ResponseWriter, r *http.Request) {
+	month := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("month")))
+	if len(month) > maxPeriod {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	session, ok := a.studentSession(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	attendance, err := a.cabinet.Attendance(ctx, session)
+	if err != nil {
+		a.studentError(w, r, err)
+		return
+	}
+
+	if month != "" {
+		attendance = filterAttendance(attendance, month)
+	}
+
+	write(w, r, http.StatusOK, attendance)
+}
+
+// filterAttendance keeps the absences of the months starting with the month
+// parameter, like "окт" or "октябрь", and counts the totals again
+func filterAttendance(attendance cabinet.Attendance, month string) cabinet.Attendance {
+	filtered := cabinet.Attendance{Subjects: make([]cabinet.SubjectAttendance, 0, len(attendance.Subjects))}
+	for _, subject := range attendance.Subjects {
+		entry := cabinet.SubjectAttendance{Subject: subject.Subject, Absences: []cabinet.Absence{}}
+		for _, absence := range subject.Absences {
+			if !strings.HasPrefix(strings.ToLower(absence.Month), month) {
+				continue
+			}
+
+			entry.Absences = append(entry.Absences, absence)
+			entry.Hours += absence.Hours
+			entry.Excused += absence.Excused
+		}
+
+		filtered.Subjects = append(filtered.Subjects, entry)
+		filtered.Hours += entry.Hours
+		filtered.Excused += entry.Excused
+	}
+
+	return filtered
+}
+
+// sessionKey identifies the session of the cabinet by its cookies
+func sessionKey(session cabinet.Session) string {
+	hash := sha256.New()
+	hash.Write([]byte(session.Login))
+	for _, cookie := range session.Cookies {
+		hash.Write([]byte{0})
+		hash.Write([]byte(cookie.Name + "=" + cookie.Value))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// sessionCache keeps the pages of the cabinet read for the sessions
+type sessionCache[T any] struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]sessionEntry[T]
+}
+
+type sessionEntry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+func newSessionCache[T any](ttl time.Duration) *sessionCache[T] {
+	return &sessionCache[T]{ttl: ttl, entries: make(map[string]sessionEntry[T])}
+}
+
+func (c *sessionCache[T]) get(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		var zero T
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// set keeps the value of the session, dropping the expired ones
+func (c *sessionCache[T]) set(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = sessionEntry[T]{value: value, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chazari-x/hmtpk-parser-api/suggest"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// SuggestResponse is the groups and the teachers matching the query, the best first
+type SuggestResponse struct {
+	Query       string               `json:"query"`
+	Suggestions []suggest.Suggestion `json:"suggestions"`
+}
+
+// suggest completes the names of the groups and the teachers typed in q for
+// the schedule lookups, kind limits the suggestions to the groups or the teachers
+func (a *API) suggest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" || len([]rune(q)) > maxOptionsQuery {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxSuggestLimit {
+			write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+			return
+		}
+	}
+
+	kind := query.Get("kind")
+	if kind != "" && kind != suggest.KindGroup && kind != suggest.KindTeacher {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	var candidates []suggest.Candidate
+	add := func(kind string, options []model.Option) {
+		for _, option := range options {
+			candidates = append(candidates, suggest.Candidate{Kind: kind, Label: option.Label, Value: option.Value})
+		}
+	}
+
+	if kind != suggest.KindTeacher {
+		groups, err := a.hmtpk.GetGroupOptions(ctx)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		add(suggest.KindGroup, groups)
+	}
+
+	if kind != suggest.KindGroup {
+		teachers, err := a.hmtpk.GetTeacherOptions(ctx)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		add(suggest.KindTeacher, teachers)
+	}
+
+	write(w, r, http.StatusOK, SuggestResponse{
+		Query:       q,
+		Suggestions: suggest.Suggest(q, candidates, limit),
+	})
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// SummaryResponse is the schedule of a day told in one paragraph
+type SummaryResponse struct {
+	Date string `json:"date"`
+	Text string `json:"text"`
+}
+
+var (
+	weekdaysAccusative = [...]string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"}
+	monthsGenitive     = [...]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+)
+
+// scheduleSummary describes the lessons of the day in natural language
+// for voice assistants and screen readers
+func (a *API) scheduleSummary(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	group, teacher := r.URL.Query().Get("group"), r.URL.Query().Get("teacher")
+	if group == "" && teacher == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != formatText {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	schedule, err := a.getSchedule(r.Context(), group, teacher, date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	day, _ := findDay(schedule, date)
+	d, _ := time.Parse("02.01.2006", date)
+	text := summarize(day.Lessons, d, a.now(), group == "")
+
+	if format == formatText {
+		w.Header().Set("Content-Type", contentTypeText)
+		_, _ = w.Write([]byte(text))
+		return
+	}
+
+	write(w, r, http.StatusOK, SummaryResponse{Date: date, Text: text})
+}
+
+// summarize tells how many lessons there are, when and where the first one
+// starts, what follows and when the day ends
+func summarize(lessons []model.Lesson, date, now time.Time, byTeacher bool) string {
+	label := dayLabel(date, now)
+
+	// subgroups share the lesson number, the pair is counted once
+	var pairs []model.Lesson
+	for _, lesson := range lessons {
+		if len(pairs) > 0 && lesson.Num != "" && pairs[len(pairs)-1].Num == lesson.Num {
+			continue
+		}
+		pairs = append(pairs, lesson)
+	}
+
+	if len(pairs) == 0 {
+		return label + " занятий нет."
+	}
+
+	var b strings.Builder
+	first := pairs[0]
+	fmt.Fprintf(&b, "%s %d %s", label, len(pairs), plural(len(pairs), "пара", "пары", "пар"))
+
+	if len(pairs) == 1 {
+		b.WriteString(":")
+	} else {
+		b.WriteString(", первая")
+	}
+
+	if start, _, ok := bells.ParseTime(first.Time); ok {
+		fmt.Fprintf(&b, " в %s", spokenTime(start))
+	}
+	fmt.Fprintf(&b, " — %s", strings.TrimSuffix(describe(first, byTeacher), "."))
+	b.WriteString(".")
+
+	if len(pairs) > 1 {
+		names := make([]string, 0, len(pairs)-1)
+		for _, lesson := range pairs[1:] {
+			names = append(names, lowerFirst(lesson.Name))
+		}
+		fmt.Fprintf(&b, " Затем: %s.", strings.Join(names, ", "))
+	}
+
+	if _, end, ok := bells.ParseTime(pairs[len(pairs)-1].Time); ok {
+		fmt.Fprintf(&b, " Занятия закончатся в %s.", spokenTime(end))
+	}
+
+	return b.String()
+}
+
+// describe names the lesson with its room and the group or the teacher
+func describe(lesson model.Lesson, byTeacher bool) string {
+	text := lowerFirst(lesson.Name)
+	if lesson.Room != "" {
+		text += " в " + lesson.Room
+	}
+
+	if byTeacher && lesson.Group != "" {
+		text += " у группы " + lesson.Group
+	} else if !byTeacher && lesson.Teacher != "" {
+		text += ", ведёт " + lesson.Teacher
+	}
+
+	return text
+}
+
+// dayLabel names the day relative to now: "Сегодня", "Завтра" or "В среду, 21 октября"
+func dayLabel(date, now time.Time) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch date.Sub(today) {
+	case 0:
+		return "Сегодня"
+	case time.Hour * 24:
+		return "Завтра"
+	}
+
+	preposition := "В"
+	if date.Weekday() == time.Tuesday {
+		preposition = "Во"
+	}
+
+	return fmt.Sprintf("%s %s, %d %s,", preposition, weekdaysAccusative[date.Weekday()], date.Day(), monthsGenitive[date.Month()-1])
+}
+
+// lowerFirst lowercases the first letter so the name can go mid-sentence
+func lowerFirst(value string) string {
+	runes := []rune(value)
+	if len(runes) > 1 && unicode.IsUpper(runes[1]) {
+		// abbreviations like "МДК" are kept
+		return value
+	}
+
+	if len(runes) > 0 {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+
+	return string(runes)
+}
+
+// spokenTime drops the leading zero of the hours: "08:30" is read as "8:30"
+func spokenTime(clock string) string {
+	return strings.TrimPrefix(clock, "0")
+}
+
+// plural returns the form of the noun for the number
+func plural(n int, one, few, many string) string {
+	n %= 100
+	if n >= 11 && n <= 14 {
+		return many
+	}
+
+	switch n % 10 {
+	case 1:
+		return one
+	case 2, 3, 4:
+		return few
+	}
+
+	return many
+}
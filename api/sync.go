@@ -0,0 +1,221 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// SyncResponse is the data changed since the previous sync of the client
+type SyncResponse struct {
+	// Token is passed as since on the next sync
+	Token string `json:"token"`
+	// Full is set when the token is unknown and everything is returned
+	Full      bool             `json:"full"`
+	Groups    []model.Option   `json:"groups,omitempty"`
+	Teachers  []model.Option   `json:"teachers,omitempty"`
+	Schedules map[string][]Day `json:"schedules,omitempty"`
+	// Removed are the groups whose schedules are no longer served
+	Removed   []string         `json:"removed,omitempty"`
+	Announces []model.Announce `json:"announces,omitempty"`
+}
+
+// changes assigns versions to the data served by sync, a version grows every
+// time the content of an item changes
+type changes struct {
+	// refresh is held by a sync from the first update to taking the token,
+	// so no change of another sync gets a version below the token unseen
+	refresh sync.Mutex
+
+	mu      sync.Mutex
+	epoch   int64
+	version int64
+	items   map[string]change
+}
+
+type change struct {
+	hash    [sha256.Size]byte
+	version int64
+	at      time.Time
+	removed bool
+}
+
+func newChanges() *changes {
+	return &changes{epoch: time.Now().Unix(), items: make(map[string]change)}
+}
+
+// update records the content of the item and returns its change
+func (c *changes) update(key string, value interface{}) change {
+	data, _ := json.Marshal(value)
+	hash := sha256.Sum256(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok || item.hash != hash || item.removed {
+		c.version++
+		item = change{hash: hash, version: c.version, at: time.Now()}
+		c.items[key] = item
+	}
+
+	return item
+}
+
+// remove marks the items with the prefix missing from keep as removed and returns their keys
+func (c *changes) remove(prefix string, keep map[string]bool) map[string]change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := make(map[string]change)
+	for key, item := range c.items {
+		if !strings.HasPrefix(key, prefix) || keep[key] {
+			continue
+		}
+
+		if !item.removed {
+			c.version++
+			item = change{version: c.version, at: time.Now(), removed: true}
+			c.items[key] = item
+		}
+		removed[key] = item
+	}
+
+	return removed
+}
+
+// token returns the sync token of the current version
+func (c *changes) token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return strconv.FormatInt(c.epoch, 36) + "-" + strconv.FormatInt(c.version, 36)
+}
+
+// since parses the since parameter: a token of this process or a timestamp.
+// The returned function reports whether a change is newer, nil means a full sync
+func (c *changes) since(value string) func(change) bool {
+	if value == "" {
+		return nil
+	}
+
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return func(item change) bool {
+			return item.at.After(at)
+		}
+	}
+
+	epoch, version, ok := strings.Cut(strings.Trim(value, `"`), "-")
+	if !ok || epoch != strconv.FormatInt(c.epoch, 36) {
+		return nil
+	}
+
+	v, err := strconv.ParseInt(version, 36, 64)
+	if err != nil {
+		return nil
+	}
+
+	return func(item change) bool {
+		return item.version > v
+	}
+}
+
+// syncChanges returns the option lists, the warmed schedules and the first
+// page of announces changed since the token or the RFC 3339 timestamp,
+// everything without since. The group parameter, repeated, limits the
+// schedules to those groups
+func (a *API) syncChanges(w http.ResponseWriter, r *http.Request) {
+	newer := a.changes.since(r.URL.Query().Get("since"))
+	changed := func(item change) bool {
+		return newer == nil || newer(item)
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	response := SyncResponse{Full: newer == nil, Schedules: make(map[string][]Day)}
+
+	// the lists are fetched concurrently, a failed one is left for the next sync
+	var (
+		wg        sync.WaitGroup
+		groups    []model.Option
+		teachers  []model.Option
+		announces model.Announces
+		errs      [3]error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		groups, errs[0] = a.hmtpk.GetGroupOptions(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		teachers, errs[1] = a.hmtpk.GetTeacherOptions(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		announces, errs[2] = a.hmtpk.GetAnnounces(ctx, 1)
+	}()
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			a.log.WithContext(r.Context()).Warnf("sync: %s", err)
+		}
+	}
+
+	a.changes.refresh.Lock()
+	defer a.changes.refresh.Unlock()
+
+	if errs[0] == nil && changed(a.changes.update("groups", groups)) {
+		response.Groups = groups
+	}
+
+	if errs[1] == nil && changed(a.changes.update("teachers", teachers)) {
+		response.Teachers = teachers
+	}
+
+	if errs[2] == nil {
+		for _, announce := range a.withLocalAnnounces(ctx, 1, announces.Announces) {
+			if changed(a.changes.update("announce:"+announce.Path, announce)) {
+				response.Announces = append(response.Announces, announce)
+			}
+		}
+	}
+
+	filter := make(map[string]bool)
+	for _, group := range r.URL.Query()["group"] {
+		filter[group] = true
+	}
+
+	keep := make(map[string]bool)
+	for group, schedule := range a.snapshot() {
+		key := "schedule:" + group
+		keep[key] = true
+
+		if changed(a.changes.update(key, schedule)) && (len(filter) == 0 || filter[group]) {
+			response.Schedules[group] = a.isoSchedule(schedule)
+		}
+	}
+
+	for key, item := range a.changes.remove("schedule:", keep) {
+		group := strings.TrimPrefix(key, "schedule:")
+		if newer != nil && newer(item) && (len(filter) == 0 || filter[group]) {
+			response.Removed = append(response.Removed, group)
+		}
+	}
+	sort.Strings(response.Removed)
+
+	response.Token = a.changes.token()
+	w.Header().Set("ETag", fmt.Sprintf("%q", response.Token))
+
+	write(w, r, http.StatusOK, response)
+}
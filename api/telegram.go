@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// telegramAuthScheme prefixes the init data in the Authorization header
+	telegramAuthScheme = "tma "
+
+	telegramPrefix = "telegram:user:"
+	// telegramImportancePrefix keeps the lowest importance of the announces sent to a user
+	telegramImportancePrefix = "telegram:importance:"
+)
+
+type telegramUserKey struct{}
+
+// TelegramProfile is the Telegram user with the group bound to them
+type TelegramProfile struct {
+	User  telegram.User  `json:"user"`
+	Group *TelegramGroup `json:"group"`
+	// Importance is the lowest importance of the announces sent to the user, all when empty
+	Importance string `json:"importance,omitempty"`
+}
+
+// TelegramGroup is the group bound to a Telegram user
+type TelegramGroup struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	// Archived is set when the group disappeared from the site
+	Archived bool `json:"archived,omitempty"`
+	// Successor is the group suggested in place of the archived one
+	Successor *TelegramGroup `json:"successor,omitempty"`
+}
+
+// SetStore sets the store of the data owned by the service
+func (a *API) SetStore(store storage.Store) {
+	a.store = store
+}
+
+// SetTelegram enables the Mini App endpoints for the bot
+func (a *API) SetTelegram(cfg telegram.Config) {
+	a.telegram = cfg
+}
+
+func (a *API) telegramRoutes(r chi.Router) {
+	r.Use(a.telegramMiddleware)
+
+	r.Post("/me", a.telegramMe)
+	r.Post("/me/announces", a.telegramAnnounces)
+	r.Post("/me/announces/read", a.telegramAnnouncesRead)
+	r.Post("/bind", a.telegramBind)
+	r.Post("/unbind", a.telegramUnbind)
+	r.Post("/schedule", a.telegramSchedule)
+	r.Post("/notifications", a.telegramNotifications)
+}
+
+// telegramMiddleware accepts requests signed with the init data of the Mini App
+func (a *API) telegramMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, telegramAuthScheme) {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return
+		}
+
+		data, err := telegram.Validate(strings.TrimPrefix(header, telegramAuthScheme), a.telegram, time.Now())
+		if err != nil {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), telegramUserKey{}, data.User)))
+	})
+}
+
+func telegramUser(r *http.Request) telegram.User {
+	user, _ := r.Context().Value(telegramUserKey{}).(telegram.User)
+	return user
+}
+
+func telegramKey(user telegram.User) string {
+	return telegramPrefix + strconv.FormatInt(user.ID, 10)
+}
+
+// telegramGroup returns the group bound to the user, nil if there is none
+func (a *API) telegramGroup(ctx context.Context, user telegram.User) (*TelegramGroup, error) {
+	value, err := a.store.Get(ctx, telegramKey(user))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var group TelegramGroup
+	if err = json.Unmarshal([]byte(value), &group); err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// telegramImportance returns the lowest importance of the announces sent to the user, empty for all
+func (a *API) telegramImportance(ctx context.Context, id int64) (string, error) {
+	value, err := a.store.Get(ctx, telegramImportancePrefix+strconv.FormatInt(id, 10))
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", nil
+	}
+
+	return value, err
+}
+
+func (a *API) telegramMe(w http.ResponseWriter, r *http.Request) {
+	user := telegramUser(r)
+
+	group, err := a.telegramGroup(r.Context(), user)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	importance, err := a.telegramImportance(r.Context(), user.ID)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, TelegramProfile{User: user, Group: group, Importance: importance})
+}
+
+// telegramNotifications sets the lowest importance of the announces sent to
+// the user, e.g. high to receive the urgent and the important ones only.
+// Without the importance every announce is sent
+func (a *API) telegramNotifications(w http.ResponseWriter, r *http.Request) {
+	importance := r.URL.Query().Get("importance")
+	if importance != "" && !announce.ValidImportance(importance) {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	user := telegramUser(r)
+	key := telegramImportancePrefix + strconv.FormatInt(user.ID, 10)
+
+	var err error
+	if importance == "" {
+		err = a.store.Delete(r.Context(), key)
+	} else {
+		err = a.store.Set(r.Context(), key, importance)
+	}
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	group, err := a.telegramGroup(r.Context(), user)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, TelegramProfile{User: user, Group: group, Importance: importance})
+}
+
+// telegramBind binds the group given by its value or name to the user
+func (a *API) telegramBind(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("group")
+	if name == "" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	groups, err := a.hmtpk.GetGroupOptions(ctx)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	var group *TelegramGroup
+	for _, option := range groups {
+		if option.Value == name || squash(option.Label) == squash(name) {
+			group = &TelegramGroup{Value: option.Value, Label: option.Label}
+			break
+		}
+	}
+
+	if group == nil {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	}
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	user := telegramUser(r)
+	if err = a.store.Set(ctx, telegramKey(user), string(data)); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, TelegramProfile{User: user, Group: group})
+}
+
+func (a *API) telegramUnbind(w http.ResponseWriter, r *http.Request) {
+	user := telegramUser(r)
+	if err := a.store.Delete(r.Context(), telegramKey(user)); err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, TelegramProfile{User: user})
+}
+
+// telegramSchedule returns the weekly schedule of the group bound to the user
+func (a *API) telegramSchedule(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	group, err := a.telegramGroup(r.Context(), telegramUser(r))
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	if group == nil {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorNotFound})
+		return
+	}
+
+	if group.Archived {
+		write(w, r, http.StatusNotFound, Response{Error: ErrorGroupArchived})
+		return
+	}
+
+	schedule, err := a.getSchedule(r.Context(), group.Value, "", date)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	write(w, r, http.StatusOK, schedule)
+}
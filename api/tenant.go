@@ -0,0 +1,331 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"golang.org/x/time/rate"
+)
+
+// Tenant describes an API consumer and its share of the upstream budget
+type Tenant struct {
+	// Name is used in logs only
+	Name string
+	// Key is the value of the X-API-Key header identifying the tenant
+	Key string
+	// RPS is the number of upstream requests per second allowed for the tenant
+	RPS float64
+	// Burst is the number of requests the tenant may issue at once
+	Burst int
+	// Concurrency is the number of upstream requests the tenant may have in flight
+	Concurrency int
+	// Admin allows the tenant to use the administration endpoints publishing data
+	Admin bool
+}
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	// upstreamConcurrency is the upstream concurrency budget shared by all tenants
+	upstreamConcurrency = 16
+	// maxTenantConcurrency keeps a part of the shared budget for the other tenants
+	maxTenantConcurrency = upstreamConcurrency / 2
+
+	anonymousBurst       = 5
+	anonymousConcurrency = 2
+	anonymousIdle        = time.Minute * 10
+	// sweepInterval is how often the idle anonymous clients are forgotten
+	sweepInterval = time.Minute
+)
+
+var (
+	// ErrUnknownKey and ErrRateLimited are returned by Admit
+	ErrUnknownKey  = errors.New("unknown API key")
+	ErrRateLimited = errors.New("rate limited")
+	// ErrBudgetExhausted is returned by the fetches waiting too long for a slot of the upstream budget
+	ErrBudgetExhausted = errors.New("upstream budget exhausted")
+)
+
+// anonymousTenant is the budget applied to every client without an API key
+var anonymousTenant = Tenant{
+	Name:        "anonymous",
+	RPS:         float64(time.Second / requestTimeout),
+	Burst:       anonymousBurst,
+	Concurrency: anonymousConcurrency,
+}
+
+type tenantState struct {
+	tenant  Tenant
+	limiter *rate.Limiter
+	// peak allows the requests above the limit during the peak
+	peak     *rate.Limiter
+	sem      chan struct{}
+	lastSeen time.Time
+}
+
+func newTenantState(tenant Tenant, peakFactor float64) *tenantState {
+	if tenant.Burst < 1 {
+		tenant.Burst = 1
+	}
+
+	if tenant.Concurrency < 1 {
+		tenant.Concurrency = 1
+	}
+
+	extra := max(peakFactor-1, 0)
+
+	return &tenantState{
+		tenant:  tenant,
+		limiter: rate.NewLimiter(rate.Limit(tenant.RPS), tenant.Burst),
+		peak:    rate.NewLimiter(rate.Limit(tenant.RPS*extra), int(float64(tenant.Burst)*extra)),
+		sem:     make(chan struct{}, tenant.Concurrency),
+	}
+}
+
+// allow reports whether the rate limit of the tenant allows the request now
+func (s *tenantState) allow(peak bool) bool {
+	return s.limiter.Allow() || (peak && s.peak.Allow())
+}
+
+// tenants keeps the per-tenant limiters and the shared upstream budget
+type tenants struct {
+	mu        sync.Mutex
+	byKey     map[string]*tenantState
+	anonymous map[string]*tenantState
+	upstream  chan struct{}
+	swept     time.Time
+
+	// peak reports the peak, the rate limits are multiplied by peakFactor then
+	peak       func() bool
+	peakFactor float64
+}
+
+func newTenants() *tenants {
+	return &tenants{
+		byKey:     make(map[string]*tenantState),
+		anonymous: make(map[string]*tenantState),
+		upstream:  make(chan struct{}, upstreamConcurrency),
+	}
+}
+
+// SetTenants replaces the list of known tenants
+func (a *API) SetTenants(tenants []Tenant) {
+	a.tenants.mu.Lock()
+	defer a.tenants.mu.Unlock()
+
+	a.tenants.byKey = make(map[string]*tenantState, len(tenants))
+	for _, tenant := range tenants {
+		if tenant.Key == "" {
+			continue
+		}
+
+		if tenant.Concurrency > maxTenantConcurrency {
+			tenant.Concurrency = maxTenantConcurrency
+		}
+
+		a.tenants.byKey[tenant.Key] = newTenantState(tenant, a.tenants.peakFactor)
+	}
+}
+
+// SetPeak relaxes the rate limits of the tenants by the factor while peak reports the morning rush
+func (a *API) SetPeak(peak func() bool, factor float64) {
+	a.tenants.mu.Lock()
+	defer a.tenants.mu.Unlock()
+
+	a.tenants.peak, a.tenants.peakFactor = peak, factor
+
+	for key, state := range a.tenants.byKey {
+		a.tenants.byKey[key] = newTenantState(state.tenant, factor)
+	}
+	a.tenants.anonymous = make(map[string]*tenantState)
+}
+
+// peaking reports whether the rate limits are relaxed now
+func (t *tenants) peaking() bool {
+	t.mu.Lock()
+	peak := t.peak
+	t.mu.Unlock()
+
+	return peak != nil && peak()
+}
+
+// get returns the state of the tenant making the request, false if the API key is unknown
+func (t *tenants) get(r *http.Request) (*tenantState, bool) {
+	return t.lookup(r.Header.Get(apiKeyHeader), r.RemoteAddr)
+}
+
+// lookup returns the state of the tenant of the API key, of the anonymous
+// client at the address without it, false if the API key is unknown
+func (t *tenants) lookup(key, addr string) (*tenantState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if key != "" {
+		state, ok := t.byKey[key]
+		return state, ok
+	}
+
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		ip = addr
+	}
+
+	now := time.Now()
+	if now.Sub(t.swept) > sweepInterval {
+		t.sweep(now)
+	}
+
+	state, ok := t.anonymous[ip]
+	if !ok {
+		state = newTenantState(anonymousTenant, t.peakFactor)
+		t.anonymous[ip] = state
+	}
+	state.lastSeen = now
+
+	return state, true
+}
+
+// sweep forgets the idle anonymous clients, the lock must be held
+func (t *tenants) sweep(now time.Time) {
+	for addr, state := range t.anonymous {
+		if now.Sub(state.lastSeen) > anonymousIdle {
+			delete(t.anonymous, addr)
+		}
+	}
+
+	t.swept = now
+}
+
+// acquire takes a slot of the tenant budget and then of the shared budget
+func (t *tenants) acquire(ctx context.Context, state *tenantState) (func(), error) {
+	select {
+	case state.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case t.upstream <- struct{}{}:
+	case <-ctx.Done():
+		<-state.sem
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-t.upstream
+		<-state.sem
+	}, nil
+}
+
+// isAdmin reports whether the request is made by an administrator tenant
+func (a *API) isAdmin(r *http.Request) bool {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		return false
+	}
+
+	state, ok := a.tenants.get(r)
+	return ok && state.tenant.Admin
+}
+
+// budgetKey passes the upstream budget of the tenant of a request to LimitUpstream
+type budgetKey struct{}
+
+type budget struct {
+	tenants *tenants
+	state   *tenantState
+}
+
+// Admit applies the rate limit of the tenant of the API key, of the anonymous
+// client at the address without it, and returns the context whose fetches
+// from hmtpk.ru take the upstream budget of the tenant
+func (a *API) Admit(ctx context.Context, key, addr string) (context.Context, error) {
+	state, ok := a.tenants.lookup(key, addr)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if !state.allow(a.tenants.peaking()) {
+		return nil, ErrRateLimited
+	}
+
+	return context.WithValue(ctx, budgetKey{}, budget{tenants: a.tenants, state: state}), nil
+}
+
+// tenantsMiddleware enforces the tenant rate limit, the upstream concurrency
+// is enforced by LimitUpstream around the fetches only
+func (a *API) tenantsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := a.Admit(r.Context(), r.Header.Get(apiKeyHeader), r.RemoteAddr)
+		if errors.Is(err, ErrUnknownKey) {
+			write(w, r, http.StatusUnauthorized, Response{Error: ErrorToken})
+			return
+		} else if err != nil {
+			write(w, r, http.StatusTooManyRequests, Response{Error: ErrorRequestTimeout})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// LimitUpstream wraps the source fetching from hmtpk.ru so every fetch of a
+// request takes a slot of the budget of its tenant and of the shared budget.
+// The answers of the caches above it take no slot, the fetches outside of the
+// requests like the warmup are not limited
+func LimitUpstream(source ScheduleProvider) ScheduleProvider {
+	return limitedProvider{source: source}
+}
+
+type limitedProvider struct {
+	source ScheduleProvider
+}
+
+// limited calls the source holding a slot of the budget of the context
+func limited[T any](ctx context.Context, call func(ctx context.Context) (T, error)) (T, error) {
+	b, ok := ctx.Value(budgetKey{}).(budget)
+	if !ok {
+		return call(ctx)
+	}
+
+	release, err := b.tenants.acquire(ctx, b.state)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("tenant %s: %w", b.state.tenant.Name, ErrBudgetExhausted)
+	}
+	defer release()
+
+	return call(ctx)
+}
+
+func (p limitedProvider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	return limited(ctx, p.source.GetGroupOptions)
+}
+
+func (p limitedProvider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return limited(ctx, p.source.GetTeacherOptions)
+}
+
+func (p limitedProvider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	return limited(ctx, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	})
+}
+
+func (p limitedProvider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	return limited(ctx, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	})
+}
+
+func (p limitedProvider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	return limited(ctx, func(ctx context.Context) (model.Announces, error) {
+		return p.source.GetAnnounces(ctx, page)
+	})
+}
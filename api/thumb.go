@@ -0,0 +1,81 @@
+//go:build !no_render
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/thumb"
+	"github.com/go-chi/chi/v5"
+)
+
+func init() {
+	optionalRoutes = append(optionalRoutes, func(a *API, r chi.Router) {
+		if a.readOnly {
+			return
+		}
+
+		thumbnailer := thumb.New(a.thumbnails)
+
+		// served with GET so the URL can be used in <img src>
+		r.Get("/announces/assets/thumb/{size}/*", func(w http.ResponseWriter, r *http.Request) {
+			a.announceThumbnail(w, r, thumbnailer)
+		})
+	})
+
+	detailLinks = append(detailLinks, func(a *API, r *http.Request, detail *announce.Detail) {
+		if !a.readOnly {
+			a.thumbnailLinks(r, detail, thumb.New(a.thumbnails).Sizes())
+		}
+	})
+}
+
+const contentTypeJPEG = "image/jpeg"
+
+// thumbnailLinks adds the srcset of the thumbnails of every image of the site
+func (a *API) thumbnailLinks(r *http.Request, detail *announce.Detail, sizes []int) {
+	for _, image := range detail.Images {
+		src, ok := thumb.Path(image)
+		if !ok {
+			continue
+		}
+
+		set := make([]string, 0, len(sizes))
+		for _, size := range sizes {
+			set = append(set, fmt.Sprintf("%s %dw", a.link(r, fmt.Sprintf("/announces/assets/thumb/%d%s", size, src)), size))
+		}
+
+		if detail.Thumbnails == nil {
+			detail.Thumbnails = make(map[string]string)
+		}
+		detail.Thumbnails[image] = strings.Join(set, ", ")
+	}
+}
+
+// announceThumbnail returns the downsized image of an announce, e.g.
+// /announces/assets/thumb/320/upload/iblock/photo.jpg
+func (a *API) announceThumbnail(w http.ResponseWriter, r *http.Request, thumbnailer *thumb.Thumbnailer) {
+	size, err := strconv.Atoi(chi.URLParam(r, "size"))
+	if err != nil {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	ctx, cancel := upstreamContext(r.Context())
+	defer cancel()
+
+	data, err := thumbnailer.Get(ctx, chi.URLParam(r, "*"), size)
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJPEG)
+	w.Header().Set("Cache-Control", "public, max-age=604800, immutable")
+
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// timeoutHeader is sent by the clients with an API key to get an answer,
+	// an error or the cached data sooner than the timeout of the route
+	timeoutHeader = "X-Timeout"
+	// minClientTimeout is the shortest timeout a client may request
+	minClientTimeout = time.Millisecond * 100
+)
+
+// Timeouts bound the fetches from hmtpk.ru made for a request
+type Timeouts struct {
+	// Default is the timeout of the routes not listed, 15 seconds when unset
+	Default time.Duration `yaml:"default"`
+	// Routes are the timeouts of the routes like /schedule/batch under the
+	// prefix, a route also applies to the routes below it unless they are listed
+	Routes map[string]time.Duration `yaml:"routes"`
+}
+
+// SetTimeouts replaces the timeouts of the routes
+func (a *API) SetTimeouts(timeouts Timeouts) {
+	a.timeouts = timeouts
+}
+
+// route returns the timeout of the path, the one of the longest listed route it is under
+func (t Timeouts) route(path string) time.Duration {
+	d, longest := t.Default, -1
+	for route, timeout := range t.Routes {
+		route = strings.TrimSuffix(route, "/")
+		if timeout > 0 && len(route) > longest && (path == route || strings.HasPrefix(path, route+"/")) {
+			d, longest = timeout, len(route)
+		}
+	}
+
+	if d <= 0 {
+		return timeout
+	}
+
+	return d
+}
+
+// clientTimeout parses the X-Timeout header, a duration like 3s or 800ms or
+// a number of seconds
+func clientTimeout(value string) (time.Duration, bool) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+
+	return max(d, minClientTimeout), d > 0
+}
+
+// timeoutMiddleware sets the deadline of the request: the timeout of the
+// route or the shorter one requested by a client with an API key in X-Timeout
+func (a *API) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the path under the prefix the router is mounted at
+		path := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+			path = rctx.RoutePath
+		}
+
+		d := a.timeouts.route(path)
+		if v := r.Header.Get(timeoutHeader); v != "" && a.tenantName(r) != "" {
+			if requested, ok := clientTimeout(v); ok {
+				d = min(d, requested)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// upstreamContext bounds the fetches by the deadline of the request, the
+// default timeout outside a request
+func upstreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/translate"
+)
+
+// SetTranslator enables ?translate= on announces
+func (a *API) SetTranslator(translator translate.Translator) {
+	a.translator = translator
+}
+
+// translate returns the translation of the title and the body of the announce
+func (a *API) translate(ctx context.Context, detail announce.Detail, language string) (*announce.Translation, error) {
+	title, err := a.translator.Translate(ctx, detail.Title, language)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := a.translator.Translate(ctx, detail.Body, language)
+	if err != nil {
+		return nil, err
+	}
+
+	return &announce.Translation{Language: language, Title: title, Body: body}, nil
+}
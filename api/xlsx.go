@@ -0,0 +1,282 @@
+//go:build !no_xlsx
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/xuri/excelize/v2"
+)
+
+func init() {
+	optionalRoutes = append(optionalRoutes, func(a *API, r chi.Router) {
+		r.Post("/schedule/xlsx", a.scheduleXLSX)
+	})
+}
+
+const (
+	contentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// maxExportTargets limits the number of groups and teachers in one export
+	maxExportTargets = 10
+	// maxSheetName is the length limit of a sheet name in Excel
+	maxSheetName = 31
+)
+
+// exportTarget is a group or a teacher whose schedule is exported
+type exportTarget struct {
+	group   string
+	teacher string
+}
+
+func (t exportTarget) name() string {
+	if t.group != "" {
+		return t.group
+	}
+
+	return t.teacher
+}
+
+// exportTargets returns the group and teacher query parameters, both may be repeated
+func exportTargets(r *http.Request) ([]exportTarget, bool) {
+	var targets []exportTarget
+	for _, group := range r.URL.Query()["group"] {
+		targets = append(targets, exportTarget{group: group})
+	}
+
+	for _, teacher := range r.URL.Query()["teacher"] {
+		targets = append(targets, exportTarget{teacher: teacher})
+	}
+
+	return targets, len(targets) > 0 && len(targets) <= maxExportTargets
+}
+
+// scheduleXLSX exports the weekly schedules as a spreadsheet with one sheet
+// per day (layout=day, default) or one sheet per group or teacher (layout=target)
+func (a *API) scheduleXLSX(w http.ResponseWriter, r *http.Request) {
+	date, ok := a.parseDate(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadDate})
+		return
+	}
+
+	targets, ok := exportTargets(r)
+	if !ok {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	layout := r.URL.Query().Get("layout")
+	if layout != "" && layout != "day" && layout != "target" {
+		write(w, r, http.StatusBadRequest, Response{Error: ErrorBadRequest})
+		return
+	}
+
+	schedules := make([][]model.Schedule, len(targets))
+	for i, target := range targets {
+		schedule, err := a.getSchedule(r.Context(), target.group, target.teacher, date)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+
+		schedules[i] = schedule
+	}
+
+	book, err := newScheduleBook()
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+	defer func() {
+		_ = book.file.Close()
+	}()
+
+	if layout == "target" {
+		err = book.byTarget(targets, schedules)
+	} else {
+		err = book.byDay(targets, schedules)
+	}
+
+	if err != nil {
+		a.error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeXLSX)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="schedule-%s.xlsx"`, date))
+
+	if err = book.file.Write(w); err != nil {
+		a.log.WithContext(r.Context()).Error(err)
+	}
+}
+
+// scheduleBook builds the spreadsheet of schedules
+type scheduleBook struct {
+	file   *excelize.File
+	header int
+	cell   int
+	sheets int
+}
+
+func newScheduleBook() (*scheduleBook, error) {
+	file := excelize.NewFile()
+
+	border := []excelize.Border{
+		{Type: "left", Color: "A0A0A0", Style: 1},
+		{Type: "top", Color: "A0A0A0", Style: 1},
+		{Type: "right", Color: "A0A0A0", Style: 1},
+		{Type: "bottom", Color: "A0A0A0", Style: 1},
+	}
+
+	header, err := file.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"D9E1F2"}, Pattern: 1},
+		Border:    border,
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cell, err := file.NewStyle(&excelize.Style{
+		Border:    border,
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheduleBook{file: file, header: header, cell: cell}, nil
+}
+
+// byDay adds a sheet for every day of the week with the lessons of all targets
+func (b *scheduleBook) byDay(targets []exportTarget, schedules [][]model.Schedule) error {
+	var days int
+	for _, schedule := range schedules {
+		days = max(days, len(schedule))
+	}
+
+	for day := 0; day < days; day++ {
+		var (
+			title string
+			rows  [][]interface{}
+		)
+
+		for i, schedule := range schedules {
+			if day >= len(schedule) {
+				continue
+			}
+
+			if title == "" {
+				title = schedule[day].Date
+			}
+
+			for _, lesson := range schedule[day].Lessons {
+				rows = append(rows, lessonRow(targets[i].name(), lesson))
+			}
+		}
+
+		if err := b.addSheet(title, []string{"Группа / преподаватель", "№", "Время", "Предмет", "Кабинет", "Преподаватель", "Группа", "Подгруппа"}, rows); err != nil {
+			return err
+		}
+	}
+
+	return b.finish()
+}
+
+// byTarget adds a sheet for every group or teacher with all days of the week
+func (b *scheduleBook) byTarget(targets []exportTarget, schedules [][]model.Schedule) error {
+	for i, schedule := range schedules {
+		var rows [][]interface{}
+		for _, day := range schedule {
+			for _, lesson := range day.Lessons {
+				rows = append(rows, lessonRow(day.Date, lesson))
+			}
+		}
+
+		if err := b.addSheet(targets[i].name(), []string{"Дата", "№", "Время", "Предмет", "Кабинет", "Преподаватель", "Группа", "Подгруппа"}, rows); err != nil {
+			return err
+		}
+	}
+
+	return b.finish()
+}
+
+func lessonRow(first string, lesson model.Lesson) []interface{} {
+	return []interface{}{first, lesson.Num, lesson.Time, lesson.Name, lesson.Room, lesson.Teacher, lesson.Group, lesson.Subgroup}
+}
+
+var sheetNameReplacer = strings.NewReplacer(":", " ", "\\", " ", "/", " ", "?", " ", "*", " ", "[", "(", "]", ")")
+
+func (b *scheduleBook) addSheet(title string, header []string, rows [][]interface{}) error {
+	b.sheets++
+
+	name := []rune(strings.TrimSpace(sheetNameReplacer.Replace(title)))
+	if len(name) == 0 {
+		name = []rune(fmt.Sprintf("Лист %d", b.sheets))
+	}
+
+	if len(name) > maxSheetName {
+		name = name[:maxSheetName]
+	}
+
+	sheet := string(name)
+	if index, _ := b.file.GetSheetIndex(sheet); index != -1 {
+		sheet = fmt.Sprintf("%s %d", string(name[:min(len(name), maxSheetName-3)]), b.sheets)
+	}
+
+	if _, err := b.file.NewSheet(sheet); err != nil {
+		return err
+	}
+
+	if err := b.file.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := b.file.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	last, _ := excelize.ColumnNumberToName(len(header))
+	if err := b.file.SetCellStyle(sheet, "A1", last+"1", b.header); err != nil {
+		return err
+	}
+
+	if len(rows) > 0 {
+		if err := b.file.SetCellStyle(sheet, "A2", fmt.Sprintf("%s%d", last, len(rows)+1), b.cell); err != nil {
+			return err
+		}
+	}
+
+	for column, width := range []float64{24, 5, 13, 40, 10, 30, 12, 10} {
+		name, _ := excelize.ColumnNumberToName(column + 1)
+		if err := b.file.SetColWidth(sheet, name, name, width); err != nil {
+			return err
+		}
+	}
+
+	return b.file.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// finish removes the default sheet created with the file
+func (b *scheduleBook) finish() error {
+	if b.sheets == 0 {
+		return nil
+	}
+
+	if err := b.file.DeleteSheet("Sheet1"); err != nil {
+		return err
+	}
+
+	b.file.SetActiveSheet(0)
+
+	return nil
+}
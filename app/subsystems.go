@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusRunning  = "running"
+	StatusStopped  = "stopped"
+	StatusDisabled = "disabled"
+)
+
+// Subsystems starts the optional parts of the service after the core API.
+// A subsystem that fails is logged and disabled, the rest keeps serving
+type Subsystems struct {
+	log *logrus.Logger
+
+	mu      sync.Mutex
+	status  map[string]string
+	closers []func()
+	wg      sync.WaitGroup
+}
+
+// NewSubsystems creates a new set of subsystems
+func NewSubsystems(logger *logrus.Logger) *Subsystems {
+	return &Subsystems{log: logger, status: make(map[string]string)}
+}
+
+// Start initializes a subsystem, the returned function is called on Close.
+// It reports whether the subsystem is running
+func (s *Subsystems) Start(name string, start func() (func(), error)) bool {
+	closer, err := start()
+	if err != nil {
+		s.disable(name, err)
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status[name] = StatusRunning
+	if closer != nil {
+		s.closers = append(s.closers, closer)
+	}
+
+	return true
+}
+
+// Go runs a long-living subsystem in the background until the context is canceled
+func (s *Subsystems) Go(ctx context.Context, name string, run func(ctx context.Context) error) {
+	s.mu.Lock()
+	s.status[name] = StatusRunning
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if err := run(ctx); err != nil && ctx.Err() == nil {
+			s.disable(name, err)
+			return
+		}
+
+		s.mu.Lock()
+		s.status[name] = StatusStopped
+		s.mu.Unlock()
+	}()
+}
+
+func (s *Subsystems) disable(name string, err error) {
+	s.log.Warnf("%s disabled: %s", name, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status[name] = StatusDisabled
+}
+
+// Status returns the status of every subsystem
+func (s *Subsystems) Status() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make(map[string]string, len(s.status))
+	for name, value := range s.status {
+		status[name] = value
+	}
+
+	return status
+}
+
+// Health writes the status of the subsystems. It is always 200:
+// a disabled subsystem degrades features but not the service
+func (s *Subsystems) Health(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Status())
+}
+
+// Close waits for the background subsystems and closes the started ones in reverse order
+func (s *Subsystems) Close() {
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+	s.closers = nil
+}
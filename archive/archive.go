@@ -0,0 +1,348 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Config is the configuration of the archive of parsed data
+type Config struct {
+	// Driver is sqlite or postgres, the archive is disabled when empty
+	Driver string `yaml:"driver"`
+	// DSN is the path of the SQLite file or the Postgres connection string
+	DSN string `yaml:"dsn"`
+}
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+
+	KindGroup   = "group"
+	KindTeacher = "teacher"
+
+	// SourceArchive marks the data served from the archive
+	SourceArchive = "archive"
+)
+
+// ErrNotFound is returned when the data was never archived
+var ErrNotFound = errors.New("not archived")
+
+// drivers maps the configured driver to the name registered in database/sql
+var drivers = map[string]string{
+	DriverSQLite:   "sqlite3",
+	DriverPostgres: "postgres",
+}
+
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS schedules (
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		date TEXT NOT NULL,
+		data TEXT NOT NULL,
+		fetched_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (kind, value, date)
+	)`,
+	`CREATE TABLE IF NOT EXISTS options (
+		kind TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		fetched_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS announce_pages (
+		page INTEGER PRIMARY KEY,
+		data TEXT NOT NULL,
+		fetched_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS announces (
+		path TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		fetched_at TIMESTAMP NOT NULL
+	)`,
+}
+
+const (
+	upsertSchedule = `INSERT INTO schedules (kind, value, date, data, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, value, date) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`
+	upsertOptions = `INSERT INTO options (kind, data, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT (kind) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`
+	upsertAnnouncePage = `INSERT INTO announce_pages (page, data, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT (page) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`
+	upsertAnnounce = `INSERT INTO announces (path, data, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`
+)
+
+// Archive keeps every parsed schedule, option list and announce in a
+// relational database with the time it was fetched
+type Archive struct {
+	db       *sql.DB
+	postgres bool
+}
+
+// Open connects to the database and creates the tables
+func Open(ctx context.Context, cfg Config) (*Archive, error) {
+	driver, ok := drivers[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown archive driver %q", cfg.Driver)
+	}
+
+	db, err := sql.Open(driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Driver == DriverSQLite {
+		// SQLite allows a single writer
+		db.SetMaxOpenConns(1)
+	}
+
+	a := &Archive{db: db, postgres: cfg.Driver == DriverPostgres}
+	for _, migration := range migrations {
+		if _, err = db.ExecContext(ctx, migration); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Close closes the database
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// query rewrites the ? placeholders for Postgres
+func (a *Archive) query(query string) string {
+	if !a.postgres {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// PutSchedule archives the days of a weekly schedule, date returns the date of a day
+func (a *Archive) PutSchedule(ctx context.Context, kind, value string, schedule []model.Schedule, date func(model.Schedule) (time.Time, bool)) error {
+	now := time.Now().UTC()
+	for _, day := range schedule {
+		d, ok := date(day)
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(day)
+		if err != nil {
+			return err
+		}
+
+		if err = a.exec(ctx, upsertSchedule, kind, value, d.Format(time.DateOnly), string(data), now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Schedule returns the archived days from..to inclusive with the time the oldest of them was fetched
+func (a *Archive) Schedule(ctx context.Context, kind, value string, from, to time.Time) ([]model.Schedule, time.Time, error) {
+	rows, err := a.db.QueryContext(ctx, a.query("SELECT data, fetched_at FROM schedules WHERE kind = ? AND value = ? AND date >= ? AND date <= ? ORDER BY date"),
+		kind, value, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		schedule []model.Schedule
+		oldest   time.Time
+	)
+	for rows.Next() {
+		var (
+			data      string
+			fetchedAt time.Time
+			day       model.Schedule
+		)
+		if err = rows.Scan(&data, &fetchedAt); err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if err = json.Unmarshal([]byte(data), &day); err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if oldest.IsZero() || fetchedAt.Before(oldest) {
+			oldest = fetchedAt
+		}
+		schedule = append(schedule, day)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if len(schedule) == 0 {
+		return nil, time.Time{}, ErrNotFound
+	}
+
+	return schedule, oldest, nil
+}
+
+// Days returns the archived days from..to inclusive of every group or teacher of the kind
+func (a *Archive) Days(ctx context.Context, kind string, from, to time.Time) (map[string][]model.Schedule, error) {
+	rows, err := a.db.QueryContext(ctx, a.query("SELECT value, data FROM schedules WHERE kind = ? AND date >= ? AND date <= ? ORDER BY value, date"),
+		kind, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	days := make(map[string][]model.Schedule)
+	for rows.Next() {
+		var (
+			value, data string
+			day         model.Schedule
+		)
+		if err = rows.Scan(&value, &data); err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal([]byte(data), &day); err != nil {
+			return nil, err
+		}
+
+		days[value] = append(days[value], day)
+	}
+
+	return days, rows.Err()
+}
+
+// Week returns the archived days of the week of the date with the time the oldest of them was fetched
+func (a *Archive) Week(ctx context.Context, kind, value string, date time.Time) ([]model.Schedule, time.Time, error) {
+	monday := date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+
+	return a.Schedule(ctx, kind, value, monday, monday.AddDate(0, 0, 6))
+}
+
+// PutOptions archives the list of groups or teachers
+func (a *Archive) PutOptions(ctx context.Context, kind string, options []model.Option) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	return a.exec(ctx, upsertOptions, kind, string(data), time.Now().UTC())
+}
+
+// Options returns the archived list of groups or teachers
+func (a *Archive) Options(ctx context.Context, kind string) ([]model.Option, error) {
+	var options []model.Option
+	err := a.get(ctx, "SELECT data FROM options WHERE kind = ?", &options, kind)
+
+	return options, err
+}
+
+// PutAnnounces archives a page of announces and every announce on it
+func (a *Archive) PutAnnounces(ctx context.Context, page int, announces model.Announces) error {
+	data, err := json.Marshal(announces)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if err = a.exec(ctx, upsertAnnouncePage, page, string(data), now); err != nil {
+		return err
+	}
+
+	for _, announce := range announces.Announces {
+		if data, err = json.Marshal(announce); err != nil {
+			return err
+		}
+
+		if err = a.exec(ctx, upsertAnnounce, announce.Path, string(data), now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Announces returns the archived page of announces
+func (a *Archive) Announces(ctx context.Context, page int) (model.Announces, error) {
+	var announces model.Announces
+	err := a.get(ctx, "SELECT data FROM announce_pages WHERE page = ?", &announces, page)
+
+	return announces, err
+}
+
+// AllAnnounces returns every archived announce
+func (a *Archive) AllAnnounces(ctx context.Context) ([]model.Announce, error) {
+	rows, err := a.db.QueryContext(ctx, a.query("SELECT data FROM announces ORDER BY path"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var announces []model.Announce
+	for rows.Next() {
+		var (
+			data     string
+			announce model.Announce
+		)
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal([]byte(data), &announce); err != nil {
+			return nil, err
+		}
+
+		announces = append(announces, announce)
+	}
+
+	return announces, rows.Err()
+}
+
+func (a *Archive) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := a.db.ExecContext(ctx, a.query(query), args...)
+	return err
+}
+
+// get unmarshals the JSON data of the single row of the query
+func (a *Archive) get(ctx context.Context, query string, v interface{}, args ...interface{}) error {
+	var data string
+	err := a.db.QueryRowContext(ctx, a.query(query), args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(data), v)
+}
@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Source is the provider of the data archived
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// fallbackTimeout bounds reading the archive after the source failed, the
+// request context may already be expired by then
+const fallbackTimeout = time.Second * 5
+
+// Provider archives everything the source returns and answers from the
+// archive when the source fails, e.g. while hmtpk.ru is down
+type Provider struct {
+	source  Source
+	archive *Archive
+	date    func(model.Schedule) (time.Time, bool)
+	log     *logrus.Logger
+}
+
+// NewProvider wraps the source, date returns the date of a day of a weekly schedule
+func NewProvider(source Source, archive *Archive, date func(model.Schedule) (time.Time, bool), logger *logrus.Logger) *Provider {
+	return &Provider{source: source, archive: archive, date: date, log: logger}
+}
+
+// fallback reports whether the error of the source is answered from the archive
+func fallback(err error) bool {
+	return !errors.Is(err, hmtpkErrors.ErrorBadRequest) && !errors.Is(err, context.Canceled)
+}
+
+// warnf logs a fallback, the ones during a maintenance window of hmtpk.ru are expected
+func (p *Provider) warnf(ctx context.Context, err error, format string, args ...interface{}) {
+	log := p.log.WithContext(ctx)
+	if errors.Is(err, maintenance.ErrMaintenance) {
+		log.Debugf(format, args...)
+		return
+	}
+
+	log.Warnf(format, args...)
+}
+
+// fallbackContext returns a context for reading the archive that outlives an expired request
+func fallbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), fallbackTimeout)
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	return p.options(ctx, KindGroup, p.source.GetGroupOptions)
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return p.options(ctx, KindTeacher, p.source.GetTeacherOptions)
+}
+
+func (p *Provider) options(ctx context.Context, kind string, get func(ctx context.Context) ([]model.Option, error)) ([]model.Option, error) {
+	options, marked, err := stale.Track(ctx, get)
+	if err == nil {
+		// the data served by a fallback of the source is not archived again
+		if !marked {
+			if err := p.archive.PutOptions(ctx, kind, options); err != nil {
+				p.log.WithContext(ctx).Errorf("archive: %s", err)
+			}
+		}
+		return options, nil
+	}
+
+	if !fallback(err) {
+		return nil, err
+	}
+
+	archiveCtx, cancel := fallbackContext(ctx)
+	defer cancel()
+
+	archived, archiveErr := p.archive.Options(archiveCtx, kind)
+	if archiveErr != nil {
+		return nil, err
+	}
+
+	p.warnf(ctx, err, "archive: %s options served from the archive: %s", kind, err)
+	stale.Mark(ctx, SourceArchive, time.Time{})
+
+	return archived, nil
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	return p.schedule(ctx, KindGroup, group, date, p.source.GetScheduleByGroup)
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	return p.schedule(ctx, KindTeacher, teacher, date, p.source.GetScheduleByTeacher)
+}
+
+func (p *Provider) schedule(ctx context.Context, kind, value, date string, get func(ctx context.Context, value, date string) ([]model.Schedule, error)) ([]model.Schedule, error) {
+	schedule, marked, err := stale.Track(ctx, func(ctx context.Context) ([]model.Schedule, error) {
+		return get(ctx, value, date)
+	})
+	if err == nil {
+		// the data served by a fallback of the source is not archived again
+		if !marked {
+			if err := p.archive.PutSchedule(ctx, kind, value, schedule, p.date); err != nil {
+				p.log.WithContext(ctx).Errorf("archive: %s", err)
+			}
+		}
+		return schedule, nil
+	}
+
+	if !fallback(err) {
+		return nil, err
+	}
+
+	archiveCtx, cancel := fallbackContext(ctx)
+	defer cancel()
+
+	d, parseErr := time.Parse("02.01.2006", date)
+	if parseErr != nil {
+		return nil, err
+	}
+
+	archived, fetchedAt, archiveErr := p.archive.Week(archiveCtx, kind, value, d)
+	if archiveErr != nil {
+		return nil, err
+	}
+
+	p.warnf(ctx, err, "archive: schedule of %s %s served from the archive: %s", kind, value, err)
+	stale.Mark(ctx, SourceArchive, fetchedAt)
+
+	return archived, nil
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	announces, marked, err := stale.Track(ctx, func(ctx context.Context) (model.Announces, error) {
+		return p.source.GetAnnounces(ctx, page)
+	})
+	if err == nil {
+		// the data served by a fallback of the source is not archived again
+		if !marked {
+			if err := p.archive.PutAnnounces(ctx, page, announces); err != nil {
+				p.log.WithContext(ctx).Errorf("archive: %s", err)
+			}
+		}
+		return announces, nil
+	}
+
+	if !fallback(err) {
+		return model.Announces{}, err
+	}
+
+	archiveCtx, cancel := fallbackContext(ctx)
+	defer cancel()
+
+	archived, archiveErr := p.archive.Announces(archiveCtx, page)
+	if archiveErr != nil {
+		return model.Announces{}, err
+	}
+
+	p.warnf(ctx, err, "archive: announces page %d served from the archive: %s", page, err)
+	stale.Mark(ctx, SourceArchive, time.Time{})
+
+	return archived, nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Attempts limits the attempts to log in with one login: loginBurst at once,
+// then one every loginEvery, whatever client makes them
+type Attempts struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	// forgetAt is when the logins that recovered every attempt are dropped
+	forgetAt time.Time
+}
+
+// NewAttempts creates the limit of the attempts to log in
+func NewAttempts() *Attempts {
+	return &Attempts{
+		now:      time.Now,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// NormalizeLogin returns the login the attempts are counted for
+func NormalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// Allow reports whether one more attempt to log in with the login is allowed
+func (a *Attempts) Allow(login string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if now.After(a.forgetAt) {
+		a.forget(now)
+		a.forgetAt = now.Add(cleanupInterval)
+	}
+
+	login = NormalizeLogin(login)
+	limiter, ok := a.limiters[login]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(loginEvery), loginBurst)
+		a.limiters[login] = limiter
+	}
+
+	return limiter.AllowN(now, 1)
+}
+
+// forget drops the limits of the logins that recovered every attempt
+func (a *Attempts) forget(now time.Time) {
+	for login, limiter := range a.limiters {
+		if limiter.TokensAt(now) >= loginBurst {
+			delete(a.limiters, login)
+		}
+	}
+}
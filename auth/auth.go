@@ -0,0 +1,376 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the authentication of the students
+type Config struct {
+	// Secret signs the access tokens, enables the authentication when not empty
+	Secret string `yaml:"secret"`
+	// AccessTTL is how long an access token is accepted
+	AccessTTL time.Duration `yaml:"access_ttl"`
+	// RefreshTTL is how long a refresh token can be exchanged for new tokens
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+const (
+	defaultAccessTTL  = time.Minute * 15
+	defaultRefreshTTL = time.Hour * 24 * 30
+
+	issuer = "hmtpk-parser-api"
+
+	refreshPrefix = "auth:refresh:"
+	// revokedPrefix keeps the time before which the tokens of a subject are revoked
+	revokedPrefix = "auth:revoked:"
+
+	cleanupInterval = time.Hour
+
+	// loginBurst attempts to log in with one login are allowed at once, then
+	// one every loginEvery, whatever client makes them
+	loginBurst = 5
+	loginEvery = time.Minute
+)
+
+var (
+	// ErrInvalid is returned for a token that is malformed, forged, expired or revoked
+	ErrInvalid = errors.New("invalid token")
+	// ErrCredentials is returned by the authenticators for wrong credentials
+	ErrCredentials = errors.New("invalid credentials")
+	// ErrNoAuthenticator is returned by Login when no authenticator is set
+	ErrNoAuthenticator = errors.New("no authenticator")
+	// ErrTooManyAttempts is returned by Login when the login is tried too often
+	ErrTooManyAttempts = errors.New("too many login attempts")
+)
+
+// Authenticator checks the credentials of a student and returns the subject
+// of the tokens identifying the student, ErrCredentials for wrong ones
+type Authenticator interface {
+	Authenticate(ctx context.Context, login, password string) (string, error)
+}
+
+// Claims are the claims of an access token
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+// Tokens are issued on the login and the refresh
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int `json:"expires_in"`
+}
+
+// session is the stored refresh token
+type session struct {
+	Subject   string    `json:"subject"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Auth issues short-lived HS256 JWTs to the authenticated students and
+// refresh tokens kept in the store, Redis when configured
+type Auth struct {
+	cfg           Config
+	secret        []byte
+	store         storage.Store
+	authenticator Authenticator
+	now           func() time.Time
+	log           *logrus.Logger
+	attempts      *Attempts
+}
+
+// New creates the authentication of the configuration
+func New(cfg Config, store storage.Store, logger *logrus.Logger) *Auth {
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = defaultAccessTTL
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = defaultRefreshTTL
+	}
+
+	return &Auth{
+		cfg:      cfg,
+		secret:   []byte(cfg.Secret),
+		store:    store,
+		now:      time.Now,
+		log:      logger,
+		attempts: NewAttempts(),
+	}
+}
+
+// SetAuthenticator sets the check of the credentials of the students
+func (a *Auth) SetAuthenticator(authenticator Authenticator) {
+	a.authenticator = authenticator
+}
+
+// CanLogin reports whether an authenticator is set
+func (a *Auth) CanLogin() bool {
+	return a.authenticator != nil
+}
+
+// Login checks the credentials and issues the tokens of the student
+func (a *Auth) Login(ctx context.Context, login, password string) (Tokens, error) {
+	if a.authenticator == nil {
+		return Tokens{}, ErrNoAuthenticator
+	}
+
+	if !a.attempts.Allow(login) {
+		return Tokens{}, ErrTooManyAttempts
+	}
+
+	subject, err := a.authenticator.Authenticate(ctx, login, password)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return a.issue(ctx, subject)
+}
+
+// Attempts returns the limit of the attempts to log in of Login, the other
+// checks of the credentials of the students share it
+func (a *Auth) Attempts() *Attempts {
+	return a.attempts
+}
+
+// Refresh exchanges the refresh token for new tokens, the refresh token is
+// used once: of the concurrent exchanges of one token only one succeeds
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	s, err := a.take(ctx, refreshToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return a.issue(ctx, s.Subject)
+}
+
+// Revoke revokes the refresh token, with all also every token of its subject
+// issued until now, the access tokens included
+func (a *Auth) Revoke(ctx context.Context, refreshToken string, all bool) error {
+	s, err := a.take(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	if !all {
+		return nil
+	}
+
+	return a.RevokeSubject(ctx, s.Subject)
+}
+
+// RevokeSubject revokes every token of the subject issued before the current
+// second. The tokens are issued with seconds, so the ones issued earlier in the
+// same second stay valid rather than the ones of a login right after it
+func (a *Auth) RevokeSubject(ctx context.Context, subject string) error {
+	// no token issued before the revocation outlives the longest lifetime
+	ttl := max(a.cfg.AccessTTL, a.cfg.RefreshTTL)
+
+	return a.store.SetTTL(ctx, revokedPrefix+subject, strconv.FormatInt(a.now().Unix(), 10), ttl)
+}
+
+// Verify returns the claims of the access token if it is valid and not revoked
+func (a *Auth) Verify(ctx context.Context, token string) (Claims, error) {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalid
+	}
+
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok || header != jwtHeader {
+		return Claims{}, ErrInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, a.sign(header+"."+payload)) {
+		return Claims{}, ErrInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Claims{}, ErrInvalid
+	}
+
+	var claims Claims
+	if err = json.Unmarshal(data, &claims); err != nil || claims.Issuer != issuer || claims.Subject == "" {
+		return Claims{}, ErrInvalid
+	}
+
+	if a.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrInvalid
+	}
+
+	if revoked, err := a.revokedBefore(ctx, claims.Subject); err != nil {
+		return Claims{}, err
+	} else if claims.IssuedAt < revoked {
+		return Claims{}, ErrInvalid
+	}
+
+	return claims, nil
+}
+
+// jwtHeader is the encoded {"alg":"HS256","typ":"JWT"}, the only header accepted
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (a *Auth) issue(ctx context.Context, subject string) (Tokens, error) {
+	now := a.now()
+
+	claims := Claims{
+		Issuer:    issuer,
+		Subject:   subject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(a.cfg.AccessTTL).Unix(),
+		ID:        random(),
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(data)
+	access := signed + "." + base64.RawURLEncoding.EncodeToString(a.sign(signed))
+
+	refresh := random()
+	data, err = json.Marshal(session{Subject: subject, IssuedAt: now, ExpiresAt: now.Add(a.cfg.RefreshTTL)})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// only the hash is stored so a leaked store doesn't leak the tokens
+	if err = a.store.SetTTL(ctx, refreshPrefix+hash(refresh), string(data), a.cfg.RefreshTTL); err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(a.cfg.AccessTTL / time.Second),
+	}, nil
+}
+
+// take deletes the refresh token and returns its session if it is not
+// expired or revoked
+func (a *Auth) take(ctx context.Context, refreshToken string) (session, error) {
+	if refreshToken == "" {
+		return session{}, ErrInvalid
+	}
+
+	value, err := a.store.GetDel(ctx, refreshPrefix+hash(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return session{}, ErrInvalid
+	} else if err != nil {
+		return session{}, err
+	}
+
+	var s session
+	if err = json.Unmarshal([]byte(value), &s); err != nil {
+		return session{}, err
+	}
+
+	if !a.now().Before(s.ExpiresAt) {
+		return session{}, ErrInvalid
+	}
+
+	if revoked, err := a.revokedBefore(ctx, s.Subject); err != nil {
+		return session{}, err
+	} else if s.IssuedAt.Unix() < revoked {
+		return session{}, ErrInvalid
+	}
+
+	return s, nil
+}
+
+// revokedBefore returns the time in seconds before which the tokens of the subject are revoked
+func (a *Auth) revokedBefore(ctx context.Context, subject string) (int64, error) {
+	value, err := a.store.Get(ctx, revokedPrefix+subject)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (a *Auth) sign(value string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func random() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Run deletes the expired refresh tokens every hour until the context is done
+func (a *Auth) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Cleanup(ctx); err != nil {
+				a.log.Errorf("auth: cleanup: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Cleanup deletes the expired refresh tokens
+func (a *Auth) Cleanup(ctx context.Context) error {
+	keys, err := a.store.Keys(ctx, refreshPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := a.now()
+	for _, key := range keys {
+		value, err := a.store.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+
+		var s session
+		if json.Unmarshal([]byte(value), &s) == nil && now.Before(s.ExpiresAt) {
+			continue
+		}
+
+		if err = a.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
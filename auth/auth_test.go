@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+)
+
+// student accepts every password of any login
+type student struct{}
+
+func (student) Authenticate(_ context.Context, login, _ string) (string, error) {
+	return "student:" + login, nil
+}
+
+// clock is the time of the tokens, moved by the cases
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func newAuth() (*Auth, *clock) {
+	c := &clock{now: time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)}
+
+	a := New(Config{Secret: "secret", AccessTTL: time.Minute * 15, RefreshTTL: time.Hour}, storage.NewMemory(), nil)
+	a.SetAuthenticator(student{})
+	a.now = c.Now
+	a.attempts.now = c.Now
+
+	return a, c
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// before changes the state between the login and the refresh
+		before func(t *testing.T, a *Auth, c *clock, tokens Tokens)
+		err    error
+	}{
+		{
+			name:   "fresh token",
+			before: func(*testing.T, *Auth, *clock, Tokens) {},
+		},
+		{
+			name: "token used already",
+			before: func(t *testing.T, a *Auth, _ *clock, tokens Tokens) {
+				if _, err := a.Refresh(context.Background(), tokens.RefreshToken); err != nil {
+					t.Fatal(err)
+				}
+			},
+			err: ErrInvalid,
+		},
+		{
+			name: "expired token",
+			before: func(_ *testing.T, _ *Auth, c *clock, _ Tokens) {
+				c.Add(time.Hour)
+			},
+			err: ErrInvalid,
+		},
+		{
+			name: "subject revoked",
+			before: func(t *testing.T, a *Auth, c *clock, _ Tokens) {
+				c.Add(time.Second)
+				if err := a.RevokeSubject(context.Background(), "student:login"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			err: ErrInvalid,
+		},
+		{
+			name: "token logged out",
+			before: func(t *testing.T, a *Auth, _ *clock, tokens Tokens) {
+				if err := a.Revoke(context.Background(), tokens.RefreshToken, false); err != nil {
+					t.Fatal(err)
+				}
+			},
+			err: ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, c := newAuth()
+
+			tokens, err := a.Login(context.Background(), "login", "password")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.before(t, a, c, tokens)
+
+			refreshed, err := a.Refresh(context.Background(), tokens.RefreshToken)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if refreshed.RefreshToken == tokens.RefreshToken {
+				t.Fatal("the refresh token is not rotated")
+			}
+
+			if _, err = a.Refresh(context.Background(), refreshed.RefreshToken); err != nil {
+				t.Fatalf("the rotated token: %v", err)
+			}
+		})
+	}
+}
+
+func TestRefreshOnce(t *testing.T) {
+	a, _ := newAuth()
+
+	tokens, err := a.Login(context.Background(), "login", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		refreshed int
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := a.Refresh(context.Background(), tokens.RefreshToken); err == nil {
+				mu.Lock()
+				refreshed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshed != 1 {
+		t.Fatalf("the token is exchanged %d times", refreshed)
+	}
+}
+
+func TestRevokeSubject(t *testing.T) {
+	tests := []struct {
+		name string
+		// issued is when the token is issued relative to the revocation
+		issued time.Duration
+		err    error
+	}{
+		{"issued a second before", -time.Second, ErrInvalid},
+		{"issued an hour before", -time.Hour, ErrInvalid},
+		{"issued in the same second after", time.Millisecond * 500, nil},
+		{"issued a second after", time.Second, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, c := newAuth()
+
+			c.Add(tt.issued)
+			tokens, err := a.Login(context.Background(), "login", "password")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.Add(-tt.issued)
+			if err = a.RevokeSubject(context.Background(), "student:login"); err != nil {
+				t.Fatal(err)
+			}
+
+			c.Add(max(tt.issued, 0))
+			if _, err = a.Verify(context.Background(), tokens.AccessToken); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoginAttempts(t *testing.T) {
+	a, c := newAuth()
+
+	for i := 0; i < loginBurst; i++ {
+		if _, err := a.Login(context.Background(), "login", "password"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	if _, err := a.Login(context.Background(), "LOGIN", "password"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("got %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	if _, err := a.Login(context.Background(), "other", "password"); err != nil {
+		t.Fatalf("another login: %v", err)
+	}
+
+	c.Add(loginEvery)
+	if _, err := a.Login(context.Background(), "login", "password"); err != nil {
+		t.Fatalf("after %s: %v", loginEvery, err)
+	}
+}
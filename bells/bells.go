@@ -0,0 +1,122 @@
+package bells
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Bell is the start and end time of a lesson
+type Bell struct {
+	Num   string `json:"num" yaml:"num"`
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+}
+
+// Bells is the bell schedule of weekdays and Saturday
+type Bells struct {
+	Weekday  []Bell `json:"weekday" yaml:"weekday"`
+	Saturday []Bell `json:"saturday" yaml:"saturday"`
+}
+
+// Empty reports whether the bell schedule has no lessons
+func (b Bells) Empty() bool {
+	return len(b.Weekday) == 0 && len(b.Saturday) == 0
+}
+
+// For returns the bells of the day of the week
+func (b Bells) For(weekday time.Weekday) []Bell {
+	if weekday == time.Saturday {
+		return b.Saturday
+	}
+
+	return b.Weekday
+}
+
+var lessonTime = regexp.MustCompile(`(\d{1,2})[:.](\d{2})\s*[-–—]\s*(\d{1,2})[:.](\d{2})`)
+
+// ParseTime returns the start and end of a lesson time like "08:30 - 10:00"
+func ParseTime(value string) (start, end string, ok bool) {
+	match := lessonTime.FindStringSubmatch(value)
+	if match == nil {
+		return "", "", false
+	}
+
+	return clock(match[1], match[2]), clock(match[3], match[4]), true
+}
+
+func clock(hours, minutes string) string {
+	h, _ := strconv.Atoi(hours)
+	return fmt.Sprintf("%02d:%s", h, minutes)
+}
+
+// DateFunc returns the date of a day of the weekly schedule
+type DateFunc func(day model.Schedule) (time.Time, bool)
+
+// FromSchedules builds the bell schedule from lesson times observed in
+// schedules: the most frequent time of every lesson number wins
+func FromSchedules(schedules [][]model.Schedule, date DateFunc) Bells {
+	weekday := make(map[string]map[Bell]int)
+	saturday := make(map[string]map[Bell]int)
+
+	for _, schedule := range schedules {
+		for _, day := range schedule {
+			d, ok := date(day)
+			if !ok || d.Weekday() == time.Sunday {
+				continue
+			}
+
+			counts := weekday
+			if d.Weekday() == time.Saturday {
+				counts = saturday
+			}
+
+			for _, lesson := range day.Lessons {
+				start, end, ok := ParseTime(lesson.Time)
+				if !ok || lesson.Num == "" {
+					continue
+				}
+
+				bell := Bell{Num: lesson.Num, Start: start, End: end}
+				if counts[lesson.Num] == nil {
+					counts[lesson.Num] = make(map[Bell]int)
+				}
+				counts[lesson.Num][bell]++
+			}
+		}
+	}
+
+	return Bells{Weekday: mostFrequent(weekday), Saturday: mostFrequent(saturday)}
+}
+
+func mostFrequent(counts map[string]map[Bell]int) []Bell {
+	result := make([]Bell, 0, len(counts))
+	for _, bells := range counts {
+		var (
+			best  Bell
+			count int
+		)
+
+		for bell, n := range bells {
+			if n > count || (n == count && bell.Start < best.Start) {
+				best, count = bell, n
+			}
+		}
+
+		result = append(result, best)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Start != result[j].Start {
+			return result[i].Start < result[j].Start
+		}
+
+		return result[i].Num < result[j].Num
+	})
+
+	return result
+}
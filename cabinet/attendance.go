@@ -0,0 +1,105 @@
+package cabinet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+)
+
+// Absence is the missed hours of a subject in a month, Excused of them with a reason
+type Absence struct {
+	Month   string `json:"month"`
+	Hours   int    `json:"hours"`
+	Excused int    `json:"excused,omitempty"`
+}
+
+// SubjectAttendance are the missed hours of a subject per month
+type SubjectAttendance struct {
+	Subject  string    `json:"subject"`
+	Absences []Absence `json:"absences"`
+	Hours    int       `json:"hours"`
+	Excused  int       `json:"excused,omitempty"`
+}
+
+// Attendance is the missed hours of a student per subject
+type Attendance struct {
+	Subjects []SubjectAttendance `json:"subjects"`
+	Hours    int                 `json:"hours"`
+	Excused  int                 `json:"excused,omitempty"`
+}
+
+// Attendance returns the missed hours of the student
+func (c *Client) Attendance(ctx context.Context, session Session) (Attendance, error) {
+	doc, err := c.page(ctx, session, c.cfg.AttendancePath, nil)
+	if err != nil {
+		return Attendance{}, err
+	}
+
+	return parseAttendance(doc)
+}
+
+// parseAttendance reads the attendance table: a row per subject, a column per
+// month with the missed hours like "6" or "6/2" with the excused ones. The
+// columns of the totals are skipped, the totals are counted
+func parseAttendance(doc *goquery.Document) (Attendance, error) {
+	headings, rows := journal(doc)
+	if headings == nil {
+		return Attendance{}, fmt.Errorf("%w: no attendance table", hmtpkErrors.ErrorBadResponse)
+	}
+
+	attendance := Attendance{Subjects: []SubjectAttendance{}}
+	for _, row := range rows {
+		// the row of the totals
+		if total(row[0]) {
+			continue
+		}
+
+		subject := SubjectAttendance{Subject: row[0], Absences: []Absence{}}
+		for i := 1; i < len(row) && i < len(headings); i++ {
+			if total(headings[i]) {
+				continue
+			}
+
+			hours, excused, ok := parseHours(row[i])
+			if !ok || hours == 0 {
+				continue
+			}
+
+			subject.Absences = append(subject.Absences, Absence{Month: headings[i], Hours: hours, Excused: excused})
+			subject.Hours += hours
+			subject.Excused += excused
+		}
+
+		attendance.Subjects = append(attendance.Subjects, subject)
+		attendance.Hours += subject.Hours
+		attendance.Excused += subject.Excused
+	}
+
+	return attendance, nil
+}
+
+func total(heading string) bool {
+	heading = strings.ToLower(heading)
+	return strings.HasPrefix(heading, "всего") || strings.HasPrefix(heading, "итого")
+}
+
+// parseHours reads the missed hours of a cell, false when it holds no number
+func parseHours(cell string) (int, int, bool) {
+	if cell == "" || cell == "-" {
+		return 0, 0, false
+	}
+
+	missed, reasoned, _ := strings.Cut(cell, "/")
+	hours, err := strconv.Atoi(strings.TrimSpace(missed))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	excused, _ := strconv.Atoi(strings.TrimSpace(reasoned))
+
+	return hours, min(excused, hours), true
+}
@@ -0,0 +1,202 @@
+package cabinet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+)
+
+// Config is the configuration of the personal cabinet of the students
+type Config struct {
+	// URL is the address of the personal cabinet, enables the student endpoints when not empty
+	URL string `yaml:"url"`
+	// LoginPath is the page with the login form
+	LoginPath string `yaml:"login_path"`
+	// MarksPath is the page of the grade journal
+	MarksPath string `yaml:"marks_path"`
+	// HomeworkPath is the page of the assigned homework
+	HomeworkPath string `yaml:"homework_path"`
+	// AttendancePath is the page of the missed hours
+	AttendancePath string `yaml:"attendance_path"`
+}
+
+const (
+	defaultLoginPath      = "/login"
+	defaultMarksPath      = "/journal"
+	defaultHomeworkPath   = "/homework"
+	defaultAttendancePath = "/attendance"
+
+	// maxPage bounds the size of a page of the cabinet
+	maxPage = 4 << 20
+	timeout = time.Second * 20
+)
+
+// ErrSessionExpired is returned when the cabinet asks to log in again
+var ErrSessionExpired = errors.New("cabinet session expired")
+
+// Session is the cookies of a student logged into the cabinet
+type Session struct {
+	Login   string         `json:"login"`
+	Cookies []*http.Cookie `json:"cookies"`
+	Created time.Time      `json:"created"`
+}
+
+// Client logs the students into the personal cabinet and reads their pages
+type Client struct {
+	cfg  Config
+	base *url.URL
+}
+
+// New creates the client of the cabinet at the URL of the configuration
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("cabinet url: %q", cfg.URL)
+	}
+
+	if cfg.LoginPath == "" {
+		cfg.LoginPath = defaultLoginPath
+	}
+	if cfg.MarksPath == "" {
+		cfg.MarksPath = defaultMarksPath
+	}
+	if cfg.HomeworkPath == "" {
+		cfg.HomeworkPath = defaultHomeworkPath
+	}
+	if cfg.AttendancePath == "" {
+		cfg.AttendancePath = defaultAttendancePath
+	}
+
+	return &Client{cfg: cfg, base: base}, nil
+}
+
+func (c *Client) url(path string, query url.Values) *url.URL {
+	u := *c.base
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	return &u
+}
+
+// Login submits the login form of the cabinet, auth.ErrCredentials is
+// returned when the cabinet shows the form again
+func (c *Client) Login(ctx context.Context, login, password string) (Session, error) {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Timeout: timeout}
+
+	page := c.url(c.cfg.LoginPath, nil)
+	doc, err := get(ctx, client, page)
+	if err != nil {
+		return Session{}, err
+	}
+
+	form := doc.Find("form").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.Find("input[type=password]").Length() > 0
+	}).First()
+	if form.Length() == 0 {
+		return Session{}, fmt.Errorf("%w: no login form", hmtpkErrors.ErrorBadResponse)
+	}
+
+	// the hidden fields like the CSRF token are sent back
+	values := url.Values{}
+	form.Find("input[name]").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		value, _ := s.Attr("value")
+
+		switch strings.ToLower(s.AttrOr("type", "text")) {
+		case "password":
+			values.Set(name, password)
+		case "text", "email", "tel":
+			values.Set(name, login)
+		case "checkbox", "radio", "submit", "button":
+		default:
+			values.Set(name, value)
+		}
+	})
+
+	action, err := page.Parse(form.AttrOr("action", ""))
+	if err != nil {
+		return Session{}, fmt.Errorf("%w: %s", hmtpkErrors.ErrorBadResponse, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, action.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return Session{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	doc, err = do(client, request)
+	if errors.Is(err, ErrSessionExpired) {
+		return Session{}, auth.ErrCredentials
+	} else if err != nil {
+		return Session{}, err
+	}
+
+	if loginForm(doc) {
+		return Session{}, auth.ErrCredentials
+	}
+
+	return Session{Login: login, Cookies: jar.Cookies(c.base), Created: time.Now()}, nil
+}
+
+// page returns the page of the cabinet read with the cookies of the session
+func (c *Client) page(ctx context.Context, session Session, path string, query url.Values) (*goquery.Document, error) {
+	jar, _ := cookiejar.New(nil)
+	jar.SetCookies(c.base, session.Cookies)
+	client := &http.Client{Jar: jar, Timeout: timeout}
+
+	doc, err := get(ctx, client, c.url(path, query))
+	if err != nil {
+		return nil, err
+	}
+
+	if loginForm(doc) {
+		return nil, ErrSessionExpired
+	}
+
+	return doc, nil
+}
+
+func get(ctx context.Context, client *http.Client, u *url.URL) (*goquery.Document, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return do(client, request)
+}
+
+func do(client *http.Client, request *http.Request) (*goquery.Document, error) {
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, ErrSessionExpired
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", hmtpkErrors.ErrorBadResponse, resp.Status)
+	}
+
+	return goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxPage))
+}
+
+// loginForm reports whether the page asks for the password
+func loginForm(doc *goquery.Document) bool {
+	return doc.Find("form input[type=password]").Length() > 0
+}
@@ -0,0 +1,143 @@
+package cabinet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+)
+
+// Assignment is a task given on a subject
+type Assignment struct {
+	Task string `json:"task"`
+	// Assigned and Deadline are dates like 2006-01-02, as written on the page when they are not dates
+	Assigned string `json:"assigned,omitempty"`
+	Deadline string `json:"deadline,omitempty"`
+	Overdue  bool   `json:"overdue,omitempty"`
+}
+
+// SubjectHomework are the tasks of a subject, the nearest deadline first
+type SubjectHomework struct {
+	Subject     string       `json:"subject"`
+	Assignments []Assignment `json:"assignments"`
+}
+
+// Homework is the homework of a student per subject
+type Homework struct {
+	Subjects []SubjectHomework `json:"subjects"`
+}
+
+// dateLayouts are the formats of the dates written in the cabinet
+var dateLayouts = []string{"02.01.2006", "02.01.2006 15:04", "02.01.06", "2006-01-02"}
+
+// Homework returns the tasks assigned to the student
+func (c *Client) Homework(ctx context.Context, session Session) (Homework, error) {
+	doc, err := c.page(ctx, session, c.cfg.HomeworkPath, nil)
+	if err != nil {
+		return Homework{}, err
+	}
+
+	return parseHomework(doc, time.Now())
+}
+
+// parseHomework reads the table of the tasks: a row per task with the columns
+// recognized by their headings, the subject and the task are required
+func parseHomework(doc *goquery.Document, now time.Time) (Homework, error) {
+	headings, rows := journal(doc)
+	if headings == nil {
+		return Homework{}, fmt.Errorf("%w: no homework table", hmtpkErrors.ErrorBadResponse)
+	}
+
+	subject, task, assigned, deadline := -1, -1, -1, -1
+	for i, heading := range headings {
+		heading = strings.ToLower(heading)
+		switch {
+		case strings.Contains(heading, "предмет") || strings.Contains(heading, "дисциплин"):
+			subject = i
+		case strings.Contains(heading, "срок") || strings.Contains(heading, "сда"):
+			deadline = i
+		case strings.Contains(heading, "задан"):
+			task = i
+		case strings.Contains(heading, "выдан") || strings.Contains(heading, "дата"):
+			assigned = i
+		}
+	}
+
+	if subject < 0 || task < 0 {
+		return Homework{}, fmt.Errorf("%w: no subject or task column", hmtpkErrors.ErrorBadResponse)
+	}
+
+	cell := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	var (
+		homework = Homework{Subjects: []SubjectHomework{}}
+		index    = map[string]int{}
+		today    = now.Format(time.DateOnly)
+	)
+	for _, row := range rows {
+		name, text := cell(row, subject), cell(row, task)
+		if name == "" || text == "" {
+			continue
+		}
+
+		assignment := Assignment{
+			Task:     text,
+			Assigned: normalizeDate(cell(row, assigned)),
+			Deadline: normalizeDate(cell(row, deadline)),
+		}
+		// the normalized dates compare as strings
+		if isDate(assignment.Deadline) && assignment.Deadline < today {
+			assignment.Overdue = true
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(homework.Subjects)
+			index[name] = i
+			homework.Subjects = append(homework.Subjects, SubjectHomework{Subject: name})
+		}
+		homework.Subjects[i].Assignments = append(homework.Subjects[i].Assignments, assignment)
+	}
+
+	for _, s := range homework.Subjects {
+		sortAssignments(s.Assignments)
+	}
+
+	return homework, nil
+}
+
+// sortAssignments orders the tasks by deadline, the ones without a date last
+func sortAssignments(assignments []Assignment) {
+	sort.SliceStable(assignments, func(i, j int) bool {
+		a, b := assignments[i].Deadline, assignments[j].Deadline
+		if isDate(a) != isDate(b) {
+			return isDate(a)
+		}
+		return a < b
+	})
+}
+
+// normalizeDate returns the date like 2006-01-02, the value itself when it is not a date
+func normalizeDate(value string) string {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(time.DateOnly)
+		}
+	}
+
+	return value
+}
+
+func isDate(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil
+}
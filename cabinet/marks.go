@@ -0,0 +1,140 @@
+package cabinet
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+)
+
+// Mark is a mark of the journal, Date is the heading of its column
+type Mark struct {
+	Date  string `json:"date,omitempty"`
+	Value string `json:"value"`
+}
+
+// SubjectMarks are the marks of a subject in the period
+type SubjectMarks struct {
+	Subject string `json:"subject"`
+	Marks   []Mark `json:"marks"`
+	// Average is the average of the numeric marks, the one of the journal when it shows it
+	Average float64 `json:"average,omitempty"`
+}
+
+// Marks is the grade journal of a student in the period
+type Marks struct {
+	Period   string         `json:"period,omitempty"`
+	Subjects []SubjectMarks `json:"subjects"`
+}
+
+// Marks returns the grade journal of the student in the period, the current
+// one when empty
+func (c *Client) Marks(ctx context.Context, session Session, period string) (Marks, error) {
+	query := url.Values{}
+	if period != "" {
+		query.Set("period", period)
+	}
+
+	doc, err := c.page(ctx, session, c.cfg.MarksPath, query)
+	if err != nil {
+		return Marks{}, err
+	}
+
+	return parseMarks(doc, period)
+}
+
+// parseMarks reads the journal table: a row per subject, a column per date
+// and the average in the last column when its heading says so
+func parseMarks(doc *goquery.Document, period string) (Marks, error) {
+	headings, rows := journal(doc)
+	if headings == nil {
+		return Marks{}, fmt.Errorf("%w: no journal table", hmtpkErrors.ErrorBadResponse)
+	}
+
+	average := -1
+	if last := len(headings) - 1; last > 0 && strings.Contains(strings.ToLower(headings[last]), "сред") {
+		average = last
+	}
+
+	marks := Marks{Period: period, Subjects: []SubjectMarks{}}
+	for _, row := range rows {
+		subject := SubjectMarks{Subject: row[0], Marks: []Mark{}}
+		for i := 1; i < len(row) && i < len(headings); i++ {
+			if i == average {
+				subject.Average, _ = strconv.ParseFloat(strings.ReplaceAll(row[i], ",", "."), 64)
+				continue
+			}
+
+			// a cell holds several marks got the same day, like "5 4"
+			for _, value := range strings.Fields(row[i]) {
+				subject.Marks = append(subject.Marks, Mark{Date: headings[i], Value: value})
+			}
+		}
+
+		if average < 0 {
+			subject.Average = Average(subject.Marks)
+		}
+
+		marks.Subjects = append(marks.Subjects, subject)
+	}
+
+	return marks, nil
+}
+
+// Average returns the average of the numeric marks rounded to hundredths, 0 without them
+func Average(marks []Mark) float64 {
+	var sum, n float64
+	for _, mark := range marks {
+		if v, err := strconv.Atoi(mark.Value); err == nil {
+			sum += float64(v)
+			n++
+		}
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return math.Round(sum/n*100) / 100
+}
+
+// journal returns the headings and the rows with a name in the first cell of
+// the largest table of the page, nil headings without a table
+func journal(doc *goquery.Document) ([]string, [][]string) {
+	var table *goquery.Selection
+	doc.Find("table").Each(func(_ int, s *goquery.Selection) {
+		if table == nil || s.Find("tr").Length() > table.Find("tr").Length() {
+			table = s
+		}
+	})
+
+	if table == nil {
+		return nil, nil
+	}
+
+	var (
+		headings []string
+		rows     [][]string
+	)
+	table.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		var cells []string
+		tr.Find("th, td").Each(func(_ int, cell *goquery.Selection) {
+			cells = append(cells, strings.Join(strings.Fields(cell.Text()), " "))
+		})
+
+		switch {
+		case len(cells) == 0:
+		case headings == nil:
+			headings = cells
+		case cells[0] != "":
+			rows = append(rows, cells)
+		}
+	})
+
+	return headings, rows
+}
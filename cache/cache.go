@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Cache stores the data fetched from hmtpk.ru for a while, embedders of the
+// API supply their own storage through it
+type Cache interface {
+	// Get returns the value of the key, ErrMiss when it is not cached or expired
+	Get(ctx context.Context, key string) (string, error)
+	// Set caches the value of the key for the ttl
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ErrMiss is returned when the key is not cached
+var ErrMiss = errors.New("cache miss")
+
+const (
+	BackendRedis     = "redis"
+	BackendMemory    = "memory"
+	BackendMemcached = "memcached"
+)
+
+// Config is the configuration of the cache of the data fetched from hmtpk.ru
+type Config struct {
+	// Backend is redis, memory or memcached, the parser caches in Redis
+	// itself when empty
+	Backend string `yaml:"backend"`
+	// Memcached are the addresses of the memcached servers
+	Memcached []string `yaml:"memcached"`
+	TTL       `yaml:",inline"`
+}
+
+// TTL is the time the data stays in the cache, the default one is used when zero
+type TTL struct {
+	GroupOptions   time.Duration `yaml:"group_options"`
+	TeacherOptions time.Duration `yaml:"teacher_options"`
+	// Today is the expiration of the schedules of the current week
+	Today time.Duration `yaml:"today"`
+	// Future is the expiration of the schedules of other weeks
+	Future    time.Duration `yaml:"future"`
+	Announces time.Duration `yaml:"announces"`
+}
+
+// the default expiration, the one the parser caches its data in Redis with
+const (
+	DefaultScheduleTTL = time.Minute * 5
+	DefaultOptionsTTL  = time.Minute * 60
+	DefaultAnnounceTTL = time.Minute * 60
+)
+
+// Or returns the configured expiration, the default one when not set
+func Or(configured, fallback time.Duration) time.Duration {
+	if configured > 0 {
+		return configured
+	}
+
+	return fallback
+}
+
+type bypassKey struct{}
+
+// Bypass makes the fetches of the context skip the cached data, the fresh data is still cached
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
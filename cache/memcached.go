@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Memcached is a cache in memcached
+type Memcached struct {
+	client *memcache.Client
+	prefix string
+}
+
+// NewMemcached creates a cache in the memcached servers
+func NewMemcached(prefix string, servers ...string) *Memcached {
+	return &Memcached{client: memcache.New(servers...), prefix: prefix}
+}
+
+// key hashes the key, memcached keys are limited to 250 bytes without spaces
+func (m *Memcached) key(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return m.prefix + hex.EncodeToString(sum[:])
+}
+
+func (m *Memcached) Get(_ context.Context, key string) (string, error) {
+	it, err := m.client.Get(m.key(key))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return "", ErrMiss
+	} else if err != nil {
+		return "", err
+	}
+
+	return string(it.Value), nil
+}
+
+func (m *Memcached) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	// memcached takes whole seconds, zero never expires
+	seconds := int32(max(ttl/time.Second, 1))
+
+	return m.client.Set(&memcache.Item{Key: m.key(key), Value: []byte(value), Expiration: seconds})
+}
+
+func (m *Memcached) Delete(_ context.Context, key string) error {
+	err := m.client.Delete(m.key(key))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil
+	}
+
+	return err
+}
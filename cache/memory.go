@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of writes between removals of the expired keys
+const sweepEvery = 1024
+
+// Memory is a cache in the memory of the process
+type Memory struct {
+	mu     sync.Mutex
+	items  map[string]item
+	writes int
+}
+
+type item struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemory creates an empty cache in memory
+func NewMemory() *Memory {
+	return &Memory{items: make(map[string]item)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, ok := m.items[key]
+	if !ok {
+		return "", ErrMiss
+	}
+
+	if time.Now().After(it.expires) {
+		delete(m.items, key)
+		return "", ErrMiss
+	}
+
+	return it.value, nil
+}
+
+func (m *Memory) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.items[key] = item{value: value, expires: now.Add(ttl)}
+
+	if m.writes++; m.writes%sweepEvery == 0 {
+		for key, it := range m.items {
+			if now.After(it.expires) {
+				delete(m.items, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.items, key)
+
+	return nil
+}
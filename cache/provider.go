@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Source is the parser fetching from hmtpk.ru
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// Provider caches the data of the source in the cache, for the parser running
+// without its own Redis cache
+type Provider struct {
+	cfg      TTL
+	source   Source
+	cache    Cache
+	layer    string
+	location *time.Location
+	extend   func(time.Duration) time.Duration
+	log      *logrus.Logger
+}
+
+// entry is the cached data with the time it was cached
+type entry[T any] struct {
+	Data     T         `json:"data"`
+	CachedAt time.Time `json:"cached_at"`
+}
+
+// NewProvider wraps the source, layer names the cache in the cache headers
+func NewProvider(cfg TTL, source Source, cache Cache, layer string, logger *logrus.Logger) *Provider {
+	return &Provider{cfg: cfg, source: source, cache: cache, layer: layer, location: time.Local, log: logger}
+}
+
+// SetLocation sets the time zone in which the current week is resolved
+func (p *Provider) SetLocation(location *time.Location) {
+	p.location = location
+}
+
+// SetTTL sets the function extending the expiration of the data cached now, e.g. during the peak
+func (p *Provider) SetTTL(extend func(time.Duration) time.Duration) {
+	p.extend = extend
+}
+
+// cached returns the data of the key from the cache or caches the data of the call for ttl
+func cached[T any](ctx context.Context, p *Provider, key string, ttl time.Duration, call func(ctx context.Context) (T, error)) (T, error) {
+	if !bypassed(ctx) {
+		value, err := p.cache.Get(ctx, key)
+		if err == nil {
+			var e entry[T]
+			if err = json.Unmarshal([]byte(value), &e); err == nil {
+				stale.Hit(ctx, p.layer, max(time.Since(e.CachedAt), 0))
+				return e.Data, nil
+			}
+		}
+
+		if err != nil && !errors.Is(err, ErrMiss) {
+			p.log.Warnf("cache: %s: %s", key, err)
+		}
+	}
+
+	stale.Miss(ctx)
+
+	result, marked, err := stale.Track(ctx, call)
+	// the data served by a fallback of the source is not cached
+	if err != nil || marked {
+		return result, err
+	}
+
+	if p.extend != nil {
+		ttl = p.extend(ttl)
+	}
+
+	data, err := json.Marshal(entry[T]{Data: result, CachedAt: time.Now()})
+	if err == nil {
+		err = p.cache.Set(ctx, key, string(data), ttl)
+	}
+	if err != nil {
+		p.log.Warnf("cache: %s: %s", key, err)
+	}
+
+	return result, nil
+}
+
+// scheduleKey returns the key of the week of the date, empty for an invalid date
+func scheduleKey(kind, value, date string) string {
+	d, err := time.Parse("02.01.2006", date)
+	if err != nil {
+		return ""
+	}
+
+	year, week := d.ISOWeek()
+	return fmt.Sprintf("schedule:%s:%s:%d/%d", kind, value, year, week)
+}
+
+// scheduleTTL returns the expiration of the week of the date
+func (p *Provider) scheduleTTL(date string) time.Duration {
+	configured := p.cfg.Future
+	if d, err := time.Parse("02.01.2006", date); err == nil {
+		year, week := d.ISOWeek()
+		nowYear, nowWeek := time.Now().In(p.location).ISOWeek()
+		if year == nowYear && week == nowWeek {
+			configured = p.cfg.Today
+		}
+	}
+
+	return Or(configured, DefaultScheduleTTL)
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	return cached(ctx, p, "options:groups", Or(p.cfg.GroupOptions, DefaultOptionsTTL), p.source.GetGroupOptions)
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return cached(ctx, p, "options:teachers", Or(p.cfg.TeacherOptions, DefaultOptionsTTL), p.source.GetTeacherOptions)
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	key := scheduleKey("group", group, date)
+	if key == "" {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	}
+
+	return cached(ctx, p, key, p.scheduleTTL(date), func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	})
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	key := scheduleKey("teacher", teacher, date)
+	if key == "" {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	}
+
+	return cached(ctx, p, key, p.scheduleTTL(date), func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	})
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	return cached(ctx, p, fmt.Sprintf("announces:%d", page), Or(p.cfg.Announces, DefaultAnnounceTTL), func(ctx context.Context) (model.Announces, error) {
+		return p.source.GetAnnounces(ctx, page)
+	})
+}
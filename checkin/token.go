@@ -0,0 +1,100 @@
+package checkin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of attendance check-ins
+type Config struct {
+	// Secret signs lesson tokens, enables check-ins when not empty
+	Secret string `yaml:"secret"`
+	// TTL is how long a lesson token accepts check-ins
+	TTL time.Duration `yaml:"ttl"`
+}
+
+const defaultTTL = time.Minute * 15
+
+var (
+	ErrInvalid = errors.New("invalid lesson token")
+	ErrExpired = errors.New("lesson token expired")
+)
+
+// Token identifies the lesson students check in to
+type Token struct {
+	Group   string    `json:"group"`
+	Date    string    `json:"date"`
+	Lesson  string    `json:"lesson"`
+	Expires time.Time `json:"expires"`
+}
+
+// Signer issues and verifies lesson tokens
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner creates a new signer
+func NewSigner(cfg Config) *Signer {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+
+	return &Signer{secret: []byte(cfg.Secret), ttl: cfg.TTL}
+}
+
+// Issue returns the signed token of the lesson valid for the TTL
+func (s *Signer) Issue(group, date, lesson string, now time.Time) (Token, string) {
+	token := Token{Group: group, Date: date, Lesson: lesson, Expires: now.Add(s.ttl).Truncate(time.Second)}
+
+	payload := strings.Join([]string{group, date, lesson, strconv.FormatInt(token.Expires.Unix(), 10)}, "\n")
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return token, encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+}
+
+// Verify checks the signature and the expiry of the token
+func (s *Signer) Verify(value string, now time.Time) (Token, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return Token{}, ErrInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(encoded)) {
+		return Token{}, ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Token{}, ErrInvalid
+	}
+
+	parts := strings.Split(string(payload), "\n")
+	if len(parts) != 4 {
+		return Token{}, ErrInvalid
+	}
+
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return Token{}, ErrInvalid
+	}
+
+	token := Token{Group: parts[0], Date: parts[1], Lesson: parts[2], Expires: time.Unix(expires, 0)}
+	if now.After(token.Expires) {
+		return Token{}, ErrExpired
+	}
+
+	return token, nil
+}
+
+func (s *Signer) sign(data string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,519 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/announce"
+	"github.com/chazari-x/hmtpk-parser-api/api"
+	"github.com/chazari-x/hmtpk-parser-api/archive"
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk-parser-api/cabinet"
+	"github.com/chazari-x/hmtpk-parser-api/cache"
+	"github.com/chazari-x/hmtpk-parser-api/checkin"
+	"github.com/chazari-x/hmtpk-parser-api/edge"
+	"github.com/chazari-x/hmtpk-parser-api/fixture"
+	"github.com/chazari-x/hmtpk-parser-api/ids"
+	"github.com/chazari-x/hmtpk-parser-api/limits"
+	"github.com/chazari-x/hmtpk-parser-api/logging"
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/metrics"
+	"github.com/chazari-x/hmtpk-parser-api/mirror"
+	"github.com/chazari-x/hmtpk-parser-api/moodle"
+	"github.com/chazari-x/hmtpk-parser-api/notify"
+	"github.com/chazari-x/hmtpk-parser-api/peak"
+	"github.com/chazari-x/hmtpk-parser-api/plugin"
+	"github.com/chazari-x/hmtpk-parser-api/replay"
+	"github.com/chazari-x/hmtpk-parser-api/retry"
+	"github.com/chazari-x/hmtpk-parser-api/rollover"
+	"github.com/chazari-x/hmtpk-parser-api/sentry"
+	"github.com/chazari-x/hmtpk-parser-api/stats"
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/chazari-x/hmtpk-parser-api/thumb"
+	"github.com/chazari-x/hmtpk-parser-api/translate"
+	"github.com/chazari-x/hmtpk-parser-api/vault"
+	"github.com/chazari-x/hmtpk-parser-api/warmup"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the service
+type Config struct {
+	Profile     string                `yaml:"-"`
+	Role        string                `yaml:"role"`
+	Addr        string                `yaml:"addr"`
+	Prefix      string                `yaml:"prefix"`
+	PublicURL   string                `yaml:"public_url"`
+	GRPCAddr    string                `yaml:"grpc_addr"`
+	LogLevel    string                `yaml:"log_level"`
+	Timezone    string                `yaml:"timezone"`
+	Mock        bool                  `yaml:"mock"`
+	LogFile     logging.File          `yaml:"log_file"`
+	Syslog      logging.Syslog        `yaml:"syslog"`
+	Loki        logging.Loki          `yaml:"loki"`
+	AccessLog   logging.Access        `yaml:"access_log"`
+	Sentry      sentry.Config         `yaml:"sentry"`
+	Limits      limits.Config         `yaml:"limits"`
+	Timeouts    api.Timeouts          `yaml:"timeouts"`
+	Metrics     metrics.Config        `yaml:"metrics"`
+	Warmup      warmup.Config         `yaml:"warmup"`
+	Redis       Redis                 `yaml:"redis"`
+	Tenants     []api.Tenant          `yaml:"tenants"`
+	Bells       bells.Bells           `yaml:"bells"`
+	Plugins     []plugin.Config       `yaml:"plugins"`
+	Translate   translate.Config      `yaml:"translate"`
+	Announces   announce.Config       `yaml:"announces"`
+	Telegram    telegram.Config       `yaml:"telegram"`
+	Alice       api.Alice             `yaml:"alice"`
+	Moodle      moodle.Config         `yaml:"moodle"`
+	Checkin     checkin.Config        `yaml:"checkin"`
+	Edge        edge.Config           `yaml:"edge"`
+	Thumbnails  thumb.Config          `yaml:"thumbnails"`
+	Rollover    rollover.Config       `yaml:"rollover"`
+	Archive     archive.Config        `yaml:"archive"`
+	Retry       retry.Config          `yaml:"retry"`
+	Replay      replay.Config         `yaml:"replay"`
+	Mirrors     mirror.Config         `yaml:"mirrors"`
+	IDs         ids.Config            `yaml:"ids"`
+	Cache       cache.Config          `yaml:"cache"`
+	Presets     map[string]api.Preset `yaml:"presets"`
+	Report      stats.Config          `yaml:"report"`
+	Maintenance maintenance.Config    `yaml:"maintenance"`
+	Peak        peak.Config           `yaml:"peak"`
+	Fixtures    fixture.Config        `yaml:"fixtures"`
+	Auth        auth.Config           `yaml:"auth"`
+	Cabinet     cabinet.Config        `yaml:"cabinet"`
+	Vault       vault.Config          `yaml:"vault"`
+	Notify      notify.Config         `yaml:"notify"`
+}
+
+// Redis is the configuration of the Redis cache
+type Redis struct {
+	// URL is a redis:// or rediss:// URL used instead of Addr, Password and DB
+	URL      string `yaml:"url"`
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	// Sentinel finds the master of a Sentinel deployment instead of Addr
+	Sentinel RedisSentinel `yaml:"sentinel"`
+	// Cluster are the addresses of the nodes of a Redis Cluster used instead
+	// of Addr. The parser caches in a single Redis only and runs without its
+	// cache on a cluster, the data of the service is kept in the cluster
+	Cluster []string `yaml:"cluster"`
+}
+
+// RedisSentinel is the configuration of a Redis Sentinel deployment
+type RedisSentinel struct {
+	// MasterName enables Sentinel when not empty
+	MasterName string   `yaml:"master_name"`
+	Addrs      []string `yaml:"addrs"`
+	Password   string   `yaml:"password"`
+}
+
+const (
+	// ProfileNone is the profile when none is selected: the defaults of the
+	// service before the profiles, hmtpk.ru fetched with no cache
+	ProfileNone    = "none"
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+
+	// ProfileEnv is the environment variable selecting the profile
+	ProfileEnv = "HMTPK_PROFILE"
+)
+
+// profiles holds the defaults of every profile
+var profiles = map[string]Config{
+	ProfileNone: {
+		Addr:     ":8080",
+		Prefix:   "/api/hmtpk",
+		GRPCAddr: ":9090",
+		LogLevel: "trace",
+	},
+	ProfileDev: {
+		Addr:     ":8080",
+		Prefix:   "/api/hmtpk",
+		GRPCAddr: ":9090",
+		LogLevel: "trace",
+		Mock:     true,
+		Cache:    cache.Config{Backend: cache.BackendMemory},
+	},
+	ProfileStaging: {
+		Addr:      ":8080",
+		Prefix:    "/api/hmtpk",
+		GRPCAddr:  ":9090",
+		LogLevel:  "debug",
+		Metrics:   metrics.Config{Path: "/metrics"},
+		Warmup:    warmup.Config{Interval: time.Minute * 5},
+		Redis:     Redis{Addr: "localhost:6379"},
+		Announces: announce.Config{Interval: time.Minute * 30},
+		Rollover:  rollover.Config{Interval: time.Hour},
+		Retry:     retry.Config{Attempts: 3},
+	},
+	ProfileProd: {
+		Addr:      ":8080",
+		Prefix:    "/api/hmtpk",
+		GRPCAddr:  ":9090",
+		LogLevel:  "info",
+		Metrics:   metrics.Config{Path: "/metrics"},
+		Warmup:    warmup.Config{Interval: time.Minute * 5},
+		Redis:     Redis{Addr: "localhost:6379"},
+		Announces: announce.Config{Interval: time.Minute * 30},
+		Rollover:  rollover.Config{Interval: time.Hour},
+		Retry:     retry.Config{Attempts: 3},
+	},
+}
+
+// Load builds the configuration of the profile: profile defaults, then
+// config.yaml and config.<profile>.yaml from dir, then environment variables.
+// Without a profile only config.yaml is read on top of the defaults of none
+func Load(profile, dir string) (*Config, error) {
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+
+	if profile == "" {
+		profile = ProfileNone
+	}
+
+	defaults, ok := profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", profile)
+	}
+
+	// .env never overrides variables already set in the environment
+	if profile == ProfileDev {
+		if err := godotenv.Load(filepath.Join(dir, ".env")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	cfg := defaults
+	cfg.Profile = profile
+
+	files := []string{"config.yaml"}
+	if profile != ProfileNone {
+		files = append(files, fmt.Sprintf("config.%s.yaml", profile))
+	}
+
+	for _, name := range files {
+		if err := overlay(&cfg, filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	env(&cfg)
+
+	return &cfg, nil
+}
+
+// overlay reads the file on top of cfg, a missing file is not an error
+func overlay(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// env applies environment variable overrides
+func env(cfg *Config) {
+	if v, ok := os.LookupEnv("HMTPK_ROLE"); ok {
+		cfg.Role = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_ADDR"); ok {
+		cfg.Addr = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_PREFIX"); ok {
+		cfg.Prefix = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_PUBLIC_URL"); ok {
+		cfg.PublicURL = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_GRPC_ADDR"); ok {
+		cfg.GRPCAddr = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_LOG_LEVEL"); ok {
+		cfg.LogLevel = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_LOG_FILE"); ok {
+		cfg.LogFile.Path = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_SYSLOG_ADDR"); ok {
+		cfg.Syslog.Addr = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_LOKI_URL"); ok {
+		cfg.Loki.URL = v
+	}
+
+	// SENTRY_DSN is the variable read by the Sentry SDKs
+	if v, ok := os.LookupEnv("SENTRY_DSN"); ok {
+		cfg.Sentry.DSN = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_SENTRY_DSN"); ok {
+		cfg.Sentry.DSN = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_METRICS_PATH"); ok {
+		cfg.Metrics.Path = v
+	}
+
+	// REDIS_URL is the variable set by most hosting platforms
+	if v, ok := os.LookupEnv("REDIS_URL"); ok {
+		cfg.Redis.URL = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_URL"); ok {
+		cfg.Redis.URL = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_ADDR"); ok {
+		cfg.Redis.Addr = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_PASSWORD"); ok {
+		cfg.Redis.Password = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_SENTINEL_MASTER"); ok {
+		cfg.Redis.Sentinel.MasterName = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_SENTINELS"); ok {
+		cfg.Redis.Sentinel.Addrs = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_SENTINEL_PASSWORD"); ok {
+		cfg.Redis.Sentinel.Password = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REDIS_CLUSTER"); ok {
+		cfg.Redis.Cluster = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_TRANSLATE_KEY"); ok {
+		cfg.Translate.Key = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_TELEGRAM_BOT_TOKEN"); ok {
+		cfg.Telegram.BotToken = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_ALICE_SKILL_ID"); ok {
+		cfg.Alice.SkillID = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_MOODLE_TOKEN"); ok {
+		cfg.Moodle.Token = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_CHECKIN_SECRET"); ok {
+		cfg.Checkin.Secret = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_EDGE_TOKEN"); ok {
+		cfg.Edge.Token = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_EDGE_ORIGIN"); ok {
+		cfg.Edge.Origin = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_IDS_SECRET"); ok {
+		cfg.IDs.Secret = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_AUTH_SECRET"); ok {
+		cfg.Auth.Secret = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_VAULT_KEY"); ok {
+		cfg.Vault.Key = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_FCM_SERVER_KEY"); ok {
+		cfg.Notify.FCM.ServerKey = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_ARCHIVE_DSN"); ok {
+		cfg.Archive.DSN = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_MOCK"); ok {
+		cfg.Mock = v == "true" || v == "1"
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_FIXTURES_MODE"); ok {
+		cfg.Fixtures.Mode = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_FIXTURES_DIR"); ok {
+		cfg.Fixtures.Dir = v
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REPORT_TO"); ok {
+		cfg.Report.To = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("HMTPK_REPORT_SMTP_PASSWORD"); ok {
+		cfg.Report.Password = v
+	}
+}
+
+// splitList returns the comma separated values of an environment variable
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+const redacted = "******"
+
+// Redacted returns a copy of the configuration with secrets hidden
+func (c Config) Redacted() Config {
+	if c.Redis.Password != "" {
+		c.Redis.Password = redacted
+	}
+
+	// the URL may hold the password
+	if c.Redis.URL != "" {
+		c.Redis.URL = redacted
+	}
+
+	if c.Redis.Sentinel.Password != "" {
+		c.Redis.Sentinel.Password = redacted
+	}
+
+	// the URL may hold the credentials of the push endpoint
+	if u, err := url.Parse(c.Loki.URL); err != nil || u.User != nil {
+		c.Loki.URL = redacted
+	}
+
+	if c.Translate.Key != "" {
+		c.Translate.Key = redacted
+	}
+
+	if c.Telegram.BotToken != "" {
+		c.Telegram.BotToken = redacted
+	}
+
+	if c.Moodle.Token != "" {
+		c.Moodle.Token = redacted
+	}
+
+	if c.Checkin.Secret != "" {
+		c.Checkin.Secret = redacted
+	}
+
+	if c.IDs.Secret != "" {
+		c.IDs.Secret = redacted
+	}
+
+	if c.Auth.Secret != "" {
+		c.Auth.Secret = redacted
+	}
+
+	if c.Vault.Key != "" {
+		c.Vault.Key = redacted
+	}
+
+	if c.Notify.Email.Password != "" {
+		c.Notify.Email.Password = redacted
+	}
+
+	if c.Notify.FCM.ServerKey != "" {
+		c.Notify.FCM.ServerKey = redacted
+	}
+
+	if c.Edge.Token != "" {
+		c.Edge.Token = redacted
+	}
+
+	if c.Edge.APIKey != "" {
+		c.Edge.APIKey = redacted
+	}
+
+	// the DSN holds the client key of the project
+	if c.Sentry.DSN != "" {
+		c.Sentry.DSN = redacted
+	}
+
+	if c.Report.Password != "" {
+		c.Report.Password = redacted
+	}
+
+	// a Postgres connection string may hold the password
+	if c.Archive.Driver == archive.DriverPostgres && c.Archive.DSN != "" {
+		c.Archive.DSN = redacted
+	}
+
+	tenants := make([]api.Tenant, len(c.Tenants))
+	for i, tenant := range c.Tenants {
+		if tenant.Key != "" {
+			tenant.Key = redacted
+		}
+		tenants[i] = tenant
+	}
+	c.Tenants = tenants
+
+	// the environment of a plugin usually passes its credentials
+	plugins := make([]plugin.Config, len(c.Plugins))
+	for i, p := range c.Plugins {
+		if len(p.Env) > 0 {
+			env := make(map[string]string, len(p.Env))
+			for name := range p.Env {
+				env[name] = redacted
+			}
+			p.Env = env
+		}
+		plugins[i] = p
+	}
+	c.Plugins = plugins
+
+	return c
+}
+
+// Print writes the redacted configuration as YAML
+func (c Config) Print(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# profile: %s\n", c.Profile); err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	defer func() {
+		_ = encoder.Close()
+	}()
+
+	return encoder.Encode(c.Redacted())
+}
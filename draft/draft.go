@@ -0,0 +1,203 @@
+package draft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/chazari-x/hmtpk_parser/v2/utils"
+)
+
+// ErrEmpty is returned when the file has no schedule in the format of the site
+var ErrEmpty = errors.New("no schedule in the file")
+
+const (
+	// the layout of the group schedule page of hmtpk.ru
+	firstDayNum = 2
+	lastDayNum  = firstDayNum + 6
+
+	href = "https://hmtpk.ru/ru/students/schedule"
+)
+
+const (
+	KindRoom       = "room"
+	KindTeacher    = "teacher"
+	KindIncomplete = "incomplete"
+)
+
+// Conflict is a problem found in the draft schedule
+type Conflict struct {
+	Kind string `json:"kind"`
+	Date string `json:"date"`
+	Num  string `json:"num"`
+	// Value is the room or the teacher taken twice
+	Value string `json:"value,omitempty"`
+	// Lessons are the lessons in conflict, the ones of other groups have the group set
+	Lessons []model.Lesson `json:"lessons"`
+}
+
+// Parse reads the weekly schedule of the group from a page saved in the
+// format of the group schedule page of the site
+func Parse(r io.Reader, group string) ([]model.Schedule, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule []model.Schedule
+	for num := firstDayNum; num <= lastDayNum; num++ {
+		heading := strings.TrimSpace(doc.Find(fmt.Sprintf("div.raspcontent.m5 div:nth-child(%d) div.panel-heading.edu_today > h2", num)).Text())
+		if heading == "" {
+			continue
+		}
+
+		day := model.Schedule{Date: heading}
+		if date, ok := headingDate(heading); ok {
+			day.Href = fmt.Sprintf("%s/?group=%s&date_edu1c=%s&send=Показать#current", href, url.QueryEscape(group), date)
+		}
+
+		rows := doc.Find(fmt.Sprintf("div.raspcontent.m5 div:nth-child(%d) div.panel-body > #mobile-friendly > tbody:nth-child(2) > tr", num))
+		rows.Each(func(_ int, row *goquery.Selection) {
+			if lesson, ok := parseLesson(row, day.Lessons); ok {
+				day.Lessons = append(day.Lessons, lesson)
+			}
+		})
+
+		schedule = append(schedule, day)
+	}
+
+	if len(schedule) == 0 {
+		return nil, ErrEmpty
+	}
+
+	return schedule, nil
+}
+
+// headingDate returns the date of a day heading like "20 окт 2025, понедельник" as 02.01.2006
+func headingDate(heading string) (string, bool) {
+	fields := strings.Fields(strings.Split(heading, ",")[0])
+	// the month is matched by its first three letters, six bytes in Cyrillic
+	if len(fields) != 3 || len(fields[1]) < 6 {
+		return "", false
+	}
+
+	date := utils.GetDate(strings.Join(fields, " "))
+	d, err := time.Parse("2.01.2006", date)
+	if err != nil {
+		return "", false
+	}
+
+	return d.Format("02.01.2006"), true
+}
+
+// parseLesson reads a row of the day table, a row without the number
+// continues the lesson above it like on the site
+func parseLesson(row *goquery.Selection, before []model.Lesson) (model.Lesson, bool) {
+	var lesson model.Lesson
+	row.Find("td").Each(func(_ int, cell *goquery.Selection) {
+		text := strings.TrimSpace(cell.Text())
+		switch title, _ := cell.Attr("data-title"); title {
+		case "Номер урока":
+			lesson.Num = text
+		case "Время":
+			lesson.Time = text
+		case "Название предмета":
+			lesson.Name = text
+			for _, subgroup := range []string{"1", "2"} {
+				if strings.HasSuffix(text, "("+subgroup+")") {
+					lesson.Subgroup = subgroup
+					lesson.Name = strings.TrimSpace(strings.TrimSuffix(text, "("+subgroup+")"))
+				}
+			}
+		case "Кабинет":
+			lesson.Room = text
+		case "Преподаватель":
+			lesson.Teacher = text
+		}
+	})
+
+	if lesson.Num == "" && len(before) > 0 {
+		lesson.Num = before[len(before)-1].Num
+	}
+
+	return lesson, lesson.Num != "" || lesson.Name != ""
+}
+
+// Conflicts finds the lessons of the draft taking a room or a teacher twice at
+// the same time, within the draft and against the schedules of other groups.
+// A lesson of several groups held together, with the same name and room, is
+// not a conflict
+func Conflicts(schedule []model.Schedule, group string, others map[string][]model.Schedule, date func(model.Schedule) (time.Time, bool)) []Conflict {
+	type slot struct {
+		date string
+		num  string
+	}
+
+	taken := make(map[slot][]model.Lesson)
+	for other, days := range others {
+		if other == group {
+			continue
+		}
+
+		for _, day := range days {
+			d, ok := date(day)
+			if !ok {
+				continue
+			}
+
+			for _, lesson := range day.Lessons {
+				lesson.Group = other
+				key := slot{d.Format("02.01.2006"), lesson.Num}
+				taken[key] = append(taken[key], lesson)
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for _, day := range schedule {
+		d, ok := date(day)
+		if !ok {
+			continue
+		}
+		dayDate := d.Format("02.01.2006")
+
+		for i, lesson := range day.Lessons {
+			if lesson.Num == "" || lesson.Time == "" || lesson.Name == "" || lesson.Room == "" || lesson.Teacher == "" {
+				conflicts = append(conflicts, Conflict{Kind: KindIncomplete, Date: dayDate, Num: lesson.Num, Lessons: []model.Lesson{lesson}})
+			}
+
+			// lessons of the draft are compared once, with the ones after them
+			var same []model.Lesson
+			for _, other := range day.Lessons[i+1:] {
+				if other.Num == lesson.Num {
+					same = append(same, other)
+				}
+			}
+			same = append(same, taken[slot{dayDate, lesson.Num}]...)
+
+			for _, other := range same {
+				together := strings.EqualFold(other.Name, lesson.Name) && equal(other.Room, lesson.Room)
+				switch {
+				case together:
+				case equal(other.Room, lesson.Room):
+					conflicts = append(conflicts, Conflict{Kind: KindRoom, Date: dayDate, Num: lesson.Num, Value: lesson.Room, Lessons: []model.Lesson{lesson, other}})
+				case equal(other.Teacher, lesson.Teacher):
+					conflicts = append(conflicts, Conflict{Kind: KindTeacher, Date: dayDate, Num: lesson.Num, Value: lesson.Teacher, Lessons: []model.Lesson{lesson, other}})
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// equal compares rooms or teachers, an empty one is never equal
+func equal(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	return a != "" && strings.EqualFold(a, b)
+}
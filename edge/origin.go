@@ -0,0 +1,83 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const timeout = time.Second * 15
+
+// Origin reads the data from the API of the origin instance
+type Origin struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewOrigin creates a client of the origin API, e.g. https://example.com/api/hmtpk
+func NewOrigin(url, apiKey string) *Origin {
+	return &Origin{url: strings.TrimSuffix(url, "/"), apiKey: apiKey, client: &http.Client{Timeout: timeout}}
+}
+
+func (o *Origin) call(ctx context.Context, path string, params url.Values, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if o.apiKey != "" {
+		request.Header.Set("X-API-Key", o.apiKey)
+	}
+
+	resp, err := o.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		return hmtpkErrors.ErrorBadRequest
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%w: origin: %s", hmtpkErrors.ErrorBadResponse, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (o *Origin) GetGroupOptions(ctx context.Context) (options []model.Option, err error) {
+	err = o.call(ctx, "/groups", nil, &options)
+	return
+}
+
+func (o *Origin) GetTeacherOptions(ctx context.Context) (options []model.Option, err error) {
+	err = o.call(ctx, "/teachers", nil, &options)
+	return
+}
+
+func (o *Origin) GetScheduleByGroup(ctx context.Context, group, date string) (schedule []model.Schedule, err error) {
+	err = o.call(ctx, "/schedule", url.Values{"group": {group}, "date": {date}, "key": {"edge"}}, &schedule)
+	return
+}
+
+func (o *Origin) GetScheduleByTeacher(ctx context.Context, teacher, date string) (schedule []model.Schedule, err error) {
+	err = o.call(ctx, "/schedule", url.Values{"teacher": {teacher}, "date": {date}, "key": {"edge"}}, &schedule)
+	return
+}
+
+func (o *Origin) GetAnnounces(ctx context.Context, page int) (announces model.Announces, err error) {
+	err = o.call(ctx, "/announces", url.Values{"page": {strconv.Itoa(page)}}, &announces)
+	return
+}
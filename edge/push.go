@@ -0,0 +1,91 @@
+package edge
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultInterval = time.Minute
+
+// Pusher periodically sends the snapshot of the origin to the edge instances
+type Pusher struct {
+	cfg      Config
+	snapshot func() Snapshot
+	client   *http.Client
+	log      *logrus.Logger
+}
+
+// NewPusher creates a new pusher
+func NewPusher(cfg Config, snapshot func() Snapshot, logger *logrus.Logger) *Pusher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	return &Pusher{cfg: cfg, snapshot: snapshot, client: &http.Client{Timeout: timeout}, log: logger}
+}
+
+// Run pushes the snapshot until the context is canceled
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		snapshot := p.snapshot()
+		if len(snapshot.Entries) == 0 {
+			continue
+		}
+
+		for _, peer := range p.cfg.Peers {
+			if err := p.push(ctx, peer, snapshot); err != nil && ctx.Err() == nil {
+				p.log.Warnf("edge: push to %s: %s", peer, err)
+			}
+		}
+	}
+}
+
+func (p *Pusher) push(ctx context.Context, peer string, snapshot Snapshot) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(snapshot); err != nil {
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(peer, "/")+"/edge/push", &buf)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Encoding", "gzip")
+	request.Header.Set(TokenHeader, p.cfg.Token)
+
+	resp, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("edge: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package edge
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Config is the configuration of the replication between the origin and edge instances
+type Config struct {
+	// Token is the shared secret sent in X-Edge-Token, enables replication when not empty
+	Token string `yaml:"token"`
+	// Peers are the API URLs of the edge instances the origin pushes to
+	Peers []string `yaml:"peers"`
+	// Interval between pushes of the origin
+	Interval time.Duration `yaml:"interval"`
+	// Origin is the API URL of the origin, makes the instance an edge when not empty
+	Origin string `yaml:"origin"`
+	// APIKey is the tenant key the edge uses for misses sent to the origin
+	APIKey string `yaml:"api_key"`
+}
+
+// TokenHeader carries the shared secret of the replication
+const TokenHeader = "X-Edge-Token"
+
+// Entry is the weekly schedule of a group with the time it was fetched from hmtpk.ru
+type Entry struct {
+	Schedule []model.Schedule `json:"schedule"`
+	Updated  time.Time        `json:"updated"`
+}
+
+// Snapshot is the replicated state: the group options and the warmed schedules.
+// Snapshots merge entry by entry with the newest entry winning, so pushes
+// may arrive in any order and any number of times
+type Snapshot struct {
+	Groups        []model.Option   `json:"groups"`
+	GroupsUpdated time.Time        `json:"groups_updated"`
+	Entries       map[string]Entry `json:"entries"`
+}
+
+// DateFunc returns the date of a day of the weekly schedule
+type DateFunc func(day model.Schedule) (time.Time, bool)
+
+// Replica serves the replicated snapshot and falls back to the origin for everything else
+type Replica struct {
+	origin Provider
+	date   DateFunc
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+// Provider is the source of the data served by the API
+type Provider interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// NewReplica creates an empty replica in front of the origin
+func NewReplica(origin Provider, date DateFunc) *Replica {
+	return &Replica{origin: origin, date: date, snapshot: Snapshot{Entries: make(map[string]Entry)}}
+}
+
+// Merge applies the snapshot pushed by the origin
+func (r *Replica) Merge(snapshot Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if snapshot.GroupsUpdated.After(r.snapshot.GroupsUpdated) {
+		r.snapshot.Groups = snapshot.Groups
+		r.snapshot.GroupsUpdated = snapshot.GroupsUpdated
+	}
+
+	for group, entry := range snapshot.Entries {
+		if entry.Updated.After(r.snapshot.Entries[group].Updated) {
+			r.snapshot.Entries[group] = entry
+		}
+	}
+}
+
+// Len returns the number of replicated schedules
+func (r *Replica) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.snapshot.Entries)
+}
+
+func (r *Replica) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	r.mu.RLock()
+	groups, updated := r.snapshot.Groups, r.snapshot.GroupsUpdated
+	r.mu.RUnlock()
+
+	if len(groups) > 0 {
+		stale.Hit(ctx, stale.LayerMemory, time.Since(updated))
+		return groups, nil
+	}
+
+	return r.origin.GetGroupOptions(ctx)
+}
+
+// GetScheduleByGroup serves the replicated week containing the date
+func (r *Replica) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	r.mu.RLock()
+	entry, ok := r.snapshot.Entries[group]
+	r.mu.RUnlock()
+
+	if ok {
+		for _, day := range entry.Schedule {
+			if d, ok := r.date(day); ok && d.Format("02.01.2006") == date {
+				stale.Hit(ctx, stale.LayerMemory, time.Since(entry.Updated))
+				return entry.Schedule, nil
+			}
+		}
+	}
+
+	return r.origin.GetScheduleByGroup(ctx, group, date)
+}
+
+func (r *Replica) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return r.origin.GetTeacherOptions(ctx)
+}
+
+func (r *Replica) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	return r.origin.GetScheduleByTeacher(ctx, teacher, date)
+}
+
+func (r *Replica) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	return r.origin.GetAnnounces(ctx, page)
+}
@@ -0,0 +1,178 @@
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Config is the configuration of recording and replaying the responses of hmtpk.ru
+type Config struct {
+	// Mode is record or replay, disabled when empty
+	Mode string `yaml:"mode"`
+	// Dir keeps one file per request
+	Dir string `yaml:"dir"`
+}
+
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+
+	encodingBase64 = "base64"
+)
+
+// ErrNotRecorded is returned in the replay mode for a request never recorded
+var ErrNotRecorded = errors.New("no recorded response")
+
+// Fixture is a recorded response of hmtpk.ru
+type Fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+	// Encoding is base64 for a body that is not UTF-8 text
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Transport records the responses of hmtpk.ru to the directory or replays
+// them from it without reaching the site. The requests to other hosts pass
+type Transport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// NewTransport validates the configuration and creates the transport
+func NewTransport(cfg Config, next http.RoundTripper) (*Transport, error) {
+	switch cfg.Mode {
+	case ModeRecord:
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, err
+		}
+	case ModeReplay:
+		if _, err := os.Stat(cfg.Dir); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown fixtures mode %q", cfg.Mode)
+	}
+
+	return &Transport{mode: cfg.Mode, dir: cfg.Dir, next: next}, nil
+}
+
+func site(request *http.Request) bool {
+	host := request.URL.Hostname()
+	return host == "hmtpk.ru" || strings.HasSuffix(host, ".hmtpk.ru")
+}
+
+// path returns the file of the request, named by the hash of its method, URL and body
+func (t *Transport) path(request *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, request.Method+" "+request.URL.String()+"\n")
+	_, _ = h.Write(body)
+
+	return filepath.Join(t.dir, hex.EncodeToString(h.Sum(nil))[:32]+".json")
+}
+
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !site(request) {
+		return t.next.RoundTrip(request)
+	}
+
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		_ = request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	path := t.path(request, body)
+	if t.mode == ModeReplay {
+		return replay(request, path)
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(data))
+
+	fixture := Fixture{Method: request.Method, URL: request.URL.String(), Status: response.StatusCode, Header: response.Header, Body: string(data)}
+	if !utf8.Valid(data) {
+		fixture.Body, fixture.Encoding = base64.StdEncoding.EncodeToString(data), encodingBase64
+	}
+
+	if err = write(path, fixture); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// write saves the fixture through a temporary file, a replay never reads half of it
+func write(path string, fixture Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func replay(request *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, request.Method, request.URL)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err = json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	body := []byte(fixture.Body)
+	if fixture.Encoding == encodingBase64 {
+		if body, err = base64.StdEncoding.DecodeString(fixture.Body); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fixture.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package ids
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Config is the configuration of the obfuscation of public identifiers
+type Config struct {
+	// Secret enables the obfuscation, changing it invalidates every public identifier
+	Secret string `yaml:"secret"`
+}
+
+// KindAnnounce is the kind of the identifiers of announces
+const KindAnnounce = "announce"
+
+// ErrInvalid is returned for a public identifier that was not issued by the codec
+var ErrInvalid = errors.New("invalid identifier")
+
+// Codec converts the internal identifiers of a kind to the public ones and back
+type Codec interface {
+	Encode(kind, id string) string
+	Decode(kind, public string) (string, error)
+}
+
+// New returns the codec of the configuration, identifiers are public as is without the secret
+func New(cfg Config) Codec {
+	if cfg.Secret == "" {
+		return Plain{}
+	}
+
+	return &Obfuscated{secret: []byte(cfg.Secret)}
+}
+
+// Plain uses the internal identifiers as the public ones
+type Plain struct{}
+
+func (Plain) Encode(_, id string) string {
+	return id
+}
+
+func (Plain) Decode(_, public string) (string, error) {
+	return public, nil
+}
+
+const (
+	alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// length is the number of base62 digits of a 64-bit block
+	length = 11
+	// maxID bounds the numeric identifiers obfuscated, the bits above it
+	// check that a public identifier was issued by the codec
+	maxID  = 1<<48 - 1
+	rounds = 4
+)
+
+// Obfuscated hides numeric identifiers behind a keyed permutation, like
+// hashids, so neighbouring identifiers cannot be guessed from a known one.
+// Other identifiers are public as is, a numeric one is only accepted
+// obfuscated
+type Obfuscated struct {
+	secret []byte
+}
+
+func (o *Obfuscated) Encode(kind, id string) string {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n > maxID {
+		return id
+	}
+
+	block := o.permute(kind, n, false)
+
+	var b [length]byte
+	for i := length - 1; i >= 0; i-- {
+		b[i] = alphabet[block%62]
+		block /= 62
+	}
+
+	return string(b[:])
+}
+
+func (o *Obfuscated) Decode(kind, public string) (string, error) {
+	if n, err := strconv.ParseUint(public, 10, 64); err == nil {
+		if n <= maxID {
+			return "", ErrInvalid
+		}
+		return public, nil
+	}
+
+	if len(public) != length {
+		return public, nil
+	}
+
+	var block uint64
+	for i := 0; i < length; i++ {
+		digit := strings.IndexByte(alphabet, public[i])
+		if digit < 0 {
+			return public, nil
+		}
+
+		next := block*62 + uint64(digit)
+		if next/62 != block {
+			// larger than a block, not issued by the codec
+			return public, nil
+		}
+		block = next
+	}
+
+	n := o.permute(kind, block, true)
+	if n > maxID {
+		return public, nil
+	}
+
+	return strconv.FormatUint(n, 10), nil
+}
+
+// permute runs the Feistel network keyed by the secret and the kind, so the
+// same number has different public identifiers in different kinds
+func (o *Obfuscated) permute(kind string, block uint64, inverse bool) uint64 {
+	left, right := uint32(block>>32), uint32(block)
+	for i := 0; i < rounds; i++ {
+		round := i
+		if inverse {
+			round = rounds - 1 - i
+			left, right = right^o.round(kind, round, left), left
+			continue
+		}
+		left, right = right, left^o.round(kind, round, right)
+	}
+
+	return uint64(left)<<32 | uint64(right)
+}
+
+func (o *Obfuscated) round(kind string, round int, half uint32) uint32 {
+	mac := hmac.New(sha256.New, o.secret)
+	var b [5]byte
+	b[0] = byte(round)
+	binary.BigEndian.PutUint32(b[1:], half)
+	mac.Write([]byte(kind))
+	mac.Write(b[:])
+
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
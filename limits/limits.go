@@ -0,0 +1,130 @@
+package limits
+
+import (
+	"net/http"
+	"time"
+)
+
+// Config is the configuration of the limits of the HTTP server
+type Config struct {
+	// MaxBodyBytes is the largest request body accepted
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// MaxInFlight is the number of requests served at once, the ones above it
+	// are answered with 503. Negative disables the cap
+	MaxInFlight int `yaml:"max_in_flight"`
+	// ReadHeaderTimeout bounds reading the headers of a request
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	// ReadTimeout bounds reading the whole request, body included
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// WriteTimeout bounds serving a request, it must outlast the CPU profiles
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// IdleTimeout is how long a kept-alive connection waits for the next request
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+const (
+	defaultMaxBodyBytes      = 16 << 20
+	defaultMaxInFlight       = 512
+	defaultReadHeaderTimeout = time.Second * 10
+	defaultReadTimeout       = time.Second * 30
+	defaultWriteTimeout      = time.Minute
+	defaultIdleTimeout       = time.Minute * 2
+)
+
+// WithDefaults returns the configuration with the unset limits set to the defaults
+func (c Config) WithDefaults() Config {
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaultMaxBodyBytes
+	}
+
+	if c.MaxInFlight == 0 {
+		c.MaxInFlight = defaultMaxInFlight
+	}
+
+	if c.ReadHeaderTimeout <= 0 {
+		c.ReadHeaderTimeout = defaultReadHeaderTimeout
+	}
+
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = defaultReadTimeout
+	}
+
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = defaultWriteTimeout
+	}
+
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = defaultIdleTimeout
+	}
+
+	return c
+}
+
+// Server returns the HTTP server with the timeouts of the configuration, a
+// slow client can't hold a connection forever
+func (c Config) Server(addr string, handler http.Handler) *http.Server {
+	c = c.WithDefaults()
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
+}
+
+// Limiter caps the size of the request bodies and the requests in flight
+type Limiter struct {
+	maxBody  int64
+	inFlight chan struct{}
+}
+
+// New creates the limiter of the configuration
+func New(cfg Config) *Limiter {
+	cfg = cfg.WithDefaults()
+
+	l := &Limiter{maxBody: cfg.MaxBodyBytes}
+	if cfg.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+
+	return l
+}
+
+// Acquire takes a slot for a request in flight, false when the server is
+// saturated. A taken slot is given back with Release
+func (l *Limiter) Acquire() bool {
+	if l.inFlight == nil {
+		return true
+	}
+
+	select {
+	case l.inFlight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release gives back the slot of a request
+func (l *Limiter) Release() {
+	if l.inFlight != nil {
+		<-l.inFlight
+	}
+}
+
+// TooLarge reports whether the declared body of the request is over the
+// limit, otherwise the body is capped at it for the chunked ones
+func (l *Limiter) TooLarge(w http.ResponseWriter, r *http.Request) bool {
+	if r.ContentLength > l.maxBody {
+		return true
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, l.maxBody)
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package links
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Builder builds the absolute URLs of the API given out to clients. With the
+// public base URL configured links are independent of the request, which is
+// required behind CDNs rewriting the host, otherwise they are guessed from the
+// request and the X-Forwarded-* headers of the reverse proxy
+type Builder struct {
+	base   string
+	prefix string
+}
+
+// New creates a builder of links to the API served under the prefix, the base
+// is the public URL of the service like https://example.com, optionally with
+// the path a proxy strips
+func New(base, prefix string) (*Builder, error) {
+	b := &Builder{prefix: strings.TrimSuffix(prefix, "/")}
+	if base == "" {
+		return b, nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("public url: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("public url %q: must be an http or https URL without query", base)
+	}
+
+	b.base = strings.TrimSuffix(u.String(), "/")
+
+	return b, nil
+}
+
+// Base returns the public URL of the API itself
+func (b *Builder) Base(r *http.Request) string {
+	return b.URL(r, "")
+}
+
+// URL returns the absolute URL of the path of the API. The request is used
+// only without the public base URL, a nil one gives a link relative to the host
+func (b *Builder) URL(r *http.Request, path string) string {
+	if b.base != "" {
+		return b.base + b.prefix + path
+	}
+
+	if r == nil {
+		return b.prefix + path
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if v := r.Header.Get("X-Forwarded-Proto"); v != "" {
+		scheme = strings.TrimSpace(strings.Split(v, ",")[0])
+	}
+
+	host := r.Host
+	if v := r.Header.Get("X-Forwarded-Host"); v != "" {
+		host = strings.TrimSpace(strings.Split(v, ",")[0])
+	}
+
+	prefix := strings.TrimSuffix(r.Header.Get("X-Forwarded-Prefix"), "/")
+
+	return scheme + "://" + host + prefix + b.prefix + path
+}
@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Access is the configuration of the access log
+type Access struct {
+	// Path is the file of the access log, stdout writes it to the standard
+	// output. The access log is disabled when empty
+	Path string `yaml:"path"`
+	// MaxSize is the size in megabytes at which the file is rotated
+	MaxSize int `yaml:"max_size"`
+	// MaxAge is the number of days rotated files are kept
+	MaxAge int `yaml:"max_age"`
+	// MaxBackups is the number of rotated files kept
+	MaxBackups int `yaml:"max_backups"`
+	// Compress gzips rotated files
+	Compress bool `yaml:"compress"`
+}
+
+// AccessStdout is the path of the access log written to the standard output
+const AccessStdout = "stdout"
+
+// redactedParams are the query parameters carrying secrets
+var redactedParams = map[string]bool{"token": true}
+
+const redacted = "******"
+
+// AccessRecord is the JSON record of a request in the access log
+type AccessRecord struct {
+	Time      time.Time           `json:"time"`
+	Method    string              `json:"method"`
+	Path      string              `json:"path"`
+	Params    map[string][]string `json:"params,omitempty"`
+	Status    int                 `json:"status"`
+	Duration  float64             `json:"duration_ms"`
+	Bytes     int                 `json:"bytes"`
+	ClientIP  string              `json:"client_ip"`
+	RequestID string              `json:"request_id,omitempty"`
+	Cache     string              `json:"cache,omitempty"`
+	UserAgent string              `json:"user_agent,omitempty"`
+}
+
+// AccessLog writes a JSON record per request, one per line, apart from the
+// application logs for ingestion into Loki or ELK
+type AccessLog struct {
+	mu  sync.Mutex
+	out io.Writer
+	// close closes the file of the access log
+	close func() error
+}
+
+// NewAccessLog opens the access log of the configuration
+func NewAccessLog(cfg Access) (*AccessLog, error) {
+	if cfg.Path == AccessStdout {
+		return &AccessLog{out: os.Stdout, close: func() error { return nil }}, nil
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSize,
+		MaxAge:     cfg.MaxAge,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+		LocalTime:  true,
+	}
+
+	// lumberjack opens the file lazily, open it now to fail fast on a bad path
+	if _, err := file.Write(nil); err != nil {
+		return nil, err
+	}
+
+	return &AccessLog{out: file, close: file.Close}, nil
+}
+
+// Close closes the access log
+func (l *AccessLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.close()
+}
+
+// Middleware writes the record of every request after it is served, the
+// request ID and the cache status are taken from the X-Request-ID and
+// X-Cache headers of the response
+func (l *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		record := AccessRecord{
+			Time:      start.UTC(),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Params:    params(r),
+			Status:    ww.Status(),
+			Duration:  float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:     ww.BytesWritten(),
+			ClientIP:  clientIP(r),
+			RequestID: ww.Header().Get("X-Request-ID"),
+			Cache:     ww.Header().Get("X-Cache"),
+			UserAgent: r.UserAgent(),
+		}
+
+		// a handler writing nothing answers 200
+		if record.Status == 0 {
+			record.Status = http.StatusOK
+		}
+
+		l.write(record)
+	})
+}
+
+func (l *AccessLog) write(record AccessRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, _ = l.out.Write(append(data, '\n'))
+}
+
+// params returns the query parameters with the secrets redacted
+func params(r *http.Request) map[string][]string {
+	query := r.URL.Query()
+	if len(query) == 0 {
+		return nil
+	}
+
+	for name := range query {
+		if redactedParams[name] {
+			query[name] = []string{redacted}
+		}
+	}
+
+	return query
+}
+
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
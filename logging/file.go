@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// File is the configuration of the log file output
+type File struct {
+	// Path enables file logging when not empty
+	Path string `yaml:"path"`
+	// MaxSize is the size in megabytes at which the file is rotated
+	MaxSize int `yaml:"max_size"`
+	// MaxAge is the number of days rotated files are kept
+	MaxAge int `yaml:"max_age"`
+	// MaxBackups is the number of rotated files kept
+	MaxBackups int `yaml:"max_backups"`
+	// Compress gzips rotated files
+	Compress bool `yaml:"compress"`
+	// RotateEvery rotates the file periodically regardless of its size
+	RotateEvery time.Duration `yaml:"rotate_every"`
+}
+
+// SetFile duplicates the output of the logger to a rotated file.
+// The returned function stops the rotation and closes the file
+func SetFile(log *logrus.Logger, cfg File) (func(), error) {
+	if cfg.Path == "" {
+		return func() {}, nil
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSize,
+		MaxAge:     cfg.MaxAge,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+		LocalTime:  true,
+	}
+
+	// lumberjack opens the file lazily, open it now to fail fast on a bad path
+	if _, err := file.Write(nil); err != nil {
+		return nil, err
+	}
+
+	log.SetOutput(io.MultiWriter(os.Stderr, file))
+
+	done := make(chan struct{})
+	if cfg.RotateEvery > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.RotateEvery)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if err := file.Rotate(); err != nil {
+						log.Error(err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	return func() {
+		close(done)
+		log.SetOutput(os.Stderr)
+		_ = file.Close()
+	}, nil
+}
@@ -0,0 +1,205 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Loki is the configuration of the Loki push output
+type Loki struct {
+	// URL is the base URL of Loki, enables the output when not empty
+	URL string `yaml:"url"`
+	// TenantID is sent as X-Scope-OrgID for multi-tenant Loki
+	TenantID string `yaml:"tenant_id"`
+	// Labels are attached to every stream in addition to the level
+	Labels map[string]string `yaml:"labels"`
+	// BatchSize is the number of entries sent in one push
+	BatchSize int `yaml:"batch_size"`
+	// BatchWait is the longest time an entry waits before being pushed
+	BatchWait time.Duration `yaml:"batch_wait"`
+}
+
+const (
+	lokiPushPath  = "/loki/api/v1/push"
+	lokiBuffer    = 1024
+	lokiBatchSize = 100
+	lokiBatchWait = time.Second
+	lokiTimeout   = time.Second * 5
+)
+
+type lokiEntry struct {
+	level string
+	time  time.Time
+	line  string
+}
+
+type lokiHook struct {
+	cfg       Loki
+	client    *http.Client
+	formatter logrus.Formatter
+	entries   chan lokiEntry
+	done      chan struct{}
+}
+
+// AddLoki pushes the entries of the logger to Loki in batches.
+// Entries are dropped when Loki can't keep up so logging never blocks
+func AddLoki(log *logrus.Logger, cfg Loki) (func(), error) {
+	if cfg.URL == "" {
+		return func() {}, nil
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = lokiBatchSize
+	}
+
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = lokiBatchWait
+	}
+
+	hook := &lokiHook{
+		cfg:       cfg,
+		client:    &http.Client{Timeout: lokiTimeout},
+		formatter: &logrus.JSONFormatter{},
+		entries:   make(chan lokiEntry, lokiBuffer),
+		done:      make(chan struct{}),
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		hook.run()
+	}()
+
+	log.AddHook(hook)
+
+	return func() {
+		close(hook.done)
+		<-stopped
+	}, nil
+}
+
+// Levels returns the levels the hook fires for
+func (h *lokiHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire queues the entry for the next push
+func (h *lokiHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case h.entries <- lokiEntry{level: entry.Level.String(), time: entry.Time, line: strings.TrimSpace(string(line))}:
+	default:
+	}
+
+	return nil
+}
+
+func (h *lokiHook) run() {
+	ticker := time.NewTicker(h.cfg.BatchWait)
+	defer ticker.Stop()
+
+	batch := make([]lokiEntry, 0, h.cfg.BatchSize)
+	for {
+		select {
+		case entry := <-h.entries:
+			batch = append(batch, entry)
+			if len(batch) >= h.cfg.BatchSize {
+				h.push(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				h.push(batch)
+				batch = batch[:0]
+			}
+		case <-h.done:
+			for len(h.entries) > 0 {
+				batch = append(batch, <-h.entries)
+			}
+
+			if len(batch) > 0 {
+				h.push(batch)
+			}
+			return
+		}
+	}
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends the batch grouped into one stream per level. Errors are
+// written to stderr, logging them would feed them back into the hook
+func (h *lokiHook) push(batch []lokiEntry) {
+	streams := make(map[string]*lokiStream)
+	for _, entry := range batch {
+		stream, ok := streams[entry.level]
+		if !ok {
+			labels := make(map[string]string, len(h.cfg.Labels)+1)
+			for key, value := range h.cfg.Labels {
+				labels[key] = value
+			}
+			labels["level"] = entry.level
+
+			stream = &lokiStream{Stream: labels}
+			streams[entry.level] = stream
+		}
+
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line})
+	}
+
+	body := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, stream := range streams {
+		body.Streams = append(body.Streams, stream)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loki:", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lokiTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(h.cfg.URL, "/")+lokiPushPath, bytes.NewReader(data))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loki:", err)
+		return
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	if h.cfg.TenantID != "" {
+		request.Header.Set("X-Scope-OrgID", h.cfg.TenantID)
+	}
+
+	resp, err := h.client.Do(request)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loki:", err)
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		fmt.Fprintln(os.Stderr, "loki:", resp.Status)
+	}
+}
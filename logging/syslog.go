@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Syslog is the configuration of the RFC 5424 syslog output
+type Syslog struct {
+	// Network is udp, tcp or unix
+	Network string `yaml:"network"`
+	// Addr enables the syslog output when not empty
+	Addr string `yaml:"addr"`
+	// AppName is the APP-NAME of the messages
+	AppName string `yaml:"app_name"`
+	// Facility is the numeric syslog facility, 1 (user) by default
+	Facility int `yaml:"facility"`
+}
+
+const (
+	syslogTimeout = time.Second
+	// syslogSDID is the structured data ID used for the entry fields
+	syslogSDID = "fields@32473"
+)
+
+type syslogHook struct {
+	mu       sync.Mutex
+	cfg      Syslog
+	hostname string
+	conn     net.Conn
+}
+
+// AddSyslog sends the entries of the logger to a syslog server
+func AddSyslog(log *logrus.Logger, cfg Syslog) (func(), error) {
+	if cfg.Addr == "" {
+		return func() {}, nil
+	}
+
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+
+	if cfg.AppName == "" {
+		cfg.AppName = "hmtpk-parser-api"
+	}
+
+	if cfg.Facility == 0 {
+		cfg.Facility = 1
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	hook := &syslogHook{cfg: cfg, hostname: hostname}
+	if err = hook.connect(); err != nil {
+		return nil, err
+	}
+
+	log.AddHook(hook)
+
+	return func() {
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+
+		if hook.conn != nil {
+			_ = hook.conn.Close()
+			hook.conn = nil
+		}
+	}, nil
+}
+
+func (h *syslogHook) connect() (err error) {
+	h.conn, err = net.DialTimeout(h.cfg.Network, h.cfg.Addr, syslogTimeout)
+	return
+}
+
+// Levels returns the levels the hook fires for
+func (h *syslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes the entry, reconnecting once if the connection was lost
+func (h *syslogHook) Fire(entry *logrus.Entry) error {
+	message := h.format(entry)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.conn == nil {
+		if err := h.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := h.write(message); err != nil {
+		_ = h.conn.Close()
+		if err = h.connect(); err != nil {
+			h.conn = nil
+			return err
+		}
+
+		return h.write(message)
+	}
+
+	return nil
+}
+
+func (h *syslogHook) write(message string) error {
+	_ = h.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+
+	// stream transports use octet counting framing (RFC 6587)
+	if h.cfg.Network == "tcp" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	_, err := h.conn.Write([]byte(message))
+	return err
+}
+
+// format builds the RFC 5424 message of the entry
+func (h *syslogHook) format(entry *logrus.Entry) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s",
+		h.cfg.Facility*8+severity(entry.Level),
+		entry.Time.Format(time.RFC3339Nano),
+		h.hostname,
+		h.cfg.AppName,
+		os.Getpid(),
+		structuredData(entry.Data),
+		entry.Message,
+	)
+}
+
+func severity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func structuredData(fields logrus.Fields) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("[" + syslogSDID)
+	for _, key := range keys {
+		fmt.Fprintf(&b, ` %s="%s"`, key, sdEscaper.Replace(fmt.Sprint(fields[key])))
+	}
+	b.WriteString("]")
+
+	return b.String()
+}
@@ -1,18 +1,91 @@
+// Command hmtpk-parser-api serves the HTTP API of the hmtpk.ru parser.
+//
+// Optional features can be excluded from the binary with build tags:
+// no_grpc (gRPC server and protobuf responses), no_render (PDF and PNG
+// schedules, announce thumbnails) and no_xlsx (spreadsheet export).
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
+	"github.com/chazari-x/hmtpk-parser-api/announce"
 	"github.com/chazari-x/hmtpk-parser-api/api"
+	"github.com/chazari-x/hmtpk-parser-api/app"
+	"github.com/chazari-x/hmtpk-parser-api/archive"
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	"github.com/chazari-x/hmtpk-parser-api/cabinet"
+	"github.com/chazari-x/hmtpk-parser-api/cache"
+	"github.com/chazari-x/hmtpk-parser-api/config"
+	"github.com/chazari-x/hmtpk-parser-api/edge"
+	"github.com/chazari-x/hmtpk-parser-api/fixture"
+	"github.com/chazari-x/hmtpk-parser-api/ids"
+	"github.com/chazari-x/hmtpk-parser-api/links"
+	"github.com/chazari-x/hmtpk-parser-api/logging"
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/metrics"
+	"github.com/chazari-x/hmtpk-parser-api/mirror"
+	"github.com/chazari-x/hmtpk-parser-api/mock"
+	"github.com/chazari-x/hmtpk-parser-api/moodle"
+	"github.com/chazari-x/hmtpk-parser-api/notify"
+	"github.com/chazari-x/hmtpk-parser-api/peak"
+	"github.com/chazari-x/hmtpk-parser-api/plugin"
+	"github.com/chazari-x/hmtpk-parser-api/rediscache"
+	"github.com/chazari-x/hmtpk-parser-api/replay"
+	"github.com/chazari-x/hmtpk-parser-api/replica"
+	"github.com/chazari-x/hmtpk-parser-api/requestid"
+	"github.com/chazari-x/hmtpk-parser-api/retry"
+	"github.com/chazari-x/hmtpk-parser-api/rollover"
+	"github.com/chazari-x/hmtpk-parser-api/sentry"
+	"github.com/chazari-x/hmtpk-parser-api/stableid"
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	"github.com/chazari-x/hmtpk-parser-api/stats"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/translate"
+	"github.com/chazari-x/hmtpk-parser-api/vault"
+	"github.com/chazari-x/hmtpk-parser-api/warmup"
+	"github.com/chazari-x/hmtpk-parser-api/window"
+	hmtpk "github.com/chazari-x/hmtpk_parser/v2"
+	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	redisPingTimeout = time.Second * 2
+	shutdownTimeout  = time.Second * 15
+
+	// the pool of a single Redis: a slow Redis fails the cache lookup fast
+	// instead of holding the request
+	redisDialTimeout  = time.Second * 5
+	redisReadTimeout  = time.Second * 3
+	redisWriteTimeout = time.Second * 3
+	redisMinIdleConns = 2
+
+	// storePrefix keeps the data of the service apart from the parser cache in Redis
+	storePrefix = "hmtpk-api:"
+)
+
 func main() {
+	profile := flag.String("profile", "", "configuration profile: dev, staging or prod, none by default")
+	dir := flag.String("config", ".", "directory with config.yaml and config.<profile>.yaml")
+	mocked := flag.Bool("mock", false, "serve synthetic data without contacting hmtpk.ru")
+	flag.Parse()
+
 	log := logrus.New()
+	log.AddHook(requestid.Hook{})
 
 	log.SetLevel(logrus.TraceLevel)
 	log.SetReportCaller(true)
@@ -25,16 +98,714 @@ func main() {
 		},
 	})
 
+	cfg, err := config.Load(*profile, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *mocked {
+		cfg.Mock = true
+	}
+
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetLevel(level)
+
+	if args := flag.Args(); len(args) > 0 {
+		if len(args) == 2 && args[0] == "config" && args[1] == "print" {
+			if err = cfg.Print(os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		log.Fatalf("unknown command %q", args)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Optional subsystems never prevent the core API from starting:
+	// a failure is logged and the related feature is disabled
+	subsystems := app.NewSubsystems(log)
+	defer subsystems.Close()
+
+	if cfg.LogFile.Path != "" {
+		subsystems.Start("log_file", func() (func(), error) {
+			return logging.SetFile(log, cfg.LogFile)
+		})
+	}
+
+	if cfg.Syslog.Addr != "" {
+		subsystems.Start("syslog", func() (func(), error) {
+			return logging.AddSyslog(log, cfg.Syslog)
+		})
+	}
+
+	if cfg.Loki.URL != "" {
+		subsystems.Start("loki", func() (func(), error) {
+			return logging.AddLoki(log, cfg.Loki)
+		})
+	}
+
+	// the unexpected errors, e.g. after a change of the layout of the site, are reported to Sentry
+	var reporter *sentry.Reporter
+	if cfg.Sentry.DSN != "" {
+		subsystems.Start("sentry", func() (func(), error) {
+			if cfg.Sentry.Environment == "" {
+				cfg.Sentry.Environment = cfg.Profile
+			}
+
+			var err error
+			if reporter, err = sentry.New(cfg.Sentry, log); err != nil {
+				return nil, err
+			}
+
+			return reporter.Close, nil
+		})
+	}
+
+	// client is the single Redis shared with the parser, shared is the Redis
+	// or the Redis Cluster keeping the data of the service
+	var (
+		client *redis.Client
+		shared redis.UniversalClient
+	)
+	if cfg.Redis.URL != "" || cfg.Redis.Addr != "" || cfg.Redis.Sentinel.MasterName != "" || len(cfg.Redis.Cluster) > 0 {
+		subsystems.Start("redis", func() (func(), error) {
+			c, single, err := newRedis(cfg.Redis)
+			if err != nil {
+				return nil, err
+			}
+
+			pingCtx, cancel := context.WithTimeout(ctx, redisPingTimeout)
+			defer cancel()
+
+			if err := c.Ping(pingCtx).Err(); err != nil {
+				_ = c.Close()
+				return nil, err
+			}
+
+			shared = c
+			if single != nil {
+				client = single
+			} else {
+				log.Warn("The parser caches in a single Redis only, it runs without its cache on the cluster")
+			}
+
+			return func() {
+				_ = c.Close()
+			}, nil
+		})
+	}
+
+	if shared == nil {
+		log.Warn("Running without Redis: every request reaches hmtpk.ru and the data of the service is lost on restart, set REDIS_URL to enable the cache")
+	}
+
 	r := chi.NewRouter()
 
-	a := api.NewApi(nil, log)
+	// every request is traced by its ID through the log lines and the upstream calls
+	r.Use(requestid.Middleware)
+
+	if cfg.AccessLog.Path != "" {
+		subsystems.Start("access_log", func() (func(), error) {
+			access, err := logging.NewAccessLog(cfg.AccessLog)
+			if err != nil {
+				return nil, err
+			}
+
+			r.Use(access.Middleware)
+
+			return func() {
+				_ = access.Close()
+			}, nil
+		})
+	}
+
+	// the requests are recorded for capacity replays against this instance
+	var replayer *replay.Replayer
+	if cfg.Replay.Dir != "" {
+		subsystems.Start("replay", func() (func(), error) {
+			recorder, err := replay.NewRecorder(cfg.Replay, log)
+			if err != nil {
+				return nil, err
+			}
+
+			r.Use(recorder.Middleware)
+			replayer = replay.NewReplayer(cfg.Replay, r, log)
+
+			return func() {
+				replayer.Close()
+				recorder.Close()
+			}, nil
+		})
+	}
+
+	var collector *metrics.Metrics
+	if cfg.Metrics.Path != "" {
+		subsystems.Start("metrics", func() (func(), error) {
+			m, err := metrics.New(cfg.Metrics)
+			if err != nil {
+				return nil, err
+			}
+
+			collector = m
+			r.Use(m.Middleware)
+
+			return nil, nil
+		})
+	}
+
+	// the weekly report is assembled from the counters of the requests and the fetches
+	var counters *stats.Stats
+	if len(cfg.Report.To) > 0 {
+		counters = stats.New()
+		r.Use(counters.Middleware)
+	}
+
+	readOnly := cfg.Role == replica.RoleReplica
+	switch {
+	case cfg.Role != "" && cfg.Role != replica.RolePrimary && !readOnly:
+		log.Fatalf("unknown role %q", cfg.Role)
+	case readOnly && client == nil:
+		log.Fatal("the replica role requires Redis shared with the primary")
+	}
+
+	// a configured cache backend replaces the Redis cache of the parser
+	var backend cache.Cache
+	switch cfg.Cache.Backend {
+	case "":
+	case cache.BackendRedis:
+		if shared == nil {
+			log.Fatal("the redis cache backend requires Redis")
+		}
+		backend = cache.NewRedis(shared, storePrefix+"cache:")
+	case cache.BackendMemory:
+		backend = cache.NewMemory()
+	case cache.BackendMemcached:
+		if len(cfg.Cache.Memcached) == 0 {
+			log.Fatal("the memcached cache backend requires the addresses of the servers")
+		}
+		backend = cache.NewMemcached(storePrefix, cfg.Cache.Memcached...)
+	default:
+		log.Fatalf("unknown cache backend %q", cfg.Cache.Backend)
+	}
+
+	parserClient := client
+	if backend != nil {
+		parserClient = nil
+	}
+	var provider api.ScheduleProvider = hmtpk.NewController(parserClient, log)
+
+	// frontend and bot developers build against synthetic data offline and in CI
+	if cfg.Mock {
+		provider = mock.NewSource()
+		log.Warn("Serving synthetic mock data, hmtpk.ru is never contacted")
+	}
+
+	// a source plugin replaces hmtpk.ru, the layers below wrap it like the parser
+	sources := 0
+	for _, p := range cfg.Plugins {
+		if p.Kind == plugin.KindSource {
+			sources++
+		}
+	}
+	if sources > 1 {
+		log.Fatalf("only one source plugin can be configured, got %d", sources)
+	}
+
+	for _, p := range cfg.Plugins {
+		subsystems.Start("plugin "+p.Name, func() (func(), error) {
+			if p.Kind != plugin.KindSource {
+				return nil, fmt.Errorf("unknown plugin kind %q", p.Kind)
+			}
+
+			process, err := plugin.Start(p, log)
+			if err != nil {
+				return nil, err
+			}
+
+			provider = plugin.NewSource(process)
+			log.Infof("Using plugin %s as the source provider", p.Name)
+
+			return process.Close, nil
+		})
+	}
+
+	// hmtpk.ru is not fetched during its maintenance windows, the cached and
+	// the stored data is served instead
+	var windows *window.Set
+	if len(cfg.Maintenance.Windows) > 0 && !cfg.Mock {
+		subsystems.Start("maintenance", func() (func(), error) {
+			w, err := window.New(cfg.Maintenance.Windows)
+			if err != nil {
+				return nil, err
+			}
+
+			windows = w
+			provider = maintenance.NewProvider(provider, w)
+
+			return nil, nil
+		})
+	}
+
+	// the fetches reaching hmtpk.ru take the upstream budget of the tenant of
+	// the request, the cache hits above take none
+	provider = api.LimitUpstream(provider)
+
+	// the cache gets the configured expiration and is reported in X-Cache
+	var cached interface {
+		SetLocation(*time.Location)
+		SetTTL(func(time.Duration) time.Duration)
+	}
+	switch {
+	case readOnly:
+	case backend != nil:
+		p := cache.NewProvider(cfg.Cache.TTL, provider, backend, cfg.Cache.Backend, log)
+		cached, provider = p, p
+		log.Infof("Caching in %s", cfg.Cache.Backend)
+	case client != nil:
+		p := rediscache.NewProvider(cfg.Cache.TTL, provider, client)
+		cached, provider = p, p
+	}
+	if readOnly {
+		provider = replica.NewReader(client)
+		log.Info("Serving as a read-only replica")
+	}
+
+	// an edge serves the snapshot pushed by the origin and sends misses to it
+	var edgeReplica *edge.Replica
+	if cfg.Edge.Origin != "" {
+		edgeReplica = edge.NewReplica(edge.NewOrigin(cfg.Edge.Origin, cfg.Edge.APIKey), api.DayDate)
+		provider = edgeReplica
+		log.Infof("Serving as an edge of %s", cfg.Edge.Origin)
+	}
+
+	// the parser reaches hmtpk.ru with the default transport, the layers are
+	// wrapped around it from the site outwards and installed once below
+	transport := http.DefaultTransport
+	if cfg.Mock {
+		transport = mock.NewTransport(transport)
+	}
+
+	// the requests to hmtpk.ru fail over to the mirrors
+	var mirrors *mirror.Transport
+	if len(cfg.Mirrors.URLs)+len(cfg.Mirrors.Addresses) > 0 && !readOnly && !cfg.Mock {
+		subsystems.Start("mirrors", func() (func(), error) {
+			t, err := mirror.NewTransport(cfg.Mirrors, transport)
+			if err != nil {
+				return nil, err
+			}
+
+			mirrors, transport = t, t
+			log.Infof("Using %d mirrors and %d addresses of hmtpk.ru", len(cfg.Mirrors.URLs), len(cfg.Mirrors.Addresses))
+
+			return nil, nil
+		})
+	}
+
+	// the responses of hmtpk.ru are recorded to fixtures or replayed from them,
+	// a repeatable harness for the integration tests
+	if cfg.Fixtures.Mode != "" && !cfg.Mock {
+		subsystems.Start("fixtures", func() (func(), error) {
+			t, err := fixture.NewTransport(cfg.Fixtures, transport)
+			if err != nil {
+				return nil, err
+			}
+
+			transport = t
+			log.Infof("Fixtures of hmtpk.ru: %s in %s", cfg.Fixtures.Mode, cfg.Fixtures.Dir)
+
+			return nil, nil
+		})
+	}
+
+	// the upstream calls are logged with the ID of the request making them
+	http.DefaultTransport = requestid.NewTransport(transport, log)
+
+	var store storage.Store = storage.NewMemory()
+	if shared != nil {
+		store = storage.NewRedis(shared, storePrefix)
+	}
+
+	// every attempt reaching hmtpk.ru is counted, retries included
+	if counters != nil && !readOnly {
+		provider = stats.NewProvider(provider, counters)
+	}
+
+	// transient failures of hmtpk.ru are retried before falling back to the archive
+	if cfg.Retry.Attempts > 1 && !readOnly {
+		provider = retry.NewProvider(cfg.Retry, provider, log)
+	}
+
+	// the archive keeps what the source returned and answers when it fails
+	var archived *archive.Archive
+	if cfg.Archive.Driver != "" && !readOnly && !cfg.Mock {
+		subsystems.Start("archive", func() (func(), error) {
+			db, err := archive.Open(ctx, cfg.Archive)
+			if err != nil {
+				return nil, err
+			}
+
+			provider = archive.NewProvider(provider, db, api.DayDate, log)
+			archived = db
+
+			return func() {
+				_ = db.Close()
+			}, nil
+		})
+	}
+
+	// the last successful fetch is served when both hmtpk.ru and the archive fail
+	if !readOnly {
+		provider = stale.NewProvider(provider, store, log)
+	}
+
+	// concurrent identical requests share one fetch
+	provider = api.Coalesce(provider)
+
+	a := api.NewApi(provider, client, log)
+	a.SetArchive(archived)
+	a.SetReplayer(replayer)
+	a.SetReporter(reporter)
+	a.SetMetrics(collector)
+	a.SetLimits(cfg.Limits)
+	a.SetTimeouts(cfg.Timeouts)
+	// the keys of the parser cache are listed and flushed by the administrators
+	if client != nil && backend == nil {
+		a.SetCache(rediscache.NewCache(client))
+	}
+	a.SetMirrors(mirrors)
+	a.SetIDs(ids.New(cfg.IDs))
+	a.SetReadOnly(readOnly)
+	a.SetThumbnails(cfg.Thumbnails)
+	a.SetTenants(cfg.Tenants)
+	a.SetPresets(cfg.Presets)
+	a.SetBells(cfg.Bells)
+	a.SetTelegram(cfg.Telegram)
+	a.SetAlice(cfg.Alice)
+	a.SetCheckin(cfg.Checkin)
+	a.SetEdge(cfg.Edge, edgeReplica)
+
+	if cfg.Timezone != "" {
+		location, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a.SetLocation(location)
+
+		if cfg.Moodle.Location == "" {
+			cfg.Moodle.Location = cfg.Timezone
+		}
+	}
+
+	warmer := warmup.NewWarmer(cfg.Warmup, provider, log)
+	warmer.SetLocation(a.Location())
+	if cached != nil {
+		cached.SetLocation(a.Location())
+	}
+	if windows != nil {
+		windows.SetLocation(a.Location())
+	}
+	a.SetWarmer(warmer)
+
+	// during the morning peak the warmer keeps every group fresh, the cached
+	// data lives longer, the rate limits relax and the background jobs pause
+	var mode *peak.Mode
+	if len(cfg.Peak.Windows) > 0 {
+		subsystems.Start("peak", func() (func(), error) {
+			m, err := peak.New(cfg.Peak)
+			if err != nil {
+				return nil, err
+			}
+			m.SetLocation(a.Location())
+
+			mode = m
+			warmer.SetPeak(m.Active, m.Warmup())
+			a.SetPeak(m.Active, m.RateFactor())
+			if cached != nil {
+				cached.SetTTL(m.TTL)
+			}
+
+			return nil, nil
+		})
+	}
+	peaking := func() bool {
+		return mode != nil && mode.Active()
+	}
+
+	// the routes are registered after every middleware, chi refuses middlewares added after a route
+	if collector != nil {
+		r.Handle(cfg.Metrics.Path, collector.Handler())
+	}
+	r.Get("/healthz", subsystems.Health)
+	r.Get("/readyz", warmer.Ready)
+
+	a.SetStore(store)
+
+	// the replicas read the IDs assigned by the primary
+	stableIDs := stableid.New(store, readOnly)
+	a.SetStableIDs(stableIDs)
+
+	var students api.Cabinet
+	if cfg.Mock {
+		students = mock.NewCabinet()
+	} else if cfg.Cabinet.URL != "" {
+		subsystems.Start("cabinet", func() (func(), error) {
+			client, err := cabinet.New(cfg.Cabinet)
+			if err != nil {
+				return nil, err
+			}
+
+			students = client
+
+			return nil, nil
+		})
+	}
+
+	if students != nil {
+		sessions, err := vault.New(cfg.Vault, store, shared != nil, log)
+		if err != nil {
+			log.Fatalf("vault: %s", err)
+		}
+		a.SetCabinet(students, sessions)
+
+		if !readOnly {
+			subsystems.Go(ctx, "vault_cleanup", func(ctx context.Context) error {
+				sessions.Run(ctx)
+				return nil
+			})
+		}
+	}
+
+	if cfg.Auth.Secret != "" {
+		authentication := auth.New(cfg.Auth, store, log)
+		a.SetAuth(authentication)
+
+		// the students log in with the credentials of the personal cabinet
+		if students != nil {
+			authentication.SetAuthenticator(a.CabinetAuthenticator())
+		}
+
+		// the primary deletes the expired sessions of the shared store
+		if !readOnly {
+			subsystems.Go(ctx, "auth_cleanup", func(ctx context.Context) error {
+				authentication.Run(ctx)
+				return nil
+			})
+		}
+	}
+
+	// the users choose the events and the channels of their notifications
+	notifier := notify.New(store, a.Location(), log)
+	notifier.SetRetry(cfg.Notify.Retry)
+	notifier.SetSender(notify.ChannelWebhook, notify.Webhook{})
+	if cfg.Telegram.BotToken != "" {
+		notifier.SetSender(notify.ChannelTelegram, notify.Telegram{Config: cfg.Telegram})
+	}
+	if cfg.Notify.FCM.ServerKey != "" {
+		notifier.SetSender(notify.ChannelFCM, notify.FCM{Config: cfg.Notify.FCM})
+	}
+	if cfg.Notify.Email.SMTP != "" {
+		subsystems.Start("notify_email", func() (func(), error) {
+			email, err := notify.NewEmail(cfg.Notify.Email)
+			if err != nil {
+				return nil, err
+			}
+
+			notifier.SetSender(notify.ChannelEmail, email)
+
+			return nil, nil
+		})
+	}
+	notifier.SetDigest(a.NotificationDigest)
+	a.SetNotifier(notifier)
+
+	// the primary sends the notifications, the held back ones and the digests
+	if !readOnly {
+		warmer.OnChange(a.NotifyScheduleChange)
+		subsystems.Go(ctx, "notify", func(ctx context.Context) error {
+			notifier.Run(ctx)
+			return nil
+		})
+	}
+
+	if cfg.Translate.Provider != "" {
+		subsystems.Start("translate", func() (func(), error) {
+			translator, err := translate.New(cfg.Translate)
+			if err != nil {
+				return nil, err
+			}
+
+			a.SetTranslator(translator)
+
+			return nil, nil
+		})
+	}
+
+	prefix := strings.TrimSuffix(cfg.Prefix, "/")
+	builder, err := links.New(cfg.PublicURL, prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+	a.SetLinks(builder)
 
-	r.Route("/api/hmtpk", a.Router())
+	if prefix == "" {
+		r.Group(a.Router())
+	} else {
+		r.Route(prefix, a.Router())
+	}
 
-	log.Trace("Starting server on http://localhost:8080/api/hmtpk")
+	server := cfg.Limits.Server(cfg.Addr, r)
 
-	err := http.ListenAndServe(":8080", r)
+	listener, err := net.Listen("tcp", cfg.Addr)
 	if err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		log.Infof("Starting server on %s%s with profile %s", cfg.Addr, prefix, cfg.Profile)
+
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(err)
+			stop()
+		}
+	}()
+
+	if cfg.GRPCAddr != "" && readOnly {
+		log.Warn("gRPC is not served by a read-only replica")
+	} else if cfg.GRPCAddr != "" {
+		startGRPC(ctx, subsystems, cfg.GRPCAddr, a, provider, a.Location(), log)
+	}
+
+	if cfg.Warmup.Interval > 0 {
+		subsystems.Go(ctx, "warmup", func(ctx context.Context) error {
+			warmer.Run(ctx)
+			return nil
+		})
+	}
+
+	if cfg.Announces.Interval > 0 {
+		crawler := announce.NewCrawler(cfg.Announces, provider, a.Index(), log)
+		crawler.SetPaused(peaking)
+		subsystems.Go(ctx, "announces_crawler", func(ctx context.Context) error {
+			crawler.Run(ctx)
+			return nil
+		})
+	}
+
+	// the primary keeps the archive, a replica reads it from the shared Redis
+	if cfg.Rollover.Interval > 0 && !readOnly {
+		detector := rollover.NewDetector(cfg.Rollover, provider, store, warmer.Schedules, log)
+		detector.AddMigrator(a)
+		detector.AddMigrator(stableIDs)
+		detector.SetPaused(peaking)
+		subsystems.Go(ctx, "rollover", func(ctx context.Context) error {
+			detector.Run(ctx)
+			return nil
+		})
+	}
+
+	if cfg.Edge.Token != "" && len(cfg.Edge.Peers) > 0 {
+		pusher := edge.NewPusher(cfg.Edge, a.EdgeSnapshot, log)
+		subsystems.Go(ctx, "edge_push", func(ctx context.Context) error {
+			pusher.Run(ctx)
+			return nil
+		})
+	}
+
+	if cfg.Moodle.URL != "" {
+		subsystems.Start("moodle", func() (func(), error) {
+			exporter, err := moodle.NewExporter(cfg.Moodle, provider, store, api.DayDate, log)
+			if err != nil {
+				return nil, err
+			}
+
+			exporter.SetPaused(peaking)
+
+			if shared == nil {
+				log.Warn("moodle: event ids are kept in memory, events are created again after a restart")
+			}
+
+			subsystems.Go(ctx, "moodle_export", func(ctx context.Context) error {
+				exporter.Run(ctx)
+				return nil
+			})
+
+			return nil, nil
+		})
+	}
+
+	if counters != nil {
+		subsystems.Start("report", func() (func(), error) {
+			mailer, err := stats.NewMailer(cfg.Report, counters, store, log)
+			if err != nil {
+				return nil, err
+			}
+			mailer.SetLocation(a.Location())
+
+			subsystems.Go(ctx, "report_mail", func(ctx context.Context) error {
+				mailer.Run(ctx)
+				return nil
+			})
+
+			return nil, nil
+		})
+	}
+
+	<-ctx.Done()
+
+	log.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err = server.Shutdown(shutdownCtx); err != nil {
 		log.Error(err)
 	}
 }
+
+// newRedis connects to a Redis Cluster, to the master of a Sentinel deployment
+// or to a single Redis given by its redis:// or rediss:// URL or its address.
+// The single client is returned unless it is a cluster
+func newRedis(cfg config.Redis) (redis.UniversalClient, *redis.Client, error) {
+	switch {
+	case len(cfg.Cluster) > 0:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    cfg.Cluster,
+			Password: cfg.Password,
+		}), nil, nil
+	case cfg.Sentinel.MasterName != "":
+		c := redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.Sentinel.MasterName,
+			SentinelAddrs:    cfg.Sentinel.Addrs,
+			SentinelPassword: cfg.Sentinel.Password,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+		})
+		return c, c, nil
+	}
+
+	options := &redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.URL != "" {
+		var err error
+		if options, err = redis.ParseURL(cfg.URL); err != nil {
+			return nil, nil, fmt.Errorf("redis url: %w", err)
+		}
+	}
+
+	options.DialTimeout = redisDialTimeout
+	options.ReadTimeout = redisReadTimeout
+	options.WriteTimeout = redisWriteTimeout
+	options.MinIdleConns = redisMinIdleConns
+
+	c := redis.NewClient(options)
+	return c, c, nil
+}
@@ -0,0 +1,35 @@
+//go:build !no_grpc
+
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/api"
+	"github.com/chazari-x/hmtpk-parser-api/app"
+	"github.com/chazari-x/hmtpk-parser-api/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// startGRPC runs the gRPC server serving the data of the provider of the HTTP
+// API with the limits of its tenants as an optional subsystem
+func startGRPC(ctx context.Context, subsystems *app.Subsystems, addr string, a *api.API, provider api.ScheduleProvider, location *time.Location, log *logrus.Logger) {
+	subsystems.Go(ctx, "grpc", func(ctx context.Context) error {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		s := rpc.NewServer(provider, a.Admit, location, log)
+		go func() {
+			<-ctx.Done()
+			s.GracefulStop()
+		}()
+
+		log.Infof("Starting gRPC server on %s", addr)
+
+		return s.Serve(listener)
+	})
+}
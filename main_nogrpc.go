@@ -0,0 +1,17 @@
+//go:build no_grpc
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/api"
+	"github.com/chazari-x/hmtpk-parser-api/app"
+	"github.com/sirupsen/logrus"
+)
+
+// startGRPC reports that the binary was built without the gRPC server
+func startGRPC(_ context.Context, _ *app.Subsystems, addr string, _ *api.API, _ api.ScheduleProvider, _ *time.Location, log *logrus.Logger) {
+	log.Warnf("gRPC server on %s is not started: built with the no_grpc tag", addr)
+}
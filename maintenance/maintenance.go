@@ -0,0 +1,96 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/window"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Config is the configuration of the known maintenance windows of hmtpk.ru
+type Config struct {
+	Windows []window.Window `yaml:"windows"`
+}
+
+// ErrMaintenance is returned instead of fetching from hmtpk.ru during a maintenance window
+var ErrMaintenance = errors.New("hmtpk.ru is down for maintenance")
+
+type cachedKey struct{}
+
+// Cached marks the fetches of the context as answered from a cache, they are
+// allowed during a maintenance window
+func Cached(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cachedKey{}, true)
+}
+
+func cached(ctx context.Context) bool {
+	ok, _ := ctx.Value(cachedKey{}).(bool)
+	return ok
+}
+
+// Source is the parser fetching from hmtpk.ru
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// Provider skips the fetches from hmtpk.ru during the maintenance windows,
+// the fallbacks above it serve the stored data instead
+type Provider struct {
+	source  Source
+	windows *window.Set
+}
+
+// NewProvider wraps the parser
+func NewProvider(source Source, windows *window.Set) *Provider {
+	return &Provider{source: source, windows: windows}
+}
+
+func (p *Provider) skip(ctx context.Context) bool {
+	return !cached(ctx) && p.windows.Active(time.Now())
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	if p.skip(ctx) {
+		return nil, ErrMaintenance
+	}
+
+	return p.source.GetGroupOptions(ctx)
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	if p.skip(ctx) {
+		return nil, ErrMaintenance
+	}
+
+	return p.source.GetTeacherOptions(ctx)
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	if p.skip(ctx) {
+		return nil, ErrMaintenance
+	}
+
+	return p.source.GetScheduleByGroup(ctx, group, date)
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	if p.skip(ctx) {
+		return nil, ErrMaintenance
+	}
+
+	return p.source.GetScheduleByTeacher(ctx, teacher, date)
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	if p.skip(ctx) {
+		return model.Announces{}, ErrMaintenance
+	}
+
+	return p.source.GetAnnounces(ctx, page)
+}
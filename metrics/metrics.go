@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Config is the configuration of the Prometheus metrics
+type Config struct {
+	// Path enables the metrics endpoint when not empty
+	Path string `yaml:"path"`
+	// Labels is the label set of the request metrics: route, method, status, group, teacher
+	Labels []string `yaml:"labels"`
+	// MaxLabelValues is the number of distinct values kept per label,
+	// further values are reported as "other"
+	MaxLabelValues int `yaml:"max_label_values"`
+}
+
+const (
+	LabelRoute   = "route"
+	LabelMethod  = "method"
+	LabelStatus  = "status"
+	LabelGroup   = "group"
+	LabelTeacher = "teacher"
+
+	// overflowValue replaces label values once the label reached its limit
+	overflowValue = "other"
+
+	defaultMaxLabelValues = 100
+)
+
+var defaultLabels = []string{LabelRoute, LabelMethod, LabelStatus}
+
+// Metrics collects the metrics of the HTTP API
+type Metrics struct {
+	registry *prometheus.Registry
+	labels   []string
+	guard    *guard
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	panics   prometheus.Counter
+}
+
+// New creates the metrics with the configured label set
+func New(cfg Config) (*Metrics, error) {
+	labels := cfg.Labels
+	if len(labels) == 0 {
+		labels = defaultLabels
+	}
+
+	for _, label := range labels {
+		switch label {
+		case LabelRoute, LabelMethod, LabelStatus, LabelGroup, LabelTeacher:
+		default:
+			return nil, fmt.Errorf("unknown metrics label %q", label)
+		}
+	}
+
+	if cfg.MaxLabelValues <= 0 {
+		cfg.MaxLabelValues = defaultMaxLabelValues
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		labels:   labels,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "hmtpk",
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "hmtpk",
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		panics: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "hmtpk",
+			Name:      "panics_total",
+			Help:      "Number of panics recovered in the handlers and the fetches.",
+		}),
+	}
+
+	overflows := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hmtpk",
+		Name:      "metrics_label_overflow_total",
+		Help:      "Number of label values replaced by \"other\" by the cardinality guard.",
+	}, []string{"label"})
+
+	m.guard = newGuard(cfg.MaxLabelValues, overflows)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.panics,
+		overflows,
+	)
+
+	return m, nil
+}
+
+// Handler returns the handler of the metrics endpoint
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the registry for metrics of other subsystems
+func (m *Metrics) Registry() prometheus.Registerer {
+	return m.registry
+}
+
+// Panic counts a recovered panic
+func (m *Metrics) Panic() {
+	m.panics.Inc()
+}
+
+// Middleware records the request count and duration
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		values := make([]string, 0, len(m.labels))
+		for _, label := range m.labels {
+			var value string
+			switch label {
+			case LabelRoute:
+				value = "unknown"
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					value = rctx.RoutePattern()
+				}
+			case LabelMethod:
+				value = r.Method
+			case LabelStatus:
+				value = strconv.Itoa(status)
+			case LabelGroup:
+				value = r.URL.Query().Get("group")
+			case LabelTeacher:
+				value = r.URL.Query().Get("teacher")
+			}
+
+			values = append(values, m.guard.value(label, value))
+		}
+
+		m.requests.WithLabelValues(values...).Inc()
+		m.duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// guard limits the number of distinct values of every label
+type guard struct {
+	mu        sync.Mutex
+	max       int
+	seen      map[string]map[string]struct{}
+	overflows *prometheus.CounterVec
+}
+
+func newGuard(max int, overflows *prometheus.CounterVec) *guard {
+	return &guard{max: max, seen: make(map[string]map[string]struct{}), overflows: overflows}
+}
+
+// value returns the value itself while the label is under its limit
+func (g *guard) value(label, value string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	values, ok := g.seen[label]
+	if !ok {
+		values = make(map[string]struct{})
+		g.seen[label] = values
+	}
+
+	if _, ok = values[value]; ok {
+		return value
+	}
+
+	if len(values) >= g.max {
+		g.overflows.WithLabelValues(label).Inc()
+		return overflowValue
+	}
+
+	values[value] = struct{}{}
+
+	return value
+}
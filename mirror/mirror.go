@@ -0,0 +1,309 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the configuration of the alternative origins of the college site
+type Config struct {
+	// URLs are the base URLs tried in order when hmtpk.ru fails, e.g. a mirror
+	// or a caching proxy, optionally with a path the site is served under
+	URLs []string `yaml:"urls"`
+	// Addresses are IP addresses of hmtpk.ru tried before the URLs, dialed
+	// directly with the host name and the certificate of the site, e.g. while
+	// its DNS is down
+	Addresses []string `yaml:"addresses"`
+	// AttemptTimeout bounds waiting for an origin while others remain
+	AttemptTimeout time.Duration `yaml:"attempt_timeout"`
+	// Cooldown is how long an origin failing in a row is skipped
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+const (
+	// site is the primary origin, requests to other hosts are passed through
+	site = "https://hmtpk.ru"
+
+	defaultAttemptTimeout = time.Second * 5
+	defaultCooldown       = time.Second * 30
+
+	// failureThreshold is the number of failures in a row putting an origin on cooldown
+	failureThreshold = 3
+)
+
+// Health is the state of an origin
+type Health struct {
+	URL         string     `json:"url"`
+	Address     string     `json:"address,omitempty"`
+	Healthy     bool       `json:"healthy"`
+	Failures    int        `json:"failures"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	DownUntil   *time.Time `json:"down_until,omitempty"`
+}
+
+type origin struct {
+	base *url.URL
+	// address is dialed instead of resolving the host of the site, with its own transport
+	address   string
+	transport http.RoundTripper
+
+	failures    int
+	lastError   string
+	lastFailure time.Time
+	lastSuccess time.Time
+	downUntil   time.Time
+}
+
+// Transport sends the requests to hmtpk.ru to the first healthy origin and
+// the next ones when it fails with a network error or a 5xx response
+type Transport struct {
+	next           http.RoundTripper
+	attemptTimeout time.Duration
+	cooldown       time.Duration
+
+	mu      sync.Mutex
+	origins []*origin
+}
+
+// NewTransport creates the transport sending the requests with next
+func NewTransport(cfg Config, next http.RoundTripper) (*Transport, error) {
+	t := &Transport{next: next, attemptTimeout: cfg.AttemptTimeout, cooldown: cfg.Cooldown}
+	if t.attemptTimeout <= 0 {
+		t.attemptTimeout = defaultAttemptTimeout
+	}
+
+	if t.cooldown <= 0 {
+		t.cooldown = defaultCooldown
+	}
+
+	for _, raw := range append([]string{site}, cfg.URLs...) {
+		base, err := url.Parse(strings.TrimSuffix(raw, "/"))
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+			return nil, fmt.Errorf("mirror url %q: must be an http or https URL", raw)
+		}
+
+		t.origins = append(t.origins, &origin{base: base})
+
+		// the addresses of the site follow the site itself
+		if raw == site {
+			for _, address := range cfg.Addresses {
+				o, err := addressOrigin(base, address)
+				if err != nil {
+					return nil, err
+				}
+
+				t.origins = append(t.origins, o)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// addressOrigin returns the origin dialing the address, an IP optionally with a port, for the site
+func addressOrigin(base *url.URL, address string) (*origin, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "443"
+	}
+
+	if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("mirror address %q: must be an IP address", address)
+	}
+	address = net.JoinHostPort(host, port)
+
+	transport := &http.Transport{}
+	if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaultTransport.Clone()
+	}
+
+	dialer := &net.Dialer{Timeout: defaultAttemptTimeout, KeepAlive: time.Second * 30}
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &origin{base: base, address: address, transport: transport}, nil
+}
+
+// Health returns the state of every origin, the primary first
+func (t *Transport) Health() []Health {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	health := make([]Health, 0, len(t.origins))
+	for _, o := range t.origins {
+		health = append(health, Health{
+			URL:         o.base.String(),
+			Address:     o.address,
+			Healthy:     !now.Before(o.downUntil),
+			Failures:    o.failures,
+			LastError:   o.lastError,
+			LastFailure: optional(o.lastFailure),
+			LastSuccess: optional(o.lastSuccess),
+			DownUntil:   optional(o.downUntil),
+		})
+	}
+
+	return health
+}
+
+// optional returns nil for the zero time, omitted in the health
+func optional(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// candidates returns the origins in order, the ones on cooldown last so a
+// request is still tried when every origin is down
+func (t *Transport) candidates() []*origin {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var healthy, down []*origin
+	for _, o := range t.origins {
+		if now.Before(o.downUntil) {
+			down = append(down, o)
+		} else {
+			healthy = append(healthy, o)
+		}
+	}
+
+	return append(healthy, down...)
+}
+
+// name returns the host of the origin, with the address dialed for it
+func (o *origin) name() string {
+	if o.address != "" {
+		return o.base.Host + " (" + o.address + ")"
+	}
+
+	return o.base.Host
+}
+
+func (t *Transport) report(o *origin, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if err == nil {
+		o.failures = 0
+		o.lastSuccess = now
+		o.downUntil = time.Time{}
+		return
+	}
+
+	o.failures++
+	o.lastError = err.Error()
+	o.lastFailure = now
+	if o.failures >= failureThreshold {
+		o.downUntil = now.Add(t.cooldown)
+	}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Scheme+"://"+r.URL.Host != site {
+		return t.next.RoundTrip(r)
+	}
+
+	candidates := t.candidates()
+	var lastErr error
+	for i, o := range candidates {
+		if i > 0 && r.Body != nil && r.GetBody == nil {
+			// the body was consumed by the previous attempt
+			break
+		}
+
+		resp, err := t.attempt(r, o, i < len(candidates)-1)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			t.report(o, nil)
+			return resp, nil
+		}
+
+		if err == nil {
+			err = fmt.Errorf("%s: %s", o.name(), resp.Status)
+			if i == len(candidates)-1 {
+				t.report(o, err)
+				return resp, nil
+			}
+			_ = resp.Body.Close()
+		}
+
+		// the request itself giving up is not a failure of the origin
+		if r.Context().Err() != nil {
+			return nil, r.Context().Err()
+		}
+
+		t.report(o, err)
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// attempt sends the request to the origin, bounded by the attempt timeout
+// while other origins remain
+func (t *Transport) attempt(r *http.Request, o *origin, bounded bool) (*http.Response, error) {
+	ctx, cancel := r.Context(), context.CancelFunc(func() {})
+	if bounded {
+		ctx, cancel = context.WithTimeout(ctx, t.attemptTimeout)
+	}
+
+	req := r.Clone(ctx)
+	req.URL.Scheme = o.base.Scheme
+	req.URL.Host = o.base.Host
+	req.URL.Path = o.base.Path + r.URL.Path
+	if r.URL.RawPath != "" {
+		req.URL.RawPath = o.base.Path + r.URL.RawPath
+	}
+	req.Host = ""
+
+	if r.Body != nil && r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		req.Body = body
+	}
+
+	next := t.next
+	if o.transport != nil {
+		next = o.transport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the attempt context lives until the body is read
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
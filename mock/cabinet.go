@@ -0,0 +1,150 @@
+package mock
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/auth"
+	"github.com/chazari-x/hmtpk-parser-api/cabinet"
+)
+
+// studentSubjects are the subjects of the journals of the students
+var studentSubjects = subjects[:8]
+
+// Cabinet serves synthetic journals of the students instead of the personal
+// cabinet. Any login is accepted with a non-empty password, the journal of a
+// login is always the same
+type Cabinet struct{}
+
+// NewCabinet creates the mock cabinet
+func NewCabinet() *Cabinet {
+	return &Cabinet{}
+}
+
+func (c *Cabinet) Login(_ context.Context, login, password string) (cabinet.Session, error) {
+	if login == "" || password == "" {
+		return cabinet.Session{}, auth.ErrCredentials
+	}
+
+	return cabinet.Session{
+		Login:   login,
+		Cookies: []*http.Cookie{{Name: "session", Value: strconv.Itoa(pick(1<<30, login))}},
+		Created: time.Now(),
+	}, nil
+}
+
+// days returns the dates of the journal columns, the weekdays of the last four weeks
+func days(now time.Time) []time.Time {
+	var list []time.Time
+	for day := now.AddDate(0, 0, -28); !day.After(now); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Sunday && day.Weekday() != time.Saturday {
+			list = append(list, day)
+		}
+	}
+
+	return list
+}
+
+func (c *Cabinet) Marks(_ context.Context, session cabinet.Session, period string) (cabinet.Marks, error) {
+	marks := cabinet.Marks{Period: period, Subjects: make([]cabinet.SubjectMarks, 0, len(studentSubjects))}
+	for _, subject := range studentSubjects {
+		entry := cabinet.SubjectMarks{Subject: subject, Marks: []cabinet.Mark{}}
+		for _, day := range days(time.Now()) {
+			date := day.Format("02.01.2006")
+			// a mark every few lessons, mostly good ones
+			if pick(4, session.Login, subject, date, period) != 0 {
+				continue
+			}
+
+			entry.Marks = append(entry.Marks, cabinet.Mark{Date: date, Value: strconv.Itoa(5 - pick(3, session.Login, date, subject))})
+		}
+		entry.Average = cabinet.Average(entry.Marks)
+
+		marks.Subjects = append(marks.Subjects, entry)
+	}
+
+	return marks, nil
+}
+
+// homeworkTasks are the tasks given by the mock cabinet
+var homeworkTasks = []string{
+	"Выполнить упражнения в конце параграфа",
+	"Подготовить доклад",
+	"Решить задачи из методички",
+	"Оформить отчёт по практической работе",
+	"Выучить конспект лекции",
+}
+
+func (c *Cabinet) Homework(_ context.Context, session cabinet.Session) (cabinet.Homework, error) {
+	now := time.Now()
+	homework := cabinet.Homework{Subjects: make([]cabinet.SubjectHomework, 0, len(studentSubjects))}
+	for _, subject := range studentSubjects {
+		entry := cabinet.SubjectHomework{Subject: subject, Assignments: []cabinet.Assignment{}}
+		for i := 0; i < pick(3, session.Login, subject); i++ {
+			assigned := now.AddDate(0, 0, -pick(7, session.Login, subject, strconv.Itoa(i)))
+			deadline := assigned.AddDate(0, 0, 3+pick(10, subject, session.Login, strconv.Itoa(i)))
+			entry.Assignments = append(entry.Assignments, cabinet.Assignment{
+				Task:     homeworkTasks[pick(len(homeworkTasks), subject, strconv.Itoa(i))],
+				Assigned: assigned.Format(time.DateOnly),
+				Deadline: deadline.Format(time.DateOnly),
+				Overdue:  deadline.Format(time.DateOnly) < now.Format(time.DateOnly),
+			})
+		}
+		if len(entry.Assignments) == 0 {
+			continue
+		}
+		sort.Slice(entry.Assignments, func(i, j int) bool {
+			return entry.Assignments[i].Deadline < entry.Assignments[j].Deadline
+		})
+
+		homework.Subjects = append(homework.Subjects, entry)
+	}
+
+	return homework, nil
+}
+
+// monthNames are the months as the headings of the attendance table
+var monthNames = []string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+// studyMonths returns the months of the academic year up to now, it starts in September
+func studyMonths(now time.Time) []time.Month {
+	start := time.September
+	if now.Month() < start {
+		start = time.January
+	}
+
+	var list []time.Month
+	for month := start; month <= now.Month(); month++ {
+		list = append(list, month)
+	}
+
+	return list
+}
+
+func (c *Cabinet) Attendance(_ context.Context, session cabinet.Session) (cabinet.Attendance, error) {
+	attendance := cabinet.Attendance{Subjects: make([]cabinet.SubjectAttendance, 0, len(studentSubjects))}
+	for _, subject := range studentSubjects {
+		entry := cabinet.SubjectAttendance{Subject: subject, Absences: []cabinet.Absence{}}
+		for _, month := range studyMonths(time.Now()) {
+			// the lessons last two hours, most of the months are without absences
+			hours := 2 * max(0, pick(6, session.Login, subject, month.String())-3)
+			if hours == 0 {
+				continue
+			}
+			excused := 2 * pick(hours/2+1, subject, session.Login, month.String())
+
+			entry.Absences = append(entry.Absences, cabinet.Absence{Month: monthNames[month-1], Hours: hours, Excused: excused})
+			entry.Hours += hours
+			entry.Excused += excused
+		}
+
+		attendance.Subjects = append(attendance.Subjects, entry)
+		attendance.Hours += entry.Hours
+		attendance.Excused += entry.Excused
+	}
+
+	return attendance, nil
+}
@@ -0,0 +1,244 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	href = "https://hmtpk.ru/ru/students/schedule"
+
+	announcesPerPage = 10
+	announcePages    = 3
+)
+
+var (
+	groups = []string{
+		"ИСП-21", "ИСП-22", "ИСП-31", "ИСП-32", "ИСП-41",
+		"ПКС-21", "ПКС-31", "ЭК-21", "ЭК-31", "МТ-21", "МТ-31", "ТО-41",
+	}
+
+	teachers = []string{
+		"Андреева О.В.", "Белов С.Н.", "Васильева Е.А.", "Григорьев П.И.",
+		"Дмитриева Н.С.", "Егоров А.М.", "Жукова Т.Ю.", "Зайцев В.К.",
+		"Иванова М.П.", "Козлов Д.Р.", "Лебедева Ю.О.", "Морозов И.Г.",
+	}
+
+	subjects = []string{
+		"Математика", "Физика", "Информатика", "Русский язык", "Литература",
+		"История", "Английский язык", "Физическая культура", "Основы алгоритмизации",
+		"Базы данных", "Компьютерные сети", "Экономика организации", "Электротехника",
+		"Инженерная графика",
+	}
+
+	rooms = []string{"101", "105", "112", "203", "207", "214", "301", "308", "315", "Спортзал"}
+
+	bells = []string{"08:30 - 10:00", "10:10 - 11:40", "12:10 - 13:40", "13:50 - 15:20", "15:30 - 17:00"}
+
+	months   = []string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	weekdays = []string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+
+	titles = []string{
+		"Внимание! Изменение расписания на завтра",
+		"Родительское собрание",
+		"Приглашаем на день открытых дверей",
+		"Срочно: отмена занятий из-за погодных условий",
+		"Итоги конкурса профессионального мастерства",
+		"Напоминание о сроках сдачи курсовых работ",
+		"Экскурсия на предприятие для студентов второго курса",
+		"Спортивные соревнования между группами",
+	}
+)
+
+// Source serves realistic synthetic groups, teachers, schedules and announces
+// without contacting hmtpk.ru. The data is deterministic: the same week of a
+// group is always the same, and the schedules of teachers match the groups
+type Source struct{}
+
+// NewSource creates the mock source
+func NewSource() *Source {
+	return &Source{}
+}
+
+// pick returns an index below n derived from the parts
+func pick(n int, parts ...string) int {
+	h := fnv.New32a()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return int(h.Sum32() % uint32(n))
+}
+
+func options(values []string) []model.Option {
+	list := make([]model.Option, 0, len(values))
+	for _, value := range values {
+		list = append(list, model.Option{Label: value, Value: value})
+	}
+
+	return list
+}
+
+func (s *Source) GetGroupOptions(context.Context) ([]model.Option, error) {
+	return options(groups), nil
+}
+
+func (s *Source) GetTeacherOptions(context.Context) ([]model.Option, error) {
+	return options(teachers), nil
+}
+
+// lessons returns the lessons of the group on the day, none on Sundays
+func lessons(group string, day time.Time) []model.Lesson {
+	if day.Weekday() == time.Sunday {
+		return nil
+	}
+
+	date := day.Format("02.01.2006")
+	count := 2 + pick(3, group, date)
+	if day.Weekday() == time.Saturday {
+		count = 1 + pick(2, group, date)
+	}
+
+	list := make([]model.Lesson, 0, count)
+	for i := 0; i < count; i++ {
+		num := fmt.Sprint(i + 1)
+		// the groups are shifted apart so two of them never share a teacher at once
+		slot := (pick(len(teachers), date, num) + indexOf(groups, group)) % len(teachers)
+		lesson := model.Lesson{
+			Num:     num,
+			Time:    bells[i],
+			Name:    subjects[pick(len(subjects), group, date, num)],
+			Room:    rooms[(slot+pick(len(rooms), date))%len(rooms)],
+			Teacher: teachers[slot],
+		}
+
+		if pick(5, group, date, num, "subgroup") == 0 {
+			lesson.Subgroup = "1"
+		}
+
+		list = append(list, lesson)
+	}
+
+	return list
+}
+
+// indexOf returns the index of the value in the list, -1 when missing
+func indexOf(list []string, value string) int {
+	for i, item := range list {
+		if item == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// week returns the days of the week of the date with the lessons returned by get
+func week(kind, value, date string, get func(day time.Time) []model.Lesson) ([]model.Schedule, error) {
+	d, err := time.Parse("02.01.2006", date)
+	if err != nil {
+		return nil, hmtpkErrors.ErrorBadRequest
+	}
+
+	monday := d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+
+	schedule := make([]model.Schedule, 0, 7)
+	for i := 0; i < 7; i++ {
+		day := monday.AddDate(0, 0, i)
+		schedule = append(schedule, model.Schedule{
+			Date:    fmt.Sprintf("%d %s %d, %s", day.Day(), months[day.Month()-1], day.Year(), weekdays[day.Weekday()]),
+			Href:    fmt.Sprintf("%s/?%s=%s&date_edu1c=%s&send=Показать#current", href, kind, value, day.Format("02.01.2006")),
+			Lessons: get(day),
+		})
+	}
+
+	return schedule, nil
+}
+
+func (s *Source) GetScheduleByGroup(_ context.Context, group, date string) ([]model.Schedule, error) {
+	if indexOf(groups, group) < 0 {
+		return nil, hmtpkErrors.ErrorBadRequest
+	}
+
+	return week("group", group, date, func(day time.Time) []model.Lesson {
+		return lessons(group, day)
+	})
+}
+
+func (s *Source) GetScheduleByTeacher(_ context.Context, teacher, date string) ([]model.Schedule, error) {
+	if indexOf(teachers, teacher) < 0 {
+		return nil, hmtpkErrors.ErrorBadRequest
+	}
+
+	return week("teacher", teacher, date, func(day time.Time) []model.Lesson {
+		var list []model.Lesson
+		for _, group := range groups {
+			for _, lesson := range lessons(group, day) {
+				if lesson.Teacher == teacher {
+					lesson.Group = group
+					list = append(list, lesson)
+				}
+			}
+		}
+
+		return list
+	})
+}
+
+func (s *Source) GetAnnounces(_ context.Context, page int) (model.Announces, error) {
+	if page < 1 {
+		return model.Announces{}, hmtpkErrors.ErrorBadRequest
+	}
+
+	announces := model.Announces{LastPage: announcePages}
+	if page > announcePages {
+		return announces, nil
+	}
+
+	// the newest announce is published today, one every two days before it
+	today := time.Now()
+	for i := 0; i < announcesPerPage; i++ {
+		n := (page-1)*announcesPerPage + i
+		title := titles[n%len(titles)]
+		announces.Announces = append(announces.Announces, model.Announce{
+			Path:  fmt.Sprintf("/ru/press-center/announce/mock-%d/", n+1),
+			Date:  today.AddDate(0, 0, -2*n).Format("02.01.2006"),
+			Title: title,
+			Body:  fmt.Sprintf("<p>%s. Подробности у кураторов групп.</p>", title),
+		})
+	}
+
+	return announces, nil
+}
+
+// ErrOffline is returned for the requests to hmtpk.ru in the mock mode
+var ErrOffline = errors.New("hmtpk.ru is not contacted in the mock mode")
+
+// transport refuses the requests to hmtpk.ru, the announce details, the news
+// and the thumbnails fail instead of reaching the site
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if host := request.URL.Hostname(); host == "hmtpk.ru" || strings.HasSuffix(host, ".hmtpk.ru") {
+		return nil, ErrOffline
+	}
+
+	return t.next.RoundTrip(request)
+}
+
+// NewTransport returns the transport refusing the requests to hmtpk.ru and
+// sending the others with next
+func NewTransport(next http.RoundTripper) http.RoundTripper {
+	return transport{next: next}
+}
@@ -0,0 +1,341 @@
+package moodle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the Moodle calendar export
+type Config struct {
+	// URL is the base URL of the Moodle instance, enables the export when not empty
+	URL string `yaml:"url"`
+	// Token is the web service token of the deployment
+	Token string `yaml:"token"`
+	// Interval between synchronizations
+	Interval time.Duration `yaml:"interval"`
+	// Weeks is the number of weeks synchronized starting from the current one
+	Weeks int `yaml:"weeks"`
+	// Location is the time zone of the lesson times
+	Location string `yaml:"location"`
+	// Groups are the groups exported and the Moodle courses they belong to
+	Groups []Group `yaml:"groups"`
+}
+
+// Group binds a group of the college to a Moodle course and course group
+type Group struct {
+	Group    string `yaml:"group"`
+	CourseID int    `yaml:"course_id"`
+	GroupID  int    `yaml:"group_id"`
+}
+
+// Source is the part of the parser used by the exporter
+type Source interface {
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+}
+
+const (
+	defaultInterval = time.Hour
+	defaultWeeks    = 2
+	defaultLocation = "Asia/Yekaterinburg"
+
+	servicePath = "/webservice/rest/server.php"
+	keyPrefix   = "moodle:event:"
+
+	timeout = time.Second * 15
+)
+
+// Exporter keeps lesson events of the Moodle calendar in sync with the
+// timetable. The ids of created events are kept in the store so changed
+// and cancelled lessons are removed from the calendar
+type Exporter struct {
+	cfg      Config
+	source   Source
+	store    storage.Store
+	date     bells.DateFunc
+	location *time.Location
+	client   *http.Client
+	paused   func() bool
+	log      *logrus.Logger
+}
+
+// NewExporter creates a new exporter
+func NewExporter(cfg Config, source Source, store storage.Store, date bells.DateFunc, logger *logrus.Logger) (*Exporter, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("moodle: token is required")
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	if cfg.Weeks <= 0 {
+		cfg.Weeks = defaultWeeks
+	}
+
+	if cfg.Location == "" {
+		cfg.Location = defaultLocation
+	}
+
+	location, err := time.LoadLocation(cfg.Location)
+	if err != nil {
+		return nil, fmt.Errorf("moodle: %w", err)
+	}
+
+	return &Exporter{
+		cfg:      cfg,
+		source:   source,
+		store:    store,
+		date:     date,
+		location: location,
+		client:   &http.Client{Timeout: timeout},
+		log:      logger,
+	}, nil
+}
+
+// SetPaused skips the synchronizations while paused reports true, e.g. during the peak
+func (e *Exporter) SetPaused(paused func() bool) {
+	e.paused = paused
+}
+
+// Run synchronizes the calendar until the context is canceled
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, group := range e.cfg.Groups {
+			if e.paused != nil && e.paused() {
+				break
+			}
+
+			if err := e.sync(ctx, group); err != nil && ctx.Err() == nil {
+				e.log.Warnf("moodle: group %s: %s", group.Group, err)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// event is a lesson as a Moodle calendar event
+type event struct {
+	key         string
+	day         string
+	name        string
+	description string
+	start       time.Time
+	duration    time.Duration
+}
+
+func (e *Exporter) sync(ctx context.Context, group Group) error {
+	events := make(map[string]event)
+	days := make(map[string]bool)
+
+	now := time.Now().In(e.location)
+	for week := 0; week < e.cfg.Weeks; week++ {
+		scheduleCtx, cancel := context.WithTimeout(ctx, timeout)
+		schedule, err := e.source.GetScheduleByGroup(scheduleCtx, group.Group, now.AddDate(0, 0, 7*week).Format("02.01.2006"))
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		for _, day := range schedule {
+			date, ok := e.date(day)
+			if !ok {
+				continue
+			}
+
+			days[date.Format(time.DateOnly)] = true
+			for _, lesson := range day.Lessons {
+				if ev, ok := e.event(group, date, lesson); ok {
+					events[ev.key] = ev
+				}
+			}
+		}
+	}
+
+	var created, deleted int
+	for day := range days {
+		prefix := fmt.Sprintf("%s%s:%s:", keyPrefix, group.Group, day)
+		keys, err := e.store.Keys(ctx, prefix)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if _, ok := events[key]; ok {
+				delete(events, key)
+				continue
+			}
+
+			if err = e.deleteEvent(ctx, key); err != nil {
+				return err
+			}
+			deleted++
+		}
+	}
+
+	for _, ev := range events {
+		if err := e.createEvent(ctx, group, ev); err != nil {
+			return err
+		}
+		created++
+	}
+
+	e.log.Infof("moodle: group %s: %d events created, %d deleted", group.Group, created, deleted)
+
+	return nil
+}
+
+// event converts the lesson, lessons without a parsable time are skipped
+func (e *Exporter) event(group Group, date time.Time, lesson model.Lesson) (event, bool) {
+	start, end, ok := bells.ParseTime(lesson.Time)
+	if !ok {
+		return event{}, false
+	}
+
+	at := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, e.location)
+	}
+
+	ev := event{
+		day:      date.Format(time.DateOnly),
+		name:     lesson.Name,
+		start:    at(start),
+		duration: at(end).Sub(at(start)),
+	}
+
+	var details []string
+	if lesson.Room != "" {
+		details = append(details, "Кабинет: "+lesson.Room)
+	}
+	if lesson.Teacher != "" {
+		details = append(details, "Преподаватель: "+lesson.Teacher)
+	}
+	if lesson.Subgroup != "" {
+		details = append(details, "Подгруппа: "+lesson.Subgroup)
+	}
+	ev.description = strings.Join(details, "<br>")
+
+	// any change of the lesson gives a new key, so the old event is replaced
+	sum := sha256.Sum256([]byte(strings.Join([]string{lesson.Num, lesson.Time, lesson.Name, lesson.Room, lesson.Teacher, lesson.Subgroup}, "|")))
+	ev.key = fmt.Sprintf("%s%s:%s:%s", keyPrefix, group.Group, ev.day, hex.EncodeToString(sum[:8]))
+
+	return ev, true
+}
+
+func (e *Exporter) createEvent(ctx context.Context, group Group, ev event) error {
+	params := url.Values{
+		"events[0][name]":         {ev.name},
+		"events[0][description]":  {ev.description},
+		"events[0][format]":       {"1"},
+		"events[0][timestart]":    {strconv.FormatInt(ev.start.Unix(), 10)},
+		"events[0][timeduration]": {strconv.FormatInt(int64(ev.duration.Seconds()), 10)},
+		"events[0][eventtype]":    {"course"},
+		"events[0][courseid]":     {strconv.Itoa(group.CourseID)},
+	}
+
+	if group.GroupID != 0 {
+		params.Set("events[0][eventtype]", "group")
+		params.Set("events[0][groupid]", strconv.Itoa(group.GroupID))
+	}
+
+	var result struct {
+		Events []struct {
+			ID int `json:"id"`
+		} `json:"events"`
+	}
+
+	if err := e.call(ctx, "core_calendar_create_calendar_events", params, &result); err != nil {
+		return err
+	}
+
+	if len(result.Events) == 0 {
+		return errors.New("moodle: event not created")
+	}
+
+	return e.store.Set(ctx, ev.key, strconv.Itoa(result.Events[0].ID))
+}
+
+func (e *Exporter) deleteEvent(ctx context.Context, key string) error {
+	id, err := e.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{
+		"events[0][eventid]": {id},
+		"events[0][repeat]":  {"0"},
+	}
+
+	if err = e.call(ctx, "core_calendar_delete_calendar_events", params, nil); err != nil {
+		return err
+	}
+
+	return e.store.Delete(ctx, key)
+}
+
+// call invokes the function of the Moodle REST web service
+func (e *Exporter) call(ctx context.Context, function string, params url.Values, result interface{}) error {
+	params.Set("wstoken", e.cfg.Token)
+	params.Set("wsfunction", function)
+	params.Set("moodlewsrestformat", "json")
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.cfg.URL, "/")+servicePath, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("moodle: %s", resp.Status)
+	}
+
+	var body json.RawMessage
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	// errors are returned with status 200 as an exception object
+	var exception struct {
+		Exception string `json:"exception"`
+		Message   string `json:"message"`
+	}
+	if json.Unmarshal(body, &exception) == nil && exception.Exception != "" {
+		return fmt.Errorf("moodle: %s: %s", function, exception.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, result)
+}
@@ -0,0 +1,167 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	href = "https://hmtpk.ru/ru/press-center/news/"
+
+	// ttl matches the cache lifetime of announces in the parser
+	ttl = time.Minute * 60
+
+	redisTimeout = time.Second
+)
+
+// News parses the news section of the site, it is laid out like the announces
+type News struct {
+	log   *logrus.Logger
+	redis *redis.Client
+	re    *regexp.Regexp
+}
+
+// NewNews creates a new news parser, redis may be nil
+func NewNews(redis *redis.Client, logger *logrus.Logger) *News {
+	return &News{log: logger, redis: redis, re: regexp.MustCompile(`\s+`)}
+}
+
+// GetNews returns the page of the news, cached in Redis
+func (n *News) GetNews(ctx context.Context, page int) (news model.Announces, err error) {
+	if page < 1 {
+		return news, hmtpkErrors.ErrorBadRequest
+	}
+
+	key := fmt.Sprintf("news?page=%d", page)
+	if news, ok := n.Cached(ctx, page); ok {
+		n.log.Trace("news получены из redis")
+		return news, nil
+	}
+
+	doc, err := n.getDocument(ctx, page)
+	if err != nil {
+		return
+	}
+
+	news.Announces = n.parseNews(doc)
+	if news.LastPage, err = n.searchLastPage(doc); err != nil {
+		return
+	}
+
+	if n.redis != nil {
+		if data, err := json.Marshal(news); err == nil {
+			redisCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+			if err = n.redis.Set(redisCtx, key, data, ttl).Err(); err != nil {
+				n.log.Error(err)
+			}
+			cancel()
+		}
+	}
+
+	return
+}
+
+// Cached returns the page of the news only if it is in Redis
+func (n *News) Cached(ctx context.Context, page int) (news model.Announces, ok bool) {
+	if n.redis == nil {
+		return news, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	data, err := n.redis.Get(ctx, fmt.Sprintf("news?page=%d", page)).Result()
+	if err != nil {
+		return news, false
+	}
+
+	return news, json.Unmarshal([]byte(data), &news) == nil
+}
+
+func (n *News) getDocument(ctx context.Context, page int) (*goquery.Document, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?PAGEN_1=%d", href, page), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", hmtpkErrors.ErrorBadResponse, resp.Status)
+	}
+
+	return goquery.NewDocumentFromReader(resp.Body)
+}
+
+func (n *News) parseNews(doc *goquery.Document) []model.Announce {
+	news := make([]model.Announce, 0, 10)
+	doc.Find("main div.iblock-list-item-text").Each(func(_ int, s *goquery.Selection) {
+		item, err := n.parseItem(s)
+		if err != nil {
+			n.log.Error(err)
+			return
+		}
+
+		news = append(news, item)
+	})
+
+	return news
+}
+
+func (n *News) parseItem(s *goquery.Selection) (item model.Announce, err error) {
+	if item.Date = strings.TrimSpace(s.Find("p.c-text-secondary").First().Text()); item.Date == "" {
+		return item, errors.New("date not found")
+	}
+
+	link := s.Find("h3 > a").First()
+	path, ok := link.Attr("href")
+	if !ok {
+		return item, errors.New("path not found")
+	}
+
+	item.Path = strings.TrimSpace(path)
+	item.Title = strings.TrimSpace(n.re.ReplaceAllString(link.Text(), " "))
+
+	body, err := s.Find("div.c-text-secondary").Html()
+	if err != nil {
+		return item, err
+	}
+
+	item.Body = strings.TrimSpace(n.re.ReplaceAllString(body, " "))
+
+	return item, nil
+}
+
+func (n *News) searchLastPage(doc *goquery.Document) (int, error) {
+	elements := doc.Find("main div.sf-viewbox.position-relative > div:last-child > *")
+	if elements.Length() == 0 {
+		// a single page has no pagination
+		return 1, nil
+	}
+
+	last := elements.Last()
+	if !last.Is("span") {
+		last = last.Prev()
+	}
+
+	return strconv.Atoi(strings.TrimSpace(last.Text()))
+}
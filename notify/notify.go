@@ -0,0 +1,675 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk-parser-api/traceparent"
+	"github.com/sirupsen/logrus"
+)
+
+// the events the subscribers choose from
+const (
+	EventScheduleChange = "schedule_change"
+	EventAnnounce       = "announce"
+	EventDigest         = "digest"
+)
+
+// the channels the notifications are delivered through
+const (
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+	ChannelFCM      = "fcm"
+	ChannelEmail    = "email"
+)
+
+const (
+	prefsPrefix = "notify:prefs:"
+	// queuePrefix keeps the notifications held back during the quiet hours
+	queuePrefix = "notify:queue:"
+	// digestPrefix keeps the date of the last digest sent to a subscriber
+	digestPrefix = "notify:digest:"
+
+	// maxQueue bounds the notifications held back for a subscriber, the oldest are dropped
+	maxQueue = 50
+	// maxChannels bounds the channels and maxFilters the groups and teachers of a subscriber
+	maxChannels = 5
+	maxFilters  = 20
+
+	defaultDigestHour = 7
+
+	tickInterval = time.Minute
+	sendTimeout  = time.Second * 30
+)
+
+// Events are the events the subscribers choose from
+var Events = []string{EventScheduleChange, EventAnnounce, EventDigest}
+
+// ErrInvalid is returned for the preferences that can't be saved
+var ErrInvalid = errors.New("invalid notification preferences")
+
+// Channel is where the notifications of a subscriber are delivered: the URL
+// of a webhook, the Telegram chat id, the FCM registration token or the email
+type Channel struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	// Secret signs the requests of a webhook, it is generated when the webhook is added
+	Secret string `json:"secret,omitempty"`
+	// Failures counts the deliveries failed after every retry since the last
+	// success, the channel is disabled after RetryConfig.DisableAfter of them
+	Failures int  `json:"failures,omitempty"`
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// same reports whether the channels deliver to the same place
+func (c Channel) same(other Channel) bool {
+	return c.Kind == other.Kind && c.Target == other.Target
+}
+
+// QuietHours are the local times like 22:00 and 07:00 between which the
+// notifications are held back and sent together at the end
+type QuietHours struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Preferences are the notifications a subscriber wants
+type Preferences struct {
+	Events   []string  `json:"events"`
+	Channels []Channel `json:"channels"`
+	// Groups and Teachers limit the schedule changes and make the digest, all changes when empty
+	Groups   []string    `json:"groups,omitempty"`
+	Teachers []string    `json:"teachers,omitempty"`
+	Quiet    *QuietHours `json:"quiet_hours,omitempty"`
+	// DigestHour is the local hour the daily digest is sent at
+	DigestHour int       `json:"digest_hour"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Notification is an event sent to the subscribers. Group and Teacher are set
+// for the schedule changes, Groups limit an announce to their subscribers
+type Notification struct {
+	Event   string    `json:"event"`
+	Title   string    `json:"title"`
+	Text    string    `json:"text,omitempty"`
+	Group   string    `json:"group,omitempty"`
+	Teacher string    `json:"teacher,omitempty"`
+	Groups  []string  `json:"groups,omitempty"`
+	Time    time.Time `json:"time"`
+	// Traceparent is the trace context of the change making the notification,
+	// its deliveries are spans of that trace
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+// Sender delivers the notifications to the target of a channel, several at
+// once after the quiet hours
+type Sender interface {
+	Send(ctx context.Context, channel Channel, notifications []Notification) error
+}
+
+// Digest returns the daily digest of the preferences, none when nothing is to be sent
+type Digest func(ctx context.Context, preferences Preferences, now time.Time) []Notification
+
+// Notifier keeps the notification preferences of the subscribers and
+// delivers the events to the channels they chose
+type Notifier struct {
+	store    storage.Store
+	location *time.Location
+	now      func() time.Time
+	log      *logrus.Logger
+
+	mu      sync.RWMutex
+	senders map[string]Sender
+	digest  Digest
+	retry   RetryConfig
+
+	// state serializes the changes of the held back notifications and of the
+	// state of the channels
+	state sync.Mutex
+}
+
+// New creates the notifier keeping the preferences in the store, the quiet
+// hours are in the location
+func New(store storage.Store, location *time.Location, logger *logrus.Logger) *Notifier {
+	if location == nil {
+		location = time.Local
+	}
+
+	return &Notifier{
+		store:    store,
+		location: location,
+		now:      time.Now,
+		log:      logger,
+		senders:  make(map[string]Sender),
+		retry:    RetryConfig{}.withDefaults(),
+	}
+}
+
+// SetRetry sets the retries of the failed deliveries
+func (n *Notifier) SetRetry(cfg RetryConfig) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.retry = cfg.withDefaults()
+}
+
+// SetSender enables the channel of the kind
+func (n *Notifier) SetSender(kind string, sender Sender) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.senders[kind] = sender
+}
+
+// SetDigest sets the maker of the daily digests, they are not sent without it
+func (n *Notifier) SetDigest(digest Digest) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.digest = digest
+}
+
+// Channels returns the kinds of the enabled channels
+func (n *Notifier) Channels() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	kinds := make([]string, 0, len(n.senders))
+	for kind := range n.senders {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	return kinds
+}
+
+func (n *Notifier) sender(kind string) Sender {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.senders[kind]
+}
+
+// DefaultPreferences are the preferences of a new subscriber, no events
+func DefaultPreferences() Preferences {
+	return Preferences{Events: []string{}, Channels: []Channel{}, DigestHour: defaultDigestHour}
+}
+
+// Preferences returns the preferences of the subscriber, storage.ErrNotFound
+// when they are not set
+func (n *Notifier) Preferences(ctx context.Context, subscriber string) (Preferences, error) {
+	value, err := n.store.Get(ctx, prefsPrefix+subscriber)
+	if err != nil {
+		return Preferences{}, err
+	}
+
+	var preferences Preferences
+	if err = json.Unmarshal([]byte(value), &preferences); err != nil {
+		return Preferences{}, err
+	}
+
+	return preferences, nil
+}
+
+// SetPreferences validates and saves the preferences of the subscriber. The
+// webhooks kept get their secret back, the new ones a new secret, and every
+// channel given is enabled again
+func (n *Notifier) SetPreferences(ctx context.Context, subscriber string, preferences *Preferences) error {
+	if err := n.validate(ctx, preferences); err != nil {
+		return err
+	}
+
+	n.state.Lock()
+	defer n.state.Unlock()
+
+	previous, err := n.Preferences(ctx, subscriber)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	for i := range preferences.Channels {
+		channel := &preferences.Channels[i]
+		channel.Secret, channel.Failures, channel.Disabled = "", 0, false
+
+		for _, kept := range previous.Channels {
+			if kept.same(*channel) {
+				channel.Secret = kept.Secret
+			}
+		}
+
+		if channel.Kind == ChannelWebhook && channel.Secret == "" {
+			channel.Secret = newID() + newID()
+		}
+	}
+
+	preferences.UpdatedAt = n.now()
+
+	return n.save(ctx, subscriber, *preferences)
+}
+
+func (n *Notifier) save(ctx context.Context, subscriber string, preferences Preferences) error {
+	data, err := json.Marshal(preferences)
+	if err != nil {
+		return err
+	}
+
+	return n.store.Set(ctx, prefsPrefix+subscriber, string(data))
+}
+
+// Unsubscribe deletes the preferences and the held back notifications of the subscriber
+func (n *Notifier) Unsubscribe(ctx context.Context, subscriber string) error {
+	// the pending retries are dropped when they find no channel
+	for _, prefix := range []string{prefsPrefix, queuePrefix, digestPrefix} {
+		if err := n.store.Delete(ctx, prefix+subscriber); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *Notifier) validate(ctx context.Context, p *Preferences) error {
+	if p.Events == nil {
+		p.Events = []string{}
+	}
+	if p.Channels == nil {
+		p.Channels = []Channel{}
+	}
+
+	for _, event := range p.Events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("%w: event %q", ErrInvalid, event)
+		}
+	}
+
+	if len(p.Channels) > maxChannels || len(p.Groups) > maxFilters || len(p.Teachers) > maxFilters {
+		return fmt.Errorf("%w: too many channels, groups or teachers", ErrInvalid)
+	}
+
+	for _, channel := range p.Channels {
+		if n.sender(channel.Kind) == nil {
+			return fmt.Errorf("%w: channel %q", ErrInvalid, channel.Kind)
+		}
+
+		if !validTarget(channel) {
+			return fmt.Errorf("%w: %s target %q", ErrInvalid, channel.Kind, channel.Target)
+		}
+
+		if channel.Kind == ChannelWebhook {
+			u, _ := url.Parse(channel.Target)
+			if err := publicHost(ctx, u.Hostname()); err != nil {
+				return fmt.Errorf("%w: %s target %q: %s", ErrInvalid, channel.Kind, channel.Target, err)
+			}
+		}
+	}
+
+	if p.Quiet != nil {
+		if _, err := clock(p.Quiet.From); err != nil {
+			return fmt.Errorf("%w: quiet hours: %s", ErrInvalid, err)
+		}
+		if _, err := clock(p.Quiet.To); err != nil {
+			return fmt.Errorf("%w: quiet hours: %s", ErrInvalid, err)
+		}
+	}
+
+	if p.DigestHour < 0 || p.DigestHour > 23 {
+		return fmt.Errorf("%w: digest hour %d", ErrInvalid, p.DigestHour)
+	}
+
+	return nil
+}
+
+// validTarget checks the form of the target of the channel
+func validTarget(channel Channel) bool {
+	target := channel.Target
+	if target == "" || len(target) > 512 {
+		return false
+	}
+
+	switch channel.Kind {
+	case ChannelWebhook:
+		u, err := url.Parse(target)
+		return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+	case ChannelTelegram:
+		_, err := strconv.ParseInt(target, 10, 64)
+		return err == nil
+	case ChannelEmail:
+		address, err := mail.ParseAddress(target)
+		return err == nil && address.Address == target
+	default:
+		return true
+	}
+}
+
+// clock returns the minutes after midnight of a time like 22:00
+func clock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// quiet reports whether the time is in the quiet hours, they may span midnight
+func (n *Notifier) quiet(p Preferences, now time.Time) bool {
+	if p.Quiet == nil {
+		return false
+	}
+
+	from, err := clock(p.Quiet.From)
+	if err != nil {
+		return false
+	}
+	to, err := clock(p.Quiet.To)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(n.location)
+	minute := local.Hour()*60 + local.Minute()
+
+	if from <= to {
+		return from <= minute && minute < to
+	}
+
+	return minute >= from || minute < to
+}
+
+// wants reports whether the subscriber wants the notification
+func (p Preferences) wants(notification Notification) bool {
+	if !slices.Contains(p.Events, notification.Event) {
+		return false
+	}
+
+	switch notification.Event {
+	case EventScheduleChange:
+		if len(p.Groups)+len(p.Teachers) == 0 {
+			return true
+		}
+
+		return (notification.Group != "" && slices.Contains(p.Groups, notification.Group)) ||
+			(notification.Teacher != "" && slices.Contains(p.Teachers, notification.Teacher))
+	case EventAnnounce:
+		if len(notification.Groups) == 0 {
+			return true
+		}
+
+		return slices.ContainsFunc(p.Groups, func(group string) bool {
+			return slices.Contains(notification.Groups, group)
+		})
+	default:
+		return true
+	}
+}
+
+// subscribers returns the ids of the subscribers
+func (n *Notifier) subscribers(ctx context.Context) ([]string, error) {
+	keys, err := n.store.Keys(ctx, prefsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefsPrefix)
+	}
+
+	return keys, nil
+}
+
+// Publish sends the notification to the subscribers wanting it, the ones in
+// their quiet hours get it at the end of them. It returns the number of the
+// subscribers notified or holding it
+func (n *Notifier) Publish(ctx context.Context, notification Notification) (int, error) {
+	if notification.Time.IsZero() {
+		notification.Time = n.now()
+	}
+
+	if notification.Traceparent == "" {
+		notification.Traceparent = traceparent.FromContext(ctx)
+	}
+	if notification.Traceparent == "" {
+		notification.Traceparent = traceparent.New()
+	}
+
+	subscribers, err := n.subscribers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, subscriber := range subscribers {
+		preferences, err := n.Preferences(ctx, subscriber)
+		if err != nil {
+			n.log.WithContext(ctx).Warnf("notify: %s: %s", subscriber, err)
+			continue
+		}
+
+		if !preferences.wants(notification) {
+			continue
+		}
+
+		if err = n.notify(ctx, subscriber, preferences, notification); err != nil {
+			n.log.WithContext(ctx).Warnf("notify: %s: %s", subscriber, err)
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// notify delivers the notification or holds it back during the quiet hours
+func (n *Notifier) notify(ctx context.Context, subscriber string, preferences Preferences, notification Notification) error {
+	if n.quiet(preferences, n.now()) {
+		return n.hold(ctx, subscriber, notification)
+	}
+
+	n.deliver(ctx, subscriber, preferences, []Notification{notification})
+
+	return nil
+}
+
+// hold appends the notification to the queue of the subscriber
+func (n *Notifier) hold(ctx context.Context, subscriber string, notification Notification) error {
+	n.state.Lock()
+	defer n.state.Unlock()
+
+	queue, err := n.queue(ctx, subscriber)
+	if err != nil {
+		return err
+	}
+
+	queue = append(queue, notification)
+	if len(queue) > maxQueue {
+		queue = queue[len(queue)-maxQueue:]
+	}
+
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+
+	return n.store.Set(ctx, queuePrefix+subscriber, string(data))
+}
+
+func (n *Notifier) queue(ctx context.Context, subscriber string) ([]Notification, error) {
+	value, err := n.store.Get(ctx, queuePrefix+subscriber)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var queue []Notification
+	if err = json.Unmarshal([]byte(value), &queue); err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+// deliver sends the notifications through every enabled channel of the
+// subscriber, the failed deliveries are retried later
+func (n *Notifier) deliver(ctx context.Context, subscriber string, preferences Preferences, notifications []Notification) {
+	for _, channel := range preferences.Channels {
+		if channel.Disabled {
+			continue
+		}
+
+		id := newID()
+		if err := n.send(ctx, id, channel, notifications); err != nil {
+			n.log.WithContext(ctx).Warnf("notify: %s: %s: %s", subscriber, channel.Kind, err)
+
+			if err = n.retryLater(ctx, delivery{
+				ID:            id,
+				Subscriber:    subscriber,
+				Channel:       channel,
+				Notifications: notifications,
+				Attempt:       1,
+			}); err != nil {
+				n.log.WithContext(ctx).Errorf("notify: %s: %s", subscriber, err)
+			}
+			continue
+		}
+
+		if channel.Failures > 0 {
+			n.succeeded(ctx, subscriber, channel)
+		}
+	}
+}
+
+// send delivers the notifications through the channel, the webhooks get the
+// id of the delivery. The delivery is a span of the trace of the last notification
+func (n *Notifier) send(ctx context.Context, id string, channel Channel, notifications []Notification) error {
+	sender := n.sender(channel.Kind)
+	if sender == nil {
+		return fmt.Errorf("channel %q is not enabled", channel.Kind)
+	}
+
+	ctx = traceparent.NewContext(ctx, traceparent.Child(notifications[len(notifications)-1].Traceparent))
+	ctx, cancel := context.WithTimeout(withDelivery(ctx, id), sendTimeout)
+	defer cancel()
+
+	return sender.Send(ctx, channel, notifications)
+}
+
+// Run sends the notifications held back when the quiet hours end and the
+// daily digests until the context is done
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := n.tick(ctx); err != nil {
+				n.log.Errorf("notify: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *Notifier) tick(ctx context.Context) error {
+	subscribers, err := n.subscribers(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := n.now()
+	if err = n.retryDue(ctx, now); err != nil {
+		n.log.Errorf("notify: retries: %s", err)
+	}
+
+	for _, subscriber := range subscribers {
+		preferences, err := n.Preferences(ctx, subscriber)
+		if err != nil {
+			continue
+		}
+
+		if err = n.sendDigest(ctx, subscriber, preferences, now); err != nil {
+			n.log.Warnf("notify: %s: digest: %s", subscriber, err)
+		}
+
+		if n.quiet(preferences, now) {
+			continue
+		}
+
+		if err = n.flush(ctx, subscriber, preferences); err != nil {
+			n.log.Warnf("notify: %s: %s", subscriber, err)
+		}
+	}
+
+	return nil
+}
+
+// flush sends the held back notifications together
+func (n *Notifier) flush(ctx context.Context, subscriber string, preferences Preferences) error {
+	n.state.Lock()
+	queue, err := n.queue(ctx, subscriber)
+	if err == nil && len(queue) > 0 {
+		err = n.store.Delete(ctx, queuePrefix+subscriber)
+	}
+	n.state.Unlock()
+
+	if err != nil || len(queue) == 0 {
+		return err
+	}
+
+	n.deliver(ctx, subscriber, preferences, queue)
+
+	return nil
+}
+
+// sendDigest sends the daily digest once a day from the digest hour
+func (n *Notifier) sendDigest(ctx context.Context, subscriber string, preferences Preferences, now time.Time) error {
+	n.mu.RLock()
+	digest := n.digest
+	n.mu.RUnlock()
+
+	if digest == nil || !slices.Contains(preferences.Events, EventDigest) {
+		return nil
+	}
+
+	local := now.In(n.location)
+	if local.Hour() < preferences.DigestHour {
+		return nil
+	}
+
+	today := local.Format(time.DateOnly)
+	sent, err := n.store.Get(ctx, digestPrefix+subscriber)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if sent == today {
+		return nil
+	}
+
+	// the day is marked first so a failing digest is not sent every minute
+	if err = n.store.Set(ctx, digestPrefix+subscriber, today); err != nil {
+		return err
+	}
+
+	for _, notification := range digest(ctx, preferences, now) {
+		if notification.Time.IsZero() {
+			notification.Time = now
+		}
+		if err = n.notify(ctx, subscriber, preferences, notification); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
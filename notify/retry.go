@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+)
+
+// RetryConfig is the configuration of the retries of the failed deliveries
+type RetryConfig struct {
+	// Attempts is the number of sends of a delivery, the first one included
+	Attempts int `yaml:"attempts"`
+	// Backoff is the pause before the first retry, doubled every retry
+	Backoff time.Duration `yaml:"backoff"`
+	// MaxBackoff bounds the pause before a retry
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// DisableAfter is the number of deliveries in a row failing every attempt
+	// after which the channel is disabled
+	DisableAfter int `yaml:"disable_after"`
+}
+
+const (
+	// retryPrefix keeps the deliveries waiting for a retry
+	retryPrefix = "notify:retry:"
+
+	defaultAttempts     = 6
+	defaultBackoff      = time.Minute
+	defaultMaxBackoff   = time.Hour
+	defaultDisableAfter = 5
+)
+
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.Attempts <= 0 {
+		c.Attempts = defaultAttempts
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = defaultBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.DisableAfter <= 0 {
+		c.DisableAfter = defaultDisableAfter
+	}
+
+	return c
+}
+
+// backoff returns the pause after the failed attempt
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	pause := c.Backoff
+	for i := 1; i < attempt && pause < c.MaxBackoff; i++ {
+		pause *= 2
+	}
+
+	return min(pause, c.MaxBackoff)
+}
+
+// delivery is a failed delivery waiting for a retry, Attempt sends were made
+type delivery struct {
+	ID            string         `json:"id"`
+	Subscriber    string         `json:"subscriber"`
+	Channel       Channel        `json:"channel"`
+	Notifications []Notification `json:"notifications"`
+	Attempt       int            `json:"attempt"`
+	Next          time.Time      `json:"next"`
+}
+
+func (n *Notifier) retryConfig() RetryConfig {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.retry
+}
+
+// retryLater keeps the delivery for the next attempt, the channel fails when
+// no attempt is left
+func (n *Notifier) retryLater(ctx context.Context, d delivery) error {
+	cfg := n.retryConfig()
+	if d.Attempt >= cfg.Attempts {
+		n.failed(ctx, d.Subscriber, d.Channel)
+		return nil
+	}
+
+	d.Next = n.now().Add(cfg.backoff(d.Attempt))
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return n.store.Set(ctx, retryPrefix+d.ID, string(data))
+}
+
+// retryDue sends again the deliveries whose pause is over
+func (n *Notifier) retryDue(ctx context.Context, now time.Time) error {
+	keys, err := n.store.Keys(ctx, retryPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		value, err := n.store.Get(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		var d delivery
+		if err = json.Unmarshal([]byte(value), &d); err != nil || now.Before(d.Next) {
+			continue
+		}
+
+		if err = n.store.Delete(ctx, key); err != nil {
+			return err
+		}
+
+		// the channel may have been removed, disabled or given a new secret since
+		channel, ok := n.channel(ctx, d.Subscriber, d.Channel)
+		if !ok {
+			continue
+		}
+		d.Channel = channel
+
+		if err = n.send(ctx, d.ID, channel, d.Notifications); err == nil {
+			n.succeeded(ctx, d.Subscriber, channel)
+			continue
+		}
+
+		n.log.Warnf("notify: %s: %s: attempt %d: %s", d.Subscriber, channel.Kind, d.Attempt+1, err)
+
+		d.Attempt++
+		if err = n.retryLater(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// channel returns the current state of the enabled channel of the subscriber
+func (n *Notifier) channel(ctx context.Context, subscriber string, channel Channel) (Channel, bool) {
+	preferences, err := n.Preferences(ctx, subscriber)
+	if err != nil {
+		return Channel{}, false
+	}
+
+	for _, current := range preferences.Channels {
+		if current.same(channel) {
+			return current, !current.Disabled
+		}
+	}
+
+	return Channel{}, false
+}
+
+// succeeded resets the failures of the channel
+func (n *Notifier) succeeded(ctx context.Context, subscriber string, channel Channel) {
+	n.updateChannel(ctx, subscriber, channel, func(c *Channel) {
+		c.Failures = 0
+	})
+}
+
+// failed counts a delivery failed every attempt and disables the channel
+// failing persistently
+func (n *Notifier) failed(ctx context.Context, subscriber string, channel Channel) {
+	disableAfter := n.retryConfig().DisableAfter
+
+	n.updateChannel(ctx, subscriber, channel, func(c *Channel) {
+		c.Failures++
+		if c.Failures >= disableAfter && !c.Disabled {
+			c.Disabled = true
+			n.log.Warnf("notify: %s: %s disabled after %d failed deliveries", subscriber, c.Kind, c.Failures)
+		}
+	})
+}
+
+// updateChannel changes the stored channel of the subscriber
+func (n *Notifier) updateChannel(ctx context.Context, subscriber string, channel Channel, change func(c *Channel)) {
+	n.state.Lock()
+	defer n.state.Unlock()
+
+	preferences, err := n.Preferences(ctx, subscriber)
+	if err != nil {
+		return
+	}
+
+	for i := range preferences.Channels {
+		if preferences.Channels[i].same(channel) {
+			change(&preferences.Channels[i])
+		}
+	}
+
+	if err = n.save(ctx, subscriber, preferences); err != nil {
+		n.log.Errorf("notify: %s: %s", subscriber, err)
+	}
+}
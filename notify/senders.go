@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/telegram"
+	"github.com/chazari-x/hmtpk-parser-api/traceparent"
+)
+
+// Config is the configuration of the channels needing credentials, the
+// webhooks are always enabled and Telegram is with the bot token
+type Config struct {
+	Email EmailConfig `yaml:"email"`
+	FCM   FCMConfig   `yaml:"fcm"`
+	Retry RetryConfig `yaml:"retry"`
+}
+
+// EmailConfig is the mail server sending the notifications, the email channel is enabled with SMTP
+type EmailConfig struct {
+	// SMTP is the host:port of the mail server
+	SMTP     string `yaml:"smtp"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender, Username when empty
+	From string `yaml:"from"`
+}
+
+// FCMConfig enables the push notifications of Firebase Cloud Messaging
+type FCMConfig struct {
+	// ServerKey is the key of the legacy HTTP API of the Firebase project
+	ServerKey string `yaml:"server_key"`
+}
+
+const fcmURL = "https://fcm.googleapis.com/fcm/send"
+
+var client = &http.Client{Timeout: sendTimeout}
+
+// text joins the notifications into a message
+func text(notifications []Notification) string {
+	parts := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		part := notification.Title
+		if notification.Text != "" {
+			part += "\n" + notification.Text
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// title is the title of the message of the notifications
+func title(notifications []Notification) string {
+	if len(notifications) == 1 {
+		return notifications[0].Title
+	}
+
+	return "Уведомления: " + strconv.Itoa(len(notifications))
+}
+
+// Telegram sends the notifications from the bot to the chat of the channel
+type Telegram struct {
+	Config telegram.Config
+}
+
+func (t Telegram) Send(ctx context.Context, channel Channel, notifications []Notification) error {
+	chatID, err := strconv.ParseInt(channel.Target, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	return telegram.SendMessage(ctx, t.Config, chatID, text(notifications))
+}
+
+// Email mails the notifications to the address of the channel
+type Email struct {
+	Config EmailConfig
+}
+
+// NewEmail validates the configuration and creates the email channel
+func NewEmail(cfg EmailConfig) (*Email, error) {
+	if _, _, err := net.SplitHostPort(cfg.SMTP); err != nil {
+		return nil, fmt.Errorf("smtp: %w", err)
+	}
+
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+
+	if cfg.From == "" {
+		return nil, errors.New("the email notifications require the sender")
+	}
+
+	return &Email{Config: cfg}, nil
+}
+
+func (e *Email) Send(_ context.Context, channel Channel, notifications []Notification) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.Config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", channel.Target)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", title(notifications)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(text(notifications), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if e.Config.Username != "" {
+		host, _, _ := net.SplitHostPort(e.Config.SMTP)
+		auth = smtp.PlainAuth("", e.Config.Username, e.Config.Password, host)
+	}
+
+	return smtp.SendMail(e.Config.SMTP, auth, e.Config.From, []string{channel.Target}, []byte(msg.String()))
+}
+
+// FCM pushes the notifications to the device of the registration token of the channel
+type FCM struct {
+	Config FCMConfig
+}
+
+func (f FCM) Send(ctx context.Context, channel Channel, notifications []Notification) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"to": channel.Target,
+		"notification": map[string]string{
+			"title": title(notifications),
+			"body":  text(notifications),
+		},
+		"data": map[string]string{"event": notifications[len(notifications)-1].Event},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fcmURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+f.Config.ServerKey)
+	traceparent.Set(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fcm: %s", resp.Status)
+	}
+
+	var result struct {
+		Failure int `json:"failure"`
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	if result.Failure > 0 && len(result.Results) > 0 {
+		return errors.New("fcm: " + result.Results[0].Error)
+	}
+
+	return nil
+}
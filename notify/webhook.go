@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/traceparent"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the body of the webhook requests
+// keyed with the secret of the channel, like sha256=<hex>
+const SignatureHeader = "X-Signature"
+
+// Webhook posts the notifications as JSON to the URL of the channel, signed
+// with the secret of the channel. Only public addresses are reached, the
+// services of the network of the instance can't be called through it
+type Webhook struct{}
+
+// ErrPrivateAddress is returned for a webhook resolving to an address that
+// is not public, like a loopback, private or link-local one
+var ErrPrivateAddress = errors.New("webhook: address is not public")
+
+// lookupTimeout bounds resolving the host of a webhook on validation
+const lookupTimeout = time.Second * 5
+
+// reserved are the ranges not reachable from the internet that net.IP has no
+// method for: this network, shared address space, benchmarking and reserved
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// public reports whether the address can be reached by a webhook
+func public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// publicHost resolves the host of the webhook, every address must be public
+func publicHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !public(addr) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !public(addr) {
+			return ErrPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// webhookClient checks the address of every connection when it is dialed, so
+// a host resolving to another address after the validation, a redirect or a
+// proxy can't reach a private one
+var webhookClient = &http.Client{
+	Timeout: sendTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: sendTimeout,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil || !public(addrPort.Addr()) {
+					return ErrPrivateAddress
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: sendTimeout,
+	},
+}
+
+// WebhookPayload is the body of the webhook requests. ID is the same for the
+// retries of a delivery so the receivers can skip the repeated ones
+type WebhookPayload struct {
+	ID            string         `json:"id"`
+	SentAt        time.Time      `json:"sent_at"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// deliveryKey passes the id of the delivery to the webhook
+type deliveryKey struct{}
+
+func withDelivery(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, id)
+}
+
+func (Webhook) Send(ctx context.Context, channel Channel, notifications []Notification) error {
+	id, _ := ctx.Value(deliveryKey{}).(string)
+	if id == "" {
+		id = newID()
+	}
+
+	body, err := json.Marshal(WebhookPayload{ID: id, SentAt: time.Now(), Notifications: notifications})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(channel.Secret, body))
+	traceparent.Set(req)
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the value of the signature header of the body, the receivers
+// compute it with the secret of the channel and compare in constant time
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
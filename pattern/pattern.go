@@ -0,0 +1,308 @@
+package pattern
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// DefaultThreshold is the share of the weeks a lesson takes place in to be recurring
+const DefaultThreshold = 0.75
+
+const (
+	KindExtra   = "extra"
+	KindMissing = "missing"
+
+	// ParityOdd and ParityEven are the ISO weeks of a lesson held every other week
+	ParityOdd  = "odd"
+	ParityEven = "even"
+)
+
+// DateFunc returns the date of a day of the weekly schedule
+type DateFunc func(day model.Schedule) (time.Time, bool)
+
+// Lesson is a lesson recurring in the typical week
+type Lesson struct {
+	// Weekday is 1 for Monday to 7 for Sunday
+	Weekday  int    `json:"weekday"`
+	Num      string `json:"num"`
+	Time     string `json:"time"`
+	Name     string `json:"name"`
+	Room     string `json:"room"`
+	Teacher  string `json:"teacher"`
+	Subgroup string `json:"subgroup,omitempty"`
+	// Interval is 1 for a weekly lesson and 2 for a lesson held every other week
+	Interval int `json:"interval"`
+	// Parity is the parity of the ISO weeks of a lesson held every other week
+	Parity string `json:"parity,omitempty"`
+	// Occurrences is the number of analyzed weeks the lesson took place in
+	Occurrences int     `json:"occurrences"`
+	Frequency   float64 `json:"frequency"`
+	// First and Last are the dates of the first and the last occurrence
+	First string `json:"first"`
+	Last  string `json:"last"`
+}
+
+// Deviation is a one-off change of the typical week
+type Deviation struct {
+	Date string `json:"date"`
+	// Kind is extra for a lesson outside of the pattern and missing for a
+	// recurring lesson that did not take place
+	Kind   string       `json:"kind"`
+	Lesson model.Lesson `json:"lesson"`
+}
+
+// Pattern is the typical week inferred from several weeks of schedules
+type Pattern struct {
+	// Weeks are the Mondays of the analyzed weeks
+	Weeks      []string    `json:"weeks"`
+	Lessons    []Lesson    `json:"lessons"`
+	Deviations []Deviation `json:"deviations"`
+}
+
+// slot identifies a lesson within the week
+type slot struct {
+	weekday  int
+	num      string
+	name     string
+	room     string
+	teacher  string
+	subgroup string
+}
+
+func slotOf(weekday int, lesson model.Lesson) slot {
+	return slot{
+		weekday:  weekday,
+		num:      lesson.Num,
+		name:     strings.TrimSpace(lesson.Name),
+		room:     strings.TrimSpace(lesson.Room),
+		teacher:  strings.TrimSpace(lesson.Teacher),
+		subgroup: lesson.Subgroup,
+	}
+}
+
+type occurrence struct {
+	monday time.Time
+	date   time.Time
+	lesson model.Lesson
+}
+
+// Infer finds the lessons taking place in at least the threshold share of the
+// weeks, or of the odd or the even weeks for a lesson held every other week,
+// and the deviations of every week from them. Only the weeks with any day in
+// the schedule are analyzed, so holidays do not lower the frequencies
+func Infer(schedule []model.Schedule, date DateFunc, threshold float64) Pattern {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+
+	weeks := make(map[time.Time]bool)
+	days := make(map[time.Time]bool)
+	slots := make(map[slot][]occurrence)
+	for _, day := range schedule {
+		d, ok := date(day)
+		if !ok {
+			continue
+		}
+
+		monday := d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+		weeks[monday] = true
+		days[d] = true
+
+		for _, lesson := range day.Lessons {
+			if lesson.Name == "" {
+				continue
+			}
+
+			key := slotOf(weekday(d), lesson)
+			slots[key] = append(slots[key], occurrence{monday: monday, date: d, lesson: lesson})
+		}
+	}
+
+	mondays := make([]time.Time, 0, len(weeks))
+	parities := make(map[string]int)
+	for monday := range weeks {
+		mondays = append(mondays, monday)
+		parities[parity(monday)]++
+	}
+	sort.Slice(mondays, func(i, j int) bool {
+		return mondays[i].Before(mondays[j])
+	})
+
+	result := Pattern{Weeks: make([]string, len(mondays)), Lessons: []Lesson{}, Deviations: []Deviation{}}
+	for i, monday := range mondays {
+		result.Weeks[i] = monday.Format("02.01.2006")
+	}
+
+	recurring := make(map[slot]Lesson)
+	for key, occurrences := range slots {
+		sort.Slice(occurrences, func(i, j int) bool {
+			return occurrences[i].date.Before(occurrences[j].date)
+		})
+
+		held := make(map[time.Time]bool)
+		byParity := make(map[string]int)
+		for _, o := range occurrences {
+			if !held[o.monday] {
+				held[o.monday] = true
+				byParity[parity(o.monday)]++
+			}
+		}
+
+		lesson := Lesson{
+			Weekday:     key.weekday,
+			Num:         key.num,
+			Name:        key.name,
+			Room:        key.room,
+			Teacher:     key.teacher,
+			Subgroup:    key.subgroup,
+			Occurrences: len(held),
+			Frequency:   float64(len(held)) / float64(len(mondays)),
+			First:       occurrences[0].date.Format("02.01.2006"),
+			Last:        occurrences[len(occurrences)-1].date.Format("02.01.2006"),
+			Time:        occurrences[len(occurrences)-1].lesson.Time,
+		}
+
+		switch {
+		// a single week tells nothing about recurrence
+		case len(mondays) < 2:
+			continue
+		case lesson.Frequency >= threshold:
+			lesson.Interval = 1
+		default:
+			// held in the weeks of one parity only
+			for _, p := range []string{ParityOdd, ParityEven} {
+				other := ParityEven
+				if p == ParityEven {
+					other = ParityOdd
+				}
+
+				if parities[p] >= 2 && byParity[other] == 0 && float64(byParity[p])/float64(parities[p]) >= threshold {
+					lesson.Interval, lesson.Parity = 2, p
+					lesson.Frequency = float64(byParity[p]) / float64(parities[p])
+				}
+			}
+		}
+
+		if lesson.Interval == 0 {
+			continue
+		}
+
+		lesson.Frequency = float64(int(lesson.Frequency*1000+0.5)) / 1000
+		recurring[key] = lesson
+		result.Lessons = append(result.Lessons, lesson)
+	}
+
+	sort.Slice(result.Lessons, func(i, j int) bool {
+		a, b := result.Lessons[i], result.Lessons[j]
+		if a.Weekday != b.Weekday {
+			return a.Weekday < b.Weekday
+		}
+		if a.Num != b.Num {
+			return lessNum(a.Num, b.Num)
+		}
+		if a.Subgroup != b.Subgroup {
+			return a.Subgroup < b.Subgroup
+		}
+		return a.Name < b.Name
+	})
+
+	// lessons outside of the pattern
+	for key, occurrences := range slots {
+		if _, ok := recurring[key]; ok {
+			continue
+		}
+
+		for _, o := range occurrences {
+			result.Deviations = append(result.Deviations, Deviation{Date: o.date.Format("02.01.2006"), Kind: KindExtra, Lesson: o.lesson})
+		}
+	}
+
+	// recurring lessons missing on a day in the schedule, a day absent from
+	// the schedule is not known to have no lessons
+	for key, lesson := range recurring {
+		held := make(map[time.Time]bool)
+		for _, o := range slots[key] {
+			held[o.date] = true
+		}
+
+		for _, monday := range mondays {
+			if lesson.Interval == 2 && parity(monday) != lesson.Parity {
+				continue
+			}
+
+			d := monday.AddDate(0, 0, key.weekday-1)
+			if !days[d] || held[d] {
+				continue
+			}
+
+			result.Deviations = append(result.Deviations, Deviation{
+				Date: d.Format("02.01.2006"),
+				Kind: KindMissing,
+				Lesson: model.Lesson{
+					Num:      lesson.Num,
+					Time:     lesson.Time,
+					Name:     lesson.Name,
+					Room:     lesson.Room,
+					Teacher:  lesson.Teacher,
+					Subgroup: lesson.Subgroup,
+				},
+			})
+		}
+	}
+
+	sort.SliceStable(result.Deviations, func(i, j int) bool {
+		a, b := result.Deviations[i], result.Deviations[j]
+		da, _ := time.Parse("02.01.2006", a.Date)
+		db, _ := time.Parse("02.01.2006", b.Date)
+		if !da.Equal(db) {
+			return da.Before(db)
+		}
+		if a.Lesson.Num != b.Lesson.Num {
+			return lessNum(a.Lesson.Num, b.Lesson.Num)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Lesson.Subgroup != b.Lesson.Subgroup {
+			return a.Lesson.Subgroup < b.Lesson.Subgroup
+		}
+		return a.Lesson.Name < b.Lesson.Name
+	})
+
+	return result
+}
+
+// weekday returns 1 for Monday to 7 for Sunday
+func weekday(d time.Time) int {
+	return (int(d.Weekday())+6)%7 + 1
+}
+
+// parity returns the parity of the ISO week of the date
+func parity(d time.Time) string {
+	if _, week := d.ISOWeek(); week%2 == 0 {
+		return ParityEven
+	}
+
+	return ParityOdd
+}
+
+// lessNum orders lesson numbers numerically, the ones that are not numbers last
+func lessNum(a, b string) bool {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+
+	switch {
+	case errX == nil && errY == nil:
+		return x < y
+	case errX == nil:
+		return true
+	case errY == nil:
+		return false
+	}
+
+	return a < b
+}
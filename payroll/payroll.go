@@ -0,0 +1,148 @@
+package payroll
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/bells"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	// AcademicHour is the length of an academic hour
+	AcademicHour = time.Minute * 45
+	// DefaultLessonHours are the academic hours of a lesson whose time is unknown
+	DefaultLessonHours = 2
+)
+
+// DateFunc returns the date of a day of the weekly schedule
+type DateFunc func(day model.Schedule) (time.Time, bool)
+
+// Teacher is the academic hours a teacher actually had in the month
+type Teacher struct {
+	Teacher string `json:"teacher"`
+	// Lessons are counted once for a lesson held with several groups together
+	Lessons  int      `json:"lessons"`
+	Hours    int      `json:"hours"`
+	Groups   []string `json:"groups"`
+	Subjects []string `json:"subjects"`
+}
+
+// Report is the academic hours of the teachers in the month
+type Report struct {
+	// Month is in the form 2006-01
+	Month string `json:"month"`
+	// Days are the days of the month with archived schedules, the hours of
+	// the days never archived are missing from the report
+	Days     int       `json:"days"`
+	Teachers []Teacher `json:"teachers"`
+}
+
+// LessonHours returns the academic hours of a lesson by its time like "08:30 - 10:00"
+func LessonHours(value string) int {
+	start, end, ok := bells.ParseTime(value)
+	if !ok {
+		return DefaultLessonHours
+	}
+
+	from, err := time.Parse("15:04", start)
+	if err != nil {
+		return DefaultLessonHours
+	}
+
+	to, err := time.Parse("15:04", end)
+	if err != nil || !to.After(from) {
+		return DefaultLessonHours
+	}
+
+	return max(1, int((to.Sub(from)+AcademicHour/2)/AcademicHour))
+}
+
+// Aggregate sums the lessons of the group schedules, with the replacements
+// already applied by the site, per teacher in the month starting at month.
+// Only the teachers accepted by filter are included, every one with a nil filter
+func Aggregate(schedules map[string][]model.Schedule, month time.Time, date DateFunc, filter func(teacher string) bool) Report {
+	type slot struct {
+		teacher string
+		date    string
+		num     string
+	}
+
+	type totals struct {
+		lessons  int
+		hours    int
+		groups   map[string]bool
+		subjects map[string]bool
+	}
+
+	var (
+		seen     = make(map[slot]bool)
+		days     = make(map[string]bool)
+		teachers = make(map[string]*totals)
+	)
+
+	for group, schedule := range schedules {
+		for _, day := range schedule {
+			d, ok := date(day)
+			if !ok || d.Year() != month.Year() || d.Month() != month.Month() {
+				continue
+			}
+			dayDate := d.Format(time.DateOnly)
+			days[dayDate] = true
+
+			for _, lesson := range day.Lessons {
+				teacher := strings.TrimSpace(lesson.Teacher)
+				if teacher == "" || lesson.Name == "" || (filter != nil && !filter(teacher)) {
+					continue
+				}
+
+				t, ok := teachers[teacher]
+				if !ok {
+					t = &totals{groups: make(map[string]bool), subjects: make(map[string]bool)}
+					teachers[teacher] = t
+				}
+
+				t.groups[group] = true
+				t.subjects[strings.TrimSpace(lesson.Name)] = true
+
+				// a lesson held with several groups together is paid once
+				key := slot{teacher, dayDate, lesson.Num}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				t.lessons++
+				t.hours += LessonHours(lesson.Time)
+			}
+		}
+	}
+
+	report := Report{Month: month.Format("2006-01"), Days: len(days), Teachers: make([]Teacher, 0, len(teachers))}
+	for teacher, t := range teachers {
+		report.Teachers = append(report.Teachers, Teacher{
+			Teacher:  teacher,
+			Lessons:  t.lessons,
+			Hours:    t.hours,
+			Groups:   keys(t.groups),
+			Subjects: keys(t.subjects),
+		})
+	}
+
+	sort.Slice(report.Teachers, func(i, j int) bool {
+		return report.Teachers[i].Teacher < report.Teachers[j].Teacher
+	})
+
+	return report
+}
+
+func keys(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	return values
+}
@@ -0,0 +1,85 @@
+package peak
+
+import (
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/window"
+)
+
+// Config is the configuration of the peak mode, the hours of the morning
+// rush when every student opens the schedule at once
+type Config struct {
+	// Windows are the hours of the peak, e.g. 07:30-09:00 on weekdays
+	Windows []window.Window `yaml:"windows"`
+	// Warmup is the interval between the warmup cycles during the peak
+	Warmup time.Duration `yaml:"warmup"`
+	// TTLFactor multiplies the expiration of the data cached during the peak
+	TTLFactor float64 `yaml:"ttl_factor"`
+	// RateFactor multiplies the rate limits of the tenants during the peak
+	RateFactor float64 `yaml:"rate_factor"`
+}
+
+const (
+	defaultWarmup     = time.Minute
+	defaultTTLFactor  = 3
+	defaultRateFactor = 2
+)
+
+// Mode tells whether the service is in the peak: the warmer keeps every
+// group fresh, the background jobs pause, the cached data lives longer and
+// the rate limits relax
+type Mode struct {
+	cfg     Config
+	windows *window.Set
+}
+
+// New validates the configuration of the peak mode
+func New(cfg Config) (*Mode, error) {
+	windows, err := window.New(cfg.Windows)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Warmup <= 0 {
+		cfg.Warmup = defaultWarmup
+	}
+
+	if cfg.TTLFactor < 1 {
+		cfg.TTLFactor = defaultTTLFactor
+	}
+
+	if cfg.RateFactor < 1 {
+		cfg.RateFactor = defaultRateFactor
+	}
+
+	return &Mode{cfg: cfg, windows: windows}, nil
+}
+
+// SetLocation sets the time zone of the peak hours
+func (m *Mode) SetLocation(location *time.Location) {
+	m.windows.SetLocation(location)
+}
+
+// Active reports whether the peak is on
+func (m *Mode) Active() bool {
+	return m.windows.Active(time.Now())
+}
+
+// TTL returns the expiration of the data cached now
+func (m *Mode) TTL(ttl time.Duration) time.Duration {
+	if !m.Active() {
+		return ttl
+	}
+
+	return time.Duration(float64(ttl) * m.cfg.TTLFactor)
+}
+
+// Warmup returns the interval between the warmup cycles during the peak
+func (m *Mode) Warmup() time.Duration {
+	return m.cfg.Warmup
+}
+
+// RateFactor returns the factor of the rate limits during the peak
+func (m *Mode) RateFactor() float64 {
+	return m.cfg.RateFactor
+}
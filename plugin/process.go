@@ -0,0 +1,262 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config describes a plugin executable
+type Config struct {
+	Name    string            `yaml:"name"`
+	Kind    string            `yaml:"kind"`
+	Command []string          `yaml:"command"`
+	Env     map[string]string `yaml:"env"`
+}
+
+const (
+	// KindSource is a plugin providing groups, teachers, schedules and announces
+	KindSource = "source"
+
+	// ProtocolVersion is announced by the plugin in its handshake line
+	ProtocolVersion = 1
+
+	handshakeTimeout = time.Second * 10
+)
+
+var ErrClosed = errors.New("plugin is closed")
+
+// request is a line written to the stdin of the plugin
+type request struct {
+	ID     uint64      `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// response is a line read from the stdout of the plugin
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Code   string          `json:"code,omitempty"`
+}
+
+// Error is an error returned by the plugin
+type Error struct {
+	Plugin  string
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("plugin %s: %s", e.Plugin, e.Message)
+}
+
+// Process is a running plugin. Requests are JSON lines written to its stdin,
+// responses are JSON lines read from its stdout and matched by id. Before
+// serving, the plugin writes the handshake line "<version>|<kind>"
+type Process struct {
+	cfg Config
+	log *logrus.Logger
+	cmd *exec.Cmd
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan response
+	err     error
+	done    chan struct{}
+}
+
+// Start launches the plugin and waits for its handshake
+func Start(cfg Config, logger *logrus.Logger) (*Process, error) {
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("plugin %s: empty command", cfg.Name)
+	}
+
+	cmd := exec.Command(cfg.Command[0], cfg.Command[1:]...)
+	cmd.Env = os.Environ()
+	for key, value := range cfg.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stderr = logger.WithField("plugin", cfg.Name).WriterLevel(logrus.WarnLevel)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", cfg.Name, err)
+	}
+
+	p := &Process{
+		cfg:     cfg,
+		log:     logger,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[uint64]chan response),
+		done:    make(chan struct{}),
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	handshake := make(chan error, 1)
+	go func() {
+		handshake <- p.handshake(scanner)
+	}()
+
+	select {
+	case err = <-handshake:
+	case <-time.After(handshakeTimeout):
+		err = errors.New("handshake timeout")
+	}
+
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("plugin %s: %w", cfg.Name, err)
+	}
+
+	go p.read(scanner)
+
+	return p, nil
+}
+
+func (p *Process) handshake(scanner *bufio.Scanner) error {
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		return io.ErrUnexpectedEOF
+	}
+
+	expected := fmt.Sprintf("%d|%s", ProtocolVersion, p.cfg.Kind)
+	if line := strings.TrimSpace(scanner.Text()); line != expected {
+		return fmt.Errorf("unexpected handshake %q, want %q", line, expected)
+	}
+
+	return nil
+}
+
+func (p *Process) read(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			p.log.Warnf("plugin %s: bad response: %s", p.cfg.Name, err)
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.mu.Unlock()
+
+		if ok {
+			ch <- resp
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+
+	_ = p.cmd.Wait()
+
+	p.mu.Lock()
+	p.err = fmt.Errorf("plugin %s exited: %w", p.cfg.Name, err)
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+	p.mu.Unlock()
+
+	close(p.done)
+}
+
+// Call sends a request and decodes the result into result
+func (p *Process) Call(ctx context.Context, method string, params, result interface{}) error {
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return p.err
+	}
+
+	p.nextID++
+	id := p.nextID
+	ch := make(chan response, 1)
+	p.pending[id] = ch
+	p.mu.Unlock()
+
+	data, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err != nil {
+		p.forget(id)
+		return err
+	}
+
+	p.writeMu.Lock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		p.forget(id)
+		return fmt.Errorf("plugin %s: %w", p.cfg.Name, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrClosed
+		}
+
+		if resp.Error != "" {
+			return &Error{Plugin: p.cfg.Name, Code: resp.Code, Message: resp.Error}
+		}
+
+		if result == nil {
+			return nil
+		}
+
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		p.forget(id)
+		return ctx.Err()
+	}
+}
+
+func (p *Process) forget(id uint64) {
+	p.mu.Lock()
+	delete(p.pending, id)
+	p.mu.Unlock()
+}
+
+// Close stops the plugin: stdin is closed so it can exit gracefully, then it is killed
+func (p *Process) Close() {
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(time.Second * 5):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"context"
+
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+// Error codes a source plugin may return to map to the errors of the parser
+const (
+	CodeBadRequest  = "bad_request"
+	CodeBadResponse = "bad_response"
+)
+
+// Source is a source provider implemented by a plugin. Methods:
+//
+//	GetGroupOptions      {}                        -> [Option]
+//	GetTeacherOptions    {}                        -> [Option]
+//	GetScheduleByGroup   {"group", "date"}         -> [Schedule]
+//	GetScheduleByTeacher {"teacher", "date"}       -> [Schedule]
+//	GetAnnounces         {"page"}                  -> Announces
+type Source struct {
+	p *Process
+}
+
+// NewSource uses the running plugin as a source provider
+func NewSource(p *Process) *Source {
+	return &Source{p: p}
+}
+
+func (s *Source) call(ctx context.Context, method string, params, result interface{}) error {
+	err := s.p.Call(ctx, method, params, result)
+
+	if e, ok := err.(*Error); ok {
+		switch e.Code {
+		case CodeBadRequest:
+			return hmtpkErrors.ErrorBadRequest
+		case CodeBadResponse:
+			return hmtpkErrors.ErrorBadResponse
+		}
+	}
+
+	return err
+}
+
+// GetGroupOptions returns the list of groups
+func (s *Source) GetGroupOptions(ctx context.Context) (options []model.Option, err error) {
+	err = s.call(ctx, "GetGroupOptions", nil, &options)
+	return
+}
+
+// GetTeacherOptions returns the list of teachers
+func (s *Source) GetTeacherOptions(ctx context.Context) (options []model.Option, err error) {
+	err = s.call(ctx, "GetTeacherOptions", nil, &options)
+	return
+}
+
+// GetScheduleByGroup returns the weekly schedule of the group
+func (s *Source) GetScheduleByGroup(ctx context.Context, group, date string) (schedule []model.Schedule, err error) {
+	err = s.call(ctx, "GetScheduleByGroup", map[string]string{"group": group, "date": date}, &schedule)
+	return
+}
+
+// GetScheduleByTeacher returns the weekly schedule of the teacher
+func (s *Source) GetScheduleByTeacher(ctx context.Context, teacher, date string) (schedule []model.Schedule, err error) {
+	err = s.call(ctx, "GetScheduleByTeacher", map[string]string{"teacher": teacher, "date": date}, &schedule)
+	return
+}
+
+// GetAnnounces returns a page of announces
+func (s *Source) GetAnnounces(ctx context.Context, page int) (announces model.Announces, err error) {
+	err = s.call(ctx, "GetAnnounces", map[string]int{"page": page}, &announces)
+	return
+}
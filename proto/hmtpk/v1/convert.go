@@ -0,0 +1,52 @@
+package hmtpkv1
+
+import "github.com/chazari-x/hmtpk_parser/v2/model"
+
+// FromOptions converts the options of the parser to messages
+func FromOptions(options []model.Option) []*Option {
+	result := make([]*Option, 0, len(options))
+	for _, option := range options {
+		result = append(result, &Option{Label: option.Label, Value: option.Value})
+	}
+
+	return result
+}
+
+// FromSchedule converts the schedule of the parser to messages
+func FromSchedule(schedule []model.Schedule) []*Schedule {
+	result := make([]*Schedule, 0, len(schedule))
+	for _, day := range schedule {
+		lessons := make([]*Lesson, 0, len(day.Lessons))
+		for _, lesson := range day.Lessons {
+			lessons = append(lessons, &Lesson{
+				Num:      lesson.Num,
+				Time:     lesson.Time,
+				Name:     lesson.Name,
+				Room:     lesson.Room,
+				Location: lesson.Location,
+				Group:    lesson.Group,
+				Subgroup: lesson.Subgroup,
+				Teacher:  lesson.Teacher,
+			})
+		}
+
+		result = append(result, &Schedule{Date: day.Date, Lessons: lessons, Href: day.Href})
+	}
+
+	return result
+}
+
+// FromAnnounces converts the announces of the parser to a message
+func FromAnnounces(announces model.Announces) *GetAnnouncesResponse {
+	response := &GetAnnouncesResponse{LastPage: int32(announces.LastPage)}
+	for _, announce := range announces.Announces {
+		response.Announces = append(response.Announces, &Announce{
+			Path:  announce.Path,
+			Date:  announce.Date,
+			Title: announce.Title,
+			Body:  announce.Body,
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,880 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: hmtpk/v1/hmtpk.proto
+
+package hmtpkv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Option struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Option) Reset() {
+	*x = Option{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Option) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Option) ProtoMessage() {}
+
+func (x *Option) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Option.ProtoReflect.Descriptor instead.
+func (*Option) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Option) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Option) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type Lesson struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Num           string                 `protobuf:"bytes,1,opt,name=num,proto3" json:"num,omitempty"`
+	Time          string                 `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Room          string                 `protobuf:"bytes,4,opt,name=room,proto3" json:"room,omitempty"`
+	Location      string                 `protobuf:"bytes,5,opt,name=location,proto3" json:"location,omitempty"`
+	Group         string                 `protobuf:"bytes,6,opt,name=group,proto3" json:"group,omitempty"`
+	Subgroup      string                 `protobuf:"bytes,7,opt,name=subgroup,proto3" json:"subgroup,omitempty"`
+	Teacher       string                 `protobuf:"bytes,8,opt,name=teacher,proto3" json:"teacher,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Lesson) Reset() {
+	*x = Lesson{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Lesson) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Lesson) ProtoMessage() {}
+
+func (x *Lesson) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Lesson.ProtoReflect.Descriptor instead.
+func (*Lesson) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Lesson) GetNum() string {
+	if x != nil {
+		return x.Num
+	}
+	return ""
+}
+
+func (x *Lesson) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *Lesson) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Lesson) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Lesson) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Lesson) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Lesson) GetSubgroup() string {
+	if x != nil {
+		return x.Subgroup
+	}
+	return ""
+}
+
+func (x *Lesson) GetTeacher() string {
+	if x != nil {
+		return x.Teacher
+	}
+	return ""
+}
+
+type Schedule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Lessons       []*Lesson              `protobuf:"bytes,2,rep,name=lessons,proto3" json:"lessons,omitempty"`
+	Href          string                 `protobuf:"bytes,3,opt,name=href,proto3" json:"href,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Schedule) Reset() {
+	*x = Schedule{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Schedule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Schedule) ProtoMessage() {}
+
+func (x *Schedule) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Schedule.ProtoReflect.Descriptor instead.
+func (*Schedule) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Schedule) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Schedule) GetLessons() []*Lesson {
+	if x != nil {
+		return x.Lessons
+	}
+	return nil
+}
+
+func (x *Schedule) GetHref() string {
+	if x != nil {
+		return x.Href
+	}
+	return ""
+}
+
+type Announce struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Date          string                 `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Body          string                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Announce) Reset() {
+	*x = Announce{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Announce) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Announce) ProtoMessage() {}
+
+func (x *Announce) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Announce.ProtoReflect.Descriptor instead.
+func (*Announce) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Announce) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Announce) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Announce) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Announce) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+type GetGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGroupsRequest) Reset() {
+	*x = GetGroupsRequest{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGroupsRequest) ProtoMessage() {}
+
+func (x *GetGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGroupsRequest.ProtoReflect.Descriptor instead.
+func (*GetGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{4}
+}
+
+type GetGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Options       []*Option              `protobuf:"bytes,1,rep,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGroupsResponse) Reset() {
+	*x = GetGroupsResponse{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGroupsResponse) ProtoMessage() {}
+
+func (x *GetGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGroupsResponse.ProtoReflect.Descriptor instead.
+func (*GetGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetGroupsResponse) GetOptions() []*Option {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type GetTeachersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTeachersRequest) Reset() {
+	*x = GetTeachersRequest{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTeachersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTeachersRequest) ProtoMessage() {}
+
+func (x *GetTeachersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTeachersRequest.ProtoReflect.Descriptor instead.
+func (*GetTeachersRequest) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{6}
+}
+
+type GetTeachersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Options       []*Option              `protobuf:"bytes,1,rep,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTeachersResponse) Reset() {
+	*x = GetTeachersResponse{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTeachersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTeachersResponse) ProtoMessage() {}
+
+func (x *GetTeachersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTeachersResponse.ProtoReflect.Descriptor instead.
+func (*GetTeachersResponse) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetTeachersResponse) GetOptions() []*Option {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type GetScheduleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Target:
+	//
+	//	*GetScheduleRequest_Group
+	//	*GetScheduleRequest_Teacher
+	Target isGetScheduleRequest_Target `protobuf_oneof:"target"`
+	// date in the 02.01.2006 or 2006-01-02 format or a keyword like "tomorrow",
+	// today in the time zone of the college if empty
+	Date          string `protobuf:"bytes,3,opt,name=date,proto3" json:"date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetScheduleRequest) Reset() {
+	*x = GetScheduleRequest{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetScheduleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetScheduleRequest) ProtoMessage() {}
+
+func (x *GetScheduleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetScheduleRequest.ProtoReflect.Descriptor instead.
+func (*GetScheduleRequest) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetScheduleRequest) GetTarget() isGetScheduleRequest_Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *GetScheduleRequest) GetGroup() string {
+	if x != nil {
+		if x, ok := x.Target.(*GetScheduleRequest_Group); ok {
+			return x.Group
+		}
+	}
+	return ""
+}
+
+func (x *GetScheduleRequest) GetTeacher() string {
+	if x != nil {
+		if x, ok := x.Target.(*GetScheduleRequest_Teacher); ok {
+			return x.Teacher
+		}
+	}
+	return ""
+}
+
+func (x *GetScheduleRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+type isGetScheduleRequest_Target interface {
+	isGetScheduleRequest_Target()
+}
+
+type GetScheduleRequest_Group struct {
+	Group string `protobuf:"bytes,1,opt,name=group,proto3,oneof"`
+}
+
+type GetScheduleRequest_Teacher struct {
+	Teacher string `protobuf:"bytes,2,opt,name=teacher,proto3,oneof"`
+}
+
+func (*GetScheduleRequest_Group) isGetScheduleRequest_Target() {}
+
+func (*GetScheduleRequest_Teacher) isGetScheduleRequest_Target() {}
+
+type GetScheduleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Days          []*Schedule            `protobuf:"bytes,1,rep,name=days,proto3" json:"days,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetScheduleResponse) Reset() {
+	*x = GetScheduleResponse{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetScheduleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetScheduleResponse) ProtoMessage() {}
+
+func (x *GetScheduleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetScheduleResponse.ProtoReflect.Descriptor instead.
+func (*GetScheduleResponse) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetScheduleResponse) GetDays() []*Schedule {
+	if x != nil {
+		return x.Days
+	}
+	return nil
+}
+
+type GetAnnouncesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Page          int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAnnouncesRequest) Reset() {
+	*x = GetAnnouncesRequest{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAnnouncesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAnnouncesRequest) ProtoMessage() {}
+
+func (x *GetAnnouncesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAnnouncesRequest.ProtoReflect.Descriptor instead.
+func (*GetAnnouncesRequest) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetAnnouncesRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+type GetAnnouncesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Announces     []*Announce            `protobuf:"bytes,1,rep,name=announces,proto3" json:"announces,omitempty"`
+	LastPage      int32                  `protobuf:"varint,2,opt,name=last_page,json=lastPage,proto3" json:"last_page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAnnouncesResponse) Reset() {
+	*x = GetAnnouncesResponse{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAnnouncesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAnnouncesResponse) ProtoMessage() {}
+
+func (x *GetAnnouncesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAnnouncesResponse.ProtoReflect.Descriptor instead.
+func (*GetAnnouncesResponse) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetAnnouncesResponse) GetAnnounces() []*Announce {
+	if x != nil {
+		return x.Announces
+	}
+	return nil
+}
+
+func (x *GetAnnouncesResponse) GetLastPage() int32 {
+	if x != nil {
+		return x.LastPage
+	}
+	return 0
+}
+
+// Response is the envelope of messages and errors
+type Response struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_hmtpk_v1_hmtpk_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_hmtpk_v1_hmtpk_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Response) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Response) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_hmtpk_v1_hmtpk_proto protoreflect.FileDescriptor
+
+const file_hmtpk_v1_hmtpk_proto_rawDesc = "" +
+	"\n" +
+	"\x14hmtpk/v1/hmtpk.proto\x12\bhmtpk.v1\"4\n" +
+	"\x06Option\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\xbe\x01\n" +
+	"\x06Lesson\x12\x10\n" +
+	"\x03num\x18\x01 \x01(\tR\x03num\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\tR\x04time\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x12\n" +
+	"\x04room\x18\x04 \x01(\tR\x04room\x12\x1a\n" +
+	"\blocation\x18\x05 \x01(\tR\blocation\x12\x14\n" +
+	"\x05group\x18\x06 \x01(\tR\x05group\x12\x1a\n" +
+	"\bsubgroup\x18\a \x01(\tR\bsubgroup\x12\x18\n" +
+	"\ateacher\x18\b \x01(\tR\ateacher\"^\n" +
+	"\bSchedule\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12*\n" +
+	"\alessons\x18\x02 \x03(\v2\x10.hmtpk.v1.LessonR\alessons\x12\x12\n" +
+	"\x04href\x18\x03 \x01(\tR\x04href\"\\\n" +
+	"\bAnnounce\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04date\x18\x02 \x01(\tR\x04date\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x12\n" +
+	"\x04body\x18\x04 \x01(\tR\x04body\"\x12\n" +
+	"\x10GetGroupsRequest\"?\n" +
+	"\x11GetGroupsResponse\x12*\n" +
+	"\aoptions\x18\x01 \x03(\v2\x10.hmtpk.v1.OptionR\aoptions\"\x14\n" +
+	"\x12GetTeachersRequest\"A\n" +
+	"\x13GetTeachersResponse\x12*\n" +
+	"\aoptions\x18\x01 \x03(\v2\x10.hmtpk.v1.OptionR\aoptions\"f\n" +
+	"\x12GetScheduleRequest\x12\x16\n" +
+	"\x05group\x18\x01 \x01(\tH\x00R\x05group\x12\x1a\n" +
+	"\ateacher\x18\x02 \x01(\tH\x00R\ateacher\x12\x12\n" +
+	"\x04date\x18\x03 \x01(\tR\x04dateB\b\n" +
+	"\x06target\"=\n" +
+	"\x13GetScheduleResponse\x12&\n" +
+	"\x04days\x18\x01 \x03(\v2\x12.hmtpk.v1.ScheduleR\x04days\")\n" +
+	"\x13GetAnnouncesRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\"e\n" +
+	"\x14GetAnnouncesResponse\x120\n" +
+	"\tannounces\x18\x01 \x03(\v2\x12.hmtpk.v1.AnnounceR\tannounces\x12\x1b\n" +
+	"\tlast_page\x18\x02 \x01(\x05R\blastPage\":\n" +
+	"\bResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error2\x81\x03\n" +
+	"\fHmtpkService\x12D\n" +
+	"\tGetGroups\x12\x1a.hmtpk.v1.GetGroupsRequest\x1a\x1b.hmtpk.v1.GetGroupsResponse\x12J\n" +
+	"\vGetTeachers\x12\x1c.hmtpk.v1.GetTeachersRequest\x1a\x1d.hmtpk.v1.GetTeachersResponse\x12J\n" +
+	"\vGetSchedule\x12\x1c.hmtpk.v1.GetScheduleRequest\x1a\x1d.hmtpk.v1.GetScheduleResponse\x12D\n" +
+	"\x0eStreamSchedule\x12\x1c.hmtpk.v1.GetScheduleRequest\x1a\x12.hmtpk.v1.Schedule0\x01\x12M\n" +
+	"\fGetAnnounces\x12\x1d.hmtpk.v1.GetAnnouncesRequest\x1a\x1e.hmtpk.v1.GetAnnouncesResponseB>Z<github.com/chazari-x/hmtpk-parser-api/proto/hmtpk/v1;hmtpkv1b\x06proto3"
+
+var (
+	file_hmtpk_v1_hmtpk_proto_rawDescOnce sync.Once
+	file_hmtpk_v1_hmtpk_proto_rawDescData []byte
+)
+
+func file_hmtpk_v1_hmtpk_proto_rawDescGZIP() []byte {
+	file_hmtpk_v1_hmtpk_proto_rawDescOnce.Do(func() {
+		file_hmtpk_v1_hmtpk_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_hmtpk_v1_hmtpk_proto_rawDesc), len(file_hmtpk_v1_hmtpk_proto_rawDesc)))
+	})
+	return file_hmtpk_v1_hmtpk_proto_rawDescData
+}
+
+var file_hmtpk_v1_hmtpk_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_hmtpk_v1_hmtpk_proto_goTypes = []any{
+	(*Option)(nil),               // 0: hmtpk.v1.Option
+	(*Lesson)(nil),               // 1: hmtpk.v1.Lesson
+	(*Schedule)(nil),             // 2: hmtpk.v1.Schedule
+	(*Announce)(nil),             // 3: hmtpk.v1.Announce
+	(*GetGroupsRequest)(nil),     // 4: hmtpk.v1.GetGroupsRequest
+	(*GetGroupsResponse)(nil),    // 5: hmtpk.v1.GetGroupsResponse
+	(*GetTeachersRequest)(nil),   // 6: hmtpk.v1.GetTeachersRequest
+	(*GetTeachersResponse)(nil),  // 7: hmtpk.v1.GetTeachersResponse
+	(*GetScheduleRequest)(nil),   // 8: hmtpk.v1.GetScheduleRequest
+	(*GetScheduleResponse)(nil),  // 9: hmtpk.v1.GetScheduleResponse
+	(*GetAnnouncesRequest)(nil),  // 10: hmtpk.v1.GetAnnouncesRequest
+	(*GetAnnouncesResponse)(nil), // 11: hmtpk.v1.GetAnnouncesResponse
+	(*Response)(nil),             // 12: hmtpk.v1.Response
+}
+var file_hmtpk_v1_hmtpk_proto_depIdxs = []int32{
+	1,  // 0: hmtpk.v1.Schedule.lessons:type_name -> hmtpk.v1.Lesson
+	0,  // 1: hmtpk.v1.GetGroupsResponse.options:type_name -> hmtpk.v1.Option
+	0,  // 2: hmtpk.v1.GetTeachersResponse.options:type_name -> hmtpk.v1.Option
+	2,  // 3: hmtpk.v1.GetScheduleResponse.days:type_name -> hmtpk.v1.Schedule
+	3,  // 4: hmtpk.v1.GetAnnouncesResponse.announces:type_name -> hmtpk.v1.Announce
+	4,  // 5: hmtpk.v1.HmtpkService.GetGroups:input_type -> hmtpk.v1.GetGroupsRequest
+	6,  // 6: hmtpk.v1.HmtpkService.GetTeachers:input_type -> hmtpk.v1.GetTeachersRequest
+	8,  // 7: hmtpk.v1.HmtpkService.GetSchedule:input_type -> hmtpk.v1.GetScheduleRequest
+	8,  // 8: hmtpk.v1.HmtpkService.StreamSchedule:input_type -> hmtpk.v1.GetScheduleRequest
+	10, // 9: hmtpk.v1.HmtpkService.GetAnnounces:input_type -> hmtpk.v1.GetAnnouncesRequest
+	5,  // 10: hmtpk.v1.HmtpkService.GetGroups:output_type -> hmtpk.v1.GetGroupsResponse
+	7,  // 11: hmtpk.v1.HmtpkService.GetTeachers:output_type -> hmtpk.v1.GetTeachersResponse
+	9,  // 12: hmtpk.v1.HmtpkService.GetSchedule:output_type -> hmtpk.v1.GetScheduleResponse
+	2,  // 13: hmtpk.v1.HmtpkService.StreamSchedule:output_type -> hmtpk.v1.Schedule
+	11, // 14: hmtpk.v1.HmtpkService.GetAnnounces:output_type -> hmtpk.v1.GetAnnouncesResponse
+	10, // [10:15] is the sub-list for method output_type
+	5,  // [5:10] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_hmtpk_v1_hmtpk_proto_init() }
+func file_hmtpk_v1_hmtpk_proto_init() {
+	if File_hmtpk_v1_hmtpk_proto != nil {
+		return
+	}
+	file_hmtpk_v1_hmtpk_proto_msgTypes[8].OneofWrappers = []any{
+		(*GetScheduleRequest_Group)(nil),
+		(*GetScheduleRequest_Teacher)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_hmtpk_v1_hmtpk_proto_rawDesc), len(file_hmtpk_v1_hmtpk_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_hmtpk_v1_hmtpk_proto_goTypes,
+		DependencyIndexes: file_hmtpk_v1_hmtpk_proto_depIdxs,
+		MessageInfos:      file_hmtpk_v1_hmtpk_proto_msgTypes,
+	}.Build()
+	File_hmtpk_v1_hmtpk_proto = out.File
+	file_hmtpk_v1_hmtpk_proto_goTypes = nil
+	file_hmtpk_v1_hmtpk_proto_depIdxs = nil
+}
@@ -0,0 +1,291 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: hmtpk/v1/hmtpk.proto
+
+package hmtpkv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	HmtpkService_GetGroups_FullMethodName      = "/hmtpk.v1.HmtpkService/GetGroups"
+	HmtpkService_GetTeachers_FullMethodName    = "/hmtpk.v1.HmtpkService/GetTeachers"
+	HmtpkService_GetSchedule_FullMethodName    = "/hmtpk.v1.HmtpkService/GetSchedule"
+	HmtpkService_StreamSchedule_FullMethodName = "/hmtpk.v1.HmtpkService/StreamSchedule"
+	HmtpkService_GetAnnounces_FullMethodName   = "/hmtpk.v1.HmtpkService/GetAnnounces"
+)
+
+// HmtpkServiceClient is the client API for HmtpkService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// HmtpkService exposes the same operations as the HTTP API
+type HmtpkServiceClient interface {
+	// GetGroups returns the list of groups
+	GetGroups(ctx context.Context, in *GetGroupsRequest, opts ...grpc.CallOption) (*GetGroupsResponse, error)
+	// GetTeachers returns the list of teachers
+	GetTeachers(ctx context.Context, in *GetTeachersRequest, opts ...grpc.CallOption) (*GetTeachersResponse, error)
+	// GetSchedule returns the weekly schedule of a group or a teacher
+	GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*GetScheduleResponse, error)
+	// StreamSchedule sends the weekly schedule day by day
+	StreamSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Schedule], error)
+	// GetAnnounces returns a page of announces
+	GetAnnounces(ctx context.Context, in *GetAnnouncesRequest, opts ...grpc.CallOption) (*GetAnnouncesResponse, error)
+}
+
+type hmtpkServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHmtpkServiceClient(cc grpc.ClientConnInterface) HmtpkServiceClient {
+	return &hmtpkServiceClient{cc}
+}
+
+func (c *hmtpkServiceClient) GetGroups(ctx context.Context, in *GetGroupsRequest, opts ...grpc.CallOption) (*GetGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetGroupsResponse)
+	err := c.cc.Invoke(ctx, HmtpkService_GetGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hmtpkServiceClient) GetTeachers(ctx context.Context, in *GetTeachersRequest, opts ...grpc.CallOption) (*GetTeachersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTeachersResponse)
+	err := c.cc.Invoke(ctx, HmtpkService_GetTeachers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hmtpkServiceClient) GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*GetScheduleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetScheduleResponse)
+	err := c.cc.Invoke(ctx, HmtpkService_GetSchedule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hmtpkServiceClient) StreamSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Schedule], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &HmtpkService_ServiceDesc.Streams[0], HmtpkService_StreamSchedule_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetScheduleRequest, Schedule]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HmtpkService_StreamScheduleClient = grpc.ServerStreamingClient[Schedule]
+
+func (c *hmtpkServiceClient) GetAnnounces(ctx context.Context, in *GetAnnouncesRequest, opts ...grpc.CallOption) (*GetAnnouncesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAnnouncesResponse)
+	err := c.cc.Invoke(ctx, HmtpkService_GetAnnounces_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HmtpkServiceServer is the server API for HmtpkService service.
+// All implementations must embed UnimplementedHmtpkServiceServer
+// for forward compatibility.
+//
+// HmtpkService exposes the same operations as the HTTP API
+type HmtpkServiceServer interface {
+	// GetGroups returns the list of groups
+	GetGroups(context.Context, *GetGroupsRequest) (*GetGroupsResponse, error)
+	// GetTeachers returns the list of teachers
+	GetTeachers(context.Context, *GetTeachersRequest) (*GetTeachersResponse, error)
+	// GetSchedule returns the weekly schedule of a group or a teacher
+	GetSchedule(context.Context, *GetScheduleRequest) (*GetScheduleResponse, error)
+	// StreamSchedule sends the weekly schedule day by day
+	StreamSchedule(*GetScheduleRequest, grpc.ServerStreamingServer[Schedule]) error
+	// GetAnnounces returns a page of announces
+	GetAnnounces(context.Context, *GetAnnouncesRequest) (*GetAnnouncesResponse, error)
+	mustEmbedUnimplementedHmtpkServiceServer()
+}
+
+// UnimplementedHmtpkServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHmtpkServiceServer struct{}
+
+func (UnimplementedHmtpkServiceServer) GetGroups(context.Context, *GetGroupsRequest) (*GetGroupsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetGroups not implemented")
+}
+func (UnimplementedHmtpkServiceServer) GetTeachers(context.Context, *GetTeachersRequest) (*GetTeachersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTeachers not implemented")
+}
+func (UnimplementedHmtpkServiceServer) GetSchedule(context.Context, *GetScheduleRequest) (*GetScheduleResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetSchedule not implemented")
+}
+func (UnimplementedHmtpkServiceServer) StreamSchedule(*GetScheduleRequest, grpc.ServerStreamingServer[Schedule]) error {
+	return status.Error(codes.Unimplemented, "method StreamSchedule not implemented")
+}
+func (UnimplementedHmtpkServiceServer) GetAnnounces(context.Context, *GetAnnouncesRequest) (*GetAnnouncesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAnnounces not implemented")
+}
+func (UnimplementedHmtpkServiceServer) mustEmbedUnimplementedHmtpkServiceServer() {}
+func (UnimplementedHmtpkServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeHmtpkServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HmtpkServiceServer will
+// result in compilation errors.
+type UnsafeHmtpkServiceServer interface {
+	mustEmbedUnimplementedHmtpkServiceServer()
+}
+
+func RegisterHmtpkServiceServer(s grpc.ServiceRegistrar, srv HmtpkServiceServer) {
+	// If the following call panics, it indicates UnimplementedHmtpkServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HmtpkService_ServiceDesc, srv)
+}
+
+func _HmtpkService_GetGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HmtpkServiceServer).GetGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HmtpkService_GetGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HmtpkServiceServer).GetGroups(ctx, req.(*GetGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HmtpkService_GetTeachers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTeachersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HmtpkServiceServer).GetTeachers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HmtpkService_GetTeachers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HmtpkServiceServer).GetTeachers(ctx, req.(*GetTeachersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HmtpkService_GetSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HmtpkServiceServer).GetSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HmtpkService_GetSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HmtpkServiceServer).GetSchedule(ctx, req.(*GetScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HmtpkService_StreamSchedule_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetScheduleRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HmtpkServiceServer).StreamSchedule(m, &grpc.GenericServerStream[GetScheduleRequest, Schedule]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HmtpkService_StreamScheduleServer = grpc.ServerStreamingServer[Schedule]
+
+func _HmtpkService_GetAnnounces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAnnouncesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HmtpkServiceServer).GetAnnounces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HmtpkService_GetAnnounces_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HmtpkServiceServer).GetAnnounces(ctx, req.(*GetAnnouncesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HmtpkService_ServiceDesc is the grpc.ServiceDesc for HmtpkService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HmtpkService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hmtpk.v1.HmtpkService",
+	HandlerType: (*HmtpkServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetGroups",
+			Handler:    _HmtpkService_GetGroups_Handler,
+		},
+		{
+			MethodName: "GetTeachers",
+			Handler:    _HmtpkService_GetTeachers_Handler,
+		},
+		{
+			MethodName: "GetSchedule",
+			Handler:    _HmtpkService_GetSchedule_Handler,
+		},
+		{
+			MethodName: "GetAnnounces",
+			Handler:    _HmtpkService_GetAnnounces_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSchedule",
+			Handler:       _HmtpkService_StreamSchedule_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "hmtpk/v1/hmtpk.proto",
+}
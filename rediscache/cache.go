@@ -0,0 +1,185 @@
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// the patterns of every key the parser caches, the data of the service is
+// stored under its own prefix and never matches them
+var namespace = []string{
+	weeksPattern + "*",
+	groupsKey,
+	teachersKey,
+	announcesPattern,
+}
+
+const (
+	// weeksPattern matches the year/week part of the keys of cached schedules
+	weeksPattern     = "[0-9]*/[0-9]*:"
+	announcesPattern = `announce\?page=*`
+)
+
+// scanCount is the number of keys asked from Redis per scan
+const scanCount = 100
+
+// Key is a key of the parser cache with its remaining time to live
+type Key struct {
+	Key string        `json:"key"`
+	TTL time.Duration `json:"-"`
+	// Seconds is the remaining time to live in seconds, -1 without expiration
+	Seconds int `json:"ttl"`
+}
+
+// Cache inspects and flushes the Redis cache of the parser
+type Cache struct {
+	client *redis.Client
+}
+
+// NewCache returns the cache of the parser in the Redis of the client
+func NewCache(client *redis.Client) *Cache {
+	return &Cache{client: client}
+}
+
+// Namespace returns the patterns of the whole parser cache
+func Namespace() []string {
+	return append([]string(nil), namespace...)
+}
+
+// SchedulePattern returns the pattern of the cached weeks of the group or the
+// teacher, of the week of the date only when it is set
+func SchedulePattern(value, date string) string {
+	if date != "" {
+		return escape(scheduleKey(value, date))
+	}
+
+	return weeksPattern + escape(value)
+}
+
+// AnnouncesPattern returns the pattern of the cached pages of announces
+func AnnouncesPattern() string {
+	return announcesPattern
+}
+
+// AnnouncesPagePattern returns the pattern of the cached page of announces
+func AnnouncesPagePattern(page int) string {
+	return escape(fmt.Sprintf(announceKey, page))
+}
+
+// OptionsPatterns returns the keys of the cached lists of groups and teachers
+func OptionsPatterns() []string {
+	return []string{groupsKey, teachersKey}
+}
+
+// escape quotes the characters of the value special in Redis patterns
+func escape(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// scan returns the keys matching any of the patterns, sorted
+func (c *Cache) scan(ctx context.Context, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		var cursor uint64
+		for {
+			batch, next, err := c.client.Scan(ctx, cursor, pattern, scanCount).Result()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, key := range batch {
+				seen[key] = true
+			}
+
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Keys returns up to limit keys matching the patterns from the offset in the
+// order of their names with their time to live and whether there were more of them
+func (c *Cache) Keys(ctx context.Context, patterns []string, offset, limit int) ([]Key, bool, error) {
+	names, err := c.scan(ctx, patterns)
+	if err != nil {
+		return nil, false, err
+	}
+
+	names = names[min(offset, len(names)):]
+	more := len(names) > limit
+	if more {
+		names = names[:limit]
+	}
+
+	pipe := c.client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(names))
+	for i, name := range names {
+		ttls[i] = pipe.TTL(ctx, name)
+	}
+	if len(names) > 0 {
+		if _, err = pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, false, err
+		}
+	}
+
+	keys := make([]Key, 0, len(names))
+	for i, name := range names {
+		ttl := ttls[i].Val()
+		// a key expired since the scan
+		if ttl == -2 {
+			continue
+		}
+
+		key := Key{Key: name, TTL: ttl, Seconds: -1}
+		if ttl > 0 {
+			key.Seconds = int(ttl.Seconds())
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, more, nil
+}
+
+// Flush deletes the keys matching the patterns and returns their number
+func (c *Cache) Flush(ctx context.Context, patterns []string) (int, error) {
+	names, err := c.scan(ctx, patterns)
+	if err != nil {
+		return 0, err
+	}
+
+	var flushed int64
+	for len(names) > 0 {
+		batch := names[:min(len(names), scanCount)]
+		names = names[len(batch):]
+
+		n, err := c.client.Del(ctx, batch...).Result()
+		if err != nil {
+			return int(flushed), err
+		}
+		flushed += n
+	}
+
+	return int(flushed), nil
+}
@@ -0,0 +1,144 @@
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/cache"
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-redis/redis/v8"
+)
+
+// the keys the parser caches its data in Redis with
+const (
+	groupsKey   = "groups"
+	teachersKey = "teachers"
+	announceKey = "announce?page=%d"
+)
+
+// Source is the parser caching in Redis
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// Provider applies the configured expiration to the data the parser caches
+// and reports whether the parser answers from its cache: the key of the data
+// is checked before the call and its remaining time to live gives the age of
+// the data
+type Provider struct {
+	cfg      cache.TTL
+	source   Source
+	client   *redis.Client
+	location *time.Location
+	extend   func(time.Duration) time.Duration
+}
+
+// NewProvider wraps the parser using the client
+func NewProvider(cfg cache.TTL, source Source, client *redis.Client) *Provider {
+	return &Provider{cfg: cfg, source: source, client: client, location: time.Local}
+}
+
+// SetLocation sets the time zone in which the current week is resolved
+func (p *Provider) SetLocation(location *time.Location) {
+	p.location = location
+}
+
+// SetTTL sets the function extending the expiration of the data cached now, e.g. during the peak
+func (p *Provider) SetTTL(extend func(time.Duration) time.Duration) {
+	p.extend = extend
+}
+
+// cached runs the call, a hit of the key, cached for ttl, is reported and a
+// miss gets the key the configured expiration after the parser cached it
+func cached[T any](ctx context.Context, p *Provider, key string, ttl, configured time.Duration, call func(ctx context.Context) (T, error)) (T, error) {
+	remaining, err := p.client.TTL(ctx, key).Result()
+	if err == nil && remaining > 0 {
+		stale.Hit(ctx, stale.LayerRedis, max(ttl-remaining, 0))
+		return call(maintenance.Cached(ctx))
+	}
+
+	stale.Miss(ctx)
+
+	expiration := ttl
+	if p.extend != nil {
+		expiration = p.extend(ttl)
+	}
+
+	result, err := call(ctx)
+	if err == nil && (configured > 0 || expiration != ttl) {
+		// a failure leaves the expiration of the parser
+		_ = p.client.Expire(ctx, key, expiration).Err()
+	}
+
+	return result, err
+}
+
+// scheduleKey returns the key of the week of the date, empty for an invalid date
+func scheduleKey(value, date string) string {
+	d, err := time.Parse("02.01.2006", date)
+	if err != nil {
+		return ""
+	}
+
+	year, week := d.ISOWeek()
+	return fmt.Sprintf("%d/%d:%s", year, week, value)
+}
+
+// scheduleTTLs returns the expiration of the week of the date and the configured one
+func (p *Provider) scheduleTTLs(date string) (time.Duration, time.Duration) {
+	configured := p.cfg.Future
+	if d, err := time.Parse("02.01.2006", date); err == nil {
+		year, week := d.ISOWeek()
+		nowYear, nowWeek := time.Now().In(p.location).ISOWeek()
+		if year == nowYear && week == nowWeek {
+			configured = p.cfg.Today
+		}
+	}
+
+	return cache.Or(configured, cache.DefaultScheduleTTL), configured
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	return cached(ctx, p, groupsKey, cache.Or(p.cfg.GroupOptions, cache.DefaultOptionsTTL), p.cfg.GroupOptions, p.source.GetGroupOptions)
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return cached(ctx, p, teachersKey, cache.Or(p.cfg.TeacherOptions, cache.DefaultOptionsTTL), p.cfg.TeacherOptions, p.source.GetTeacherOptions)
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	key := scheduleKey(group, date)
+	if key == "" {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	}
+
+	full, configured := p.scheduleTTLs(date)
+	return cached(ctx, p, key, full, configured, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	})
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	key := scheduleKey(teacher, date)
+	if key == "" {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	}
+
+	full, configured := p.scheduleTTLs(date)
+	return cached(ctx, p, key, full, configured, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	})
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	return cached(ctx, p, fmt.Sprintf(announceKey, page), cache.Or(p.cfg.Announces, cache.DefaultAnnounceTTL), p.cfg.Announces, func(ctx context.Context) (model.Announces, error) {
+		return p.source.GetAnnounces(ctx, page)
+	})
+}
@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of recording requests for capacity replays
+type Config struct {
+	// Dir enables recording the requests, one file per day
+	Dir string `yaml:"dir"`
+}
+
+// Entry is a recorded request
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// URL is the path with the query
+	URL string `json:"url"`
+	// Client identifies the client without keeping its address or API key
+	Client string `json:"client"`
+	Accept string `json:"accept,omitempty"`
+}
+
+const (
+	fileLayout = "requests-2006-01-02.jsonl"
+	dayLayout  = time.DateOnly
+)
+
+type replayedKey struct{}
+
+// replayed reports whether the request is sent by a replay
+func replayed(ctx context.Context) bool {
+	return ctx.Value(replayedKey{}) != nil
+}
+
+// Recorder appends every request to the file of its day
+type Recorder struct {
+	dir string
+	log *logrus.Logger
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// NewRecorder creates the directory of the recordings
+func NewRecorder(cfg Config, logger *logrus.Logger) (*Recorder, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Recorder{dir: cfg.Dir, log: logger}, nil
+}
+
+// Middleware records the requests, except the ones sent by a replay
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !replayed(r.Context()) {
+			rec.record(r)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rec *Recorder) record(r *http.Request) {
+	client := r.Header.Get("X-API-Key")
+	if client == "" {
+		client, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	sum := sha256.Sum256([]byte(client))
+
+	entry := Entry{
+		Time:   time.Now(),
+		Method: r.Method,
+		URL:    r.URL.RequestURI(),
+		Client: hex.EncodeToString(sum[:8]),
+		Accept: r.Header.Get("Accept"),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if day := entry.Time.Format(dayLayout); day != rec.day || rec.file == nil {
+		if rec.file != nil {
+			_ = rec.file.Close()
+			rec.file = nil
+		}
+
+		file, err := os.OpenFile(filepath.Join(rec.dir, entry.Time.Format(fileLayout)), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			rec.log.Errorf("replay: %s", err)
+			return
+		}
+		rec.file, rec.day = file, day
+	}
+
+	if _, err = rec.file.Write(append(data, '\n')); err != nil {
+		rec.log.Errorf("replay: %s", err)
+	}
+}
+
+// Close closes the file of the current day
+func (rec *Recorder) Close() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.file != nil {
+		_ = rec.file.Close()
+		rec.file = nil
+	}
+}
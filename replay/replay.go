@@ -0,0 +1,365 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotFound is returned when the day was not recorded
+	ErrNotFound = errors.New("no recording of the day")
+	// ErrRunning is returned when another replay is running
+	ErrRunning = errors.New("another replay is running")
+)
+
+const (
+	// bucketWidth is the span of the recorded day summarized in a point of the curves
+	bucketWidth = time.Minute * 10
+	// maxInFlight bounds the replayed requests waiting for a response
+	maxInFlight = 512
+	// maxEntryLine bounds a line of the recording
+	maxEntryLine = 64 << 10
+)
+
+// Bucket summarizes the replayed requests recorded within bucketWidth from At
+type Bucket struct {
+	At       time.Time `json:"at"`
+	Requests int       `json:"requests"`
+	// Errors are the responses with a 5xx status
+	Errors int `json:"errors"`
+	// Throttled are the responses with 429 Too Many Requests
+	Throttled int     `json:"throttled"`
+	P50       float64 `json:"p50_ms"`
+	P95       float64 `json:"p95_ms"`
+	P99       float64 `json:"p99_ms"`
+	Max       float64 `json:"max_ms"`
+
+	latencies []time.Duration
+}
+
+// Report is the progress and the latency and error curves of a replay
+type Report struct {
+	ID       string     `json:"id"`
+	Day      string     `json:"day"`
+	Speed    float64    `json:"speed"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Total    int        `json:"total"`
+	Done     int        `json:"done"`
+	// Late are the requests sent later than scheduled because maxInFlight were waiting
+	Late    int      `json:"late"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Replayer sends a recorded day of requests to the handler of the instance
+// at a multiple of the recorded pace. Clients keep their share of the
+// requests: each is replayed from its own address, as anonymous since the
+// API keys are not recorded
+type Replayer struct {
+	dir     string
+	handler http.Handler
+	log     *logrus.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	reports map[string]*Report
+	running bool
+}
+
+// NewReplayer creates a replayer of the recordings in the directory of the config
+func NewReplayer(cfg Config, handler http.Handler, logger *logrus.Logger) *Replayer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Replayer{
+		dir:     cfg.Dir,
+		handler: handler,
+		log:     logger,
+		ctx:     ctx,
+		cancel:  cancel,
+		reports: make(map[string]*Report),
+	}
+}
+
+// Close stops the running replay
+func (p *Replayer) Close() {
+	p.cancel()
+}
+
+// Days returns the recorded days
+func (p *Replayer) Days() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(p.dir, "requests-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]string, 0, len(files))
+	for _, file := range files {
+		if d, err := time.Parse(fileLayout, filepath.Base(file)); err == nil {
+			days = append(days, d.Format(dayLayout))
+		}
+	}
+	sort.Strings(days)
+
+	return days, nil
+}
+
+// Start replays the recorded day, speed 2 sends the requests twice as fast as recorded
+func (p *Replayer) Start(day string, speed float64) (Report, error) {
+	d, err := time.Parse(dayLayout, day)
+	if err != nil {
+		return Report{}, ErrNotFound
+	}
+
+	entries, err := read(filepath.Join(p.dir, d.Format(fileLayout)))
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return Report{}, ErrNotFound
+	} else if err != nil {
+		return Report{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return Report{}, ErrRunning
+	}
+	p.running = true
+
+	report := &Report{
+		ID:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		Day:     day,
+		Speed:   speed,
+		Started: time.Now(),
+		Total:   len(entries),
+	}
+	p.reports[report.ID] = report
+
+	go p.run(report, entries)
+
+	p.log.Infof("replay: %d requests of %s at x%g", len(entries), day, speed)
+
+	return p.snapshot(report), nil
+}
+
+// Report returns the report of the replay
+func (p *Replayer) Report(id string) (Report, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	report, ok := p.reports[id]
+	if !ok {
+		return Report{}, false
+	}
+
+	return p.snapshot(report), true
+}
+
+// read reads the recording ordered by time
+func read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxEntryLine)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	return entries, scanner.Err()
+}
+
+func (p *Replayer) run(report *Report, entries []Entry) {
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		finished := time.Now()
+		report.Finished = &finished
+		p.running = false
+		p.log.Infof("replay: %s finished, %d of %d requests", report.Day, report.Done, report.Total)
+	}()
+
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, maxInFlight)
+		first = entries[0].Time
+		start = time.Now()
+	)
+	defer wg.Wait()
+
+	for _, entry := range entries {
+		at := start.Add(time.Duration(float64(entry.Time.Sub(first)) / report.Speed))
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			p.mu.Lock()
+			report.Late++
+			p.mu.Unlock()
+
+			select {
+			case sem <- struct{}{}:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			status, latency := p.send(entry)
+			p.add(report, first, entry.Time, status, latency)
+		}()
+	}
+}
+
+// send serves the recorded request in process and returns the status and the latency
+func (p *Replayer) send(entry Entry) (int, time.Duration) {
+	ctx := context.WithValue(p.ctx, replayedKey{}, true)
+	r, err := http.NewRequestWithContext(ctx, entry.Method, entry.URL, nil)
+	if err != nil {
+		return http.StatusBadRequest, 0
+	}
+	r.RemoteAddr = clientAddr(entry.Client)
+	r.RequestURI = entry.URL
+	if entry.Accept != "" {
+		r.Header.Set("Accept", entry.Accept)
+	}
+
+	w := &discard{header: make(http.Header)}
+	start := time.Now()
+	p.handler.ServeHTTP(w, r)
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.status, time.Since(start)
+}
+
+// clientAddr returns a private address standing for the recorded client
+func clientAddr(client string) string {
+	id, _ := hex.DecodeString(client)
+	id = append(id, 0, 0, 0)
+
+	return net.JoinHostPort(net.IPv4(10, id[0], id[1], id[2]).String(), "0")
+}
+
+// add records the response to the request recorded at the time
+func (p *Replayer) add(report *Report, first, at time.Time, status int, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := int(at.Sub(first) / bucketWidth)
+	for len(report.Buckets) <= n {
+		report.Buckets = append(report.Buckets, Bucket{At: first.Add(time.Duration(len(report.Buckets)) * bucketWidth)})
+	}
+
+	bucket := &report.Buckets[n]
+	bucket.Requests++
+	bucket.latencies = append(bucket.latencies, latency)
+	switch {
+	case status >= http.StatusInternalServerError:
+		bucket.Errors++
+	case status == http.StatusTooManyRequests:
+		bucket.Throttled++
+	}
+
+	report.Done++
+}
+
+// snapshot copies the report computing the percentiles, the caller holds mu
+func (p *Replayer) snapshot(report *Report) Report {
+	result := *report
+	result.Buckets = make([]Bucket, len(report.Buckets))
+	for i, bucket := range report.Buckets {
+		latencies := append([]time.Duration(nil), bucket.latencies...)
+		sort.Slice(latencies, func(x, y int) bool {
+			return latencies[x] < latencies[y]
+		})
+
+		bucket.latencies = nil
+		if len(latencies) > 0 {
+			bucket.P50 = milliseconds(percentile(latencies, 0.5))
+			bucket.P95 = milliseconds(percentile(latencies, 0.95))
+			bucket.P99 = milliseconds(percentile(latencies, 0.99))
+			bucket.Max = milliseconds(latencies[len(latencies)-1])
+		}
+		result.Buckets[i] = bucket
+	}
+
+	return result
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	return sorted[int(q*float64(len(sorted)-1)+0.5)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// discard is the response writer of replayed requests keeping the status only
+type discard struct {
+	header http.Header
+	status int
+}
+
+func (d *discard) Header() http.Header {
+	return d.header
+}
+
+func (d *discard) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+
+	return len(b), nil
+}
+
+func (d *discard) WriteHeader(status int) {
+	if d.status == 0 {
+		d.status = status
+	}
+}
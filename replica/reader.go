@@ -0,0 +1,83 @@
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// ErrMiss is returned when the primary hasn't put the data into Redis
+var ErrMiss = errors.New("data not replicated yet")
+
+// Reader serves the data from the Redis cache filled by the parser of the primary
+// and never contacts hmtpk.ru. The keys are the ones used by the parser
+type Reader struct {
+	client *redis.Client
+}
+
+// NewReader creates a new reader of the shared cache
+func NewReader(client *redis.Client) *Reader {
+	return &Reader{client: client}
+}
+
+func (r *Reader) get(ctx context.Context, key string, result interface{}) error {
+	data, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) || data == "" {
+		return ErrMiss
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(data), result)
+}
+
+func (r *Reader) GetGroupOptions(ctx context.Context) (options []model.Option, err error) {
+	err = r.get(ctx, "groups", &options)
+	return
+}
+
+func (r *Reader) GetTeacherOptions(ctx context.Context) (options []model.Option, err error) {
+	err = r.get(ctx, "teachers", &options)
+	return
+}
+
+func (r *Reader) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	return r.schedule(ctx, group, date)
+}
+
+func (r *Reader) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	return r.schedule(ctx, teacher, date)
+}
+
+// schedule reads the week of the date stored under "<year>/<week>:<name>"
+func (r *Reader) schedule(ctx context.Context, name, date string) (schedule []model.Schedule, err error) {
+	d, err := time.Parse("02.01.2006", date)
+	if err != nil || name == "" {
+		return nil, hmtpkErrors.ErrorBadRequest
+	}
+
+	year, week := d.ISOWeek()
+	err = r.get(ctx, fmt.Sprintf("%d/%d:%s", year, week, name), &schedule)
+
+	return
+}
+
+func (r *Reader) GetAnnounces(ctx context.Context, page int) (announces model.Announces, err error) {
+	if page < 1 {
+		return announces, hmtpkErrors.ErrorBadRequest
+	}
+
+	err = r.get(ctx, fmt.Sprintf("announce?page=%d", page), &announces)
+	return
+}
@@ -0,0 +1,123 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Header carries the request ID from the client and back in the response
+	Header = "X-Request-ID"
+	// Field is the field of the log lines of a request holding its ID
+	Field = "request_id"
+
+	// maxLength limits the length of an ID accepted from the client
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// NewContext returns the context carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of the context, empty outside a request
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New returns a random request ID
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// valid reports whether an ID sent by the client is safe to log and return,
+// it is limited to the printable ASCII without spaces
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Middleware takes the request ID from the X-Request-ID header of the client
+// or generates one, returns it in the response and passes it in the context
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Hook adds the request ID to the log lines written with the context of a request
+type Hook struct{}
+
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (Hook) Fire(entry *logrus.Entry) error {
+	if id := FromContext(entry.Context); id != "" {
+		entry.Data[Field] = id
+	}
+
+	return nil
+}
+
+// Transport logs the calls to the upstream with the ID of the request making
+// them and passes the ID on in the X-Request-ID header
+type Transport struct {
+	next http.RoundTripper
+	log  *logrus.Logger
+}
+
+// NewTransport wraps the transport of the upstream calls
+func NewTransport(next http.RoundTripper, logger *logrus.Logger) *Transport {
+	return &Transport{next: next, log: logger}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := FromContext(req.Context()); id != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(Header, id)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	log := t.log.WithContext(req.Context())
+	if err != nil {
+		log.Debugf("upstream: %s %s: %s in %s", req.Method, req.URL, err, time.Since(start).Round(time.Millisecond))
+		return resp, err
+	}
+
+	log.Debugf("upstream: %s %s: %d in %s", req.Method, req.URL, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+
+	return resp, nil
+}
@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"syscall"
+	"time"
+
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of retrying failed upstream fetches
+type Config struct {
+	// Attempts is the number of fetches of a request, retrying is disabled below two
+	Attempts int `yaml:"attempts"`
+	// Backoff is the upper bound of the pause before the first retry, doubled every retry
+	Backoff time.Duration `yaml:"backoff"`
+	// MaxBackoff bounds the pause before a retry
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+const (
+	defaultBackoff    = time.Millisecond * 200
+	defaultMaxBackoff = time.Second * 2
+)
+
+// Source is the provider of the data fetched from hmtpk.ru
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// Provider retries the fetches of the source failing with transient errors,
+// network resets and 5xx responses, with jittered exponential backoff
+type Provider struct {
+	cfg    Config
+	source Source
+	log    *logrus.Logger
+}
+
+// NewProvider wraps the source
+func NewProvider(cfg Config, source Source, logger *logrus.Logger) *Provider {
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+
+	return &Provider{cfg: cfg, source: source, log: logger}
+}
+
+// serverError matches the status of a 5xx response in the errors of the parser
+var serverError = regexp.MustCompile(`: 5\d\d\b`)
+
+// Transient reports whether the fetch failing with the error may succeed when repeated
+func Transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, hmtpkErrors.ErrorBadRequest) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	return serverError.MatchString(err.Error())
+}
+
+// do runs the fetch until it succeeds, fails permanently, the attempts run out
+// or the pause would not leave time for another attempt before the deadline
+func do[T any](ctx context.Context, p *Provider, name string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	backoff := p.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		result, err := fetch(ctx)
+		if err == nil || attempt >= p.cfg.Attempts || !Transient(err) {
+			return result, err
+		}
+
+		pause := rand.N(backoff) + 1
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < pause*2 {
+			return result, err
+		}
+
+		p.log.WithContext(ctx).Debugf("retry: %s, attempt %d: %s", name, attempt, err)
+
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, p.cfg.MaxBackoff)
+	}
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	return do(ctx, p, "groups", p.source.GetGroupOptions)
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return do(ctx, p, "teachers", p.source.GetTeacherOptions)
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	return do(ctx, p, "group "+group, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	})
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	return do(ctx, p, "teacher "+teacher, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	})
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	return do(ctx, p, "announces", func(ctx context.Context) (model.Announces, error) {
+		return p.source.GetAnnounces(ctx, page)
+	})
+}
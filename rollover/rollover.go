@@ -0,0 +1,399 @@
+package rollover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the semester rollover detector
+type Config struct {
+	// Interval between checks of the group list, the detector is disabled when zero
+	Interval time.Duration `yaml:"interval"`
+	// Threshold is the share of groups replaced at once that is reported as a rollover
+	Threshold float64 `yaml:"threshold"`
+}
+
+// Source is the part of the parser used by the detector
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+}
+
+// Migrator moves the data bound to the disappeared groups, renamed maps the
+// value of a disappeared group to the value of the group replacing it
+type Migrator interface {
+	Migrate(ctx context.Context, removed, added []model.Option, renamed map[string]string) error
+}
+
+// Event is the semester rollover reported to the administrators
+type Event struct {
+	At      time.Time      `json:"at"`
+	Total   int            `json:"total"`
+	Added   []model.Option `json:"added"`
+	Removed []model.Option `json:"removed"`
+	// Renamed maps the value of a disappeared group to the value of the group replacing it
+	Renamed map[string]string `json:"renamed"`
+}
+
+// Archived is a disappeared group with its last known weekly schedule
+type Archived struct {
+	Group      model.Option     `json:"group"`
+	ArchivedAt time.Time        `json:"archived_at"`
+	RenamedTo  string           `json:"renamed_to,omitempty"`
+	Schedule   []model.Schedule `json:"schedule,omitempty"`
+}
+
+const (
+	defaultThreshold = 0.2
+
+	timeout = time.Second * 15
+
+	groupsKey      = "rollover:groups"
+	eventPrefix    = "rollover:event:"
+	renamedPrefix  = "rollover:renamed:"
+	archivedPrefix = "archive:group:"
+)
+
+// Detector periodically compares the group list with the previous one,
+// archives the groups that disappeared and maps them to their successors
+type Detector struct {
+	cfg       Config
+	source    Source
+	store     storage.Store
+	schedules func() map[string][]model.Schedule
+	migrators []Migrator
+	paused    func() bool
+	log       *logrus.Logger
+}
+
+// NewDetector creates a new detector, schedules returns the last known
+// weekly schedules kept when a group is archived
+func NewDetector(cfg Config, source Source, store storage.Store, schedules func() map[string][]model.Schedule, logger *logrus.Logger) *Detector {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultThreshold
+	}
+
+	return &Detector{cfg: cfg, source: source, store: store, schedules: schedules, log: logger}
+}
+
+// AddMigrator adds a migrator called for the groups that disappeared
+func (d *Detector) AddMigrator(migrator Migrator) {
+	d.migrators = append(d.migrators, migrator)
+}
+
+// SetPaused skips the checks while paused reports true, e.g. during the peak
+func (d *Detector) SetPaused(paused func() bool) {
+	d.paused = paused
+}
+
+// Run checks the group list until the context is canceled
+func (d *Detector) Run(ctx context.Context) {
+	if d.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if d.paused != nil && d.paused() {
+			d.log.Debug("rollover: paused")
+		} else if err := d.check(ctx); errors.Is(err, maintenance.ErrMaintenance) {
+			d.log.Debugf("rollover: %s", err)
+		} else if err != nil {
+			d.log.Errorf("rollover: %s", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Detector) check(ctx context.Context) error {
+	optionsCtx, cancel := context.WithTimeout(ctx, timeout)
+	groups, err := d.source.GetGroupOptions(optionsCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	var previous []model.Option
+	if value, err := d.store.Get(ctx, groupsKey); err == nil {
+		if err = json.Unmarshal([]byte(value), &previous); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	if previous != nil {
+		added, removed := diff(previous, groups), diff(groups, previous)
+
+		// a group coming back is no longer archived
+		for _, group := range added {
+			if err = d.store.Delete(ctx, archivedPrefix+group.Value); err != nil {
+				return err
+			}
+			if err = d.store.Delete(ctx, renamedPrefix+group.Value); err != nil {
+				return err
+			}
+		}
+
+		if len(removed) > 0 {
+			if err = d.archive(ctx, added, removed, len(previous)); err != nil {
+				return err
+			}
+		} else if len(added) > 0 {
+			d.log.Infof("rollover: %d new groups", len(added))
+		}
+	}
+
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+
+	return d.store.Set(ctx, groupsKey, string(data))
+}
+
+// archive keeps the disappeared groups with their successors and reports a
+// rollover when the share of replaced groups reaches the threshold
+func (d *Detector) archive(ctx context.Context, added, removed []model.Option, total int) error {
+	now := time.Now()
+	renamed := Successors(removed, added)
+	schedules := d.schedules()
+
+	for _, group := range removed {
+		archived := Archived{
+			Group:      group,
+			ArchivedAt: now,
+			RenamedTo:  renamed[group.Value],
+			Schedule:   schedules[group.Value],
+		}
+
+		data, err := json.Marshal(archived)
+		if err != nil {
+			return err
+		}
+
+		if err = d.store.Set(ctx, archivedPrefix+group.Value, string(data)); err != nil {
+			return err
+		}
+
+		if archived.RenamedTo != "" {
+			if err = d.store.Set(ctx, renamedPrefix+group.Value, archived.RenamedTo); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, migrator := range d.migrators {
+		if err := migrator.Migrate(ctx, removed, added, renamed); err != nil {
+			d.log.Errorf("rollover: migration: %s", err)
+		}
+	}
+
+	if float64(len(removed)) < float64(total)*d.cfg.Threshold {
+		d.log.Infof("rollover: %d groups archived, %d new groups", len(removed), len(added))
+		return nil
+	}
+
+	event := Event{At: now, Total: total, Added: added, Removed: removed, Renamed: renamed}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	d.log.Warnf("rollover: semester rollover, %d of %d groups replaced, %d renamed", len(removed), total, len(renamed))
+
+	return d.store.Set(ctx, fmt.Sprintf("%s%020d", eventPrefix, now.UnixNano()), string(data))
+}
+
+// diff returns the options of b missing in a
+func diff(a, b []model.Option) []model.Option {
+	known := make(map[string]struct{}, len(a))
+	for _, option := range a {
+		known[option.Value] = struct{}{}
+	}
+
+	var result []model.Option
+	for _, option := range b {
+		if _, ok := known[option.Value]; !ok {
+			result = append(result, option)
+		}
+	}
+
+	return result
+}
+
+var numbers = regexp.MustCompile(`\d+`)
+
+// Successors maps the disappeared groups to the new groups with the same
+// letters and numbers increased by one, e.g. "1ИС-21" to "2ИС-21". The course,
+// the first number, is the cheapest to advance; pairs are assigned from the
+// cheapest and a group with several equally cheap successors is left out
+func Successors(removed, added []model.Option) map[string]string {
+	type pair struct {
+		old, successor string
+		score          int
+	}
+
+	var pairs []pair
+	for _, old := range removed {
+		for _, candidate := range added {
+			if skeleton(candidate.Label) != skeleton(old.Label) {
+				continue
+			}
+
+			if score, ok := distance(old.Label, candidate.Label); ok {
+				pairs = append(pairs, pair{old.Value, candidate.Value, score})
+			}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].score < pairs[j].score
+	})
+
+	result := make(map[string]string)
+	taken := make(map[string]bool)
+	for start := 0; start < len(pairs); {
+		end := start
+		for end < len(pairs) && pairs[end].score == pairs[start].score {
+			end++
+		}
+
+		olds, news := make(map[string]int), make(map[string]int)
+		for _, p := range pairs[start:end] {
+			if _, ok := result[p.old]; !ok && !taken[p.successor] {
+				olds[p.old]++
+				news[p.successor]++
+			}
+		}
+
+		for _, p := range pairs[start:end] {
+			if olds[p.old] == 1 && news[p.successor] == 1 {
+				result[p.old] = p.successor
+				taken[p.successor] = true
+			}
+		}
+
+		start = end
+	}
+
+	return result
+}
+
+// skeleton returns the letters and separators of the group name
+func skeleton(label string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, label))
+}
+
+// distance scores the numbers of the new group name increased by one against
+// the old one, the n-th number costs n; any other change is not a successor
+func distance(a, b string) (int, bool) {
+	x, y := numbers.FindAllString(a, -1), numbers.FindAllString(b, -1)
+	if len(x) != len(y) {
+		return 0, false
+	}
+
+	var score int
+	for i := range x {
+		if x[i] == y[i] {
+			continue
+		}
+
+		n, errN := strconv.Atoi(x[i])
+		m, errM := strconv.Atoi(y[i])
+		if errN != nil || errM != nil || m != n+1 {
+			return 0, false
+		}
+		score += i + 1
+	}
+
+	return score, score > 0
+}
+
+// Events returns the reported rollovers, oldest first
+func Events(ctx context.Context, store storage.Store) ([]Event, error) {
+	keys, err := store.Keys(ctx, eventPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(keys))
+	for _, key := range keys {
+		value, err := store.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+
+		var event Event
+		if err = json.Unmarshal([]byte(value), &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// ArchivedGroups returns the archived groups without their schedules
+func ArchivedGroups(ctx context.Context, store storage.Store) ([]Archived, error) {
+	keys, err := store.Keys(ctx, archivedPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]Archived, 0, len(keys))
+	for _, key := range keys {
+		archived, err := ArchivedGroup(ctx, store, strings.TrimPrefix(key, archivedPrefix))
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		archived.Schedule = nil
+		groups = append(groups, archived)
+	}
+
+	return groups, nil
+}
+
+// ArchivedGroup returns the archived group, storage.ErrNotFound if there is none
+func ArchivedGroup(ctx context.Context, store storage.Store, value string) (Archived, error) {
+	data, err := store.Get(ctx, archivedPrefix+value)
+	if err != nil {
+		return Archived{}, err
+	}
+
+	var archived Archived
+	err = json.Unmarshal([]byte(data), &archived)
+
+	return archived, err
+}
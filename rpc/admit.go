@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/chazari-x/hmtpk-parser-api/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// admitted returns the context of the call admitted by the limits of its tenant
+func admitted(ctx context.Context, admit Admit) (context.Context, error) {
+	var key, addr string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(apiKeyMetadata); len(values) > 0 {
+			key = values[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	ctx, err := admit(ctx, key, addr)
+	switch {
+	case errors.Is(err, api.ErrUnknownKey):
+		return nil, status.Error(codes.Unauthenticated, api.ErrorToken)
+	case err != nil:
+		return nil, status.Error(codes.ResourceExhausted, api.ErrorRequestTimeout)
+	}
+
+	return ctx, nil
+}
+
+func unaryAdmit(admit Admit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := admitted(ctx, admit)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func streamAdmit(admit Admit) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := admitted(stream.Context(), admit)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, admittedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// admittedStream is the stream with the context of the admitted call
+type admittedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s admittedStream) Context() context.Context {
+	return s.ctx
+}
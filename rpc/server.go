@@ -0,0 +1,160 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/api"
+	hmtpkv1 "github.com/chazari-x/hmtpk-parser-api/proto/hmtpk/v1"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server is the gRPC implementation of HmtpkService
+type Server struct {
+	hmtpkv1.UnimplementedHmtpkServiceServer
+
+	log      *logrus.Logger
+	hmtpk    api.ScheduleProvider
+	location *time.Location
+}
+
+// Admit applies the rate limit of the tenant of the API key, of the client at
+// the address without it, like api.API.Admit
+type Admit func(ctx context.Context, key, addr string) (context.Context, error)
+
+// apiKeyMetadata is the metadata with the API key of the tenant
+const apiKeyMetadata = "x-api-key"
+
+// NewServer creates a new gRPC server serving the data of the provider, the
+// same one as of the HTTP API, with the limits of the tenants applied by admit
+// when it is not nil. Dates are resolved in the location
+func NewServer(provider api.ScheduleProvider, admit Admit, location *time.Location, logger *logrus.Logger) *grpc.Server {
+	var options []grpc.ServerOption
+	if admit != nil {
+		options = append(options, grpc.UnaryInterceptor(unaryAdmit(admit)), grpc.StreamInterceptor(streamAdmit(admit)))
+	}
+
+	s := grpc.NewServer(options...)
+	hmtpkv1.RegisterHmtpkServiceServer(s, &Server{log: logger, hmtpk: provider, location: location})
+	return s
+}
+
+const timeout = time.Second * 15
+
+// GetGroups returns the list of groups
+func (s *Server) GetGroups(ctx context.Context, _ *hmtpkv1.GetGroupsRequest) (*hmtpkv1.GetGroupsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	options, err := s.hmtpk.GetGroupOptions(ctx)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &hmtpkv1.GetGroupsResponse{Options: hmtpkv1.FromOptions(options)}, nil
+}
+
+// GetTeachers returns the list of teachers
+func (s *Server) GetTeachers(ctx context.Context, _ *hmtpkv1.GetTeachersRequest) (*hmtpkv1.GetTeachersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	options, err := s.hmtpk.GetTeacherOptions(ctx)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &hmtpkv1.GetTeachersResponse{Options: hmtpkv1.FromOptions(options)}, nil
+}
+
+// GetSchedule returns the weekly schedule of a group or a teacher
+func (s *Server) GetSchedule(ctx context.Context, req *hmtpkv1.GetScheduleRequest) (*hmtpkv1.GetScheduleResponse, error) {
+	schedule, err := s.schedule(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hmtpkv1.GetScheduleResponse{Days: hmtpkv1.FromSchedule(schedule)}, nil
+}
+
+// StreamSchedule sends the weekly schedule day by day
+func (s *Server) StreamSchedule(req *hmtpkv1.GetScheduleRequest, stream grpc.ServerStreamingServer[hmtpkv1.Schedule]) error {
+	schedule, err := s.schedule(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	for _, day := range hmtpkv1.FromSchedule(schedule) {
+		if err = stream.Send(day); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAnnounces returns a page of announces
+func (s *Server) GetAnnounces(ctx context.Context, req *hmtpkv1.GetAnnouncesRequest) (*hmtpkv1.GetAnnouncesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	announces, err := s.hmtpk.GetAnnounces(ctx, int(req.GetPage()))
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return hmtpkv1.FromAnnounces(announces), nil
+}
+
+func (s *Server) schedule(ctx context.Context, req *hmtpkv1.GetScheduleRequest) ([]model.Schedule, error) {
+	date, ok := api.ParseDate(req.GetDate(), time.Now().In(s.location))
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, api.ErrorBadRequest)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		schedule []model.Schedule
+		err      error
+	)
+
+	switch target := req.GetTarget().(type) {
+	case *hmtpkv1.GetScheduleRequest_Group:
+		schedule, err = s.hmtpk.GetScheduleByGroup(ctx, target.Group, date)
+	case *hmtpkv1.GetScheduleRequest_Teacher:
+		schedule, err = s.hmtpk.GetScheduleByTeacher(ctx, target.Teacher, date)
+	default:
+		return nil, status.Error(codes.InvalidArgument, api.ErrorBadRequest)
+	}
+
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return schedule, nil
+}
+
+// error converts an error of the parser to a gRPC status
+func (s *Server) error(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.Error(codes.Unavailable, api.ErrorHmtpkNotWorking)
+	} else if errors.Is(err, hmtpkErrors.ErrorBadRequest) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	} else if errors.Is(err, hmtpkErrors.ErrorBadResponse) {
+		return status.Error(codes.Unavailable, err.Error())
+	} else if errors.Is(err, api.ErrBudgetExhausted) {
+		return status.Error(codes.ResourceExhausted, api.ErrorRequestTimeout)
+	}
+
+	s.log.Error(err)
+
+	return status.Error(codes.Internal, api.ErrorAny)
+}
@@ -0,0 +1,322 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/requestid"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the error reporting to Sentry or a
+// compatible service like GlitchTip
+type Config struct {
+	// DSN is the client key URL of the project, enables the reporting when not empty
+	DSN string `yaml:"dsn"`
+	// Environment is sent with every event, the configuration profile by default
+	Environment string `yaml:"environment"`
+	// Release is sent with every event to tell the deployed versions apart
+	Release string `yaml:"release"`
+}
+
+const (
+	client      = "hmtpk-parser-api/1.0"
+	buffer      = 64
+	sendTimeout = time.Second * 10
+	// maxFrames bounds the stack trace of an event
+	maxFrames = 50
+)
+
+// sensitiveHeaders are never sent with the request of an event
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+}
+
+// Reporter sends the unexpected errors to the store endpoint of the project.
+// Events are dropped when the service can't keep up so reporting never blocks
+type Reporter struct {
+	cfg    Config
+	store  string
+	auth   string
+	client *http.Client
+	log    *logrus.Logger
+	events chan event
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// New parses the DSN like https://key@sentry.example.com/42 and starts sending the events
+func New(cfg Config, logger *logrus.Logger) (*Reporter, error) {
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("sentry dsn: %w", err)
+	}
+
+	project := strings.Trim(dsn.Path, "/")
+	if dsn.User == nil || dsn.User.Username() == "" || project == "" || dsn.Host == "" {
+		return nil, errors.New("sentry dsn: must be like https://key@host/project")
+	}
+
+	// a project under a path keeps its prefix, e.g. https://key@host/sentry/42
+	prefix, project := "", project
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+
+	r := &Reporter{
+		cfg:    cfg,
+		store:  fmt.Sprintf("%s://%s%s/api/%s/store/", dsn.Scheme, dsn.Host, prefix, project),
+		auth:   fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", client, dsn.User.Username()),
+		client: &http.Client{Timeout: sendTimeout},
+		log:    logger,
+		events: make(chan event, buffer),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if secret, ok := dsn.User.Password(); ok {
+		r.auth += ", sentry_secret=" + secret
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// Close sends the queued events and stops the reporter, the events captured
+// after it are dropped
+func (r *Reporter) Close() {
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Reporter) run() {
+	defer close(r.done)
+
+	for {
+		select {
+		case e := <-r.events:
+			r.sendLogged(e)
+		case <-r.stop:
+			for {
+				select {
+				case e := <-r.events:
+					r.sendLogged(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *Reporter) sendLogged(e event) {
+	if err := r.send(e); err != nil {
+		r.log.Warnf("sentry: %s", err)
+	}
+}
+
+func (r *Reporter) send(e event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.store, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("store: %s", resp.Status)
+	}
+
+	return nil
+}
+
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Exception   *exceptions       `json:"exception,omitempty"`
+	Request     *request          `json:"request,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type request struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// Capture reports the error with the request it failed and the stack trace
+// of the caller, the request may be nil
+func (r *Reporter) Capture(err error, req *http.Request) {
+	r.capture(err.Error(), exceptionType(err), req, 4)
+}
+
+// CapturePanic reports a recovered panic with the stack trace of the panicking goroutine
+func (r *Reporter) CapturePanic(recovered interface{}, req *http.Request) {
+	r.capture(fmt.Sprint(recovered), "panic", req, 4)
+}
+
+func (r *Reporter) capture(message, kind string, req *http.Request, skip int) {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	e := event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC(),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      "hmtpk-parser-api",
+		Environment: r.cfg.Environment,
+		Release:     r.cfg.Release,
+		Exception: &exceptions{Values: []exception{{
+			Type:       kind,
+			Value:      message,
+			Stacktrace: stack(skip),
+		}}},
+	}
+
+	if req != nil {
+		e.Request = requestOf(req)
+		if id := requestid.FromContext(req.Context()); id != "" {
+			e.Tags = map[string]string{requestid.Field: id}
+		}
+	}
+
+	select {
+	case r.events <- e:
+	default:
+	}
+}
+
+// exceptionType returns the type of the innermost wrapped error
+func exceptionType(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = next
+	}
+}
+
+// stack returns the frames of the caller, the outermost first as Sentry expects them
+func stack(skip int) *stacktrace {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var trace []frame
+	for {
+		f, more := frames.Next()
+		module, function := splitFunction(f.Function)
+		trace = append(trace, frame{
+			Function: function,
+			Module:   module,
+			Filename: f.File,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(module, "github.com/chazari-x/"),
+		})
+
+		if !more {
+			break
+		}
+	}
+
+	for i, j := 0, len(trace)-1; i < j; i, j = i+1, j-1 {
+		trace[i], trace[j] = trace[j], trace[i]
+	}
+
+	return &stacktrace{Frames: trace}
+}
+
+// splitFunction splits a function like github.com/a/b.(*T).M into the package and the function
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot], name[slash+1+dot+1:]
+	}
+
+	return "", name
+}
+
+func requestOf(req *http.Request) *request {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	headers := make(map[string]string, len(req.Header))
+	for name, values := range req.Header {
+		if !sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	query := req.URL.Query()
+	if query.Has("token") {
+		query.Set("token", "******")
+	}
+
+	return &request{
+		URL:         scheme + "://" + req.Host + req.URL.Path,
+		Method:      req.Method,
+		QueryString: query.Encode(),
+		Headers:     headers,
+	}
+}
@@ -0,0 +1,204 @@
+package stableid
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	// KindGroup is the kind of the IDs of the groups
+	KindGroup = "group"
+	// KindTeacher is the kind of the IDs of the teachers
+	KindTeacher = "teacher"
+
+	keyPrefix = "stableid:"
+)
+
+// Registry assigns stable integer IDs to the groups and the teachers by their
+// names, unlike the values of the options of the site they survive the
+// semesters. The IDs are kept in the store as stableid:<kind>:<id> = <name>
+type Registry struct {
+	store storage.Store
+	// readOnly registries read the IDs assigned by the primary and never assign one
+	readOnly bool
+
+	mu    sync.Mutex
+	kinds map[string]*kind
+}
+
+type kind struct {
+	// ids maps the key of a name to its ID
+	ids   map[string]int
+	names map[int]string
+	next  int
+}
+
+// New creates the registry of the IDs kept in the store
+func New(store storage.Store, readOnly bool) *Registry {
+	return &Registry{store: store, readOnly: readOnly, kinds: make(map[string]*kind)}
+}
+
+// Key returns the name without the case, the spaces and the punctuation, the
+// names with the same key share the ID and the options of the site are
+// matched to the name of an ID by it
+func Key(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// load reads the IDs of the kind from the store, again when reload is set
+func (r *Registry) load(ctx context.Context, name string, reload bool) (*kind, error) {
+	k, ok := r.kinds[name]
+	if ok && !reload {
+		return k, nil
+	}
+
+	prefix := keyPrefix + name + ":"
+	keys, err := r.store.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	k = &kind{ids: make(map[string]int, len(keys)), names: make(map[int]string, len(keys)), next: 1}
+	for _, storeKey := range keys {
+		id, err := strconv.Atoi(strings.TrimPrefix(storeKey, prefix))
+		if err != nil {
+			continue
+		}
+
+		value, err := r.store.Get(ctx, storeKey)
+		if err != nil {
+			continue
+		}
+
+		k.ids[Key(value)] = id
+		k.names[id] = value
+		k.next = max(k.next, id+1)
+	}
+	r.kinds[name] = k
+
+	return k, nil
+}
+
+// Assign returns the IDs of the names of the kind by the names, the new
+// names get the next IDs. A read-only registry leaves out the names the
+// primary has not assigned an ID yet
+func (r *Registry) Assign(ctx context.Context, name string, names []string) (map[string]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, err := r.load(ctx, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// the IDs assigned by another instance since the last load
+	for _, n := range names {
+		if _, ok := k.ids[Key(n)]; !ok && Key(n) != "" {
+			if k, err = r.load(ctx, name, true); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	ids := make(map[string]int, len(names))
+	for _, n := range names {
+		if id, ok := k.ids[Key(n)]; ok {
+			ids[n] = id
+			continue
+		}
+
+		if r.readOnly || Key(n) == "" {
+			continue
+		}
+
+		id := k.next
+		if err = r.store.Set(ctx, keyPrefix+name+":"+strconv.Itoa(id), n); err != nil {
+			return nil, err
+		}
+
+		k.ids[Key(n)], k.names[id] = id, n
+		k.next++
+		ids[n] = id
+	}
+
+	return ids, nil
+}
+
+// Name returns the name of the ID of the kind, the last one seen for a renamed group
+func (r *Registry) Name(ctx context.Context, name string, id int) (string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, err := r.load(ctx, name, false)
+	if err != nil {
+		return "", false, err
+	}
+
+	value, ok := k.names[id]
+	if !ok {
+		if k, err = r.load(ctx, name, true); err != nil {
+			return "", false, err
+		}
+		value, ok = k.names[id]
+	}
+
+	return value, ok, nil
+}
+
+// Migrate passes the IDs of the groups that disappeared to the groups
+// replacing them on the semester rollover, unless they already have one,
+// so the favorites saved by the clients follow the students
+func (r *Registry) Migrate(ctx context.Context, removed, added []model.Option, renamed map[string]string) error {
+	if r.readOnly || len(renamed) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]string, len(removed)+len(added))
+	for _, option := range removed {
+		labels[option.Value] = option.Label
+	}
+	for _, option := range added {
+		labels[option.Value] = option.Label
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, err := r.load(ctx, KindGroup, true)
+	if err != nil {
+		return err
+	}
+
+	for old, successor := range renamed {
+		id, ok := k.ids[Key(labels[old])]
+		if !ok {
+			continue
+		}
+
+		label := labels[successor]
+		if _, taken := k.ids[Key(label)]; taken || Key(label) == "" {
+			continue
+		}
+
+		if err = r.store.Set(ctx, keyPrefix+KindGroup+":"+strconv.Itoa(id), label); err != nil {
+			return err
+		}
+
+		delete(k.ids, Key(labels[old]))
+		k.ids[Key(label)], k.names[id] = id, label
+	}
+
+	return nil
+}
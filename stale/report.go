@@ -0,0 +1,164 @@
+package stale
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// LayerRedis marks the data served from the Redis cache of the parser
+	LayerRedis = "redis"
+	// LayerMemory marks the data served from the memory of the process
+	LayerMemory = "memory"
+)
+
+const (
+	StatusHit   = "HIT"
+	StatusMiss  = "MISS"
+	StatusStale = "STALE"
+)
+
+// Report tells the handler where the data of its request came from: a
+// cache, hmtpk.ru or a fallback instead of hmtpk.ru
+type Report struct {
+	mu        sync.Mutex
+	source    string
+	fetchedAt time.Time
+	layer     string
+	age       time.Duration
+	miss      bool
+}
+
+type reportKey struct{}
+
+// WithReport returns the context collecting the report of the fetches
+func WithReport(ctx context.Context) (context.Context, *Report) {
+	report := &Report{}
+	return context.WithValue(ctx, reportKey{}, report), report
+}
+
+func from(ctx context.Context) (*Report, bool) {
+	report, ok := ctx.Value(reportKey{}).(*Report)
+	return report, ok
+}
+
+// Mark records that the data was served from the source, fetched from
+// hmtpk.ru at the time, zero when unknown. The oldest time is kept
+func Mark(ctx context.Context, source string, fetchedAt time.Time) {
+	report, ok := from(ctx)
+	if !ok {
+		return
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if report.source == "" {
+		report.source = source
+	}
+
+	if report.fetchedAt.IsZero() || (!fetchedAt.IsZero() && fetchedAt.Before(report.fetchedAt)) {
+		report.fetchedAt = fetchedAt
+	}
+}
+
+// Hit records that the data was served from a cache layer, cached age ago.
+// The oldest hit is kept
+func Hit(ctx context.Context, layer string, age time.Duration) {
+	report, ok := from(ctx)
+	if !ok {
+		return
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if report.layer == "" || age > report.age {
+		report.layer, report.age = layer, age
+	}
+}
+
+// Miss records that the data was fetched from hmtpk.ru
+func Miss(ctx context.Context) {
+	report, ok := from(ctx)
+	if !ok {
+		return
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	report.miss = true
+}
+
+// Propagate records everything of the report in the report of the context
+func (r *Report) Propagate(ctx context.Context) {
+	r.mu.Lock()
+	source, fetchedAt, layer, age, miss := r.source, r.fetchedAt, r.layer, r.age, r.miss
+	r.mu.Unlock()
+
+	if source != "" {
+		Mark(ctx, source, fetchedAt)
+	}
+
+	if layer != "" {
+		Hit(ctx, layer, age)
+	}
+
+	if miss {
+		Miss(ctx)
+	}
+}
+
+// Track runs the fetch with a report of its own, so the caller learns whether
+// this fetch was served from a fallback. Everything is reported to the
+// report of the context too
+func Track[T any](ctx context.Context, fetch func(ctx context.Context) (T, error)) (T, bool, error) {
+	inner, report := WithReport(ctx)
+	result, err := fetch(inner)
+	report.Propagate(ctx)
+
+	_, _, ok := report.Get()
+
+	return result, ok, err
+}
+
+// Get returns the report of the request, false when the data is fresh
+func Get(ctx context.Context) (string, time.Time, bool) {
+	report, ok := from(ctx)
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	return report.Get()
+}
+
+// Get returns the source and the time the data was fetched, false when it is fresh
+func (r *Report) Get() (string, time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.source, r.fetchedAt, r.source != ""
+}
+
+// Status returns the cache status of the request: STALE when served from a
+// fallback, MISS when anything was fetched from hmtpk.ru, HIT otherwise,
+// with the cache layer and the age of the data
+func (r *Report) Status() (string, string, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.source != "":
+		var age time.Duration
+		if !r.fetchedAt.IsZero() {
+			age = time.Since(r.fetchedAt)
+		}
+		return StatusStale, r.source, age
+	case r.miss || r.layer == "":
+		return StatusMiss, "", 0
+	}
+
+	return StatusHit, r.layer, r.age
+}
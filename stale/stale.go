@@ -0,0 +1,139 @@
+package stale
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// SourceCache marks the data served from the last successful fetch
+const SourceCache = "cache"
+
+// Source is the provider of the data fetched from hmtpk.ru
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+const (
+	keyPrefix = "lastgood:"
+
+	// fallbackTimeout bounds reading the store after the source failed
+	fallbackTimeout = time.Second * 5
+)
+
+// entry is the stored result of a successful fetch
+type entry struct {
+	Data      json.RawMessage `json:"data"`
+	FetchedAt time.Time       `json:"fetched_at"`
+}
+
+// Provider keeps the last successful result of every request and serves it
+// when the source fails, so clients keep working while hmtpk.ru is down
+type Provider struct {
+	source Source
+	store  storage.Store
+	log    *logrus.Logger
+}
+
+// NewProvider wraps the source
+func NewProvider(source Source, store storage.Store, logger *logrus.Logger) *Provider {
+	return &Provider{source: source, store: store, log: logger}
+}
+
+// do fetches the result and stores it, or serves the stored one for the
+// failures that are not caused by the request itself
+func do[T any](ctx context.Context, p *Provider, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	// the data served by a fallback of the source is not the last fetched one
+	result, marked, err := Track(ctx, fetch)
+	if err == nil {
+		if !marked {
+			p.put(ctx, key, result)
+		}
+		return result, nil
+	}
+
+	if errors.Is(err, hmtpkErrors.ErrorBadRequest) || errors.Is(err, context.Canceled) {
+		return result, err
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fallbackTimeout)
+	defer cancel()
+
+	data, storeErr := p.store.Get(storeCtx, keyPrefix+key)
+	if storeErr != nil {
+		return result, err
+	}
+
+	var (
+		stored entry
+		cached T
+	)
+	if json.Unmarshal([]byte(data), &stored) != nil || json.Unmarshal(stored.Data, &cached) != nil {
+		return result, err
+	}
+
+	// the fallbacks during a maintenance window of hmtpk.ru are expected
+	log := p.log.WithContext(ctx)
+	logf := log.Warnf
+	if errors.Is(err, maintenance.ErrMaintenance) {
+		logf = log.Debugf
+	}
+	logf("stale: %s served from the last successful fetch of %s: %s", key, stored.FetchedAt.Format(time.RFC3339), err)
+	Mark(ctx, SourceCache, stored.FetchedAt)
+
+	return cached, nil
+}
+
+// put stores the result of a successful fetch
+func (p *Provider) put(ctx context.Context, key string, result interface{}) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	if data, err = json.Marshal(entry{Data: data, FetchedAt: time.Now()}); err != nil {
+		return
+	}
+
+	if err = p.store.Set(ctx, keyPrefix+key, string(data)); err != nil {
+		p.log.WithContext(ctx).Errorf("stale: %s", err)
+	}
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	return do(ctx, p, "groups", p.source.GetGroupOptions)
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	return do(ctx, p, "teachers", p.source.GetTeacherOptions)
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	return do(ctx, p, "group:"+group+":"+date, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByGroup(ctx, group, date)
+	})
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	return do(ctx, p, "teacher:"+teacher+":"+date, func(ctx context.Context) ([]model.Schedule, error) {
+		return p.source.GetScheduleByTeacher(ctx, teacher, date)
+	})
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	return do(ctx, p, "announces:"+strconv.Itoa(page), func(ctx context.Context) (model.Announces, error) {
+		return p.source.GetAnnounces(ctx, page)
+	})
+}
@@ -0,0 +1,158 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the weekly report emailed to the operators
+type Config struct {
+	// To are the addresses of the operators, the report is disabled when empty
+	To []string `yaml:"to"`
+	// SMTP is the host:port of the mail server
+	SMTP     string `yaml:"smtp"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender, Username when empty
+	From string `yaml:"from"`
+	// Weekday is the English name of the day the report is sent on, Monday when empty
+	Weekday string `yaml:"weekday"`
+	// Hour is the local hour the report is sent at
+	Hour int `yaml:"hour"`
+}
+
+const (
+	checkInterval = time.Minute * 10
+
+	// sentKey holds the ISO week of the last report sent, a restart does not send it twice
+	sentKey = "report:sent"
+)
+
+// Mailer emails the report of the past week to the operators once a week
+type Mailer struct {
+	cfg      Config
+	weekday  time.Weekday
+	stats    *Stats
+	store    storage.Store
+	location *time.Location
+	log      *logrus.Logger
+}
+
+// NewMailer validates the configuration and creates the mailer
+func NewMailer(cfg Config, stats *Stats, store storage.Store, logger *logrus.Logger) (*Mailer, error) {
+	if cfg.SMTP == "" {
+		return nil, errors.New("the weekly report requires the SMTP server")
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.SMTP); err != nil {
+		return nil, fmt.Errorf("smtp: %w", err)
+	}
+
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+
+	if cfg.From == "" {
+		return nil, errors.New("the weekly report requires the sender")
+	}
+
+	if cfg.Hour < 0 || cfg.Hour > 23 {
+		return nil, fmt.Errorf("invalid report hour %d", cfg.Hour)
+	}
+
+	weekday := time.Monday
+	if cfg.Weekday != "" {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(d.String(), cfg.Weekday) {
+				weekday, found = d, true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown report weekday %q", cfg.Weekday)
+		}
+	}
+
+	return &Mailer{cfg: cfg, weekday: weekday, stats: stats, store: store, location: time.Local, log: logger}, nil
+}
+
+// SetLocation sets the time zone of the sending hour and the report
+func (m *Mailer) SetLocation(location *time.Location) {
+	m.location = location
+}
+
+// Run sends the report every week until the context is canceled
+func (m *Mailer) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.check(ctx); err != nil {
+			m.log.Errorf("report: %s", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check sends the report once the sending hour of the week has come
+func (m *Mailer) check(ctx context.Context) error {
+	now := time.Now().In(m.location)
+	if now.Weekday() != m.weekday || now.Hour() < m.cfg.Hour {
+		return nil
+	}
+
+	year, week := now.ISOWeek()
+	current := fmt.Sprintf("%d/%d", year, week)
+
+	sent, err := m.store.Get(ctx, sentKey)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	if sent == current {
+		return nil
+	}
+
+	if err = m.Send(now); err != nil {
+		return err
+	}
+
+	m.log.Infof("report: weekly report sent to %s", strings.Join(m.cfg.To, ", "))
+
+	return m.store.Set(ctx, sentKey, current)
+}
+
+// Send emails the report of the seven days before now
+func (m *Mailer) Send(now time.Time) error {
+	report := m.stats.Report(now.AddDate(0, 0, -7), now)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: hmtpk-parser-api weekly report %s\r\n", now.Format("02.01.2006"))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(report.Text(m.location), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(m.cfg.SMTP)
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)
+	}
+
+	return smtp.SendMail(m.cfg.SMTP, auth, m.cfg.From, m.cfg.To, []byte(msg.String()))
+}
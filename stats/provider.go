@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+)
+
+const (
+	AnomalyEmptyGroups    = "empty group list"
+	AnomalyEmptyTeachers  = "empty teacher list"
+	AnomalyIncompleteWeek = "incomplete week"
+	AnomalyEmptyAnnounces = "empty first page of announces"
+	daysInWeek            = 7
+)
+
+// Source is the provider of the data fetched from hmtpk.ru
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetTeacherOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+	GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error)
+	GetAnnounces(ctx context.Context, page int) (model.Announces, error)
+}
+
+// Provider counts the fetches of the source and the results of the parser
+// that hint at a changed layout of hmtpk.ru
+type Provider struct {
+	source Source
+	stats  *Stats
+}
+
+// NewProvider wraps the source
+func NewProvider(source Source, stats *Stats) *Provider {
+	return &Provider{source: source, stats: stats}
+}
+
+// fetch records the fetch, a canceled or a bad request and a fetch skipped
+// during maintenance are not failures of hmtpk.ru
+func (p *Provider) fetch(ctx context.Context, err error) {
+	if ctx.Err() == nil && !errors.Is(err, hmtpkErrors.ErrorBadRequest) && !errors.Is(err, maintenance.ErrMaintenance) {
+		p.stats.Upstream(err != nil)
+	}
+}
+
+func (p *Provider) GetGroupOptions(ctx context.Context) ([]model.Option, error) {
+	options, err := p.source.GetGroupOptions(ctx)
+	p.fetch(ctx, err)
+	if err == nil && len(options) == 0 {
+		p.stats.Anomaly(AnomalyEmptyGroups)
+	}
+
+	return options, err
+}
+
+func (p *Provider) GetTeacherOptions(ctx context.Context) ([]model.Option, error) {
+	options, err := p.source.GetTeacherOptions(ctx)
+	p.fetch(ctx, err)
+	if err == nil && len(options) == 0 {
+		p.stats.Anomaly(AnomalyEmptyTeachers)
+	}
+
+	return options, err
+}
+
+func (p *Provider) GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error) {
+	schedule, err := p.source.GetScheduleByGroup(ctx, group, date)
+	p.schedule(ctx, schedule, err)
+
+	return schedule, err
+}
+
+func (p *Provider) GetScheduleByTeacher(ctx context.Context, teacher, date string) ([]model.Schedule, error) {
+	schedule, err := p.source.GetScheduleByTeacher(ctx, teacher, date)
+	p.schedule(ctx, schedule, err)
+
+	return schedule, err
+}
+
+func (p *Provider) schedule(ctx context.Context, schedule []model.Schedule, err error) {
+	p.fetch(ctx, err)
+	// the site always lists the seven days of the week, published or not
+	if err == nil && len(schedule) != daysInWeek {
+		p.stats.Anomaly(AnomalyIncompleteWeek)
+	}
+}
+
+func (p *Provider) GetAnnounces(ctx context.Context, page int) (model.Announces, error) {
+	announces, err := p.source.GetAnnounces(ctx, page)
+	p.fetch(ctx, err)
+	if err == nil && page == 1 && len(announces.Announces) == 0 {
+		p.stats.Anomaly(AnomalyEmptyAnnounces)
+	}
+
+	return announces, err
+}
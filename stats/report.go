@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// spikeFactor is how many times the average error rate an hour has to reach to be a spike
+	spikeFactor = 3
+	// minSpikeErrors keeps a couple of errors in a quiet hour from being a spike
+	minSpikeErrors = 10
+
+	topGroups = 10
+)
+
+// Report sums up the operation of the service over a period
+type Report struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Requests int       `json:"requests"`
+	// Errors are the responses with a 5xx status
+	Errors int `json:"errors"`
+	// Spikes are the hours with an error rate well above the average
+	Spikes         []Spike `json:"spikes,omitempty"`
+	Upstream       int     `json:"upstream"`
+	UpstreamFailed int     `json:"upstream_failed"`
+	// Availability is the share of the successful fetches from hmtpk.ru, 1 without fetches
+	Availability float64 `json:"availability"`
+	Hits         int     `json:"hits"`
+	Misses       int     `json:"misses"`
+	Stale        int     `json:"stale"`
+	// HitRate is the share of the cache hits among the requests with a cache status
+	HitRate   float64 `json:"hit_rate"`
+	TopGroups []Count `json:"top_groups,omitempty"`
+	Anomalies []Count `json:"anomalies,omitempty"`
+}
+
+// Spike is an hour with many server errors
+type Spike struct {
+	Hour     time.Time `json:"hour"`
+	Requests int       `json:"requests"`
+	Errors   int       `json:"errors"`
+}
+
+// Count is the number of requests of a group or occurrences of an anomaly
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Report sums up the counters of the hours from..to
+func (s *Stats) Report(from, to time.Time) Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := Report{From: from, To: to, Availability: 1}
+	groups := make(map[string]int)
+	anomalies := make(map[string]int)
+
+	var hours []time.Time
+	for hour, b := range s.hours {
+		if hour.Before(from.Truncate(time.Hour)) || !hour.Before(to) {
+			continue
+		}
+		hours = append(hours, hour)
+
+		report.Requests += b.requests
+		report.Errors += b.errors
+		report.Upstream += b.upstream
+		report.UpstreamFailed += b.upstreamFailed
+		report.Hits += b.hits
+		report.Misses += b.misses
+		report.Stale += b.stale
+
+		for group, n := range b.groups {
+			groups[group] += n
+		}
+
+		for kind, n := range b.anomalies {
+			anomalies[kind] += n
+		}
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		return hours[i].Before(hours[j])
+	})
+
+	if report.Requests > 0 {
+		rate := float64(report.Errors) / float64(report.Requests)
+		for _, hour := range hours {
+			b := s.hours[hour]
+			if b.errors >= minSpikeErrors && float64(b.errors) > spikeFactor*rate*float64(b.requests) {
+				report.Spikes = append(report.Spikes, Spike{Hour: hour, Requests: b.requests, Errors: b.errors})
+			}
+		}
+	}
+
+	if report.Upstream > 0 {
+		report.Availability = 1 - float64(report.UpstreamFailed)/float64(report.Upstream)
+	}
+
+	if cached := report.Hits + report.Misses + report.Stale; cached > 0 {
+		report.HitRate = float64(report.Hits) / float64(cached)
+	}
+
+	report.TopGroups = top(groups, topGroups)
+	report.Anomalies = top(anomalies, 0)
+
+	return report
+}
+
+// top returns the counts in descending order, at most limit of them unless zero
+func top(counts map[string]int, limit int) []Count {
+	list := make([]Count, 0, len(counts))
+	for name, n := range counts {
+		list = append(list, Count{Name: name, Count: n})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list
+}
+
+// Text renders the report as the plain text of the email
+func (r Report) Text(location *time.Location) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Weekly report %s - %s\n\n", r.From.In(location).Format("02.01.2006"), r.To.In(location).Format("02.01.2006"))
+
+	fmt.Fprintf(&b, "Requests: %d\n", r.Requests)
+	fmt.Fprintf(&b, "Server errors: %d (%s)\n", r.Errors, percent(r.Errors, r.Requests))
+	if len(r.Spikes) > 0 {
+		b.WriteString("Error spikes:\n")
+		for _, spike := range r.Spikes {
+			fmt.Fprintf(&b, "  %s: %d errors of %d requests\n", spike.Hour.In(location).Format("02.01.2006 15:04"), spike.Errors, spike.Requests)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nhmtpk.ru availability: %.2f%% (%d of %d fetches failed)\n", r.Availability*100, r.UpstreamFailed, r.Upstream)
+	fmt.Fprintf(&b, "Cache: %.2f%% hits (%d hits, %d misses, %d stale)\n", r.HitRate*100, r.Hits, r.Misses, r.Stale)
+
+	if len(r.TopGroups) > 0 {
+		b.WriteString("\nTop groups:\n")
+		for _, group := range r.TopGroups {
+			fmt.Fprintf(&b, "  %s: %d\n", group.Name, group.Count)
+		}
+	}
+
+	b.WriteString("\nParser anomalies:")
+	if len(r.Anomalies) == 0 {
+		b.WriteString(" none\n")
+	} else {
+		b.WriteString("\n")
+		for _, anomaly := range r.Anomalies {
+			fmt.Fprintf(&b, "  %s: %d\n", anomaly.Name, anomaly.Count)
+		}
+	}
+
+	return b.String()
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+
+	return fmt.Sprintf("%.2f%%", float64(n)*100/float64(total))
+}
@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/stale"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// retention is the time the hourly counters are kept, a bit over a week so
+// a report sent late still covers the whole week
+const retention = time.Hour * 24 * 8
+
+// Stats counts the requests, the upstream fetches and the anomalies of the
+// parser per hour, in memory
+type Stats struct {
+	mu    sync.Mutex
+	hours map[time.Time]*bucket
+	now   func() time.Time
+}
+
+type bucket struct {
+	requests       int
+	errors         int
+	upstream       int
+	upstreamFailed int
+	hits           int
+	misses         int
+	stale          int
+	groups         map[string]int
+	anomalies      map[string]int
+}
+
+// New creates empty stats
+func New() *Stats {
+	return &Stats{hours: make(map[time.Time]*bucket), now: time.Now}
+}
+
+// record updates the counters of the current hour
+func (s *Stats) record(update func(b *bucket)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hour := s.now().Truncate(time.Hour)
+	b, ok := s.hours[hour]
+	if !ok {
+		b = &bucket{groups: make(map[string]int), anomalies: make(map[string]int)}
+		s.hours[hour] = b
+
+		for h := range s.hours {
+			if hour.Sub(h) > retention {
+				delete(s.hours, h)
+			}
+		}
+	}
+
+	update(b)
+}
+
+// Anomaly records an unexpected result of the parser
+func (s *Stats) Anomaly(kind string) {
+	s.record(func(b *bucket) {
+		b.anomalies[kind]++
+	})
+}
+
+// Upstream records a fetch from hmtpk.ru
+func (s *Stats) Upstream(failed bool) {
+	s.record(func(b *bucket) {
+		b.upstream++
+		if failed {
+			b.upstreamFailed++
+		}
+	})
+}
+
+// Middleware counts the requests, the server errors, the cache status set in
+// X-Cache and the requested groups
+func (s *Stats) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		group := r.URL.Query().Get("group")
+		cache := ww.Header().Get("X-Cache")
+		s.record(func(b *bucket) {
+			b.requests++
+			if ww.Status() >= http.StatusInternalServerError {
+				b.errors++
+			}
+
+			switch cache {
+			case stale.StatusHit:
+				b.hits++
+			case stale.StatusMiss:
+				b.misses++
+			case stale.StatusStale:
+				b.stale++
+			}
+
+			if group != "" {
+				b.groups[group]++
+			}
+		})
+	})
+}
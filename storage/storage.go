@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrNotFound is returned when the key is not stored
+var ErrNotFound = errors.New("not found")
+
+// Store keeps the data owned by the service, unlike the cache of the parser
+// it is not expected to expire unless set with SetTTL
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+	// SetTTL sets the value deleted after the ttl
+	SetTTL(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetIfAbsent sets the value unless the key is stored, reporting whether
+	// it was set, so only one of the concurrent callers claims the key
+	SetIfAbsent(ctx context.Context, key, value string) (bool, error)
+	// GetDel returns the value and deletes it atomically, so only one of the
+	// concurrent callers gets it
+	GetDel(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+	// Keys returns the stored keys starting with the prefix, sorted
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Memory is a Store living in the memory of the process
+type Memory struct {
+	mu      sync.RWMutex
+	data    map[string]string
+	expires map[string]time.Time
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{data: make(map[string]string), expires: make(map[string]time.Time)}
+}
+
+// live reports whether the key is stored and not expired, m.mu is held
+func (m *Memory) live(key string, now time.Time) bool {
+	if _, ok := m.data[key]; !ok {
+		return false
+	}
+
+	expires, ok := m.expires[key]
+	return !ok || now.Before(expires)
+}
+
+func (m *Memory) Get(_ context.Context, key string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.live(key, time.Now()) {
+		return "", ErrNotFound
+	}
+
+	return m.data[key], nil
+}
+
+func (m *Memory) Set(_ context.Context, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[key] = value
+	delete(m.expires, key)
+
+	return nil
+}
+
+func (m *Memory) SetTTL(_ context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the expired keys are dropped here as nothing else deletes them
+	now := time.Now()
+	for k, expires := range m.expires {
+		if !now.Before(expires) {
+			delete(m.data, k)
+			delete(m.expires, k)
+		}
+	}
+
+	m.data[key] = value
+	m.expires[key] = now.Add(ttl)
+
+	return nil
+}
+
+func (m *Memory) SetIfAbsent(_ context.Context, key, value string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.live(key, time.Now()) {
+		return false, nil
+	}
+
+	m.data[key] = value
+	delete(m.expires, key)
+
+	return true, nil
+}
+
+func (m *Memory) GetDel(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	live := m.live(key, time.Now())
+	value := m.data[key]
+	delete(m.data, key)
+	delete(m.expires, key)
+
+	if !live {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data, key)
+	delete(m.expires, key)
+
+	return nil
+}
+
+func (m *Memory) Keys(_ context.Context, prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]string, 0)
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) && m.live(key, now) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Redis is a Store keeping the keys under a prefix of the Redis database
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedis creates a store in Redis or a Redis Cluster, keys are prefixed to
+// stay apart from the parser cache
+func NewRedis(client redis.UniversalClient, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (s *Redis) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+
+	return value, err
+}
+
+func (s *Redis) Set(ctx context.Context, key, value string) error {
+	return s.client.Set(ctx, s.prefix+key, value, 0).Err()
+}
+
+func (s *Redis) SetTTL(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+func (s *Redis) SetIfAbsent(ctx context.Context, key, value string) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, value, 0).Result()
+}
+
+// GetDel needs Redis 6.2 or newer
+func (s *Redis) GetDel(ctx context.Context, key string) (string, error) {
+	value, err := s.client.GetDel(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+
+	return value, err
+}
+
+func (s *Redis) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+func (s *Redis) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			batch, next, err := client.Scan(ctx, cursor, s.prefix+prefix+"*", 100).Result()
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			for _, key := range batch {
+				keys = append(keys, strings.TrimPrefix(key, s.prefix))
+			}
+			mu.Unlock()
+
+			if cursor = next; cursor == 0 {
+				return nil
+			}
+		}
+	}
+
+	// the keys of a cluster are spread over its masters
+	var err error
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	} else {
+		err = scan(ctx, s.client)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
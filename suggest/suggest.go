@@ -0,0 +1,231 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// KindGroup is the kind of the suggestions of groups
+	KindGroup = "group"
+	// KindTeacher is the kind of the suggestions of teachers
+	KindTeacher = "teacher"
+
+	scoreExact  = 3
+	scorePrefix = 2
+	scoreFuzzy  = 1
+)
+
+// Candidate is a group or a teacher that can be suggested
+type Candidate struct {
+	Kind  string
+	Label string
+	Value string
+}
+
+// Suggestion is a candidate matching the query, the best first
+type Suggestion struct {
+	Kind  string  `json:"kind"`
+	Label string  `json:"label"`
+	Value string  `json:"value"`
+	Score float64 `json:"score"`
+}
+
+// cyrillic spells the Russian letters in latin the way the queries typed on
+// a latin keyboard are normalized by latin
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "h", 'ц': "c", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// latinVariants folds the spellings of the same Russian sounds, longest first
+var latinVariants = strings.NewReplacer(
+	"shch", "sch", "kh", "h", "ts", "c", "tz", "c", "ck", "k",
+	"ja", "ya", "ju", "yu", "jo", "e", "yo", "e", "ye", "e",
+	"x", "h", "w", "v", "q", "k", "j", "y", "'", "",
+)
+
+// Transliterate spells the Russian letters of the lowercase text in latin
+func Transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if latin, ok := cyrillic[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// latin normalizes a lowercase text typed in latin
+func latin(text string) string {
+	return latinVariants.Replace(text)
+}
+
+// tokenize splits the text into the lowercase words and numbers
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isLatin reports whether the word has latin letters
+func isLatin(word string) bool {
+	for _, r := range word {
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entry is a candidate with the forms of its words compared to the query
+type entry struct {
+	candidate Candidate
+	words     []string
+	latin     []string
+}
+
+// Suggest returns up to limit candidates matching every word of the query by
+// its prefix, a typo or, for the words typed in latin, the transliteration.
+// The names can be typed without the initials, and the groups with or
+// without the separators, e.g. "исп21" for "ИСП-21"
+func Suggest(query string, candidates []Candidate, limit int) []Suggestion {
+	terms := tokenize(query)
+	if len(terms) == 0 || limit <= 0 {
+		return nil
+	}
+
+	var suggestions []Suggestion
+	for _, candidate := range candidates {
+		e := newEntry(candidate)
+
+		total := 0
+		for _, term := range terms {
+			score := e.match(term)
+			if score == 0 {
+				total = 0
+				break
+			}
+			total += score
+		}
+
+		if total > 0 {
+			suggestions = append(suggestions, Suggestion{
+				Kind:  candidate.Kind,
+				Label: candidate.Label,
+				Value: candidate.Value,
+				Score: float64(total) / float64(len(terms)*scoreExact),
+			})
+		}
+	}
+
+	// the shorter names complete the query better
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		if len(suggestions[i].Label) != len(suggestions[j].Label) {
+			return len(suggestions[i].Label) < len(suggestions[j].Label)
+		}
+		return suggestions[i].Label < suggestions[j].Label
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
+
+func newEntry(candidate Candidate) entry {
+	words := tokenize(candidate.Label)
+	// "ИСП-21" is also typed as "исп21"
+	if len(words) > 1 {
+		words = append(words, strings.Join(words, ""))
+	}
+
+	e := entry{candidate: candidate, words: words, latin: make([]string, len(words))}
+	for i, word := range words {
+		e.latin[i] = latin(Transliterate(word))
+	}
+
+	return e
+}
+
+// match returns the best score of the term against the words of the candidate, 0 without a match
+func (e entry) match(term string) int {
+	words := e.words
+	if isLatin(term) {
+		words, term = e.latin, latin(term)
+	}
+
+	best := 0
+	for _, word := range words {
+		best = max(best, score(term, word))
+	}
+
+	return best
+}
+
+// score compares the term with the word, the typos are tolerated in the terms
+// long enough to tell them apart from another word
+func score(term, word string) int {
+	switch {
+	case term == word:
+		return scoreExact
+	case strings.HasPrefix(word, term):
+		return scorePrefix
+	}
+
+	t, w := []rune(term), []rune(word)
+	allowed := 0
+	switch {
+	case len(t) >= 8:
+		allowed = 2
+	case len(t) >= 4:
+		allowed = 1
+	}
+	if allowed == 0 {
+		return 0
+	}
+
+	// a typo in the prefix typed so far or in the whole word
+	for _, n := range []int{len(t), len(t) - 1, len(t) + 1} {
+		if n > 0 && n <= len(w) && distance(t, w[:n]) <= allowed {
+			return scoreFuzzy
+		}
+	}
+
+	return 0
+}
+
+// distance is the Levenshtein distance of the words
+func distance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
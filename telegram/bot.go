@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/traceparent"
+)
+
+const (
+	apiURL = "https://api.telegram.org/bot"
+
+	sendTimeout = time.Second * 10
+)
+
+var client = &http.Client{Timeout: sendTimeout}
+
+// SendMessage sends a text message from the bot to the chat, for a user who
+// opened the Mini App the chat id is the user id
+func SendMessage(ctx context.Context, cfg Config, chatID int64, text string) error {
+	body, err := json.Marshal(map[string]interface{}{"chat_id": chatID, "text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+cfg.BotToken+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	traceparent.Set(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	if !result.OK {
+		return errors.New("telegram: " + result.Description)
+	}
+
+	return nil
+}
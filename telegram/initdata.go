@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the Telegram Mini App backend
+type Config struct {
+	// BotToken is the token of the bot the Mini App belongs to, enables the endpoints when not empty
+	BotToken string `yaml:"bot_token"`
+	// MaxAge is how long signed init data stays valid
+	MaxAge time.Duration `yaml:"max_age"`
+	// Migrate rebinds the users of a group that disappeared to the group
+	// replacing it instead of only suggesting it
+	Migrate bool `yaml:"migrate"`
+}
+
+const defaultMaxAge = time.Hour * 24
+
+var (
+	ErrInvalid = errors.New("invalid init data")
+	ErrExpired = errors.New("init data expired")
+)
+
+// User is the Telegram user opening the Mini App
+type User struct {
+	ID           int64  `json:"id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// InitData is the validated launch data of the Mini App
+type InitData struct {
+	User     User      `json:"user"`
+	AuthDate time.Time `json:"auth_date"`
+	QueryID  string    `json:"query_id,omitempty"`
+}
+
+// Validate checks the signature of the raw init data passed by Telegram.WebApp.initData
+// as described in https://core.telegram.org/bots/webapps#validating-data-received-via-the-mini-app
+func Validate(raw string, cfg Config, now time.Time) (InitData, error) {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return InitData{}, ErrInvalid
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return InitData{}, ErrInvalid
+	}
+
+	pairs := make([]string, 0, len(values))
+	for key := range values {
+		if key != "hash" {
+			pairs = append(pairs, key+"="+values.Get(key))
+		}
+	}
+	sort.Strings(pairs)
+
+	secret := sign([]byte("WebAppData"), []byte(cfg.BotToken))
+	expected := hex.EncodeToString(sign(secret, []byte(strings.Join(pairs, "\n"))))
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return InitData{}, ErrInvalid
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return InitData{}, ErrInvalid
+	}
+
+	data := InitData{AuthDate: time.Unix(authDate, 0), QueryID: values.Get("query_id")}
+
+	maxAge := cfg.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultMaxAge
+	}
+
+	if now.Sub(data.AuthDate) > maxAge {
+		return InitData{}, ErrExpired
+	}
+
+	if err = json.Unmarshal([]byte(values.Get("user")), &data.User); err != nil || data.User.ID == 0 {
+		return InitData{}, ErrInvalid
+	}
+
+	return data, nil
+}
+
+func sign(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
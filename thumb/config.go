@@ -0,0 +1,17 @@
+package thumb
+
+import "errors"
+
+// Config and ErrNotFound are kept apart from the thumbnailer so the builds
+// with the no_render tag can be configured without linking the decoders
+
+// Config is the configuration of announce image thumbnails
+type Config struct {
+	// Sizes are the allowed thumbnail widths in pixels
+	Sizes []int `yaml:"sizes"`
+	// Dir is the directory of cached thumbnails
+	Dir string `yaml:"dir"`
+}
+
+// ErrNotFound is returned when the original image doesn't exist
+var ErrNotFound = errors.New("image not found")
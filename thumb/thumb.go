@@ -0,0 +1,186 @@
+//go:build !no_render
+
+package thumb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	// decoders of the formats published on the site
+	_ "image/gif"
+	_ "image/png"
+
+	hmtpkErrors "github.com/chazari-x/hmtpk_parser/v2/errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	site = "https://hmtpk.ru"
+
+	// maxSource limits the size of a downloaded original
+	maxSource = 20 << 20
+	quality   = 80
+	timeout   = time.Second * 15
+)
+
+var (
+	defaultSizes = []int{160, 320, 640}
+
+	extensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+)
+
+// Thumbnailer downsizes images of the site and keeps the results on disk
+type Thumbnailer struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a new thumbnailer
+func New(cfg Config) *Thumbnailer {
+	if len(cfg.Sizes) == 0 {
+		cfg.Sizes = defaultSizes
+	}
+
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "hmtpk-thumbnails")
+	}
+
+	return &Thumbnailer{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+// Sizes returns the allowed thumbnail widths
+func (t *Thumbnailer) Sizes() []int {
+	return append([]int(nil), t.cfg.Sizes...)
+}
+
+// Path returns the path on the site of an image link of the site, false for
+// other links and files that are not images
+func Path(link string) (string, bool) {
+	src, ok := strings.CutPrefix(link, site)
+	if !ok || !strings.HasPrefix(src, "/") || !extensions[strings.ToLower(path.Ext(src))] {
+		return "", false
+	}
+
+	return src, true
+}
+
+// Get returns the JPEG thumbnail of the image at the path of the site
+func (t *Thumbnailer) Get(ctx context.Context, src string, size int) ([]byte, error) {
+	allowed := false
+	for _, s := range t.cfg.Sizes {
+		allowed = allowed || s == size
+	}
+
+	src = path.Clean("/" + src)
+	if !allowed || !extensions[strings.ToLower(path.Ext(src))] {
+		return nil, hmtpkErrors.ErrorBadRequest
+	}
+
+	sum := sha256.Sum256([]byte(src))
+	file := filepath.Join(t.cfg.Dir, strconv.Itoa(size), hex.EncodeToString(sum[:])+".jpg")
+
+	if data, err := os.ReadFile(file); err == nil {
+		return data, nil
+	}
+
+	img, err := t.fetch(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, resize(img, size), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	if err = save(file, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (t *Thumbnailer) fetch(ctx context.Context, src string) (image.Image, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, site+src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: %s", hmtpkErrors.ErrorBadResponse, resp.Status)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxSource))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", hmtpkErrors.ErrorBadResponse, err)
+	}
+
+	return img, nil
+}
+
+// resize scales the image down to the width on a white background, smaller images are kept
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+
+	height := max(1, bounds.Dy()*width/max(1, bounds.Dx()))
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	return dst
+}
+
+// save writes the file atomically so concurrent readers never see a partial thumbnail
+func save(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".thumb-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,86 @@
+package traceparent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Header carries the W3C trace context of the outgoing notifications, like
+// 00-<trace id>-<span id>-01
+const Header = "traceparent"
+
+const (
+	version = "00"
+	sampled = "01"
+)
+
+type contextKey struct{}
+
+// NewContext returns the context carrying the trace context
+func NewContext(ctx context.Context, parent string) context.Context {
+	return context.WithValue(ctx, contextKey{}, parent)
+}
+
+// FromContext returns the trace context of the context, empty outside a trace
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	parent, _ := ctx.Value(contextKey{}).(string)
+	return parent
+}
+
+// New returns the trace context of the root span of a new trace
+func New() string {
+	return strings.Join([]string{version, random(16), random(8), sampled}, "-")
+}
+
+// Child returns the trace context of a span of the trace of the parent, of a
+// new trace when the parent is not a valid trace context
+func Child(parent string) string {
+	if !valid(parent) {
+		return New()
+	}
+
+	parts := strings.Split(parent, "-")
+	return strings.Join([]string{version, parts[1], random(8), parts[3]}, "-")
+}
+
+// Set passes the trace context of the context in the header of the request
+func Set(req *http.Request) {
+	if parent := FromContext(req.Context()); parent != "" {
+		req.Header.Set(Header, parent)
+	}
+}
+
+// valid reports whether the value is a trace context of version 00 with
+// the trace and the span ids not zero
+func valid(parent string) bool {
+	parts := strings.Split(parent, "-")
+	if len(parts) != 4 || parts[0] != version {
+		return false
+	}
+
+	for i, length := range []int{2, 32, 16, 2} {
+		if len(parts[i]) != length || strings.ToLower(parts[i]) != parts[i] {
+			return false
+		}
+
+		if _, err := hex.DecodeString(parts[i]); err != nil {
+			return false
+		}
+	}
+
+	return strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
+
+func random(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
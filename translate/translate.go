@@ -0,0 +1,216 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is the configuration of the machine translation provider
+type Config struct {
+	// Provider is the translation service, enables translations when not empty
+	Provider string `yaml:"provider"`
+	// URL is the base URL of the provider
+	URL string `yaml:"url"`
+	// Key is the API key of the provider
+	Key string `yaml:"key"`
+	// CacheSize is the number of translations kept in memory
+	CacheSize int `yaml:"cache_size"`
+}
+
+const (
+	ProviderLibreTranslate = "libretranslate"
+	ProviderDeepL          = "deepl"
+
+	// Source is the language of the texts published by the college
+	Source = "ru"
+
+	defaultCacheSize = 1000
+	requestTimeout   = time.Second * 10
+)
+
+// Language matches the language codes accepted in requests
+var Language = regexp.MustCompile(`^[a-z]{2}$`)
+
+// Translator translates HTML texts from Russian
+type Translator interface {
+	Translate(ctx context.Context, text, target string) (string, error)
+}
+
+// New returns the translator of the configured provider wrapped in a cache
+func New(cfg Config) (Translator, error) {
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = defaultCacheSize
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+
+	var translator Translator
+	switch cfg.Provider {
+	case ProviderLibreTranslate:
+		translator = &libreTranslate{cfg: cfg, client: client}
+	case ProviderDeepL:
+		translator = &deepL{cfg: cfg, client: client}
+	default:
+		return nil, fmt.Errorf("unknown translation provider %q", cfg.Provider)
+	}
+
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("translation provider %s: url is required", cfg.Provider)
+	}
+
+	return newCache(translator, cfg.CacheSize), nil
+}
+
+// cache keeps translations by the hash of the text so an edited
+// announce is translated again and an unchanged one never is
+type cache struct {
+	translator Translator
+	size       int
+
+	mu      sync.Mutex
+	entries map[string]string
+	order   []string
+}
+
+func newCache(translator Translator, size int) *cache {
+	return &cache{translator: translator, size: size, entries: make(map[string]string)}
+}
+
+func (c *cache) Translate(ctx context.Context, text, target string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	key := target + ":" + hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	translation, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok {
+		return translation, nil
+	}
+
+	translation, err := c.translator.Translate(ctx, text, target)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok = c.entries[key]; !ok {
+		if len(c.order) >= c.size {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = translation
+
+	return translation, nil
+}
+
+// post sends the JSON body and decodes the JSON response of the provider
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	request.Header = header
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("translation provider: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// libreTranslate uses the API of LibreTranslate
+type libreTranslate struct {
+	cfg    Config
+	client *http.Client
+}
+
+func (t *libreTranslate) Translate(ctx context.Context, text, target string) (string, error) {
+	body := map[string]string{
+		"q":      text,
+		"source": Source,
+		"target": target,
+		"format": "html",
+	}
+
+	if t.cfg.Key != "" {
+		body["api_key"] = t.cfg.Key
+	}
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+	}
+
+	if err := post(ctx, t.client, strings.TrimSuffix(t.cfg.URL, "/")+"/translate", http.Header{}, body, &result); err != nil {
+		return "", err
+	}
+
+	return result.TranslatedText, nil
+}
+
+// deepL uses the v2 API of DeepL
+type deepL struct {
+	cfg    Config
+	client *http.Client
+}
+
+func (t *deepL) Translate(ctx context.Context, text, target string) (string, error) {
+	body := map[string]interface{}{
+		"text":         []string{text},
+		"source_lang":  strings.ToUpper(Source),
+		"target_lang":  strings.ToUpper(target),
+		"tag_handling": "html",
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "DeepL-Auth-Key "+t.cfg.Key)
+
+	var result struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+
+	if err := post(ctx, t.client, strings.TrimSuffix(t.cfg.URL, "/")+"/v2/translate", header, body, &result); err != nil {
+		return "", err
+	}
+
+	if len(result.Translations) == 0 {
+		return "", fmt.Errorf("translation provider: empty response")
+	}
+
+	return result.Translations[0].Text, nil
+}
@@ -0,0 +1,264 @@
+package vault
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the encryption of the stored secrets
+type Config struct {
+	// Key is the base64 AES key of 16, 24 or 32 bytes
+	Key string `yaml:"key"`
+	// KeyFile is read for the key when Key is empty, like the file a KMS agent
+	// or a secret manager mounts
+	KeyFile string `yaml:"key_file"`
+	// TTL is how long a secret is kept after it is put
+	TTL time.Duration `yaml:"ttl"`
+}
+
+const (
+	defaultTTL = time.Hour * 24 * 7
+
+	prefix = "vault:"
+
+	cleanupInterval = time.Hour
+)
+
+// Vault keeps the secrets like the sessions of the personal cabinet encrypted
+// with AES-GCM in the store, they expire after the TTL
+type Vault struct {
+	cfg  Config
+	aead cipher.AEAD
+	// store holds the sealed secrets, Redis when configured
+	store storage.Store
+	now   func() time.Time
+	log   *logrus.Logger
+}
+
+// sealed is a stored secret, the expiry is authenticated with the data so it
+// can be read by the cleanup without the key but not changed
+type sealed struct {
+	ExpiresAt int64  `json:"expires_at"`
+	Nonce     string `json:"nonce"`
+	Data      string `json:"data"`
+}
+
+// ErrNoKey is returned by New without a key for a store shared by instances
+var ErrNoKey = errors.New("no key configured for the shared store")
+
+// New creates the vault of the configuration. Without a key a random one is
+// used, the secrets are lost on restart, which only a store of the instance
+// allows: shared reports whether other instances read the store
+func New(cfg Config, store storage.Store, shared bool, logger *logrus.Logger) (*Vault, error) {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+
+	key, err := loadKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		// the secrets sealed with a random key can't be read by the other
+		// instances and every start would make the ones in the store unreadable
+		if shared {
+			return nil, ErrNoKey
+		}
+
+		key = make([]byte, 32)
+		if _, err = rand.Read(key); err != nil {
+			return nil, err
+		}
+
+		if logger != nil {
+			logger.Warn("vault: no key configured, the stored sessions are lost on restart")
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("vault key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Vault{cfg: cfg, aead: aead, store: store, now: time.Now, log: logger}, nil
+}
+
+// loadKey returns the key of the configuration, nil without one
+func loadKey(cfg Config) ([]byte, error) {
+	encoded := cfg.Key
+	if encoded == "" && cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("vault key file: %w", err)
+		}
+
+		encoded = string(data)
+	}
+
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("vault key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Put encrypts and stores the secret under the id for the TTL
+func (v *Vault) Put(ctx context.Context, id string, secret []byte) error {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	expiresAt := v.now().Add(v.cfg.TTL).Unix()
+
+	data, err := json.Marshal(sealed{
+		ExpiresAt: expiresAt,
+		Nonce:     base64.StdEncoding.EncodeToString(nonce),
+		Data:      base64.StdEncoding.EncodeToString(v.aead.Seal(nil, nonce, secret, additional(id, expiresAt))),
+	})
+	if err != nil {
+		return err
+	}
+
+	return v.store.SetTTL(ctx, prefix+id, string(data), v.cfg.TTL)
+}
+
+// Get returns the secret of the id, storage.ErrNotFound when it is missing,
+// expired or was sealed with another key. The secrets of another key are kept
+// for the instances having it, like the ones rolled out with a new key
+func (v *Vault) Get(ctx context.Context, id string) ([]byte, error) {
+	value, err := v.store.Get(ctx, prefix+id)
+	if err != nil {
+		return nil, err
+	}
+
+	var s sealed
+	if err = json.Unmarshal([]byte(value), &s); err != nil {
+		return nil, err
+	}
+
+	if v.now().Unix() >= s.ExpiresAt {
+		return nil, v.drop(ctx, id)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(s.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != v.aead.NonceSize() {
+		return nil, v.unreadable(id)
+	}
+
+	secret, err := v.aead.Open(nil, nonce, data, additional(id, s.ExpiresAt))
+	if err != nil {
+		return nil, v.unreadable(id)
+	}
+
+	return secret, nil
+}
+
+// unreadable reports the secret that can't be decrypted is not found, the key
+// was changed or the record was tampered with. It is left in the store
+func (v *Vault) unreadable(id string) error {
+	if v.log != nil {
+		v.log.Warnf("vault: %s can't be decrypted", id)
+	}
+
+	return storage.ErrNotFound
+}
+
+// drop deletes the expired secret and reports it is not found
+func (v *Vault) drop(ctx context.Context, id string) error {
+	if err := v.store.Delete(ctx, prefix+id); err != nil {
+		return err
+	}
+
+	return storage.ErrNotFound
+}
+
+// Revoke deletes the secret of the id
+func (v *Vault) Revoke(ctx context.Context, id string) error {
+	return v.store.Delete(ctx, prefix+id)
+}
+
+// additional binds the ciphertext to its id and expiry
+func additional(id string, expiresAt int64) []byte {
+	return []byte(id + "\x00" + strconv.FormatInt(expiresAt, 10))
+}
+
+// Cleanup deletes the expired secrets
+func (v *Vault) Cleanup(ctx context.Context) error {
+	keys, err := v.store.Keys(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	now := v.now().Unix()
+	for _, key := range keys {
+		value, err := v.store.Get(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		var s sealed
+		if json.Unmarshal([]byte(value), &s) == nil && now < s.ExpiresAt {
+			continue
+		}
+
+		if err = v.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run deletes the expired secrets every hour until the context is done
+func (v *Vault) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := v.Cleanup(ctx); err != nil && v.log != nil {
+				v.log.Errorf("vault: cleanup: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
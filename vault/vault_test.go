@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/storage"
+)
+
+func key(t *testing.T) string {
+	t.Helper()
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestGet(t *testing.T) {
+	sealing := key(t)
+
+	tests := []struct {
+		name string
+		// key opens the vault reading the secret
+		key string
+		id  string
+		// after is the time passed since the secret was put
+		after time.Duration
+		err   error
+		// kept reports whether the record stays in the store
+		kept bool
+	}{
+		{name: "same key", key: sealing, id: "id", kept: true},
+		{name: "another key", key: key(t), id: "id", err: storage.ErrNotFound, kept: true},
+		{name: "another id", key: sealing, id: "other", err: storage.ErrNotFound, kept: true},
+		{name: "expired", key: sealing, id: "id", after: time.Hour, err: storage.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemory()
+
+			writer, err := New(Config{Key: sealing, TTL: time.Hour}, store, true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = writer.Put(context.Background(), "id", []byte("session")); err != nil {
+				t.Fatal(err)
+			}
+
+			// the record copied under another id must not open with it
+			if tt.id != "id" {
+				value, _ := store.Get(context.Background(), prefix+"id")
+				_ = store.Set(context.Background(), prefix+tt.id, value)
+			}
+
+			reader, err := New(Config{Key: tt.key, TTL: time.Hour}, store, true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader.now = func() time.Time { return time.Now().Add(tt.after) }
+
+			secret, err := reader.Get(context.Background(), tt.id)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+
+			if err == nil && string(secret) != "session" {
+				t.Fatalf("got %q", secret)
+			}
+
+			if _, err = store.Get(context.Background(), prefix+tt.id); (err == nil) != tt.kept {
+				t.Fatalf("kept: got %v, want %v", err == nil, tt.kept)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		shared bool
+		err    bool
+	}{
+		{name: "key", cfg: Config{Key: key(t)}, shared: true},
+		{name: "random key of the instance", cfg: Config{}},
+		{name: "no key for a shared store", cfg: Config{}, shared: true, err: true},
+		{name: "short key", cfg: Config{Key: base64.StdEncoding.EncodeToString([]byte("short"))}, err: true},
+		{name: "malformed key", cfg: Config{Key: "not base64!"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg, storage.NewMemory(), tt.shared, nil); (err != nil) != tt.err {
+				t.Fatalf("got %v, want an error %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package warmup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chazari-x/hmtpk-parser-api/maintenance"
+	"github.com/chazari-x/hmtpk_parser/v2/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of the cache warmer
+type Config struct {
+	// Interval between warmup cycles, the warmer is disabled when zero
+	Interval time.Duration `yaml:"interval"`
+	// Concurrency is the number of schedules fetched at once
+	Concurrency int `yaml:"concurrency"`
+	// Threshold is the share of warmed groups below which readiness is degraded
+	Threshold float64 `yaml:"threshold"`
+	// Recheck is the interval between fetches of the weeks the site published
+	// partially, disabled when not shorter than the interval
+	Recheck time.Duration `yaml:"recheck"`
+}
+
+// Source is the part of the parser used by the warmer
+type Source interface {
+	GetGroupOptions(ctx context.Context) ([]model.Option, error)
+	GetScheduleByGroup(ctx context.Context, group, date string) ([]model.Schedule, error)
+}
+
+const (
+	defaultConcurrency = 4
+	defaultThreshold   = 0.9
+	defaultRecheck     = time.Minute
+
+	timeout = time.Second * 15
+
+	StatusStarting = "starting"
+	StatusDegraded = "degraded"
+	StatusOK       = "ok"
+)
+
+// Progress describes the state of the warmup
+type Progress struct {
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Warmed     int        `json:"warmed"`
+	Failed     int        `json:"failed"`
+	Coverage   float64    `json:"coverage"`
+	Cycles     int        `json:"cycles"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Warmer periodically fetches the schedules of all groups for the current
+// week, filling the parser cache and keeping a snapshot in memory
+type Warmer struct {
+	cfg      Config
+	source   Source
+	log      *logrus.Logger
+	location *time.Location
+
+	mu        sync.RWMutex
+	progress  Progress
+	groups    []model.Option
+	schedules map[string][]model.Schedule
+	warmedAt  map[string]time.Time
+	// partial are the groups whose week is not published completely
+	partial map[string]bool
+
+	peak         func() bool
+	peakInterval time.Duration
+
+	// changed is called when a warmed week differs from the one warmed before
+	changed func(group string, schedule []model.Schedule)
+}
+
+// NewWarmer creates a new warmer
+func NewWarmer(cfg Config, source Source, logger *logrus.Logger) *Warmer {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultThreshold
+	}
+
+	if cfg.Recheck <= 0 {
+		cfg.Recheck = defaultRecheck
+	}
+
+	return &Warmer{
+		cfg:       cfg,
+		source:    source,
+		log:       logger,
+		location:  time.Local,
+		progress:  Progress{Status: StatusStarting},
+		schedules: make(map[string][]model.Schedule),
+		warmedAt:  make(map[string]time.Time),
+		partial:   make(map[string]bool),
+	}
+}
+
+// OnChange sets the function called when the week of a group differs from
+// the one warmed before, it is not called for the first fetch of a group
+func (w *Warmer) OnChange(changed func(group string, schedule []model.Schedule)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.changed = changed
+}
+
+// Published reports whether the site published the day of the week, the site
+// lists the days it has not published yet without a heading
+func Published(day model.Schedule) bool {
+	return strings.TrimSpace(day.Date) != ""
+}
+
+// partial reports whether a day from Monday to Saturday of the weekly schedule
+// is not published yet, Sunday is rarely published at all
+func partial(schedule []model.Schedule) bool {
+	for i, day := range schedule {
+		if i < 6 && !Published(day) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetLocation sets the time zone in which the current week is resolved
+func (w *Warmer) SetLocation(location *time.Location) {
+	w.location = location
+}
+
+// SetPeak makes the warmer run a cycle every interval while peak reports the
+// morning rush, so no request of a student misses the cache
+func (w *Warmer) SetPeak(peak func() bool, interval time.Duration) {
+	w.peak, w.peakInterval = peak, interval
+}
+
+// Run warms the cache until the context is canceled
+func (w *Warmer) Run(ctx context.Context) {
+	if w.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	// the weeks published partially are fetched again between the cycles
+	var recheck <-chan time.Time
+	if w.cfg.Recheck < w.cfg.Interval {
+		recheckTicker := time.NewTicker(w.cfg.Recheck)
+		defer recheckTicker.Stop()
+		recheck = recheckTicker.C
+	}
+
+	var peak <-chan time.Time
+	if w.peak != nil && w.peakInterval < w.cfg.Interval {
+		peakTicker := time.NewTicker(w.peakInterval)
+		defer peakTicker.Stop()
+		peak = peakTicker.C
+	}
+
+	w.cycle(ctx)
+	for {
+		select {
+		case <-ticker.C:
+			w.cycle(ctx)
+		case <-recheck:
+			w.recheck(ctx)
+		case <-peak:
+			if w.peak() {
+				w.cycle(ctx)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Warm fetches the week of every group once and returns when it is done,
+// like a cycle of Run
+func (w *Warmer) Warm(ctx context.Context) {
+	w.cycle(ctx)
+}
+
+func (w *Warmer) cycle(ctx context.Context) {
+	optionsCtx, cancel := context.WithTimeout(ctx, timeout)
+	groups, err := w.source.GetGroupOptions(optionsCtx)
+	cancel()
+	if errors.Is(err, maintenance.ErrMaintenance) {
+		w.log.Debugf("warmup: %s", err)
+		return
+	} else if err != nil {
+		w.log.Errorf("warmup: %s", err)
+		return
+	}
+
+	date := time.Now().In(w.location).Format("02.01.2006")
+
+	w.mu.Lock()
+	w.groups = groups
+	w.progress.Total = len(groups)
+	w.progress.Failed = 0
+	started := time.Now()
+	w.progress.StartedAt = &started
+	w.update()
+	w.mu.Unlock()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	for _, group := range groups {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		go func(group string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if !w.warm(ctx, group, date) {
+				w.mu.Lock()
+				w.progress.Failed++
+				w.mu.Unlock()
+			}
+		}(group.Value)
+	}
+
+	wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.progress.Cycles++
+	finished := time.Now()
+	w.progress.FinishedAt = &finished
+	w.update()
+
+	w.log.Infof("warmup: %d of %d groups warmed, %d failed", w.progress.Warmed, w.progress.Total, w.progress.Failed)
+}
+
+// warm fetches the week of the group and keeps it, reports false on failure
+func (w *Warmer) warm(ctx context.Context, group, date string) bool {
+	scheduleCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	schedule, err := w.source.GetScheduleByGroup(scheduleCtx, group, date)
+	if errors.Is(err, maintenance.ErrMaintenance) {
+		w.log.Debugf("warmup: group %s: %s", group, err)
+		return false
+	} else if err != nil {
+		w.log.Warnf("warmup: group %s: %s", group, err)
+		return false
+	}
+
+	w.keep(group, schedule)
+
+	return true
+}
+
+// keep replaces the warmed week of the group, calling the change function
+// when it differs
+func (w *Warmer) keep(group string, schedule []model.Schedule) {
+	w.mu.Lock()
+	previous, known := w.schedules[group]
+	w.schedules[group] = schedule
+	w.warmedAt[group] = time.Now()
+	w.partial[group] = partial(schedule)
+	w.update()
+	changed := w.changed
+	w.mu.Unlock()
+
+	// a new week is not a change
+	if changed != nil && known && firstDate(previous) == firstDate(schedule) && !reflect.DeepEqual(previous, schedule) {
+		changed(group, schedule)
+	}
+}
+
+// firstDate returns the heading of the first published day of the week
+func firstDate(schedule []model.Schedule) string {
+	for _, day := range schedule {
+		if Published(day) {
+			return day.Date
+		}
+	}
+
+	return ""
+}
+
+// recheck fetches again the weeks of the groups the site published partially
+func (w *Warmer) recheck(ctx context.Context) {
+	w.mu.RLock()
+	groups := make([]string, 0, len(w.partial))
+	for group, partial := range w.partial {
+		if partial {
+			groups = append(groups, group)
+		}
+	}
+	w.mu.RUnlock()
+
+	if len(groups) == 0 {
+		return
+	}
+
+	date := time.Now().In(w.location).Format("02.01.2006")
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	for _, group := range groups {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		go func(group string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			w.warm(ctx, group, date)
+		}(group)
+	}
+
+	wg.Wait()
+
+	w.log.Debugf("warmup: %d partially published weeks checked again", len(groups))
+}
+
+// update recalculates coverage and status, the lock must be held
+func (w *Warmer) update() {
+	known := make(map[string]struct{}, len(w.groups))
+	for _, group := range w.groups {
+		known[group.Value] = struct{}{}
+	}
+
+	for group := range w.schedules {
+		if _, ok := known[group]; !ok {
+			delete(w.schedules, group)
+			delete(w.warmedAt, group)
+			delete(w.partial, group)
+		}
+	}
+
+	w.progress.Warmed = len(w.schedules)
+	w.progress.Coverage = 0
+	if w.progress.Total > 0 {
+		w.progress.Coverage = float64(w.progress.Warmed) / float64(w.progress.Total)
+	}
+
+	switch {
+	case w.progress.Total == 0:
+		w.progress.Status = StatusStarting
+	case w.progress.Coverage < w.cfg.Threshold:
+		w.progress.Status = StatusDegraded
+	default:
+		w.progress.Status = StatusOK
+	}
+}
+
+// Progress returns the current state of the warmup
+func (w *Warmer) Progress() Progress {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.progress
+}
+
+// Ready reports readiness. The instance accepts traffic while warming up,
+// so the response is 200 with a degraded status until coverage reaches the threshold
+func (w *Warmer) Ready(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	progress := Progress{Status: StatusOK}
+	if w != nil && w.cfg.Interval > 0 {
+		progress = w.Progress()
+	}
+
+	_ = json.NewEncoder(rw).Encode(progress)
+}
+
+// Schedules returns the warmed weekly schedules by group
+func (w *Warmer) Schedules() map[string][]model.Schedule {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	schedules := make(map[string][]model.Schedule, len(w.schedules))
+	for group, schedule := range w.schedules {
+		schedules[group] = schedule
+	}
+
+	return schedules
+}
+
+// Put replaces the warmed schedule of the group with a schedule of the current
+// week fetched outside of the cycles, e.g. refreshed by an administrator
+func (w *Warmer) Put(group string, schedule []model.Schedule) {
+	w.keep(group, schedule)
+}
+
+// Groups returns the group options of the last cycle
+func (w *Warmer) Groups() []model.Option {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return append([]model.Option(nil), w.groups...)
+}
+
+// WarmedAt returns when the schedule of every warmed group was fetched
+func (w *Warmer) WarmedAt() map[string]time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	warmedAt := make(map[string]time.Time, len(w.warmedAt))
+	for group, at := range w.warmedAt {
+		warmedAt[group] = at
+	}
+
+	return warmedAt
+}
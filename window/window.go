@@ -0,0 +1,114 @@
+package window
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a recurring period of the day
+type Window struct {
+	// Days are the English names of the weekdays of the window, every day when empty
+	Days []string `yaml:"days"`
+	// From and To are the local times of the window as 15:04, a window ending
+	// before it starts lasts past midnight and belongs to the day it starts on
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+type window struct {
+	days     map[time.Weekday]bool
+	from, to time.Duration
+}
+
+// Set tells whether a time is in one of the windows
+type Set struct {
+	windows  []window
+	location *time.Location
+}
+
+// New validates the configured windows
+func New(windows []Window) (*Set, error) {
+	w := &Set{location: time.Local}
+	for i, configured := range windows {
+		from, err := clock(configured.From)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i+1, err)
+		}
+
+		to, err := clock(configured.To)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i+1, err)
+		}
+
+		if from == to {
+			return nil, fmt.Errorf("window %d is empty", i+1)
+		}
+
+		days := make(map[time.Weekday]bool)
+		for _, name := range configured.Days {
+			day, ok := weekday(name)
+			if !ok {
+				return nil, fmt.Errorf("window %d: unknown weekday %q", i+1, name)
+			}
+			days[day] = true
+		}
+
+		w.windows = append(w.windows, window{days: days, from: from, to: to})
+	}
+
+	return w, nil
+}
+
+// clock parses the time of the day as 15:04
+func clock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func weekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+
+	return 0, false
+}
+
+// SetLocation sets the time zone of the windows
+func (w *Set) SetLocation(location *time.Location) {
+	w.location = location
+}
+
+// Active reports whether the time is in a window
+func (w *Set) Active(now time.Time) bool {
+	now = now.In(w.location)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, w.location)
+	clock := now.Sub(midnight)
+	yesterday := midnight.AddDate(0, 0, -1).Weekday()
+
+	for _, window := range w.windows {
+		on := func(day time.Weekday) bool {
+			return len(window.days) == 0 || window.days[day]
+		}
+
+		if window.from < window.to {
+			if on(now.Weekday()) && clock >= window.from && clock < window.to {
+				return true
+			}
+			continue
+		}
+
+		// past midnight the window belongs to the day before
+		if (on(now.Weekday()) && clock >= window.from) || (on(yesterday) && clock < window.to) {
+			return true
+		}
+	}
+
+	return false
+}